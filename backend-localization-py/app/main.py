@@ -236,9 +236,9 @@ async def enqueue(req: LocalizeRequest):
                         raise HTTPException(status_code=404, detail="Ruangan not found")
 
                     cursor.execute(
-                        "INSERT INTO lokalisasi_jobs (id, id_data, id_metode, id_ruangan, status, created_at, updated_at)"
-                        " VALUES (%s, %s, %s, %s, 'queued', %s, %s)",
-                        (job_id, req.id_data, req.id_metode, req.id_ruangan, datetime.now(), datetime.now())
+                        "INSERT INTO lokalisasi_jobs (id, id_data, id_metode, id_ruangan, input_object_path, filter_version, input, baseline_version, baseline_mode, feature_set, status, created_at, updated_at)"
+                        " VALUES (%s, %s, %s, %s, %s, %s, %s, %s, %s, %s, 'queued', %s, %s)",
+                        (job_id, req.id_data, req.id_metode, req.id_ruangan, req.input_object_path, req.filter_version, req.input or "raw", req.baseline_version, req.baseline_mode, req.feature_set, datetime.now(), datetime.now())
                     )
         end_validate = time.time()
         logger.info(f"VALIDATE_AND_INSERT_JOB completed in {(end_validate - start_validate) * 1000:.2f}ms")
@@ -296,6 +296,14 @@ async def get_status(job_id: str):
                     response["hasil_y"] = float(cached_data["hasil_y"])
                 if "error" in cached_data:
                     response["error_message"] = cached_data["error"]
+                if "input" in cached_data:
+                    response["input"] = cached_data["input"]
+                for field in ("filter_version", "baseline_version"):
+                    if field in cached_data:
+                        response[field] = int(cached_data[field])
+                for field in ("baseline_mode", "feature_set"):
+                    if field in cached_data:
+                        response[field] = cached_data[field]
 
                 step(req_id, "GET_JOB_STATUS_CACHE_HIT", (time.time() - task_start_time) * 1000)
                 logger.info(f"[{req_id}] Redis cache hit processed in {(time.time() - task_start_time) * 1000:.2f}ms")
@@ -311,6 +319,11 @@ async def get_status(job_id: str):
                             lj.created_at,
                             lj.updated_at,
                             lj.error_message,
+                            lj.input,
+                            lj.filter_version,
+                            lj.baseline_version,
+                            lj.baseline_mode,
+                            lj.feature_set,
                             hl.hasil_x,
                             hl.hasil_y
                         FROM lokalisasi_jobs lj
@@ -330,6 +343,11 @@ async def get_status(job_id: str):
                         "status": row['status'],
                         "created_at": row['created_at'].isoformat() if row['created_at'] else None,
                         "updated_at": row['updated_at'].isoformat() if row['updated_at'] else None,
+                        "input": row['input'] or "raw",
+                        "filter_version": row['filter_version'],
+                        "baseline_version": row['baseline_version'],
+                        "baseline_mode": row['baseline_mode'],
+                        "feature_set": row['feature_set'],
                         "from_cache": False
                     }
 
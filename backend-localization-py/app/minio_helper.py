@@ -24,4 +24,14 @@ def get_object(bucket: str, object_name: str) -> bytes:
         return response.read()
     finally:
         response.close()
-        response.release_conn()
\ No newline at end of file
+        response.release_conn()
+
+
+# Remove every object under a prefix; returns the number removed
+
+def remove_prefix(bucket: str, prefix: str) -> int:
+    removed = 0
+    for obj in client.list_objects(bucket, prefix=prefix, recursive=True):
+        client.remove_object(bucket, obj.object_name)
+        removed += 1
+    return removed
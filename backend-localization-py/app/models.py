@@ -1,6 +1,7 @@
 import uuid
 from enum import Enum
 from datetime import datetime
+from typing import Optional
 from pydantic import BaseModel
 from .db import get_connection
 
@@ -23,6 +24,19 @@ class LocalizeRequest(BaseModel):
     id_data: str
     id_ruangan: str
     id_metode: str
+    # Object MinIO input turunan yang disiapkan gateway (mis. fase tersanitasi);
+    # None berarti worker memakai file CSI asli
+    input_object_path: Optional[str] = None
+    # Jenis input: raw, filtered, sanitized_phase, atau features
+    # (None = raw untuk job lama)
+    input: Optional[str] = None
+    # Versi pipeline filter yang diterapkan pada input (None = tanpa filter)
+    filter_version: Optional[int] = None
+    # Versi dan mode baseline ruangan yang diterapkan (None = tanpa baseline)
+    baseline_version: Optional[int] = None
+    baseline_mode: Optional[str] = None
+    # ID set fitur (mis. "csi@1") untuk input features
+    feature_set: Optional[str] = None
 
 # Optional: create table if not exists
 # Call once at startup
@@ -38,6 +52,12 @@ def create_lokalisasi_table():
                     id_data CHAR(36) NOT NULL,
                     id_ruangan CHAR(36) NOT NULL,
                     id_metode CHAR(36) NOT NULL,
+                    input_object_path VARCHAR(512) NULL,
+                    filter_version INT NULL,
+                    input VARCHAR(32) NULL,
+                    baseline_version INT NULL,
+                    baseline_mode VARCHAR(16) NULL,
+                    feature_set VARCHAR(64) NULL,
                     status ENUM('queued','running','done','failed') NOT NULL DEFAULT 'queued',
                     created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
                     updated_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6) ON UPDATE CURRENT_TIMESTAMP(6),
@@ -47,6 +67,25 @@ def create_lokalisasi_table():
                 )
                 """
             )
+            # Tabel lama dibuat sebelum ada kolom input_object_path / filter_version / input / baseline / feature_set
+            cursor.execute(
+                "ALTER TABLE lokalisasi_jobs ADD COLUMN IF NOT EXISTS input_object_path VARCHAR(512) NULL AFTER id_metode"
+            )
+            cursor.execute(
+                "ALTER TABLE lokalisasi_jobs ADD COLUMN IF NOT EXISTS filter_version INT NULL AFTER input_object_path"
+            )
+            cursor.execute(
+                "ALTER TABLE lokalisasi_jobs ADD COLUMN IF NOT EXISTS input VARCHAR(32) NULL AFTER filter_version"
+            )
+            cursor.execute(
+                "ALTER TABLE lokalisasi_jobs ADD COLUMN IF NOT EXISTS baseline_version INT NULL AFTER input"
+            )
+            cursor.execute(
+                "ALTER TABLE lokalisasi_jobs ADD COLUMN IF NOT EXISTS baseline_mode VARCHAR(16) NULL AFTER baseline_version"
+            )
+            cursor.execute(
+                "ALTER TABLE lokalisasi_jobs ADD COLUMN IF NOT EXISTS feature_set VARCHAR(64) NULL AFTER baseline_mode"
+            )
             conn.commit()
     finally:
         conn.close()
\ No newline at end of file
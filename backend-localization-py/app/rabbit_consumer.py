@@ -30,6 +30,9 @@ QUEUE_NAME = os.getenv("RABBITMQ_QUEUE_NAME", "lok_requests")  # Default to 'lok
 
 prefetch = int(os.getenv("RABBITMQ_PREFETCH", 1))  # Default to 1 if not set
 
+# Jenis input job yang dikenal gateway; dukungan per model dicek worker
+JOB_INPUTS = ("raw", "filtered", "sanitized_phase", "features")
+
 class RabbitMQConsumer:
     def __init__(self):
         self.connection = None
@@ -108,6 +111,17 @@ class RabbitMQConsumer:
                     logger.warning("Received message without id_ruangan, skipping")
                     ch.basic_ack(delivery_tag=method.delivery_tag)
                     return
+                # Kosong bila job memakai file CSI asli
+                input_object_path = data.get("input_object_path") or None
+                filter_version = data.get("filter_version")
+                baseline_version = data.get("baseline_version")
+                baseline_mode = data.get("baseline_mode")
+                feature_set = data.get("feature_set")
+                input_kind = data.get("input") or "raw"
+                if input_kind not in JOB_INPUTS:
+                    logger.warning(f"Received message with unknown input '{input_kind}', skipping")
+                    ch.basic_ack(delivery_tag=method.delivery_tag)
+                    return
                 created_at = datetime.now()
             except (json.JSONDecodeError, ValueError) as e:
                 logger.error(f"Failed to decode message body: {e}")
@@ -115,7 +129,7 @@ class RabbitMQConsumer:
                 return
             total_task_time = (time.time() - task_start_time) * 1000
             step(req_id, "RABBITMQ_CONSUMER_MESSAGE_SUCCESS", total_task_time)  # Log success step
-            logger.info(f"Received job_id={job_id}, id_data={id_data}, id_metode={id_metode}, id_ruangan={id_ruangan}, created_at={created_at}")
+            logger.info(f"Received job_id={job_id}, id_data={id_data}, id_metode={id_metode}, id_ruangan={id_ruangan}, input={input_kind}, created_at={created_at}")
 
             @with_db_retry(max_retries=3, delay=2)
             def insert_job():
@@ -130,9 +144,9 @@ class RabbitMQConsumer:
                                 logger.warning(f"Job {job_id} already exists, skipping insert")
                                 return
                             cursor.execute(
-                                "INSERT INTO lokalisasi_jobs (id, id_data, id_metode, id_ruangan, status, created_at, updated_at)"
-                                " VALUES (%s,%s,%s,%s,'queued',%s,%s)",
-                                (job_id, id_data, id_metode, id_ruangan, created_at, created_at)
+                                "INSERT INTO lokalisasi_jobs (id, id_data, id_metode, id_ruangan, input_object_path, filter_version, input, baseline_version, baseline_mode, feature_set, status, created_at, updated_at)"
+                                " VALUES (%s,%s,%s,%s,%s,%s,%s,%s,%s,%s,'queued',%s,%s)",
+                                (job_id, id_data, id_metode, id_ruangan, input_object_path, filter_version, input_kind, baseline_version, baseline_mode, feature_set, created_at, created_at)
                             )
                             return True
             
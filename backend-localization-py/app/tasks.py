@@ -6,7 +6,7 @@ from typing import Dict, Any, Optional
 from celery import Celery
 from .setup_celery import celery
 from .db import get_connection, transaction, get_db_connection
-from .minio_helper import get_object
+from .minio_helper import get_object, remove_prefix
 from .setup_redis import redis_client
 import logging
 from dotenv import load_dotenv
@@ -38,6 +38,8 @@ logging.basicConfig(
 NOTIFY_CHANNEL_BASE = os.getenv("NOTIFY_CHANNEL_BASE", "lok_notify")
 CACHE_TTL_SECONDS = int(os.getenv("CACHE_TTL_SECONDS", 3600))
 MINIO_BUCKET_NAME = os.getenv("MINIO_BUCKET_NAME", "bismillahta")
+# Input turunan yang disiapkan gateway per job (Job-Inputs/<job_id>/<input>.csv)
+JOB_INPUTS_PREFIX = "Job-Inputs"
 
 def test_redis_connection():
     """Test Redis connection for debugging"""
@@ -76,6 +78,16 @@ def cache_status(job_id: str, status: str, x: Optional[float] = None, y: Optiona
     except Exception as e:
         logger.error(f"❌ Error caching status for job {job_id}: {e}")
 
+def cache_job_inputs(job_id: str, inputs: Dict[str, Any]) -> None:
+    """Cache the input provenance (input kind, filter/baseline version) of a job next to its status."""
+    key = f"lok_status:{job_id}"
+    payload = {k: v for k, v in inputs.items() if v is not None}
+    try:
+        redis_client.hset(key, mapping=payload)
+        redis_client.expire(key, CACHE_TTL_SECONDS)
+    except Exception as e:
+        logger.error(f"❌ Error caching inputs for job {job_id}: {e}")
+
 # Di tasks.py, update function notify_pubsub
 import time
 
@@ -162,6 +174,16 @@ def notify_pubsub(job_id: str, status: str, x: Optional[float] = None, y: Option
         logger.error(f"Full traceback: {traceback.format_exc()}")
 
 
+def cleanup_job_input(job_id: str) -> None:
+    """Hapus input turunan job setelah job selesai (done/failed); best effort."""
+    try:
+        removed = remove_prefix(MINIO_BUCKET_NAME, f"{JOB_INPUTS_PREFIX}/{job_id}/")
+        if removed:
+            logger.info(f"🗑️  Removed {removed} job input object(s) for job {job_id}")
+    except Exception as e:
+        logger.error(f"❌ Failed to remove job inputs for job {job_id}: {e}")
+
+
 def wait_for_subscriber(job_id: str, timeout_s=10):
     """Blocking wait until Go-gateway signals subscriber_ready."""
     ps = redis_client.pubsub()
@@ -195,6 +217,8 @@ def localize_task(self, job_id: str) -> Dict[str, Any]:
     # Inisialisasi variabel untuk cleanup
     csv_file_path: Optional[str] = None
     model_file_path: Optional[str] = None
+    # True setelah job mencapai done/failed; input turunannya lalu dihapus
+    finished = False
     
     try:
         # ===== UPDATE STATUS TO RUNNING =====
@@ -237,7 +261,12 @@ def localize_task(self, job_id: str) -> Dict[str, Any]:
                         lj.id_data,
                         lj.id_metode,
                         lj.id_ruangan,
-                        dc.object_path AS data_path,
+                        COALESCE(lj.input_object_path, dc.object_path) AS data_path,
+                        lj.input,
+                        lj.filter_version,
+                        lj.baseline_version,
+                        lj.baseline_mode,
+                        lj.feature_set,
                         ml.path_file AS model_path
                     FROM lokalisasi_jobs lj
                     JOIN data_csv dc ON lj.id_data = dc.id
@@ -254,8 +283,16 @@ def localize_task(self, job_id: str) -> Dict[str, Any]:
         ruangan_id = row['id_ruangan']
         data_path = row['data_path']
         model_path = row['model_path']
+        input_kind = row['input'] or "raw"
+        cache_job_inputs(job_id, {
+            "input": input_kind,
+            "filter_version": row['filter_version'],
+            "baseline_version": row['baseline_version'],
+            "baseline_mode": row['baseline_mode'],
+            "feature_set": row['feature_set'],
+        })
         
-        logger.info(f"📂 Job {job_id} details: data={data_path}, model={model_path}")
+        logger.info(f"📂 Job {job_id} details: data={data_path}, input={input_kind}, model={model_path}")
         
         # ===== DOWNLOAD FILES =====
         with StepTimer(req_id, "DOWNLOAD_CSV_FROM_MINIO"):
@@ -280,7 +317,7 @@ def localize_task(self, job_id: str) -> Dict[str, Any]:
         # ===== RUN LOCALIZATION =====
         logger.info(f"🔍 Job {job_id}: starting localization process")
         with StepTimer(req_id, "RUN_LOCALIZATION"):
-            result = run_localization(csv_file_path, model_file_path)
+            result = run_localization(csv_file_path, model_file_path, input_kind, row['feature_set'])
             x = result["x"]
             y = result["y"]
             logger.info(f"🎯 Job {job_id}: localization result: x={x}, y={y}")
@@ -299,6 +336,7 @@ def localize_task(self, job_id: str) -> Dict[str, Any]:
                         "UPDATE lokalisasi_jobs SET status=%s, updated_at=%s WHERE id=%s",
                         ("done", datetime.now(timezone.utc), job_id)
                     )
+        finished = True
 
         # ===== SUCCESS NOTIFICATION =====
         with StepTimer(req_id, "NOTIFY_SUCCESS"):
@@ -320,6 +358,7 @@ def localize_task(self, job_id: str) -> Dict[str, Any]:
             
     except Exception as e:
         # ===== ERROR HANDLING =====
+        finished = True
         error_msg = f"{type(e).__name__}: {str(e)}"
         logger.error(f"❌ Error in localization task for job {job_id}: {error_msg}")
         logger.error(f"Full traceback: {traceback.format_exc()}")
@@ -360,4 +399,6 @@ def localize_task(self, job_id: str) -> Dict[str, Any]:
                 logger.debug(f"🗑️  Removed model file: {model_file_path}")
             logger.info(f"🧹 Temporary files cleaned up for job {job_id}")
         except Exception as cleanup_error:
-            logger.error(f"❌ Error cleaning up temporary files for job {job_id}: {cleanup_error}")
\ No newline at end of file
+            logger.error(f"❌ Error cleaning up temporary files for job {job_id}: {cleanup_error}")
+        if finished:
+            cleanup_job_input(job_id)
\ No newline at end of file
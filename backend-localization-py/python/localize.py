@@ -4,7 +4,7 @@ import os
 import joblib
 import cloudpickle # type: ignore
 import logging
-from typing import Dict
+from typing import Dict, Optional
 import importlib.util
 from importlib.abc import Loader
 from importlib.machinery import ModuleSpec
@@ -58,17 +58,53 @@ def load_model(path: str):
             return cloudpickle.load(f)
 
 
-def run_localization(data_path: str, model_path: str) -> Dict[str, float]:
+# Jenis input job (lihat gateway) yang bisa dibaca model amplitudo bawaan:
+# blok amplitudo 3 × 30 per paket. Model lain mendeklarasikan jenis input
+# yang didukungnya lewat atribut `input_kinds`.
+DEFAULT_INPUT_KINDS = ("raw", "filtered")
+
+
+def model_input_kinds(model) -> tuple:
+    """Jenis input job yang diterima model."""
+    return tuple(getattr(model, "input_kinds", DEFAULT_INPUT_KINDS))
+
+
+# Kolom posisi jendela pada CSV input features; sisanya kolom fitur
+FEATURE_WINDOW_COLUMNS = ("start_packet", "end_packet", "start_s", "end_s")
+
+
+def run_localization(data_path: str, model_path: str, input_kind: str = "raw",
+                     feature_set: Optional[str] = None) -> Dict[str, float]:
     """
     Run localization dengan support file model:
       .pkl, *_cloud.pkl, atau .py
+    Input yang tidak didukung model (lihat model_input_kinds) ditolak
+    sebelum data dibaca. Input features diberikan ke model sebagai tabel
+    fitur (satu baris per jendela, tanpa kolom posisi jendela); model boleh
+    mengunci set fitur lewat atribut `feature_set` (mis. "csi@1").
     """
-    logger.info(f"🚀 Starting localization with data: {data_path}, model: {model_path}")
+    logger.info(f"🚀 Starting localization with data: {data_path}, model: {model_path}, input: {input_kind}")
+    model = load_model(model_path)
+    kinds = model_input_kinds(model)
+    if input_kind not in kinds:
+        raise ValueError(
+            f"Model {os.path.basename(model_path)} does not accept input '{input_kind}' "
+            f"(supported: {', '.join(kinds)})"
+        )
     df = pd.read_csv(data_path)
+    if input_kind == "features":
+        expected = getattr(model, "feature_set", None)
+        if expected and expected != feature_set:
+            raise ValueError(
+                f"Model {os.path.basename(model_path)} expects feature set '{expected}', "
+                f"job has '{feature_set}'"
+            )
+        features = df.drop(columns=[c for c in FEATURE_WINDOW_COLUMNS if c in df.columns])
+        x, y = model.predict(features)
+        return {"x": float(x), "y": float(y)}
     arr = df.values
     T = arr.shape[0]
     H_series = arr.reshape(T, 3, 30).transpose(1, 2, 0)
-    model = load_model(model_path)
     x, y = model.predict(H_series)
     return {"x": float(x), "y": float(y)}
 
@@ -16,6 +16,7 @@ import (
 	"cetasense-v2.0/config"
 	"cetasense-v2.0/database"
 	"cetasense-v2.0/internal/handlers"
+	"cetasense-v2.0/internal/models"
 	"cetasense-v2.0/internal/repositories"
 	"cetasense-v2.0/internal/routes"
 	"cetasense-v2.0/middleware"
@@ -107,6 +108,7 @@ func main() {
 	// ─── 2) Init DB ──────────────────────────────────────────────────────
 	db := database.InitDB(cfg)
 	defer database.CloseDB()
+	database.Migrate(db)
 
 	// ─── 3) Init MinIO ───────────────────────────────────────────────────
 	minioEndpoint := strings.TrimPrefix(cfg.MinioEndpoint, "http://")
@@ -160,21 +162,57 @@ func main() {
 	dataRepo := repositories.NewDataRepository(db)
 	csvRepo := repositories.NewCSVFileRepository(db)
 	methodRepo := repositories.NewMethodsRepository(db)
+	sessionRepo := repositories.NewUploadSessionRepository(db)
+	presetRepo := repositories.NewAnalysisPresetRepository(db)
+	artifactRepo := repositories.NewAnalysisArtifactRepository(db)
+	motionRepo := repositories.NewMotionRepository(db)
+	baselineRepo := repositories.NewBaselineRepository(db)
+
+	cacheClient := cache.NewClient(redisAddr, cfg.RedisDB)
 
 	// Core handlers
 	roomHandler := handlers.NewRoomHandler(*ruanganRepo)
 	filterHandler := handlers.NewFilterHandler(*filterRepo)
 	dataHandler := handlers.NewDataHandler(*dataRepo)
-	uploadHandler := handlers.NewUploadHandler(csvRepo, minioClient, cfg.MinioBucket, cfg, ruanganRepo, filterRepo)
+	uploadHandler := handlers.NewUploadHandler(csvRepo, minioClient, cfg.MinioBucket, cfg, ruanganRepo, filterRepo, sessionRepo)
 	batchHandler := handlers.NewBatchHandler(dataRepo)
 	methodHandler := handlers.NewMethodsHandler(methodRepo, minioClient, cfg.MinioBucket, cfg)
-	plotHandler := handlers.NewPlotHandler(csvRepo, minioClient, cfg.MinioBucket)
+	plotHandler := handlers.NewPlotHandler(csvRepo, filterRepo, presetRepo, artifactRepo, baselineRepo, cacheClient, minioClient, cfg.MinioBucket)
+	presetHandler := handlers.NewAnalysisPresetHandler(presetRepo)
+	motionHandler := handlers.NewMotionHandler(plotHandler, motionRepo)
+	baselineHandler := handlers.NewBaselineHandler(plotHandler, baselineRepo, ruanganRepo)
+
+	// Artifact analisis: precompute setelah upload (opsional) dan dibuang
+	// bersama upload-nya, begitu juga event gerakannya
+	precomputeKinds, err := plotHandler.ParseAnalysisKinds(cfg.AnalysisPrecompute)
+	if err != nil {
+		log.Fatalf("Invalid ANALYSIS_PRECOMPUTE: %v", err)
+	}
+	uploadHandler.SetAnalysisHooks(func(fileID string) {
+		plotHandler.PrecomputeAsync(fileID, precomputeKinds)
+	}, func(ctx context.Context, f *models.CSI_File) {
+		plotHandler.RemoveFileArtifacts(ctx, f)
+		motionHandler.RemoveFileEvents(ctx, f)
+	})
+
+	// Bersihkan sesi resumable upload yang kedaluwarsa
+	janitorCtx, stopJanitor := context.WithCancel(context.Background())
+	defer stopJanitor()
+	go uploadHandler.RunSessionJanitor(janitorCtx, 10*time.Minute)
+
+	// Buang artifact analisis yang kedaluwarsa atau melebihi anggaran ukuran
+	go plotHandler.RunArtifactJanitor(janitorCtx, time.Hour, cfg.AnalysisArtifactTTL, cfg.AnalysisArtifactMaxBytes)
+
+	// Pindahkan upload lama ke object key per file (sekali jalan, idempotent)
+	go func() {
+		if err := uploadHandler.MigrateLegacyObjectKeys(janitorCtx); err != nil {
+			log.Printf("Object key migration error: %v", err)
+		}
+	}()
 
 	// ─── 6) Router & Middleware ──────────────────────────────────────────
 	router := mux.NewRouter()
 
-	cacheClient := cache.NewClient(redisAddr, cfg.RedisDB)
-
 	// Global middleware
 	router.Use(middleware.RequestID)
 	// router.Use(middleware.TTFB)
@@ -191,9 +229,12 @@ func main() {
 	routes.RegisterBatchRoutes(router, batchHandler)
 	routes.RegisterMethodsRoutes(router, methodHandler)
 	routes.RegisterPlotRoutes(router, plotHandler)
+	routes.RegisterAnalysisPresetRoutes(router, presetHandler)
+	routes.RegisterMotionRoutes(router, motionHandler)
+	routes.RegisterBaselineRoutes(router, baselineHandler)
 
 	// SSE routes
-	router.HandleFunc("/api/localize", handlers.NewLocalizeHandler(cfg)).
+	router.HandleFunc("/api/localize", handlers.NewLocalizeHandler(cfg, plotHandler)).
 		Methods("POST")
 	router.HandleFunc("/api/localize/stream/{job_id}", handlers.LocalizationHandler(rdb)).Methods("GET")
 
@@ -208,9 +249,6 @@ func main() {
 	// router.Use(middleware.CacheMiddleware(cacheClient, 5*time.Minute)) // Set cache TTL to 5 minutes
 	// router.Use(loggingMiddleware, contentTypeMiddleware)
 
-	// Test di main.go
-	log.Printf("LocalizationHandler function: %v", handlers.LocalizationHandler)
-
 	// Di main.go setelah semua routes
 	log.Println("🚀 Registered routes:")
 	router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
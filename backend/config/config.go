@@ -3,6 +3,8 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -30,6 +32,31 @@ type Config struct {
 	RedisHost string
 	RedisPort string
 	RedisDB   int
+
+	// Ukuran maksimum satu upload (byte)
+	UploadMaxBytes int64
+	// Upload bertahap: ukuran chunk (minimal 5 MiB, batas part MinIO)
+	// dan umur sesi sejak chunk terakhir
+	UploadChunkBytes int64
+	UploadSessionTTL time.Duration
+
+	// Ambang kualitas upload (opsional, 0 = tidak dicek)
+	UploadMinPackets      int
+	UploadMinParseRatio   float64
+	UploadMaxMeanDropout  float64
+	UploadMinPacketRateHz float64
+	// "flag" (default) menyimpan upload bermasalah dengan tanda,
+	// "reject" menolaknya
+	UploadQualityAction string
+
+	// Jenis analisis yang di-precompute setelah upload (dipisah koma,
+	// misalnya "bnr,spectrogram,pca"); kosong = tidak ada precompute
+	AnalysisPrecompute string
+	// Retensi artifact analisis: artifact yang tidak dipakai selama TTL
+	// dibuang, lalu yang paling lama tidak dipakai dibuang sampai total
+	// ukurannya di bawah MaxBytes (0 = tanpa batas)
+	AnalysisArtifactTTL      time.Duration
+	AnalysisArtifactMaxBytes int64
 }
 
 func LoadConfig() *Config {
@@ -84,5 +111,60 @@ func LoadConfig() *Config {
 		RedisHost: os.Getenv("REDIS_HOST"),
 		RedisPort: os.Getenv("REDIS_PORT"),
 		RedisDB:   0, // Default Redis DB
+
+		UploadMaxBytes:        int64(getEnvInt("UPLOAD_MAX_BYTES", 2<<30)),
+		UploadChunkBytes:      uploadChunkBytes(),
+		UploadSessionTTL:      time.Duration(getEnvInt("UPLOAD_SESSION_TTL_MINUTES", 24*60)) * time.Minute,
+		UploadMinPackets:      getEnvInt("UPLOAD_MIN_PACKETS", 10),
+		UploadMinParseRatio:   getEnvFloat("UPLOAD_MIN_PARSE_RATIO", 0.9),
+		UploadMaxMeanDropout:  getEnvFloat("UPLOAD_MAX_MEAN_DROPOUT", 0.5),
+		UploadMinPacketRateHz: getEnvFloat("UPLOAD_MIN_PACKET_RATE_HZ", 0),
+		UploadQualityAction:   getEnv("UPLOAD_QUALITY_ACTION", "flag"),
+
+		AnalysisPrecompute:       getEnv("ANALYSIS_PRECOMPUTE", ""),
+		AnalysisArtifactTTL:      time.Duration(getEnvInt("ANALYSIS_ARTIFACT_TTL_HOURS", 30*24)) * time.Hour,
+		AnalysisArtifactMaxBytes: int64(getEnvInt("ANALYSIS_ARTIFACT_MAX_BYTES", 5<<30)),
+	}
+}
+
+// getEnv membaca env var opsional dengan nilai default
+func getEnv(name, def string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return def
+}
+
+// uploadChunkBytes membaca UPLOAD_CHUNK_BYTES; part multipart S3/MinIO
+// selain part terakhir minimal 5 MiB
+func uploadChunkBytes() int64 {
+	n := int64(getEnvInt("UPLOAD_CHUNK_BYTES", 8<<20))
+	if n < 5<<20 {
+		log.Fatalf("Environment variable UPLOAD_CHUNK_BYTES must be at least %d", 5<<20)
+	}
+	return n
+}
+
+func getEnvInt(name string, def int) int {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		log.Fatalf("Environment variable %s must be an integer: %v", name, err)
+	}
+	return n
+}
+
+func getEnvFloat(name string, def float64) float64 {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		log.Fatalf("Environment variable %s must be a number: %v", name, err)
 	}
+	return f
 }
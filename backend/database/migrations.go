@@ -0,0 +1,148 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+)
+
+// migrations berisi perubahan skema yang idempotent (MariaDB) di atas
+// tabel dasar: ruangan, filter, data, data_csv, metodelokalisasi.
+var migrations = []string{
+	`ALTER TABLE data_csv ADD COLUMN IF NOT EXISTS format VARCHAR(32) NOT NULL DEFAULT 'csv'`,
+	`ALTER TABLE data_csv ADD COLUMN IF NOT EXISTS raw_object_path VARCHAR(512) NULL`,
+	`ALTER TABLE data_csv ADD COLUMN IF NOT EXISTS layout TEXT NULL`,
+	`ALTER TABLE data_csv ADD COLUMN IF NOT EXISTS profile MEDIUMTEXT NULL`,
+	`ALTER TABLE data_csv ADD COLUMN IF NOT EXISTS quality VARCHAR(16) NULL`,
+	`ALTER TABLE data_csv ADD COLUMN IF NOT EXISTS content_hash CHAR(64) NULL`,
+	`ALTER TABLE data_csv ADD COLUMN IF NOT EXISTS size_bytes BIGINT NULL`,
+	`ALTER TABLE data_csv ADD COLUMN IF NOT EXISTS labels TEXT NULL`,
+	`CREATE INDEX IF NOT EXISTS idx_data_csv_content_hash ON data_csv (content_hash)`,
+	// Dedupe upload berlaku per (isi, ruangan, filter). Duplikat lama dalam
+	// lingkup itu dilepas hash-nya (upload paling awal yang dipertahankan)
+	// agar unique index bisa dibuat.
+	`UPDATE data_csv d
+		JOIN data_csv e
+		  ON e.content_hash = d.content_hash AND e.id_ruangan = d.id_ruangan AND e.id_filter = d.id_filter
+		 AND (e.created_at < d.created_at OR (e.created_at = d.created_at AND e.id < d.id))
+		SET d.content_hash = NULL`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS uq_data_csv_content ON data_csv (content_hash, id_ruangan, id_filter)`,
+	`CREATE TABLE IF NOT EXISTS upload_sessions (
+		id              CHAR(36)     NOT NULL PRIMARY KEY,
+		filename        VARCHAR(255) NOT NULL,
+		size_bytes      BIGINT       NOT NULL,
+		chunk_size      BIGINT       NOT NULL,
+		received_chunks INT          NOT NULL DEFAULT 0,
+		offset_bytes    BIGINT       NOT NULL DEFAULT 0,
+		format          VARCHAR(32)  NULL,
+		object_path     VARCHAR(512) NULL,
+		upload_id       VARCHAR(255) NULL,
+		hash_state      VARBINARY(256) NULL,
+		nama_ruangan    VARCHAR(255) NOT NULL,
+		nama_filter     VARCHAR(255) NOT NULL,
+		layout          TEXT         NULL,
+		nexmon_chip     VARCHAR(32)  NULL,
+		status          VARCHAR(16)  NOT NULL DEFAULT 'open',
+		created_at      DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		expires_at      DATETIME     NOT NULL,
+		INDEX idx_upload_sessions_expires (expires_at)
+	)`,
+	`ALTER TABLE filter ADD COLUMN IF NOT EXISTS pipeline TEXT NULL`,
+	`ALTER TABLE filter ADD COLUMN IF NOT EXISTS version INT NOT NULL DEFAULT 1`,
+	`CREATE TABLE IF NOT EXISTS filter_versions (
+		filter_id  CHAR(36) NOT NULL,
+		version    INT      NOT NULL,
+		pipeline   TEXT     NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (filter_id, version)
+	)`,
+	// Filter lama mendapat snapshot versi pertamanya
+	`INSERT IGNORE INTO filter_versions (filter_id, version, pipeline)
+		SELECT id, version, pipeline FROM filter`,
+	`CREATE TABLE IF NOT EXISTS analysis_presets (
+		id         CHAR(36)     NOT NULL PRIMARY KEY,
+		name       VARCHAR(255) NOT NULL,
+		params     TEXT         NOT NULL,
+		created_at DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE KEY uq_analysis_presets_name (name)
+	)`,
+	`CREATE TABLE IF NOT EXISTS analysis_artifacts (
+		cache_key      VARCHAR(128) NOT NULL PRIMARY KEY,
+		kind           VARCHAR(32)  NOT NULL,
+		file_id        CHAR(36)     NOT NULL,
+		content_hash   CHAR(64)     NULL,
+		params         TEXT         NOT NULL,
+		filter_id      CHAR(36)     NULL,
+		filter_version INT          NULL,
+		object_path    VARCHAR(512) NOT NULL,
+		size_bytes     BIGINT       NOT NULL DEFAULT 0,
+		hits           BIGINT       NOT NULL DEFAULT 0,
+		created_at     DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		last_used_at   DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		INDEX idx_analysis_artifacts_file (file_id),
+		INDEX idx_analysis_artifacts_hash (content_hash)
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_analysis_artifacts_last_used ON analysis_artifacts (last_used_at)`,
+	`CREATE TABLE IF NOT EXISTS motion_runs (
+		id               CHAR(36)    NOT NULL PRIMARY KEY,
+		id_data_csv      CHAR(36)    NOT NULL,
+		id_ruangan       CHAR(36)    NOT NULL,
+		method           VARCHAR(16) NOT NULL,
+		params           TEXT        NOT NULL,
+		threshold        DOUBLE      NOT NULL,
+		threshold_source VARCHAR(16) NOT NULL,
+		created_at       DATETIME    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		INDEX idx_motion_runs_file (id_data_csv)
+	)`,
+	`CREATE TABLE IF NOT EXISTS motion_events (
+		id           CHAR(36)    NOT NULL PRIMARY KEY,
+		run_id       CHAR(36)    NOT NULL,
+		id_ruangan   CHAR(36)    NOT NULL,
+		id_data_csv  CHAR(36)    NOT NULL,
+		state        VARCHAR(8)  NOT NULL,
+		start_packet INT         NOT NULL,
+		end_packet   INT         NOT NULL,
+		start_s      DOUBLE      NOT NULL,
+		end_s        DOUBLE      NOT NULL,
+		confidence   DOUBLE      NOT NULL,
+		peak_score   DOUBLE      NOT NULL,
+		created_at   DATETIME    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		INDEX idx_motion_events_room (id_ruangan, created_at),
+		INDEX idx_motion_events_run (run_id)
+	)`,
+	`DELETE e FROM motion_events e
+		JOIN motion_runs r ON r.id = e.run_id
+		JOIN motion_runs newer ON newer.id_data_csv = r.id_data_csv
+		  AND (newer.created_at > r.created_at OR (newer.created_at = r.created_at AND newer.id > r.id))`,
+	`DELETE r FROM motion_runs r
+		JOIN motion_runs newer ON newer.id_data_csv = r.id_data_csv
+		  AND (newer.created_at > r.created_at OR (newer.created_at = r.created_at AND newer.id > r.id))`,
+	`CREATE TABLE IF NOT EXISTS room_baselines (
+		id         CHAR(36)    NOT NULL PRIMARY KEY,
+		id_ruangan CHAR(36)    NOT NULL,
+		version    INT         NOT NULL,
+		mode       VARCHAR(16) NOT NULL,
+		filtered   BOOLEAN     NOT NULL,
+		sources    TEXT        NOT NULL,
+		layout     TEXT        NOT NULL,
+		packets    INT         NOT NULL,
+		profile    LONGTEXT    NOT NULL,
+		created_at DATETIME    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE KEY uq_room_baselines_version (id_ruangan, version)
+	)`,
+}
+
+// Migrate menjalankan semua migrasi skema secara berurutan
+func Migrate(db *sql.DB) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	for i, stmt := range migrations {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			log.Fatalf("Schema migration %d failed: %v", i+1, err)
+		}
+	}
+	log.Printf("Schema migrations applied (%d statements)", len(migrations))
+}
@@ -0,0 +1,130 @@
+package dsp
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+)
+
+// biquad adalah satu second-order section (a0 = 1)
+type biquad struct {
+	b0, b1, b2 float64
+	a1, a2     float64
+}
+
+// butterworth adalah low-pass Butterworth digital (transformasi bilinear
+// dengan prewarp) yang dijalankan maju-mundur sehingga tidak menggeser fase
+type butterworth struct {
+	sections []biquad
+}
+
+func newButterworthLowpass(order int, cutoff, fs float64) butterworth {
+	wc := 2 * fs * math.Tan(math.Pi*cutoff/fs)
+	k := 2 * fs
+	var f butterworth
+	// Pole analog di setengah bidang kiri; pasangan konjugat digabung
+	for i := 0; i < order/2; i++ {
+		theta := math.Pi * float64(2*i+1+order) / float64(2*order)
+		p := complex(wc, 0) * cmplx.Exp(complex(0, theta))
+		z := (complex(k, 0) + p) / (complex(k, 0) - p)
+		a1 := -2 * real(z)
+		a2 := real(z)*real(z) + imag(z)*imag(z)
+		g := (1 + a1 + a2) / 4
+		f.sections = append(f.sections, biquad{b0: g, b1: 2 * g, b2: g, a1: a1, a2: a2})
+	}
+	if order%2 == 1 {
+		z := (k - wc) / (k + wc)
+		g := (1 - z) / 2
+		f.sections = append(f.sections, biquad{b0: g, b1: g, a1: -z})
+	}
+	return f
+}
+
+// newButterworthHighpass adalah pasangan high-pass dari
+// newButterworthLowpass: pole sama, zero di z = 1, penguatan Nyquist 1
+func newButterworthHighpass(order int, cutoff, fs float64) butterworth {
+	wc := 2 * fs * math.Tan(math.Pi*cutoff/fs)
+	k := 2 * fs
+	var f butterworth
+	for i := 0; i < order/2; i++ {
+		theta := math.Pi * float64(2*i+1+order) / float64(2*order)
+		p := complex(wc, 0) * cmplx.Exp(complex(0, theta))
+		z := (complex(k, 0) + p) / (complex(k, 0) - p)
+		a1 := -2 * real(z)
+		a2 := real(z)*real(z) + imag(z)*imag(z)
+		g := (1 - a1 + a2) / 4
+		f.sections = append(f.sections, biquad{b0: g, b1: -2 * g, b2: g, a1: a1, a2: a2})
+	}
+	if order%2 == 1 {
+		z := (k - wc) / (k + wc)
+		g := (1 + z) / 2
+		f.sections = append(f.sections, biquad{b0: g, b1: -g, a1: -z})
+	}
+	return f
+}
+
+// Bandpass memfilter x dengan Butterworth band-pass [lo, hi] Hz (low-pass
+// lalu high-pass, masing-masing orde order, maju-mundur). x tidak boleh
+// mengandung NaN.
+func Bandpass(x []float64, order int, lo, hi, fs float64) ([]float64, error) {
+	if order < 1 || order > maxButterOrder {
+		return nil, fmt.Errorf("order must be between 1 and %d", maxButterOrder)
+	}
+	if fs <= 0 {
+		return nil, fmt.Errorf("fs must be positive")
+	}
+	if lo <= 0 || hi <= lo || hi >= fs/2 {
+		return nil, fmt.Errorf("band must satisfy 0 < low < high < fs/2 (%g Hz)", fs/2)
+	}
+	y := newButterworthLowpass(order, hi, fs).Apply(x)
+	return newButterworthHighpass(order, lo, fs).Apply(y), nil
+}
+
+func (f butterworth) Apply(x []float64) []float64 {
+	n := len(x)
+	if n == 0 {
+		return nil
+	}
+	// Perpanjang deret dengan refleksi ganjil untuk meredam transien tepi
+	pad := min(3*(2*len(f.sections)+1), n-1)
+	ext := make([]float64, 0, n+2*pad)
+	for i := pad; i >= 1; i-- {
+		ext = append(ext, 2*x[0]-x[i])
+	}
+	ext = append(ext, x...)
+	for i := n - 2; i >= n-1-pad; i-- {
+		ext = append(ext, 2*x[n-1]-x[i])
+	}
+
+	y := f.run(ext)
+	reverse(y)
+	y = f.run(y)
+	reverse(y)
+	return y[pad : pad+n]
+}
+
+// run memfilter satu arah (direct form II transposed) dengan state awal
+// steady-state untuk sampel pertama
+func (f butterworth) run(x []float64) []float64 {
+	y := make([]float64, len(x))
+	copy(y, x)
+	for _, s := range f.sections {
+		x0 := y[0]
+		dc := (s.b0 + s.b1 + s.b2) / (1 + s.a1 + s.a2) // penguatan DC section
+		s2 := (s.b2 - s.a2*dc) * x0
+		s1 := (s.b1-s.a1*dc)*x0 + s2
+		for i, v := range y {
+			out := s.b0*v + s1
+			s1 = s.b1*v - s.a1*out + s2
+			s2 = s.b2*v - s.a2*out
+			y[i] = out
+		}
+	}
+	return y
+}
+
+func reverse(x []float64) {
+	for i, j := 0, len(x)-1; i < j; i, j = i+1, j-1 {
+		x[i], x[j] = x[j], x[i]
+	}
+}
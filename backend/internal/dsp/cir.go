@@ -0,0 +1,93 @@
+package dsp
+
+import (
+	"errors"
+	"math"
+	"math/cmplx"
+)
+
+// CIR mengubah CFR satu antena (subcarrier urut naik frekuensi, berjarak
+// sama) menjadi respons impuls kanal lewat IFFT sepanjang nfft. nfft >
+// len(h) berarti zero-padding (interpolasi di domain delay); tap ke-n
+// berada pada delay n / (nfft·Δf). window boleh nil (rect).
+func CIR(h []complex128, nfft int, window []float64) ([]complex128, error) {
+	if len(h) == 0 {
+		return nil, errors.New("empty CFR")
+	}
+	if nfft < len(h) || NextPow2(nfft) != nfft {
+		return nil, errors.New("nfft must be a power of two not smaller than the number of subcarriers")
+	}
+	if window != nil && len(window) != len(h) {
+		return nil, errors.New("window length must match the number of subcarriers")
+	}
+	// IFFT(x) = conj(FFT(conj(x))) / N; dinormalisasi ke jumlah subcarrier
+	// agar daya tidak bergantung pada zero-padding
+	buf := make([]complex128, nfft)
+	for k, v := range h {
+		if window != nil {
+			v *= complex(window[k], 0)
+		}
+		buf[k] = cmplx.Conj(v)
+	}
+	out := FFT(buf)
+	scale := complex(1/float64(len(h)), 0)
+	for i, v := range out {
+		out[i] = cmplx.Conj(v) * scale
+	}
+	return out, nil
+}
+
+// CenterDelay memutar CIR (fftshift) sehingga tap 0 berada di tengah;
+// dipakai setelah kemiringan fase (STO) dibuang, ketika energi kanal
+// terpusat di sekitar delay 0 dan sebagian ada di delay negatif.
+func CenterDelay(h []complex128) []complex128 {
+	n := len(h)
+	out := make([]complex128, n)
+	for i, v := range h {
+		out[(i+n/2)%n] = v
+	}
+	return out
+}
+
+// DelayStats adalah statistik dispersi delay dari sebuah PDP
+type DelayStats struct {
+	FirstTap        int     // tap pertama di atas ambang
+	LastTap         int     // tap terakhir di atas ambang
+	MeanExcessDelay float64 // terhadap tap pertama (detik)
+	RMSDelaySpread  float64 // detik
+	MaxExcessDelay  float64 // tap terakhir - tap pertama (detik)
+}
+
+// DelaySpread menghitung mean excess delay dan RMS delay spread (momen
+// pertama dan kedua) dari PDP linear dengan delays dalam detik. Tap lebih
+// dari thresholdDB di bawah puncak diabaikan agar noise floor tidak ikut
+// memperlebar sebaran. ok=false bila PDP kosong atau nol semua.
+func DelaySpread(pdp, delays []float64, thresholdDB float64) (DelayStats, bool) {
+	peak := 0.0
+	for _, v := range pdp {
+		peak = math.Max(peak, v)
+	}
+	if peak <= 0 || len(pdp) != len(delays) {
+		return DelayStats{}, false
+	}
+	floor := peak * math.Pow(10, -thresholdDB/10)
+	st := DelayStats{FirstTap: -1}
+	var p0, p1, p2 float64
+	for i, v := range pdp {
+		if v < floor {
+			continue
+		}
+		if st.FirstTap < 0 {
+			st.FirstTap = i
+		}
+		st.LastTap = i
+		p0 += v
+		p1 += v * delays[i]
+		p2 += v * delays[i] * delays[i]
+	}
+	mean := p1 / p0
+	st.MeanExcessDelay = mean - delays[st.FirstTap]
+	st.RMSDelaySpread = math.Sqrt(math.Max(0, p2/p0-mean*mean))
+	st.MaxExcessDelay = delays[st.LastTap] - delays[st.FirstTap]
+	return st, true
+}
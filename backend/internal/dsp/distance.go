@@ -0,0 +1,73 @@
+package dsp
+
+import (
+	"math"
+	"sort"
+)
+
+// validSorted mengembalikan salinan x tanpa NaN yang sudah terurut
+func validSorted(x []float64) []float64 {
+	out := make([]float64, 0, len(x))
+	for _, v := range x {
+		if !math.IsNaN(v) {
+			out = append(out, v)
+		}
+	}
+	sort.Float64s(out)
+	return out
+}
+
+// KSStatistic adalah statistik Kolmogorov-Smirnov dua sampel: jarak
+// terbesar antara kedua CDF empiris (0..1). NaN diabaikan; NaN bila salah
+// satu sampel kosong.
+func KSStatistic(a, b []float64) float64 {
+	x, y := validSorted(a), validSorted(b)
+	if len(x) == 0 || len(y) == 0 {
+		return math.NaN()
+	}
+	var i, j int
+	d := 0.0
+	for i < len(x) && j < len(y) {
+		v := math.Min(x[i], y[j])
+		for i < len(x) && x[i] == v {
+			i++
+		}
+		for j < len(y) && y[j] == v {
+			j++
+		}
+		d = math.Max(d, math.Abs(float64(i)/float64(len(x))-float64(j)/float64(len(y))))
+	}
+	return d
+}
+
+// Wasserstein1 adalah jarak earth mover's antara dua distribusi empiris
+// (luas selisih CDF), dalam satuan sampel. NaN diabaikan; NaN bila salah
+// satu sampel kosong.
+func Wasserstein1(a, b []float64) float64 {
+	x, y := validSorted(a), validSorted(b)
+	if len(x) == 0 || len(y) == 0 {
+		return math.NaN()
+	}
+	var i, j int
+	dist := 0.0
+	prev := math.Min(x[0], y[0])
+	for i < len(x) || j < len(y) {
+		var v float64
+		switch {
+		case j >= len(y) || (i < len(x) && x[i] <= y[j]):
+			v = x[i]
+		default:
+			v = y[j]
+		}
+		fx, fy := float64(i)/float64(len(x)), float64(j)/float64(len(y))
+		dist += math.Abs(fx-fy) * (v - prev)
+		prev = v
+		for i < len(x) && x[i] == v {
+			i++
+		}
+		for j < len(y) && y[j] == v {
+			j++
+		}
+	}
+	return dist
+}
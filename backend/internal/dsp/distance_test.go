@@ -0,0 +1,61 @@
+package dsp
+
+import (
+	"math"
+	"testing"
+)
+
+func TestKSStatistic(t *testing.T) {
+	nan := math.NaN()
+	cases := []struct {
+		name string
+		a, b []float64
+		want float64
+	}{
+		{"identical", []float64{1, 2, 3, 4}, []float64{4, 3, 2, 1}, 0},
+		{"disjoint", []float64{1, 2, 3}, []float64{10, 11}, 1},
+		{"half overlap", []float64{1, 2, 3, 4}, []float64{3, 4, 5, 6}, 0.5},
+		{"ties", []float64{1, 1, 2, 2}, []float64{1, 2, 2, 2}, 0.25},
+		{"NaN ignored", []float64{1, nan, 2}, []float64{1, 2, nan}, 0},
+		{"empty sample", []float64{nan}, []float64{1}, nan},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := KSStatistic(tc.a, tc.b)
+			if math.IsNaN(tc.want) != math.IsNaN(got) || (!math.IsNaN(tc.want) && math.Abs(got-tc.want) > 1e-12) {
+				t.Fatalf("got %g, want %g", got, tc.want)
+			}
+			if sym := KSStatistic(tc.b, tc.a); !math.IsNaN(got) && sym != got {
+				t.Fatalf("not symmetric: %g vs %g", got, sym)
+			}
+		})
+	}
+}
+
+func TestWasserstein1(t *testing.T) {
+	base := []float64{0.3, 1.7, 2.2, 5, 8.1}
+	// menggeser distribusi sejauh c memindahkan seluruh massa sejauh |c|
+	for _, c := range []float64{0, 2.5, -1, 1e-3} {
+		shifted := make([]float64, len(base))
+		for i, v := range base {
+			shifted[i] = v + c
+		}
+		if got := Wasserstein1(base, shifted); math.Abs(got-math.Abs(c)) > 1e-12 {
+			t.Errorf("shift %g: distance %g", c, got)
+		}
+	}
+
+	if got := Wasserstein1([]float64{0}, []float64{3}); got != 3 {
+		t.Errorf("point masses: %g, want 3", got)
+	}
+	// ukuran sampel berbeda: separuh massa di 0 dan separuh di 1 ke 0.5
+	if got := Wasserstein1([]float64{0, 0, 1, 1}, []float64{0.5}); math.Abs(got-0.5) > 1e-12 {
+		t.Errorf("different sizes: %g, want 0.5", got)
+	}
+	if got := Wasserstein1([]float64{1, math.NaN()}, []float64{2}); got != 1 {
+		t.Errorf("NaN should be ignored, got %g", got)
+	}
+	if got := Wasserstein1(nil, []float64{1}); !math.IsNaN(got) {
+		t.Errorf("empty sample gave %g, want NaN", got)
+	}
+}
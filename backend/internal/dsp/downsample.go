@@ -0,0 +1,153 @@
+package dsp
+
+import "math"
+
+// Metode downsampling deret untuk plot
+const (
+	DownsampleLTTB   = "lttb"
+	DownsampleMinMax = "minmax"
+)
+
+// Sample adalah satu titik hasil downsampling: indeks sampel asli, atau
+// penanda celah (Gap) yang dimulai di Index
+type Sample struct {
+	Index int
+	Gap   bool
+}
+
+// buckets membagi [0, n) menjadi k rentang yang hampir sama panjang
+func buckets(n, k int) [][2]int {
+	out := make([][2]int, k)
+	for i := range out {
+		out[i] = [2]int{i * n / k, (i + 1) * n / k}
+	}
+	return out
+}
+
+// appendGap menambahkan penanda celah kecuali titik sebelumnya sudah celah.
+// Indeksnya dimundurkan ke sampel masked pertama celah itu.
+func appendGap(out []Sample, x []float64, at int) []Sample {
+	if len(out) > 0 && out[len(out)-1].Gap {
+		return out
+	}
+	for at > 0 && math.IsNaN(x[at-1]) {
+		at--
+	}
+	return append(out, Sample{Index: at, Gap: true})
+}
+
+// LTTB memilih paling banyak n titik dengan largest-triangle-three-buckets
+// (satu titik per bucket). NaN dianggap masked: bucket tanpa sampel valid
+// menjadi penanda celah, dan bucket di tepi celah memakai sampel valid
+// terdekat dengan celah agar tepinya tidak bergeser; celah yang lebih
+// pendek dari satu bucket tidak terlihat. n >= len(x) mengembalikan semua
+// sampel.
+func LTTB(x []float64, n int) []Sample {
+	if n >= len(x) || n < 3 {
+		return identity(x)
+	}
+	bs := buckets(len(x), n)
+	// rata-rata bucket (NaN bila kosong)
+	avg := make([]float64, n)
+	avgIdx := make([]float64, n)
+	for b, r := range bs {
+		sum, sumI, cnt := 0.0, 0.0, 0
+		for i := r[0]; i < r[1]; i++ {
+			if !math.IsNaN(x[i]) {
+				sum += x[i]
+				sumI += float64(i)
+				cnt++
+			}
+		}
+		avg[b], avgIdx[b] = math.NaN(), math.NaN()
+		if cnt > 0 {
+			avg[b], avgIdx[b] = sum/float64(cnt), sumI/float64(cnt)
+		}
+	}
+
+	out := make([]Sample, 0, n)
+	prev := -1 // titik terpilih bucket sebelumnya; -1 setelah celah
+	for b, r := range bs {
+		if math.IsNaN(avg[b]) {
+			out = appendGap(out, x, r[0])
+			prev = -1
+			continue
+		}
+		first, last := -1, -1
+		for i := r[0]; i < r[1]; i++ {
+			if !math.IsNaN(x[i]) {
+				if first < 0 {
+					first = i
+				}
+				last = i
+			}
+		}
+		pick := first
+		switch {
+		case prev < 0:
+			// awal potongan valid: tepi kiri dipertahankan
+		case b == n-1 || math.IsNaN(avg[b+1]):
+			// akhir potongan valid: tepi kanan dipertahankan
+			pick = last
+		default:
+			bestArea := -1.0
+			px, py := float64(prev), x[prev]
+			nx, ny := avgIdx[b+1], avg[b+1]
+			for i := first; i <= last; i++ {
+				if math.IsNaN(x[i]) {
+					continue
+				}
+				area := math.Abs((px-nx)*(x[i]-py) - (px-float64(i))*(ny-py))
+				if area > bestArea {
+					pick, bestArea = i, area
+				}
+			}
+		}
+		out = append(out, Sample{Index: pick})
+		prev = pick
+	}
+	return out
+}
+
+// MinMax membagi deret menjadi n/2 bucket dan menyimpan nilai minimum dan
+// maksimum tiap bucket (urut indeks), sehingga puncak tidak hilang. Bucket
+// tanpa sampel valid menjadi penanda celah. n >= len(x) mengembalikan
+// semua sampel.
+func MinMax(x []float64, n int) []Sample {
+	if n >= len(x) || n < 2 {
+		return identity(x)
+	}
+	out := make([]Sample, 0, n)
+	for _, r := range buckets(len(x), n/2) {
+		lo, hi := -1, -1
+		for i := r[0]; i < r[1]; i++ {
+			if math.IsNaN(x[i]) {
+				continue
+			}
+			if lo < 0 || x[i] < x[lo] {
+				lo = i
+			}
+			if hi < 0 || x[i] > x[hi] {
+				hi = i
+			}
+		}
+		switch {
+		case lo < 0:
+			out = appendGap(out, x, r[0])
+		case lo == hi:
+			out = append(out, Sample{Index: lo})
+		default:
+			out = append(out, Sample{Index: min(lo, hi)}, Sample{Index: max(lo, hi)})
+		}
+	}
+	return out
+}
+
+// identity mengembalikan semua sampel; NaN menjadi penanda celah
+func identity(x []float64) []Sample {
+	out := make([]Sample, len(x))
+	for i, v := range x {
+		out[i] = Sample{Index: i, Gap: math.IsNaN(v)}
+	}
+	return out
+}
@@ -0,0 +1,130 @@
+package dsp
+
+import (
+	"math"
+	"testing"
+)
+
+func sine(n int) []float64 {
+	x := make([]float64, n)
+	for i := range x {
+		x[i] = math.Sin(float64(i) / 5)
+	}
+	return x
+}
+
+// checkIncreasing memastikan indeks sampel naik tegas
+func checkIncreasing(t *testing.T, got []Sample) {
+	t.Helper()
+	for i := 1; i < len(got); i++ {
+		if got[i].Index <= got[i-1].Index {
+			t.Fatalf("indices not increasing: %v", got)
+		}
+	}
+}
+
+func TestLTTBPassThrough(t *testing.T) {
+	got := LTTB([]float64{1, math.NaN(), 3}, 5)
+	want := []Sample{{Index: 0}, {Index: 1, Gap: true}, {Index: 2}}
+	if len(got) != 3 || got[0] != want[0] || got[1] != want[1] || got[2] != want[2] {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if got := LTTB(sine(10), 2); len(got) != 10 {
+		t.Fatalf("n < 3 should keep all %d samples, got %d", 10, len(got))
+	}
+}
+
+func TestLTTBKeepsShape(t *testing.T) {
+	got := LTTB(sine(100), 10)
+	if len(got) != 10 || got[0].Index != 0 || got[9].Index != 99 {
+		t.Fatalf("got %v, want 10 samples from 0 to 99", got)
+	}
+	checkIncreasing(t, got)
+
+	spike := make([]float64, 100)
+	spike[57] = 10
+	found := false
+	for _, s := range LTTB(spike, 10) {
+		found = found || s.Index == 57
+	}
+	if !found {
+		t.Fatal("spike at 57 was dropped")
+	}
+}
+
+func TestLTTBGap(t *testing.T) {
+	x := sine(100)
+	for i := 40; i < 60; i++ {
+		x[i] = math.NaN()
+	}
+	got := LTTB(x, 10)
+	checkIncreasing(t, got)
+	// bucket yang seluruhnya NaN digabung menjadi satu penanda celah
+	var gaps []int
+	picked := map[int]bool{}
+	for _, s := range got {
+		if s.Gap {
+			gaps = append(gaps, s.Index)
+		} else if math.IsNaN(x[s.Index]) {
+			t.Fatalf("picked NaN sample %d", s.Index)
+		} else {
+			picked[s.Index] = true
+		}
+	}
+	if len(got) != 9 || len(gaps) != 1 || gaps[0] != 40 {
+		t.Fatalf("got %v, want 9 samples with one gap at 40", got)
+	}
+	for _, k := range []int{0, 39, 60, 99} {
+		if !picked[k] {
+			t.Errorf("sample %d next to the gap or at an end was not kept", k)
+		}
+	}
+}
+
+func TestMinMax(t *testing.T) {
+	nan := math.NaN()
+	cases := []struct {
+		name string
+		x    []float64
+		n    int
+		want []Sample
+	}{
+		{
+			name: "min and max in index order",
+			x:    []float64{0, 5, -1, 2, 2, 2, 1, 7, 3, -4},
+			n:    4,
+			want: []Sample{{Index: 1}, {Index: 2}, {Index: 7}, {Index: 9}},
+		},
+		{
+			name: "flat bucket keeps one sample",
+			x:    []float64{0, 5, -1, 2, 2, 2, nan, nan, 3, -4},
+			n:    8,
+			want: []Sample{{Index: 0}, {Index: 1}, {Index: 2}, {Index: 3}, {Index: 5}, {Index: 8}, {Index: 9}},
+		},
+		{
+			name: "empty bucket is a gap",
+			x:    []float64{1, 2, nan, nan, nan, nan, 3, 4},
+			n:    6,
+			want: []Sample{{Index: 0}, {Index: 1}, {Index: 2, Gap: true}, {Index: 6}, {Index: 7}},
+		},
+		{
+			name: "identity when n >= len",
+			x:    []float64{1, nan},
+			n:    2,
+			want: []Sample{{Index: 0}, {Index: 1, Gap: true}},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := MinMax(tc.x, tc.n)
+			if len(got) != len(tc.want) {
+				t.Fatalf("got %v, want %v", got, tc.want)
+			}
+			for i := range tc.want {
+				if got[i] != tc.want[i] {
+					t.Fatalf("got %v, want %v", got, tc.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,207 @@
+package dsp
+
+import (
+	"math"
+	"sort"
+)
+
+// SymEig menghitung nilai dan vektor eigen matriks simetris (tridiagonalisasi
+// Householder lalu iterasi QL implisit, mengikuti JAMA). Hasil diurutkan
+// menurun; vectors[k] adalah vektor eigen satuan untuk values[k].
+func SymEig(a [][]float64) (values []float64, vectors [][]float64) {
+	n := len(a)
+	if n == 0 {
+		return nil, nil
+	}
+	V := make([][]float64, n)
+	for i := range a {
+		V[i] = make([]float64, n)
+		copy(V[i], a[i])
+	}
+	d := make([]float64, n)
+	e := make([]float64, n)
+	tred2(V, d, e)
+	tql2(V, d, e)
+
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return d[order[i]] > d[order[j]] })
+	values = make([]float64, n)
+	vectors = make([][]float64, n)
+	for k, col := range order {
+		values[k] = d[col]
+		vec := make([]float64, n)
+		for i := 0; i < n; i++ {
+			vec[i] = V[i][col]
+		}
+		vectors[k] = vec
+	}
+	return values, vectors
+}
+
+// tred2 mereduksi V (simetris) menjadi bentuk tridiagonal; d dan e berisi
+// diagonal dan sub-diagonalnya, V berisi transformasi ortogonalnya
+func tred2(V [][]float64, d, e []float64) {
+	n := len(d)
+	for j := 0; j < n; j++ {
+		d[j] = V[n-1][j]
+	}
+	for i := n - 1; i > 0; i-- {
+		scale, h := 0.0, 0.0
+		for k := 0; k < i; k++ {
+			scale += math.Abs(d[k])
+		}
+		if scale == 0 {
+			e[i] = d[i-1]
+			for j := 0; j < i; j++ {
+				d[j] = V[i-1][j]
+				V[i][j] = 0
+				V[j][i] = 0
+			}
+		} else {
+			for k := 0; k < i; k++ {
+				d[k] /= scale
+				h += d[k] * d[k]
+			}
+			f := d[i-1]
+			g := math.Sqrt(h)
+			if f > 0 {
+				g = -g
+			}
+			e[i] = scale * g
+			h -= f * g
+			d[i-1] = f - g
+			for j := 0; j < i; j++ {
+				e[j] = 0
+			}
+			for j := 0; j < i; j++ {
+				f = d[j]
+				V[j][i] = f
+				g = e[j] + V[j][j]*f
+				for k := j + 1; k <= i-1; k++ {
+					g += V[k][j] * d[k]
+					e[k] += V[k][j] * f
+				}
+				e[j] = g
+			}
+			f = 0
+			for j := 0; j < i; j++ {
+				e[j] /= h
+				f += e[j] * d[j]
+			}
+			hh := f / (h + h)
+			for j := 0; j < i; j++ {
+				e[j] -= hh * d[j]
+			}
+			for j := 0; j < i; j++ {
+				f = d[j]
+				g = e[j]
+				for k := j; k <= i-1; k++ {
+					V[k][j] -= f*e[k] + g*d[k]
+				}
+				d[j] = V[i-1][j]
+				V[i][j] = 0
+			}
+		}
+		d[i] = h
+	}
+
+	for i := 0; i < n-1; i++ {
+		V[n-1][i] = V[i][i]
+		V[i][i] = 1
+		h := d[i+1]
+		if h != 0 {
+			for k := 0; k <= i; k++ {
+				d[k] = V[k][i+1] / h
+			}
+			for j := 0; j <= i; j++ {
+				g := 0.0
+				for k := 0; k <= i; k++ {
+					g += V[k][i+1] * V[k][j]
+				}
+				for k := 0; k <= i; k++ {
+					V[k][j] -= g * d[k]
+				}
+			}
+		}
+		for k := 0; k <= i; k++ {
+			V[k][i+1] = 0
+		}
+	}
+	for j := 0; j < n; j++ {
+		d[j] = V[n-1][j]
+		V[n-1][j] = 0
+	}
+	V[n-1][n-1] = 1
+	e[0] = 0
+}
+
+// tql2 mendiagonalkan matriks tridiagonal (d, e) dengan iterasi QL implisit
+func tql2(V [][]float64, d, e []float64) {
+	n := len(d)
+	for i := 1; i < n; i++ {
+		e[i-1] = e[i]
+	}
+	e[n-1] = 0
+
+	f, tst1 := 0.0, 0.0
+	eps := math.Pow(2, -52)
+	for l := 0; l < n; l++ {
+		tst1 = math.Max(tst1, math.Abs(d[l])+math.Abs(e[l]))
+		m := l
+		for m < n-1 && math.Abs(e[m]) > eps*tst1 {
+			m++
+		}
+		if m > l {
+			for iter := 0; iter < 64; iter++ {
+				g := d[l]
+				p := (d[l+1] - g) / (2 * e[l])
+				r := math.Hypot(p, 1)
+				if p < 0 {
+					r = -r
+				}
+				d[l] = e[l] / (p + r)
+				d[l+1] = e[l] * (p + r)
+				dl1 := d[l+1]
+				h := g - d[l]
+				for i := l + 2; i < n; i++ {
+					d[i] -= h
+				}
+				f += h
+
+				p = d[m]
+				c, c2, c3 := 1.0, 1.0, 1.0
+				el1 := e[l+1]
+				s, s2 := 0.0, 0.0
+				for i := m - 1; i >= l; i-- {
+					c3 = c2
+					c2 = c
+					s2 = s
+					g = c * e[i]
+					h = c * p
+					r = math.Hypot(p, e[i])
+					e[i+1] = s * r
+					s = e[i] / r
+					c = p / r
+					p = c*d[i] - s*g
+					d[i+1] = h + s*(c*g+s*d[i])
+					for k := 0; k < n; k++ {
+						h = V[k][i+1]
+						V[k][i+1] = s*V[k][i] + c*h
+						V[k][i] = c*V[k][i] - s*h
+					}
+				}
+				p = -s * s2 * c3 * el1 * e[l] / dl1
+				e[l] = s * p
+				d[l] = c * p
+				if math.Abs(e[l]) <= eps*tst1 {
+					break
+				}
+			}
+		}
+		d[l] += f
+		e[l] = 0
+	}
+}
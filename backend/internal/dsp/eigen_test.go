@@ -0,0 +1,110 @@
+package dsp
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSymEig(t *testing.T) {
+	cases := []struct {
+		name   string
+		a      [][]float64
+		values []float64
+	}{
+		{"diagonal", [][]float64{{1, 0, 0}, {0, 3, 0}, {0, 0, 2}}, []float64{3, 2, 1}},
+		{"2x2", [][]float64{{2, 1}, {1, 2}}, []float64{3, 1}},
+		{"tridiagonal", [][]float64{{2, -1, 0}, {-1, 2, -1}, {0, -1, 2}}, []float64{2 + math.Sqrt2, 2, 2 - math.Sqrt2}},
+		{"rank one", [][]float64{{1, 2, 3}, {2, 4, 6}, {3, 6, 9}}, []float64{14, 0, 0}},
+		{"single", [][]float64{{-4}}, []float64{-4}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			values, vectors := SymEig(tc.a)
+			n := len(tc.a)
+			for k, want := range tc.values {
+				if math.Abs(values[k]-want) > 1e-9 {
+					t.Fatalf("values = %v, want %v", values, tc.values)
+				}
+			}
+			for k, v := range vectors {
+				// A v = λ v dan |v| = 1
+				norm := 0.0
+				for i := 0; i < n; i++ {
+					av := 0.0
+					for j := 0; j < n; j++ {
+						av += tc.a[i][j] * v[j]
+					}
+					if math.Abs(av-values[k]*v[i]) > 1e-9 {
+						t.Fatalf("vector %d is not an eigenvector: (Av)[%d]=%g, λv[%d]=%g", k, i, av, i, values[k]*v[i])
+					}
+					norm += v[i] * v[i]
+				}
+				if math.Abs(norm-1) > 1e-9 {
+					t.Fatalf("vector %d has norm² %g", k, norm)
+				}
+			}
+		})
+	}
+	if values, vectors := SymEig(nil); values != nil || vectors != nil {
+		t.Fatal("empty matrix should return nil")
+	}
+}
+
+func TestPCA(t *testing.T) {
+	// dua sinyal laten independen pada empat variabel: komponen pertama
+	// mengikuti sinyal beramplitudo besar, komponen kedua sinyal kecil
+	d1 := []float64{0.5, -0.5, 0.5, -0.5}
+	d2 := []float64{0.5, 0.5, -0.5, -0.5}
+	x := make([][]float64, 400)
+	for i := range x {
+		s1, s2 := 3*math.Sin(float64(i)/7), math.Cos(float64(i)/3)
+		x[i] = make([]float64, 4)
+		for j := range x[i] {
+			x[i][j] = 10 + s1*d1[j] + s2*d2[j]
+		}
+	}
+	pca, err := PCA(x)
+	if err != nil {
+		t.Fatalf("PCA: %v", err)
+	}
+
+	sum := 0.0
+	for _, r := range pca.ExplainedRatio {
+		sum += r
+	}
+	if math.Abs(sum-1) > 1e-9 {
+		t.Errorf("explained ratios sum to %g", sum)
+	}
+	if pca.ExplainedRatio[0]+pca.ExplainedRatio[1] < 1-1e-9 || pca.ExplainedRatio[0] < 0.85 {
+		t.Errorf("explained ratios %v, want two components carrying everything", pca.ExplainedRatio)
+	}
+	for k, d := range [][]float64{d1, d2} {
+		dot := 0.0
+		for j := range d {
+			dot += pca.Components[k][j] * d[j]
+		}
+		if math.Abs(math.Abs(dot)-1) > 1e-3 {
+			t.Errorf("component %d = %v, want ±%v", k, pca.Components[k], d)
+		}
+	}
+	for j, m := range pca.Mean {
+		if math.Abs(m-10) > 0.1 {
+			t.Errorf("mean[%d] = %g, want about 10", j, m)
+		}
+	}
+
+	// skor = proyeksi data terpusat; variansinya sama dengan eigenvalue
+	score := pca.Project(x, 0)
+	variance := 0.0
+	for _, v := range score {
+		variance += v * v
+	}
+	variance /= float64(len(score) - 1)
+	if math.Abs(variance-pca.Eigenvalues[0]) > 1e-9*variance {
+		t.Errorf("score variance %g, eigenvalue %g", variance, pca.Eigenvalues[0])
+	}
+
+	if _, err := PCA([][]float64{{1, 2}}); err == nil {
+		t.Fatal("expected error for a single observation")
+	}
+}
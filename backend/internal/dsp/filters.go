@@ -0,0 +1,223 @@
+package dsp
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"cetasense-v2.0/internal/models"
+)
+
+// Batas parameter stage filter
+const (
+	maxHampelWindow   = 100
+	maxMovingAverageN = 1000
+	maxSavGolWindow   = 201
+	maxSavGolPoly     = 6
+	maxButterOrder    = 8
+)
+
+// Stage adalah satu tahap filter yang sudah divalidasi. Input tidak
+// mengandung NaN (Pipeline mengisi celahnya lebih dulu).
+type Stage interface {
+	Apply(x []float64) []float64
+}
+
+// Pipeline menjalankan stage secara berurutan
+type Pipeline struct {
+	stages []Stage
+}
+
+// NewPipeline memvalidasi definisi filter dan menyiapkan stage-nya
+// (termasuk desain koefisien Butterworth dan Savitzky-Golay)
+func NewPipeline(defs []models.FilterStage) (*Pipeline, error) {
+	p := &Pipeline{}
+	for i, d := range defs {
+		st, err := newStage(d)
+		if err != nil {
+			return nil, fmt.Errorf("pipeline[%d] (%s): %w", i, d.Type, err)
+		}
+		p.stages = append(p.stages, st)
+	}
+	return p, nil
+}
+
+// ValidatePipeline memeriksa parameter semua stage
+func ValidatePipeline(defs []models.FilterStage) error {
+	_, err := NewPipeline(defs)
+	return err
+}
+
+// Empty bernilai true bila pipeline tidak mengubah data
+func (p *Pipeline) Empty() bool {
+	return p == nil || len(p.stages) == 0
+}
+
+// Apply menjalankan pipeline pada satu deret. Celah NaN diisi interpolasi
+// linear selama pemfilteran lalu dikembalikan menjadi NaN pada hasil.
+func (p *Pipeline) Apply(x []float64) []float64 {
+	out := make([]float64, len(x))
+	copy(out, x)
+	if p.Empty() {
+		return out
+	}
+	mask, ok := fillGaps(out)
+	if !ok {
+		return out
+	}
+	for _, st := range p.stages {
+		out = st.Apply(out)
+	}
+	for i, missing := range mask {
+		if missing {
+			out[i] = math.NaN()
+		}
+	}
+	return out
+}
+
+// ApplyMatrix menjalankan pipeline pada setiap deret [stream][subcarrier][packet]
+func (p *Pipeline) ApplyMatrix(m [][][]float64) [][][]float64 {
+	out := make([][][]float64, len(m))
+	for c := range m {
+		out[c] = make([][]float64, len(m[c]))
+		for s := range m[c] {
+			out[c][s] = p.Apply(m[c][s])
+		}
+	}
+	return out
+}
+
+// fillGaps mengisi NaN secara in-place (interpolasi linear, ujung memakai
+// nilai valid terdekat). ok=false bila tidak ada sampel valid.
+func fillGaps(x []float64) (mask []bool, ok bool) {
+	mask = make([]bool, len(x))
+	last := -1
+	for i, v := range x {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			mask[i] = true
+			continue
+		}
+		switch {
+		case last == -1:
+			for j := 0; j < i; j++ {
+				x[j] = v
+			}
+		case last < i-1:
+			for j := last + 1; j < i; j++ {
+				w := float64(j-last) / float64(i-last)
+				x[j] = x[last]*(1-w) + v*w
+			}
+		}
+		last = i
+	}
+	if last == -1 {
+		return mask, false
+	}
+	for j := last + 1; j < len(x); j++ {
+		x[j] = x[last]
+	}
+	return mask, true
+}
+
+func newStage(d models.FilterStage) (Stage, error) {
+	switch d.Type {
+	case models.FilterStageHampel:
+		if d.Window < 1 || d.Window > maxHampelWindow {
+			return nil, fmt.Errorf("window must be between 1 and %d", maxHampelWindow)
+		}
+		if d.K <= 0 {
+			return nil, fmt.Errorf("k must be positive")
+		}
+		return hampel{window: d.Window, k: d.K}, nil
+	case models.FilterStageMovingAverage:
+		if d.N < 1 || d.N > maxMovingAverageN {
+			return nil, fmt.Errorf("n must be between 1 and %d", maxMovingAverageN)
+		}
+		return movingAverage{n: d.N}, nil
+	case models.FilterStageSavitzkyGolay:
+		if d.Window < 3 || d.Window > maxSavGolWindow || d.Window%2 == 0 {
+			return nil, fmt.Errorf("window must be an odd number between 3 and %d", maxSavGolWindow)
+		}
+		if d.Poly < 0 || d.Poly >= d.Window || d.Poly > maxSavGolPoly {
+			return nil, fmt.Errorf("poly must be between 0 and min(window-1, %d)", maxSavGolPoly)
+		}
+		return newSavitzkyGolay(d.Window, d.Poly), nil
+	case models.FilterStageButterworth:
+		if d.Order < 1 || d.Order > maxButterOrder {
+			return nil, fmt.Errorf("order must be between 1 and %d", maxButterOrder)
+		}
+		if d.FS <= 0 {
+			return nil, fmt.Errorf("fs must be positive")
+		}
+		if d.Cutoff <= 0 || d.Cutoff >= d.FS/2 {
+			return nil, fmt.Errorf("cutoff must be between 0 and fs/2 (%g Hz)", d.FS/2)
+		}
+		return newButterworthLowpass(d.Order, d.Cutoff, d.FS), nil
+	}
+	return nil, fmt.Errorf("unknown stage type %q", d.Type)
+}
+
+// ---------------- Hampel ----------------
+
+// hampel mengganti sampel yang menyimpang lebih dari k·σ (σ = 1.4826·MAD)
+// dari median jendela ±window dengan median tersebut
+type hampel struct {
+	window int
+	k      float64
+}
+
+func (f hampel) Apply(x []float64) []float64 {
+	out := make([]float64, len(x))
+	buf := make([]float64, 0, 2*f.window+1)
+	dev := make([]float64, 0, 2*f.window+1)
+	for i := range x {
+		lo, hi := max(0, i-f.window), min(len(x), i+f.window+1)
+		buf = append(buf[:0], x[lo:hi]...)
+		sort.Float64s(buf)
+		med := medianSorted(buf)
+		dev = dev[:0]
+		for _, v := range buf {
+			dev = append(dev, math.Abs(v-med))
+		}
+		sort.Float64s(dev)
+		sigma := 1.4826 * medianSorted(dev)
+		if math.Abs(x[i]-med) > f.k*sigma {
+			out[i] = med
+		} else {
+			out[i] = x[i]
+		}
+	}
+	return out
+}
+
+func medianSorted(x []float64) float64 {
+	n := len(x)
+	if n%2 == 1 {
+		return x[n/2]
+	}
+	return (x[n/2-1] + x[n/2]) / 2
+}
+
+// ---------------- Moving average ----------------
+
+// movingAverage adalah rata-rata bergerak terpusat n sampel; jendela
+// menyempit di tepi deret
+type movingAverage struct {
+	n int
+}
+
+func (f movingAverage) Apply(x []float64) []float64 {
+	out := make([]float64, len(x))
+	prefix := make([]float64, len(x)+1)
+	for i, v := range x {
+		prefix[i+1] = prefix[i] + v
+	}
+	left := (f.n - 1) / 2
+	right := f.n - 1 - left
+	for i := range x {
+		lo, hi := max(0, i-left), min(len(x), i+right+1)
+		out[i] = (prefix[hi] - prefix[lo]) / float64(hi-lo)
+	}
+	return out
+}
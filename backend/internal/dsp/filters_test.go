@@ -0,0 +1,130 @@
+package dsp
+
+import (
+	"math"
+	"testing"
+
+	"cetasense-v2.0/internal/models"
+)
+
+func mustPipeline(t *testing.T, defs ...models.FilterStage) *Pipeline {
+	t.Helper()
+	p, err := NewPipeline(defs)
+	if err != nil {
+		t.Fatalf("NewPipeline: %v", err)
+	}
+	return p
+}
+
+func TestHampelReplacesSpike(t *testing.T) {
+	p := mustPipeline(t, models.FilterStage{Type: models.FilterStageHampel, Window: 3, K: 3})
+	x := []float64{10.0, 10.8, 9.6, 10.3, 9.9, 40, 10.4, 9.7, 10.1, 10.6}
+	y := p.Apply(x)
+	if math.Abs(y[5]-10.1) > 1e-12 {
+		t.Errorf("spike replaced by %g, want the window median 10.1", y[5])
+	}
+	for i, v := range y {
+		if i != 5 && v != x[i] {
+			t.Errorf("y[%d] = %g, want the untouched %g", i, v, x[i])
+		}
+	}
+	if x[5] != 40 {
+		t.Fatal("Apply modified its input")
+	}
+}
+
+func TestSavitzkyGolayKeepsPolynomials(t *testing.T) {
+	// polinom berderajat <= poly harus lolos tanpa perubahan, termasuk di tepi
+	p := mustPipeline(t, models.FilterStage{Type: models.FilterStageSavitzkyGolay, Window: 7, Poly: 2})
+	x := make([]float64, 20)
+	for i := range x {
+		u := float64(i)
+		x[i] = 0.5*u*u - 3*u + 2
+	}
+	for i, v := range p.Apply(x) {
+		if math.Abs(v-x[i]) > 1e-9 {
+			t.Fatalf("y[%d] = %g, want %g", i, v, x[i])
+		}
+	}
+}
+
+func TestMovingAverageEdges(t *testing.T) {
+	p := mustPipeline(t, models.FilterStage{Type: models.FilterStageMovingAverage, N: 3})
+	got := p.Apply([]float64{3, 6, 9, 0})
+	want := []float64{4.5, 6, 5, 4.5}
+	for i := range want {
+		if math.Abs(got[i]-want[i]) > 1e-12 {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+}
+
+func TestButterworthLowpass(t *testing.T) {
+	const fs = 100.0
+	p := mustPipeline(t, models.FilterStage{Type: models.FilterStageButterworth, Order: 4, Cutoff: 5, FS: fs})
+	n := 400
+	slow, mixed := make([]float64, n), make([]float64, n)
+	for i := range mixed {
+		tt := float64(i) / fs
+		slow[i] = 3 + math.Sin(2*math.Pi*1*tt)
+		mixed[i] = slow[i] + 0.5*math.Sin(2*math.Pi*30*tt)
+	}
+	y := p.Apply(mixed)
+	// maju-mundur: komponen 1 Hz tetap sefase, komponen 30 Hz hilang
+	worst := 0.0
+	for i := 50; i < n-50; i++ {
+		worst = math.Max(worst, math.Abs(y[i]-slow[i]))
+	}
+	if worst > 0.02 {
+		t.Fatalf("max deviation from the 1 Hz component %g, want < 0.02", worst)
+	}
+}
+
+func TestPipelineKeepsGaps(t *testing.T) {
+	p := mustPipeline(t,
+		models.FilterStage{Type: models.FilterStageHampel, Window: 2, K: 3},
+		models.FilterStage{Type: models.FilterStageMovingAverage, N: 5},
+	)
+	nan := math.NaN()
+	y := p.Apply([]float64{nan, 1, 2, nan, nan, 5, 6, nan})
+	for _, i := range []int{0, 3, 4, 7} {
+		if !math.IsNaN(y[i]) {
+			t.Errorf("y[%d] = %g, want NaN kept", i, y[i])
+		}
+	}
+	for _, i := range []int{1, 2, 5, 6} {
+		if math.IsNaN(y[i]) {
+			t.Errorf("y[%d] is NaN, want a filtered value", i)
+		}
+	}
+
+	allMissing := p.Apply([]float64{nan, nan})
+	if !math.IsNaN(allMissing[0]) || !math.IsNaN(allMissing[1]) {
+		t.Errorf("all-NaN series became %v", allMissing)
+	}
+	var empty *Pipeline
+	if !empty.Empty() || len(empty.Apply([]float64{1})) != 1 {
+		t.Error("nil pipeline should be empty and pass data through")
+	}
+}
+
+func TestValidatePipeline(t *testing.T) {
+	bad := []models.FilterStage{
+		{Type: models.FilterStageHampel, Window: 0, K: 3},
+		{Type: models.FilterStageHampel, Window: 3, K: 0},
+		{Type: models.FilterStageMovingAverage, N: 0},
+		{Type: models.FilterStageSavitzkyGolay, Window: 6, Poly: 2},
+		{Type: models.FilterStageSavitzkyGolay, Window: 5, Poly: 5},
+		{Type: models.FilterStageButterworth, Order: 2, Cutoff: 50, FS: 100},
+		{Type: models.FilterStageButterworth, Order: 9, Cutoff: 5, FS: 100},
+		{Type: "median"},
+	}
+	for _, st := range bad {
+		if err := ValidatePipeline([]models.FilterStage{st}); err == nil {
+			t.Errorf("%+v: expected error", st)
+		}
+	}
+	if err := ValidatePipeline(nil); err != nil {
+		t.Errorf("empty pipeline rejected: %v", err)
+	}
+}
@@ -0,0 +1,253 @@
+package dsp
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+// Statistik gerakan per jendela
+const (
+	// MotionVariance: rata-rata variansi ternormalisasi (var/mean²) amplitudo
+	// tiap subcarrier; naik ketika pantulan tubuh mengubah amplitudo
+	MotionVariance = "variance"
+	// MotionEigen: nilai eigen terbesar matriks korelasi subcarrier dibagi
+	// jumlah subcarrier; gerakan membuat banyak subcarrier berubah bersamaan
+	MotionEigen = "eigen"
+)
+
+// MotionScores adalah statistik gerakan per jendela geser
+type MotionScores struct {
+	Starts []int     // paket awal tiap jendela
+	Scores []float64 // statistik tiap jendela
+}
+
+// MotionStatistic menghitung statistik gerakan pada jendela [start,
+// start+window) yang digeser hop paket. series adalah deret amplitudo
+// [var][packet] tanpa NaN (lihat FillGaps).
+func MotionStatistic(series [][]float64, method string, window, hop int) (*MotionScores, error) {
+	if len(series) == 0 {
+		return nil, errors.New("no series")
+	}
+	n := len(series[0])
+	if window < 2 || hop < 1 {
+		return nil, errors.New("window must be at least 2 and hop at least 1")
+	}
+	if window > n {
+		return nil, errors.New("window is longer than the capture")
+	}
+	var stat func(start int) float64
+	switch method {
+	case MotionVariance:
+		stat = func(start int) float64 { return normalizedVariance(series, start, window) }
+	case MotionEigen:
+		stat = func(start int) float64 { return correlationEigen(series, start, window) }
+	default:
+		return nil, errors.New("unknown motion statistic " + method)
+	}
+	res := &MotionScores{}
+	for start := 0; start+window <= n; start += hop {
+		res.Starts = append(res.Starts, start)
+		res.Scores = append(res.Scores, stat(start))
+	}
+	return res, nil
+}
+
+// normalizedVariance adalah rata-rata var/mean² tiap deret dalam jendela
+func normalizedVariance(series [][]float64, start, window int) float64 {
+	total, used := 0.0, 0
+	for _, x := range series {
+		var sum, sum2 float64
+		for _, v := range x[start : start+window] {
+			sum += v
+			sum2 += v * v
+		}
+		mean := sum / float64(window)
+		if mean == 0 {
+			continue
+		}
+		variance := math.Max(0, sum2/float64(window)-mean*mean)
+		total += variance / (mean * mean)
+		used++
+	}
+	if used == 0 {
+		return 0
+	}
+	return total / float64(used)
+}
+
+// correlationEigen adalah λ1/n matriks korelasi deret dalam jendela,
+// dihitung dengan power iteration langsung pada data z-score (tanpa
+// membentuk matriks n×n)
+func correlationEigen(series [][]float64, start, window int) float64 {
+	var z [][]float64 // [var][obs]
+	for _, x := range series {
+		seg := x[start : start+window]
+		var sum, sum2 float64
+		for _, v := range seg {
+			sum += v
+			sum2 += v * v
+		}
+		mean := sum / float64(window)
+		sd := math.Sqrt(math.Max(0, sum2/float64(window)-mean*mean))
+		if sd == 0 {
+			continue
+		}
+		col := make([]float64, window)
+		for i, v := range seg {
+			col[i] = (v - mean) / sd
+		}
+		z = append(z, col)
+	}
+	n := len(z)
+	if n == 0 {
+		return 0
+	}
+	v := make([]float64, n)
+	for i := range v {
+		v[i] = 1 / math.Sqrt(float64(n))
+	}
+	u := make([]float64, window)
+	lambda := 0.0
+	for iter := 0; iter < 100; iter++ {
+		// u = Z v, v' = Zᵀ u / window
+		for t := range u {
+			u[t] = 0
+		}
+		for j, col := range z {
+			for t, c := range col {
+				u[t] += c * v[j]
+			}
+		}
+		norm := 0.0
+		next := make([]float64, n)
+		for j, col := range z {
+			s := 0.0
+			for t, c := range col {
+				s += c * u[t]
+			}
+			next[j] = s / float64(window)
+			norm += next[j] * next[j]
+		}
+		norm = math.Sqrt(norm)
+		if norm == 0 {
+			return 0
+		}
+		for j := range next {
+			next[j] /= norm
+		}
+		v = next
+		if math.Abs(norm-lambda) <= 1e-9*norm {
+			lambda = norm
+			break
+		}
+		lambda = norm
+	}
+	return lambda / float64(n)
+}
+
+// RobustThreshold mengembalikan median + k·σ dari skor referensi dengan σ
+// robust (1.4826·MAD, fallback simpangan baku). scale adalah σ itu sendiri
+// (dengan batas bawah kecil agar tidak nol).
+func RobustThreshold(ref []float64, k float64) (threshold, scale float64) {
+	if len(ref) == 0 {
+		return math.NaN(), math.NaN()
+	}
+	sorted := append([]float64(nil), ref...)
+	sort.Float64s(sorted)
+	med := medianSorted(sorted)
+	dev := make([]float64, len(sorted))
+	var sum, sum2 float64
+	for i, v := range sorted {
+		dev[i] = math.Abs(v - med)
+		sum += v
+		sum2 += v * v
+	}
+	sort.Float64s(dev)
+	scale = 1.4826 * medianSorted(dev)
+	if scale <= 0 {
+		mean := sum / float64(len(sorted))
+		scale = math.Sqrt(math.Max(0, sum2/float64(len(sorted))-mean*mean))
+	}
+	scale = math.Max(scale, 1e-9*math.Max(1, math.Abs(med)))
+	return med + k*scale, scale
+}
+
+// MotionSegment adalah potongan jendela [Start, End) dengan status sama
+type MotionSegment struct {
+	Start, End int
+	Motion     bool
+	Confidence float64 // rata-rata probabilitas status segmen
+	PeakScore  float64
+}
+
+// SegmentMotion memberi probabilitas gerakan per jendela (logistik dari
+// (skor - threshold)/scale), melabeli jendela di atas threshold sebagai
+// gerakan, lalu menggabungkan potongan yang lebih pendek dari minWindows ke
+// tetangganya sebelum menyusun segmen.
+func SegmentMotion(scores []float64, threshold, scale float64, minWindows int) ([]float64, []MotionSegment) {
+	probs := make([]float64, len(scores))
+	labels := make([]bool, len(scores))
+	for i, s := range scores {
+		probs[i] = 1 / (1 + math.Exp(-(s-threshold)/scale))
+		labels[i] = s > threshold
+	}
+
+	// potongan pendek mengikuti potongan sebelumnya (atau sesudahnya untuk
+	// potongan pertama)
+	runs := func() [][2]int {
+		var out [][2]int
+		for i := 0; i < len(labels); {
+			j := i
+			for j < len(labels) && labels[j] == labels[i] {
+				j++
+			}
+			out = append(out, [2]int{i, j})
+			i = j
+		}
+		return out
+	}
+	if minWindows > 1 {
+		for changed := true; changed; {
+			changed = false
+			rs := runs()
+			if len(rs) < 2 {
+				break
+			}
+			for k, r := range rs {
+				if r[1]-r[0] >= minWindows {
+					continue
+				}
+				neighbour := labels[r[0]-1+boolInt(k == 0)*(r[1]-r[0]+1)]
+				for i := r[0]; i < r[1]; i++ {
+					labels[i] = neighbour
+				}
+				changed = true
+				break
+			}
+		}
+	}
+
+	var segs []MotionSegment
+	for _, r := range runs() {
+		seg := MotionSegment{Start: r[0], End: r[1], Motion: labels[r[0]], PeakScore: math.Inf(-1)}
+		for i := r[0]; i < r[1]; i++ {
+			p := probs[i]
+			if !seg.Motion {
+				p = 1 - p
+			}
+			seg.Confidence += p
+			seg.PeakScore = math.Max(seg.PeakScore, scores[i])
+		}
+		seg.Confidence /= float64(r[1] - r[0])
+		segs = append(segs, seg)
+	}
+	return probs, segs
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,120 @@
+package dsp
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMotionStatisticVariance(t *testing.T) {
+	// diam di paket 0..99, amplitudo berayun di paket 100..199
+	series := make([][]float64, 3)
+	for v := range series {
+		series[v] = make([]float64, 200)
+		for i := range series[v] {
+			series[v][i] = 10
+			if i >= 100 {
+				series[v][i] += 2 * math.Sin(float64(i+v))
+			}
+		}
+	}
+	res, err := MotionStatistic(series, MotionVariance, 50, 25)
+	if err != nil {
+		t.Fatalf("MotionStatistic: %v", err)
+	}
+	wantStarts := []int{0, 25, 50, 75, 100, 125, 150}
+	if len(res.Starts) != len(wantStarts) {
+		t.Fatalf("starts %v, want %v", res.Starts, wantStarts)
+	}
+	for i, start := range res.Starts {
+		if start != wantStarts[i] {
+			t.Fatalf("starts %v, want %v", res.Starts, wantStarts)
+		}
+		still := start+50 <= 100
+		if still && res.Scores[i] != 0 {
+			t.Errorf("window at %d is still but scored %g", start, res.Scores[i])
+		}
+		if !still && res.Scores[i] <= 0.001 {
+			t.Errorf("window at %d overlaps motion but scored %g", start, res.Scores[i])
+		}
+	}
+}
+
+func TestMotionStatisticEigen(t *testing.T) {
+	n := 120
+	together := make([][]float64, 4)
+	apart := make([][]float64, 4)
+	for v := range together {
+		together[v] = make([]float64, n)
+		apart[v] = make([]float64, n)
+		for i := 0; i < n; i++ {
+			together[v][i] = 5 + float64(v+1)*math.Sin(float64(i)/4)
+			apart[v][i] = 5 + math.Sin(float64(i)*float64(2*v+3)/7)
+		}
+	}
+	// deret yang bergerak bersamaan: λ1/n = 1
+	res, err := MotionStatistic(together, MotionEigen, n, 1)
+	if err != nil {
+		t.Fatalf("MotionStatistic: %v", err)
+	}
+	if math.Abs(res.Scores[0]-1) > 1e-6 {
+		t.Errorf("correlated score %g, want 1", res.Scores[0])
+	}
+	res, _ = MotionStatistic(apart, MotionEigen, n, 1)
+	if res.Scores[0] > 0.6 {
+		t.Errorf("independent score %g, want well below 1", res.Scores[0])
+	}
+
+	if _, err := MotionStatistic(together, "entropy", 10, 1); err == nil {
+		t.Error("unknown method: expected error")
+	}
+	if _, err := MotionStatistic(together, MotionEigen, n+1, 1); err == nil {
+		t.Error("window longer than capture: expected error")
+	}
+}
+
+func TestRobustThreshold(t *testing.T) {
+	threshold, scale := RobustThreshold([]float64{1, 2, 3, 4, 100}, 3)
+	// median 3, MAD 1 → σ 1.4826; outlier tidak ikut menaikkan ambang
+	if math.Abs(scale-1.4826) > 1e-12 || math.Abs(threshold-(3+3*1.4826)) > 1e-12 {
+		t.Errorf("got threshold %g scale %g", threshold, scale)
+	}
+
+	// MAD nol: jatuh ke simpangan baku
+	_, scale = RobustThreshold([]float64{5, 5, 5, 5, 9}, 3)
+	if math.Abs(scale-1.6) > 1e-12 {
+		t.Errorf("fallback scale %g, want 1.6", scale)
+	}
+	if th, _ := RobustThreshold(nil, 3); !math.IsNaN(th) {
+		t.Errorf("empty reference gave %g, want NaN", th)
+	}
+}
+
+func TestSegmentMotion(t *testing.T) {
+	// satu jendela tenang di tengah gerakan dan satu lonjakan di awal
+	// diserap oleh tetangganya
+	scores := []float64{0, 0, 9, 0, 0, 0, 8, 9, 1, 9, 8, 0, 0, 0}
+	probs, segs := SegmentMotion(scores, 5, 1, 2)
+	if len(probs) != len(scores) || probs[2] < 0.98 || probs[0] > 0.01 {
+		t.Fatalf("probabilities %v", probs)
+	}
+	want := []MotionSegment{
+		{Start: 0, End: 6, Motion: false},
+		{Start: 6, End: 11, Motion: true},
+		{Start: 11, End: 14, Motion: false},
+	}
+	if len(segs) != len(want) {
+		t.Fatalf("segments %+v, want %d", segs, len(want))
+	}
+	for i, w := range want {
+		s := segs[i]
+		if s.Start != w.Start || s.End != w.End || s.Motion != w.Motion {
+			t.Errorf("segment %d = %+v, want %d..%d motion=%v", i, s, w.Start, w.End, w.Motion)
+		}
+		if s.Confidence <= 0.5 || s.Confidence > 1 {
+			t.Errorf("segment %d confidence %g", i, s.Confidence)
+		}
+	}
+	if segs[1].PeakScore != 9 {
+		t.Errorf("peak score %g, want 9", segs[1].PeakScore)
+	}
+}
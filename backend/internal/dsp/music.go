@@ -0,0 +1,287 @@
+package dsp
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/cmplx"
+	"sort"
+)
+
+const speedOfLight = 299792458.0
+
+// MUSICParams mengatur estimasi AoA/ToF gabungan
+type MUSICParams struct {
+	Frequency         float64 // frekuensi carrier (Hz)
+	SubcarrierSpacing float64 // jarak antar subcarrier yang dilaporkan (Hz)
+	AntennaSpacing    float64 // jarak antena ULA (m)
+	Sources           int     // jumlah jalur; 0 = dari celah nilai eigen
+}
+
+// MUSICResult adalah pseudo-spectrum MUSIC pada grid theta × tau
+type MUSICResult struct {
+	Spectrum      [][]float64 // [theta][tau], dB relatif terhadap puncak
+	Sources       int
+	Eigenvalues   []float64 // nilai eigen kovarians (menurun)
+	SubarrayShape [2]int    // antena × subcarrier per sub-array
+	Snapshots     int       // jumlah kolom matriks smoothed
+}
+
+// ToFSlope mengembalikan kemiringan fase (rad per indeks subcarrier) CSI
+// satu paket [antenna][subcarrier]: fase tiap antena di-unwrap lalu SATU
+// garis least-squares dicocokkan untuk semua antena (seperti SpotFi)
+func ToFSlope(h [][]complex128) float64 {
+	var xs, ys []float64
+	for _, row := range h {
+		ph := make([]float64, len(row))
+		for k, v := range row {
+			ph[k] = cmplx.Phase(v)
+		}
+		for k, v := range Unwrap(ph) {
+			xs = append(xs, float64(k))
+			ys = append(ys, v)
+		}
+	}
+	slope, _, _ := LinearFit(xs, ys)
+	return slope
+}
+
+// AlignToF menggeser fase CSI satu paket sebesar -delta·k sehingga
+// kemiringannya berubah delta. Pergeseran sama untuk semua antena, jadi
+// selisih fase antar antena (informasi AoA) tetap utuh.
+func AlignToF(h [][]complex128, delta float64) [][]complex128 {
+	out := make([][]complex128, len(h))
+	for m, row := range h {
+		out[m] = make([]complex128, len(row))
+		for k, v := range row {
+			out[m][k] = v * cmplx.Exp(complex(0, -delta*float64(k)))
+		}
+	}
+	return out
+}
+
+// subarrayShape memilih ukuran sub-array spatial smoothing: untuk 3 antena
+// × 30 subcarrier hasilnya 2 × 15 (seperti SpotFi)
+func subarrayShape(antennas, subcarriers int) (int, int) {
+	ms := antennas
+	if antennas >= 3 {
+		ms = antennas - 1
+	}
+	return ms, max(1, subcarriers/2)
+}
+
+// SmoothCSI menyusun kolom matriks smoothed dari CSI satu paket: setiap
+// kolom adalah sub-array ms antena × ks subcarrier yang digeser
+func SmoothCSI(h [][]complex128, ms, ks int) [][]complex128 {
+	M, K := len(h), len(h[0])
+	var cols [][]complex128
+	for m0 := 0; m0+ms <= M; m0++ {
+		for k0 := 0; k0+ks <= K; k0++ {
+			col := make([]complex128, 0, ms*ks)
+			for m := 0; m < ms; m++ {
+				col = append(col, h[m0+m][k0:k0+ks]...)
+			}
+			cols = append(cols, col)
+		}
+	}
+	return cols
+}
+
+// MUSICAoAToF menghitung pseudo-spectrum MUSIC gabungan AoA/ToF dari
+// beberapa paket CSI [packet][antenna][subcarrier] pada grid theta (derajat,
+// 0-180 terhadap sumbu array) dan tau (detik).
+func MUSICAoAToF(packets [][][]complex128, theta, tau []float64, p MUSICParams) (*MUSICResult, error) {
+	if len(packets) == 0 {
+		return nil, errors.New("no packets")
+	}
+	M, K := len(packets[0]), len(packets[0][0])
+	if M < 2 {
+		return nil, errors.New("AoA estimation needs at least two antennas")
+	}
+	if p.Frequency <= 0 || p.SubcarrierSpacing <= 0 || p.AntennaSpacing <= 0 {
+		return nil, errors.New("frequency, subcarrier spacing and antenna spacing must be positive")
+	}
+	ms, ks := subarrayShape(M, K)
+	n := ms * ks
+
+	// Kovarians sampel R = X X^H / kolom, dari semua paket
+	R := make([][]complex128, n)
+	for i := range R {
+		R[i] = make([]complex128, n)
+	}
+	// STO berbeda per paket; samakan kemiringan fase tiap paket ke median
+	// jendela agar snapshot bisa digabung tanpa mengaburkan ToF
+	slopes := make([]float64, len(packets))
+	for i, h := range packets {
+		slopes[i] = ToFSlope(h)
+	}
+	sorted := append([]float64(nil), slopes...)
+	sort.Float64s(sorted)
+	target := sorted[len(sorted)/2]
+
+	snapshots := 0
+	for i, h := range packets {
+		for _, col := range SmoothCSI(AlignToF(h, slopes[i]-target), ms, ks) {
+			for i := 0; i < n; i++ {
+				ci := col[i]
+				for j := i; j < n; j++ {
+					R[i][j] += ci * cmplx.Conj(col[j])
+				}
+			}
+			snapshots++
+		}
+	}
+	for i := 0; i < n; i++ {
+		for j := i; j < n; j++ {
+			R[i][j] /= complex(float64(snapshots), 0)
+			R[j][i] = cmplx.Conj(R[i][j])
+		}
+	}
+
+	// Matriks Hermitian n×n sebagai matriks simetris riil 2n×2n
+	// [[Re, -Im], [Im, Re]]; setiap nilai eigen muncul dua kali
+	real2 := make([][]float64, 2*n)
+	for i := range real2 {
+		real2[i] = make([]float64, 2*n)
+	}
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			re, im := real(R[i][j]), imag(R[i][j])
+			real2[i][j], real2[i+n][j+n] = re, re
+			real2[i][j+n], real2[i+n][j] = -im, im
+		}
+	}
+	values2, vectors2 := SymEig(real2)
+	values := make([]float64, n)
+	for i := range values {
+		values[i] = values2[2*i]
+	}
+
+	D := p.Sources
+	if D <= 0 {
+		D = estimateSources(values)
+	}
+	if D >= n {
+		return nil, fmt.Errorf("sources must be less than %d", n)
+	}
+
+	// Proyeksi ke subruang sinyal: |E_n^H a|² = |a|² - Σ (v·[Re a; Im a])²
+	// untuk 2D vektor sinyal riil v = [x; y] ⇒ v·[Re a; Im a] = Re(conj(e)·a)
+	signal := make([][]complex128, 2*D)
+	for r := range signal {
+		e := make([]complex128, n)
+		for i := 0; i < n; i++ {
+			e[i] = complex(vectors2[r][i], -vectors2[r][i+n]) // conj(e)
+		}
+		signal[r] = e
+	}
+	// G[r][t][m] = Σ_k conj(e_r[m,k]) · ω(τ_t)^k
+	G := make([][][]complex128, len(signal))
+	for r, e := range signal {
+		G[r] = make([][]complex128, len(tau))
+		for t, tv := range tau {
+			omega := cmplx.Exp(complex(0, -2*math.Pi*p.SubcarrierSpacing*tv))
+			g := make([]complex128, ms)
+			for m := 0; m < ms; m++ {
+				w := complex(1, 0)
+				for k := 0; k < ks; k++ {
+					g[m] += e[m*ks+k] * w
+					w *= omega
+				}
+			}
+			G[r][t] = g
+		}
+	}
+
+	spectrum := make([][]float64, len(theta))
+	peak := math.Inf(-1)
+	for i, deg := range theta {
+		phi := cmplx.Exp(complex(0, -2*math.Pi*p.AntennaSpacing*math.Cos(deg*math.Pi/180)*p.Frequency/speedOfLight))
+		powers := make([]complex128, ms)
+		w := complex(1, 0)
+		for m := range powers {
+			powers[m] = w
+			w *= phi
+		}
+		row := make([]float64, len(tau))
+		for t := range tau {
+			proj := 0.0
+			for r := range G {
+				var c complex128
+				for m, g := range G[r][t] {
+					c += powers[m] * g
+				}
+				proj += real(c) * real(c)
+			}
+			noise := math.Max(float64(n)-proj, 1e-12)
+			row[t] = -10 * math.Log10(noise)
+			peak = math.Max(peak, row[t])
+		}
+		spectrum[i] = row
+	}
+	for _, row := range spectrum {
+		for t := range row {
+			row[t] -= peak
+		}
+	}
+	return &MUSICResult{
+		Spectrum:      spectrum,
+		Sources:       D,
+		Eigenvalues:   values,
+		SubarrayShape: [2]int{ms, ks},
+		Snapshots:     snapshots,
+	}, nil
+}
+
+// estimateSources memilih jumlah jalur dari rasio terbesar antar nilai
+// eigen berurutan di antara 6 nilai eigen pertama
+func estimateSources(values []float64) int {
+	best, bestRatio := 1, 0.0
+	for i := 0; i+1 < len(values) && i < 6; i++ {
+		next := math.Max(values[i+1], 1e-15)
+		if ratio := values[i] / next; ratio > bestRatio {
+			best, bestRatio = i+1, ratio
+		}
+	}
+	return best
+}
+
+// Peak2D adalah maksimum lokal pada grid 2D
+type Peak2D struct {
+	I, J  int
+	Value float64
+}
+
+// FindPeaks2D mengembalikan paling banyak n maksimum lokal (8 tetangga)
+// terbesar
+func FindPeaks2D(grid [][]float64, n int) []Peak2D {
+	var peaks []Peak2D
+	for i := range grid {
+		for j, v := range grid[i] {
+			isPeak := true
+			for di := -1; di <= 1 && isPeak; di++ {
+				for dj := -1; dj <= 1; dj++ {
+					if di == 0 && dj == 0 {
+						continue
+					}
+					ii, jj := i+di, j+dj
+					if ii < 0 || ii >= len(grid) || jj < 0 || jj >= len(grid[ii]) {
+						continue
+					}
+					if grid[ii][jj] > v {
+						isPeak = false
+						break
+					}
+				}
+			}
+			if isPeak {
+				peaks = append(peaks, Peak2D{I: i, J: j, Value: v})
+			}
+		}
+	}
+	sort.Slice(peaks, func(a, b int) bool { return peaks[a].Value > peaks[b].Value })
+	if len(peaks) > n {
+		peaks = peaks[:n]
+	}
+	return peaks
+}
@@ -0,0 +1,136 @@
+package dsp
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+)
+
+// path adalah satu jalur propagasi sintetis
+type path struct {
+	theta float64 // derajat
+	tau   float64 // detik
+	gain  complex128
+}
+
+// syntheticCSI menyusun CSI [packet][antenna][subcarrier] dari jalur-jalur
+// dengan model steering yang sama dengan MUSICAoAToF, ditambah fase acak
+// (deterministik) per paket
+func syntheticCSI(paths []path, p MUSICParams, packets, antennas, subcarriers int) [][][]complex128 {
+	out := make([][][]complex128, packets)
+	for n := range out {
+		common := cmplx.Exp(complex(0, 0.7*float64(n*n)))
+		out[n] = make([][]complex128, antennas)
+		for m := range out[n] {
+			out[n][m] = make([]complex128, subcarriers)
+			for _, pt := range paths {
+				phi := cmplx.Exp(complex(0, -2*math.Pi*p.AntennaSpacing*math.Cos(pt.theta*math.Pi/180)*p.Frequency/speedOfLight))
+				omega := cmplx.Exp(complex(0, -2*math.Pi*p.SubcarrierSpacing*pt.tau))
+				// gain sedikit berbeda per paket agar jalur tidak koheren penuh
+				g := pt.gain * cmplx.Exp(complex(0, float64(n)*pt.tau*1e7))
+				for k := range out[n][m] {
+					out[n][m][k] += common * g * cmplx.Pow(phi, complex(float64(m), 0)) * cmplx.Pow(omega, complex(float64(k), 0))
+				}
+			}
+		}
+	}
+	return out
+}
+
+func TestMUSICAoAToFPeaks(t *testing.T) {
+	const freq = 5.32e9
+	params := MUSICParams{
+		Frequency:         freq,
+		SubcarrierSpacing: 4 * 312.5e3,
+		AntennaSpacing:    speedOfLight / freq / 2,
+	}
+	theta := make([]float64, 181)
+	for i := range theta {
+		theta[i] = float64(i)
+	}
+	tau := make([]float64, 41)
+	for i := range tau {
+		tau[i] = float64(i) * 5e-9
+	}
+
+	cases := []struct {
+		name    string
+		paths   []path
+		sources int
+	}{
+		{"single path", []path{{theta: 60, tau: 50e-9, gain: 1}}, 1},
+		{"single path near endfire", []path{{theta: 150, tau: 120e-9, gain: 2i}}, 1},
+		{"two paths", []path{{theta: 40, tau: 30e-9, gain: 1}, {theta: 120, tau: 150e-9, gain: 0.6}}, 2},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := params
+			p.Sources = tc.sources
+			res, err := MUSICAoAToF(syntheticCSI(tc.paths, p, 8, 3, 30), theta, tau, p)
+			if err != nil {
+				t.Fatalf("MUSICAoAToF: %v", err)
+			}
+			if res.SubarrayShape != [2]int{2, 15} {
+				t.Fatalf("subarray %v, want [2 15]", res.SubarrayShape)
+			}
+			peaks := FindPeaks2D(res.Spectrum, len(tc.paths))
+			if len(peaks) != len(tc.paths) {
+				t.Fatalf("got %d peaks, want %d", len(peaks), len(tc.paths))
+			}
+			for _, pt := range tc.paths {
+				wantI, wantJ := int(pt.theta), int(math.Round(pt.tau/5e-9))
+				found := false
+				for _, pk := range peaks {
+					if abs(float64(pk.I-wantI)) <= 1 && abs(float64(pk.J-wantJ)) <= 1 {
+						found = true
+					}
+				}
+				if !found {
+					t.Errorf("no peak near theta=%g tau=%g ns; peaks %v", pt.theta, pt.tau*1e9, peaks)
+				}
+			}
+		})
+	}
+}
+
+func TestMUSICAoAToFErrors(t *testing.T) {
+	p := MUSICParams{Frequency: 5.32e9, SubcarrierSpacing: 1.25e6, AntennaSpacing: 0.028, Sources: 1}
+	csi := syntheticCSI([]path{{theta: 90, gain: 1}}, p, 1, 3, 30)
+	theta, tau := []float64{90}, []float64{0}
+
+	if _, err := MUSICAoAToF(nil, theta, tau, p); err == nil {
+		t.Error("no packets: expected error")
+	}
+	if _, err := MUSICAoAToF([][][]complex128{{make([]complex128, 30)}}, theta, tau, p); err == nil {
+		t.Error("one antenna: expected error")
+	}
+	noFreq := p
+	noFreq.Frequency = 0
+	if _, err := MUSICAoAToF(csi, theta, tau, noFreq); err == nil {
+		t.Error("zero frequency: expected error")
+	}
+	// subarray 2×15 hanya punya 30 dimensi
+	tooMany := p
+	tooMany.Sources = 30
+	if _, err := MUSICAoAToF(csi, theta, tau, tooMany); err == nil {
+		t.Error("too many sources: expected error")
+	}
+}
+
+func TestFindPeaks2D(t *testing.T) {
+	grid := [][]float64{
+		{0, 1, 0, 0},
+		{1, 5, 1, 0},
+		{0, 1, 0, 2},
+		{0, 0, 3, 2},
+	}
+	// (2,3) bernilai 2 bukan puncak karena bertetangga dengan (3,2)=3
+	all := FindPeaks2D(grid, 10)
+	want := []Peak2D{{I: 1, J: 1, Value: 5}, {I: 3, J: 2, Value: 3}}
+	if len(all) != len(want) || all[0] != want[0] || all[1] != want[1] {
+		t.Fatalf("got %v, want %v", all, want)
+	}
+	if top := FindPeaks2D(grid, 1); len(top) != 1 || top[0] != want[0] {
+		t.Fatalf("n=1: got %v, want %v", top, want[:1])
+	}
+}
@@ -0,0 +1,92 @@
+package dsp
+
+import "errors"
+
+// PCAResult adalah hasil PCA atas matriks [observasi][variabel]
+type PCAResult struct {
+	Mean           []float64   // rata-rata tiap variabel
+	Eigenvalues    []float64   // variansi tiap komponen, menurun
+	ExplainedRatio []float64   // Eigenvalues / total variansi
+	Components     [][]float64 // [komponen][variabel], vektor satuan (loadings)
+}
+
+// PCA menghitung komponen utama dari x [observasi][variabel] lewat
+// dekomposisi eigen matriks kovarians. x tidak boleh mengandung NaN.
+func PCA(x [][]float64) (*PCAResult, error) {
+	n := len(x)
+	if n < 2 {
+		return nil, errors.New("PCA needs at least two observations")
+	}
+	v := len(x[0])
+	if v == 0 {
+		return nil, errors.New("PCA needs at least one variable")
+	}
+	mean := make([]float64, v)
+	for _, row := range x {
+		for j, val := range row {
+			mean[j] += val
+		}
+	}
+	for j := range mean {
+		mean[j] /= float64(n)
+	}
+
+	cov := make([][]float64, v)
+	for i := range cov {
+		cov[i] = make([]float64, v)
+	}
+	centered := make([]float64, v)
+	for _, row := range x {
+		for j := range row {
+			centered[j] = row[j] - mean[j]
+		}
+		for i := 0; i < v; i++ {
+			ci := centered[i]
+			if ci == 0 {
+				continue
+			}
+			covRow := cov[i]
+			for j := i; j < v; j++ {
+				covRow[j] += ci * centered[j]
+			}
+		}
+	}
+	for i := 0; i < v; i++ {
+		for j := i; j < v; j++ {
+			cov[i][j] /= float64(n - 1)
+			cov[j][i] = cov[i][j]
+		}
+	}
+
+	values, vectors := SymEig(cov)
+	total := 0.0
+	for k, ev := range values {
+		if ev < 0 {
+			// sisa numerik matriks semi-definit positif
+			values[k] = 0
+			ev = 0
+		}
+		total += ev
+	}
+	ratio := make([]float64, len(values))
+	if total > 0 {
+		for k, ev := range values {
+			ratio[k] = ev / total
+		}
+	}
+	return &PCAResult{Mean: mean, Eigenvalues: values, ExplainedRatio: ratio, Components: vectors}, nil
+}
+
+// Project mengembalikan skor komponen ke-k (0-based) untuk setiap observasi
+func (r *PCAResult) Project(x [][]float64, k int) []float64 {
+	comp := r.Components[k]
+	out := make([]float64, len(x))
+	for i, row := range x {
+		s := 0.0
+		for j, val := range row {
+			s += (val - r.Mean[j]) * comp[j]
+		}
+		out[i] = s
+	}
+	return out
+}
@@ -0,0 +1,128 @@
+// Package dsp berisi pengolahan sinyal CSI yang dipakai endpoint analisis
+// dan input job lokalisasi.
+package dsp
+
+import "math"
+
+// Unwrap menghilangkan loncatan 2π antar sampel berurutan (seperti
+// numpy.unwrap). Sampel NaN dilewati dan tetap NaN.
+func Unwrap(x []float64) []float64 {
+	out := make([]float64, len(x))
+	offset := 0.0
+	prev := math.NaN()
+	for i, v := range x {
+		if math.IsNaN(v) {
+			out[i] = math.NaN()
+			continue
+		}
+		if !math.IsNaN(prev) {
+			d := v - prev
+			offset -= 2 * math.Pi * math.Round(d/(2*math.Pi))
+		}
+		prev = v
+		out[i] = v + offset
+	}
+	return out
+}
+
+// LinearFit menghitung least-squares y ≈ slope·x + intercept, mengabaikan
+// titik NaN. ok=false bila titik valid kurang dari dua.
+func LinearFit(x, y []float64) (slope, intercept float64, ok bool) {
+	var n, sx, sy, sxx, sxy float64
+	for i := range y {
+		if math.IsNaN(x[i]) || math.IsNaN(y[i]) {
+			continue
+		}
+		n++
+		sx += x[i]
+		sy += y[i]
+		sxx += x[i] * x[i]
+		sxy += x[i] * y[i]
+	}
+	if n < 2 {
+		return 0, 0, false
+	}
+	den := n*sxx - sx*sx
+	if den == 0 {
+		return 0, sy / n, true
+	}
+	slope = (n*sxy - sx*sy) / den
+	intercept = (sy - slope*sx) / n
+	return slope, intercept, true
+}
+
+// PhaseFit adalah suku linear yang dibuang dari fase satu paket
+type PhaseFit struct {
+	Slope     float64 // rad per indeks tone subcarrier (STO/SFO)
+	Intercept float64 // rad (CFO/offset fase umum)
+}
+
+// SanitizePhaseVector meng-unwrap fase satu paket lintas subcarrier lalu
+// mengurangkan garis least-squares terhadap indeks subcarrier. Offset
+// waktu (STO) muncul sebagai kemiringan dan CFO sebagai konstanta, jadi
+// keduanya hilang bersama suku linear tersebut. indices adalah indeks tone
+// OFDM tiap subcarrier (boleh nil: 0..S-1); indeks NaN menandai bin null
+// (DC, guard band) yang tidak ikut unwrap maupun fit dan hasilnya NaN.
+func SanitizePhaseVector(phase, indices []float64) ([]float64, PhaseFit) {
+	if indices == nil {
+		indices = make([]float64, len(phase))
+		for i := range indices {
+			indices[i] = float64(i)
+		}
+	}
+	masked := make([]float64, len(phase))
+	for i, v := range phase {
+		if math.IsNaN(indices[i]) {
+			v = math.NaN()
+		}
+		masked[i] = v
+	}
+	unwrapped := Unwrap(masked)
+	slope, intercept, ok := LinearFit(indices, unwrapped)
+	if !ok {
+		return unwrapped, PhaseFit{}
+	}
+	out := make([]float64, len(unwrapped))
+	for i, v := range unwrapped {
+		out[i] = v - (slope*indices[i] + intercept)
+	}
+	return out, PhaseFit{Slope: slope, Intercept: intercept}
+}
+
+// SanitizePhase menjalankan SanitizePhaseVector untuk setiap paket dan
+// stream dengan indeks subcarrier yang sama (lihat
+// models.CSILayout.SubcarrierIndices). phase berbentuk
+// [stream][subcarrier][packet] (sama dengan services.CSIMatrix) dan
+// hasilnya berbentuk sama; fits berbentuk [stream][packet].
+func SanitizePhase(phase [][][]float64, indices []float64) (out [][][]float64, fits [][]PhaseFit) {
+	out = make([][][]float64, len(phase))
+	fits = make([][]PhaseFit, len(phase))
+	for c, bySC := range phase {
+		S := len(bySC)
+		if len(indices) != S {
+			indices = nil
+		}
+		P := 0
+		if S > 0 {
+			P = len(bySC[0])
+		}
+		out[c] = make([][]float64, S)
+		for s := range out[c] {
+			out[c][s] = make([]float64, P)
+		}
+		fits[c] = make([]PhaseFit, P)
+
+		vec := make([]float64, S)
+		for p := 0; p < P; p++ {
+			for s := 0; s < S; s++ {
+				vec[s] = bySC[s][p]
+			}
+			clean, fit := SanitizePhaseVector(vec, indices)
+			for s := 0; s < S; s++ {
+				out[c][s][p] = clean[s]
+			}
+			fits[c][p] = fit
+		}
+	}
+	return out, fits
+}
@@ -0,0 +1,91 @@
+package dsp
+
+import (
+	"math"
+	"testing"
+
+	"cetasense-v2.0/internal/models"
+)
+
+func wrap(x float64) float64 {
+	return math.Atan2(math.Sin(x), math.Cos(x))
+}
+
+// Fase paket dengan STO/CFO murni: garis lurus terhadap indeks tone
+func linearPhase(indices []float64, slope, intercept float64) []float64 {
+	out := make([]float64, len(indices))
+	for i, k := range indices {
+		out[i] = wrap(slope*k + intercept)
+	}
+	return out
+}
+
+func TestSanitizePhaseIntelGrouping(t *testing.T) {
+	layout := models.CSILayout{Streams: 1, Subcarriers: 30}
+	indices := layout.SubcarrierIndices()
+	vec := linearPhase(indices, -0.4, 1.1)
+
+	clean, fit := SanitizePhaseVector(vec, indices)
+	if math.Abs(fit.Slope+0.4) > 1e-9 {
+		t.Errorf("slope %g rad/tone, want -0.4", fit.Slope)
+	}
+	for i, v := range clean {
+		if math.Abs(v) > 1e-9 {
+			t.Fatalf("residual %g at tone %g, want 0", v, indices[i])
+		}
+	}
+
+	// fit terhadap posisi kolom 0..29 tidak bisa membuang STO karena
+	// jarak tone tidak seragam (langkah 2 kecuali di sekitar DC)
+	byColumn, _ := SanitizePhaseVector(vec, nil)
+	worst := 0.0
+	for _, v := range byColumn {
+		worst = math.Max(worst, math.Abs(v))
+	}
+	if worst < 0.1 {
+		t.Errorf("column-index fit left only %g rad; the test no longer tells the two apart", worst)
+	}
+}
+
+func TestSanitizePhaseNullBins(t *testing.T) {
+	layout := models.CSILayout{Streams: 2, Subcarriers: 64}
+	indices := layout.SubcarrierIndices()
+	phase := make([][][]float64, 2)
+	for c := range phase {
+		phase[c] = make([][]float64, 64)
+		for s := range phase[c] {
+			phase[c][s] = make([]float64, 3)
+		}
+		for p := 0; p < 3; p++ {
+			slope := 0.05 * float64(p+1)
+			for s, k := range indices {
+				v := wrap(slope*k + float64(c))
+				if math.IsNaN(k) {
+					// bin null berisi fase acak
+					v = math.Sin(float64(7*s + p))
+				}
+				phase[c][s][p] = v
+			}
+		}
+	}
+
+	out, fits := SanitizePhase(phase, indices)
+	for c := range out {
+		for p := 0; p < 3; p++ {
+			if want := 0.05 * float64(p+1); math.Abs(fits[c][p].Slope-want) > 1e-9 {
+				t.Errorf("stream %d packet %d slope %g, want %g", c, p, fits[c][p].Slope, want)
+			}
+			for s, k := range indices {
+				v := out[c][s][p]
+				if math.IsNaN(k) != math.IsNaN(v) || (!math.IsNaN(v) && math.Abs(v) > 1e-9) {
+					t.Fatalf("stream %d packet %d tone %g: %g", c, p, k, v)
+				}
+			}
+		}
+	}
+
+	// indeks yang tidak cocok dengan jumlah subcarrier diabaikan
+	if out, _ := SanitizePhase(phase, indices[:10]); math.IsNaN(out[0][0][0]) {
+		t.Error("mismatched indices should fall back to 0..S-1")
+	}
+}
@@ -0,0 +1,193 @@
+package dsp
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+// Metode estimasi laju napas
+const (
+	// BreathingSpectral: puncak rata-rata spektrum daya deret (ternormalisasi)
+	// di dalam pita napas
+	BreathingSpectral = "spectral"
+	// BreathingZeroCrossing: periode rata-rata antar zero crossing naik tiap
+	// deret, digabung dengan median berbobot
+	BreathingZeroCrossing = "zero_crossing"
+)
+
+// BreathingEstimate adalah laju napas satu segmen
+type BreathingEstimate struct {
+	RateBPM float64 // napas per menit; NaN bila tidak ada estimasi
+	FreqHz  float64
+	// 0..1: konsentrasi daya di sekitar puncak (spectral) atau keteraturan
+	// periode dan kesepakatan antar-deret (zero_crossing)
+	Confidence float64
+	Series     int // deret yang ikut dihitung
+}
+
+// BreathingRate memperkirakan laju napas dari deret yang sudah difilter
+// band-pass [lo, hi] Hz. series berformat [var][sample] dengan panjang sama
+// dan tanpa NaN.
+func BreathingRate(series [][]float64, fs, lo, hi float64, method string) (BreathingEstimate, error) {
+	if len(series) == 0 || len(series[0]) < 4 {
+		return BreathingEstimate{}, errors.New("segment is too short")
+	}
+	switch method {
+	case BreathingSpectral:
+		return spectralBreathing(series, fs, lo, hi), nil
+	case BreathingZeroCrossing:
+		return zeroCrossingBreathing(series, fs, lo, hi), nil
+	}
+	return BreathingEstimate{}, errors.New("unknown breathing method " + method)
+}
+
+// standardize mengurangi mean dan membagi simpangan baku; false bila datar
+func standardize(x []float64) ([]float64, bool) {
+	var sum, sum2 float64
+	for _, v := range x {
+		sum += v
+		sum2 += v * v
+	}
+	n := float64(len(x))
+	mean := sum / n
+	sd := math.Sqrt(math.Max(0, sum2/n-mean*mean))
+	if sd == 0 {
+		return nil, false
+	}
+	out := make([]float64, len(x))
+	for i, v := range x {
+		out[i] = (v - mean) / sd
+	}
+	return out, true
+}
+
+func spectralBreathing(series [][]float64, fs, lo, hi float64) BreathingEstimate {
+	n := len(series[0])
+	nfft := NextPow2(4 * n) // zero-padding untuk interpolasi spektrum
+	win, _ := WindowFunc(WindowHann, n)
+	power := make([]float64, nfft/2+1)
+	est := BreathingEstimate{RateBPM: math.NaN(), FreqHz: math.NaN()}
+	for _, x := range series {
+		z, ok := standardize(x)
+		if !ok {
+			continue
+		}
+		buf := make([]complex128, nfft)
+		for i, v := range z {
+			buf[i] = complex(v*win[i], 0)
+		}
+		spec := FFT(buf)
+		for k := range power {
+			re, im := real(spec[k]), imag(spec[k])
+			power[k] += re*re + im*im
+		}
+		est.Series++
+	}
+	if est.Series == 0 {
+		return est
+	}
+
+	df := fs / float64(nfft)
+	kLo, kHi := int(math.Ceil(lo/df)), min(int(math.Floor(hi/df)), nfft/2)
+	if kHi <= kLo {
+		return est
+	}
+	peak, band := kLo, 0.0
+	for k := kLo; k <= kHi; k++ {
+		band += power[k]
+		if power[k] > power[peak] {
+			peak = k
+		}
+	}
+	if band <= 0 {
+		return est
+	}
+	// interpolasi parabola pada puncak
+	freq := float64(peak)
+	if peak > 0 && peak < nfft/2 {
+		a, b, c := power[peak-1], power[peak], power[peak+1]
+		if d := a - 2*b + c; d < 0 {
+			freq += 0.5 * (a - c) / d
+		}
+	}
+	// daya dalam lobus utama Hann (±2 bin resolusi asli) dibanding daya pita
+	lobe := 2 * nfft / n
+	inLobe := 0.0
+	for k := max(kLo, peak-lobe); k <= min(kHi, peak+lobe); k++ {
+		inLobe += power[k]
+	}
+	est.FreqHz = freq * df
+	est.RateBPM = 60 * est.FreqHz
+	est.Confidence = inLobe / band
+	return est
+}
+
+func zeroCrossingBreathing(series [][]float64, fs, lo, hi float64) BreathingEstimate {
+	type vote struct{ rate, weight float64 }
+	var votes []vote
+	est := BreathingEstimate{RateBPM: math.NaN(), FreqHz: math.NaN()}
+	for _, x := range series {
+		z, ok := standardize(x)
+		if !ok {
+			continue
+		}
+		est.Series++
+		// waktu zero crossing naik (interpolasi linear)
+		var cross []float64
+		for i := 1; i < len(z); i++ {
+			if z[i-1] < 0 && z[i] >= 0 {
+				cross = append(cross, (float64(i-1)+z[i-1]/(z[i-1]-z[i]))/fs)
+			}
+		}
+		if len(cross) < 3 {
+			continue
+		}
+		var sum, sum2 float64
+		periods := len(cross) - 1
+		for i := 1; i < len(cross); i++ {
+			d := cross[i] - cross[i-1]
+			sum += d
+			sum2 += d * d
+		}
+		mean := sum / float64(periods)
+		freq := 1 / mean
+		if freq < lo || freq > hi {
+			continue
+		}
+		cv := math.Sqrt(math.Max(0, sum2/float64(periods)-mean*mean)) / mean
+		votes = append(votes, vote{rate: 60 * freq, weight: math.Max(0, 1-cv)})
+	}
+	if len(votes) == 0 {
+		return est
+	}
+
+	// median berbobot; bobot nol tetap ikut bila semua nol
+	sort.Slice(votes, func(i, j int) bool { return votes[i].rate < votes[j].rate })
+	total := 0.0
+	for _, v := range votes {
+		total += v.weight
+	}
+	rate := votes[len(votes)/2].rate
+	if total > 0 {
+		acc := 0.0
+		for _, v := range votes {
+			acc += v.weight
+			if acc >= total/2 {
+				rate = v.rate
+				break
+			}
+		}
+	}
+	// keyakinan: keteraturan rata-rata × porsi deret yang sepakat (±10%)
+	agree := 0
+	for _, v := range votes {
+		if math.Abs(v.rate-rate) <= 0.1*rate {
+			agree++
+		}
+	}
+	est.RateBPM = rate
+	est.FreqHz = rate / 60
+	est.Confidence = total / float64(len(votes)) * float64(agree) / float64(est.Series)
+	return est
+}
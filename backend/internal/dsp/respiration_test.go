@@ -0,0 +1,88 @@
+package dsp
+
+import (
+	"math"
+	"testing"
+)
+
+// chest mensimulasikan amplitudo subcarrier yang dimodulasi napas freq Hz
+// dengan offset, gangguan lambat, dan noise cepat
+func chest(n int, fs, freq, phase float64) []float64 {
+	x := make([]float64, n)
+	for i := range x {
+		tt := float64(i) / fs
+		x[i] = 20 + 0.8*math.Sin(2*math.Pi*freq*tt+phase) + 0.3*tt/60 + 0.2*math.Sin(2*math.Pi*4.3*tt)
+	}
+	return x
+}
+
+func TestBandpassIsolatesBreathing(t *testing.T) {
+	const fs = 20.0
+	x := chest(1200, fs, 0.25, 0)
+	y, err := Bandpass(x, 2, 0.1, 0.6, fs)
+	if err != nil {
+		t.Fatalf("Bandpass: %v", err)
+	}
+	// setelah band-pass yang tersisa hanya sinus 0.25 Hz beramplitudo 0.8
+	worst := 0.0
+	for i := 200; i < len(y)-200; i++ {
+		want := 0.8 * math.Sin(2*math.Pi*0.25*float64(i)/fs)
+		worst = math.Max(worst, math.Abs(y[i]-want))
+	}
+	if worst > 0.08 {
+		t.Errorf("max deviation %g from the breathing component", worst)
+	}
+
+	for _, band := range [][2]float64{{0, 0.6}, {0.6, 0.1}, {0.1, 10}} {
+		if _, err := Bandpass(x, 2, band[0], band[1], fs); err == nil {
+			t.Errorf("band %v: expected error", band)
+		}
+	}
+}
+
+func TestBreathingRateMethods(t *testing.T) {
+	const fs = 20.0
+	var series [][]float64
+	for v := 0; v < 4; v++ {
+		y, err := Bandpass(chest(1200, fs, 0.3, float64(v)), 2, 0.1, 0.6, fs)
+		if err != nil {
+			t.Fatalf("Bandpass: %v", err)
+		}
+		series = append(series, y)
+	}
+	// deret datar dilewati dan tidak ikut dihitung
+	series = append(series, make([]float64, 1200))
+
+	for _, method := range []string{BreathingSpectral, BreathingZeroCrossing} {
+		est, err := BreathingRate(series, fs, 0.1, 0.6, method)
+		if err != nil {
+			t.Fatalf("%s: %v", method, err)
+		}
+		if math.Abs(est.RateBPM-18) > 0.5 {
+			t.Errorf("%s: rate %g bpm, want 18", method, est.RateBPM)
+		}
+		if est.Series != 4 {
+			t.Errorf("%s: used %d series, want 4", method, est.Series)
+		}
+		if est.Confidence < 0.7 || est.Confidence > 1 {
+			t.Errorf("%s: confidence %g, want a clean signal to score high", method, est.Confidence)
+		}
+	}
+}
+
+func TestBreathingRateNoEstimate(t *testing.T) {
+	flat := [][]float64{make([]float64, 100)}
+	est, err := BreathingRate(flat, 20, 0.1, 0.6, BreathingZeroCrossing)
+	if err != nil {
+		t.Fatalf("BreathingRate: %v", err)
+	}
+	if !math.IsNaN(est.RateBPM) || est.Series != 0 {
+		t.Errorf("flat segment gave %+v, want NaN rate", est)
+	}
+	if _, err := BreathingRate(flat, 20, 0.1, 0.6, "wavelet"); err == nil {
+		t.Error("unknown method: expected error")
+	}
+	if _, err := BreathingRate([][]float64{{1, 2}}, 20, 0.1, 0.6, BreathingSpectral); err == nil {
+		t.Error("short segment: expected error")
+	}
+}
@@ -0,0 +1,127 @@
+package dsp
+
+// savitzkyGolay mencocokkan polinom derajat poly pada jendela window
+// sampel. Di tepi deret polinom dicocokkan pada jendela penuh pertama /
+// terakhir lalu dievaluasi di posisi sampel (seperti mode "interp" scipy).
+type savitzkyGolay struct {
+	window int
+	// coeffs[j] adalah bobot untuk mengevaluasi posisi j di dalam jendela
+	coeffs [][]float64
+}
+
+func newSavitzkyGolay(window, poly int) savitzkyGolay {
+	f := savitzkyGolay{window: window, coeffs: make([][]float64, window)}
+	half := window / 2
+	// Posisi dinormalisasi ke [-1, 1] agar matriks normal tetap terkondisi baik
+	u := make([]float64, window)
+	for j := range u {
+		u[j] = float64(j-half) / float64(half)
+	}
+	// A^T A
+	d := poly + 1
+	ata := make([][]float64, d)
+	for r := range ata {
+		ata[r] = make([]float64, d)
+		for c := range ata[r] {
+			for _, uj := range u {
+				ata[r][c] += powi(uj, r+c)
+			}
+		}
+	}
+	inv := invert(ata)
+	for t := range f.coeffs {
+		// c = A (A^T A)^-1 e(u_t)
+		g := make([]float64, d)
+		for r := 0; r < d; r++ {
+			for c := 0; c < d; c++ {
+				g[r] += inv[r][c] * powi(u[t], c)
+			}
+		}
+		w := make([]float64, window)
+		for j, uj := range u {
+			for r := 0; r < d; r++ {
+				w[j] += powi(uj, r) * g[r]
+			}
+		}
+		f.coeffs[t] = w
+	}
+	return f
+}
+
+func (f savitzkyGolay) Apply(x []float64) []float64 {
+	n := len(x)
+	out := make([]float64, n)
+	if n < f.window {
+		copy(out, x)
+		return out
+	}
+	half := f.window / 2
+	for i := range x {
+		start, pos := i-half, half
+		if start < 0 {
+			start, pos = 0, i
+		} else if start+f.window > n {
+			start = n - f.window
+			pos = i - start
+		}
+		sum := 0.0
+		for j, w := range f.coeffs[pos] {
+			sum += w * x[start+j]
+		}
+		out[i] = sum
+	}
+	return out
+}
+
+func powi(x float64, n int) float64 {
+	r := 1.0
+	for ; n > 0; n-- {
+		r *= x
+	}
+	return r
+}
+
+// invert membalik matriks kecil dengan eliminasi Gauss-Jordan (pivot parsial)
+func invert(a [][]float64) [][]float64 {
+	n := len(a)
+	m := make([][]float64, n)
+	for i := range a {
+		m[i] = make([]float64, 2*n)
+		copy(m[i], a[i])
+		m[i][n+i] = 1
+	}
+	for col := 0; col < n; col++ {
+		pivot := col
+		for r := col + 1; r < n; r++ {
+			if abs(m[r][col]) > abs(m[pivot][col]) {
+				pivot = r
+			}
+		}
+		m[col], m[pivot] = m[pivot], m[col]
+		p := m[col][col]
+		for c := range m[col] {
+			m[col][c] /= p
+		}
+		for r := 0; r < n; r++ {
+			if r == col || m[r][col] == 0 {
+				continue
+			}
+			factor := m[r][col]
+			for c := range m[r] {
+				m[r][c] -= factor * m[col][c]
+			}
+		}
+	}
+	out := make([][]float64, n)
+	for i := range m {
+		out[i] = m[i][n:]
+	}
+	return out
+}
+
+func abs(x float64) float64 {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
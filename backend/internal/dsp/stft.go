@@ -0,0 +1,149 @@
+package dsp
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/cmplx"
+)
+
+// Fungsi jendela STFT
+const (
+	WindowHann        = "hann"
+	WindowHamming     = "hamming"
+	WindowBlackman    = "blackman"
+	WindowRectangular = "rect"
+)
+
+// WindowFunc mengembalikan koefisien jendela (periodik) sepanjang n
+func WindowFunc(name string, n int) ([]float64, error) {
+	w := make([]float64, n)
+	for i := range w {
+		x := 2 * math.Pi * float64(i) / float64(n)
+		switch name {
+		case WindowHann:
+			w[i] = 0.5 - 0.5*math.Cos(x)
+		case WindowHamming:
+			w[i] = 0.54 - 0.46*math.Cos(x)
+		case WindowBlackman:
+			w[i] = 0.42 - 0.5*math.Cos(x) + 0.08*math.Cos(2*x)
+		case WindowRectangular:
+			w[i] = 1
+		default:
+			return nil, fmt.Errorf("unknown window function %q (expected hann, hamming, blackman or rect)", name)
+		}
+	}
+	return w, nil
+}
+
+// FFT menghitung DFT x dengan radix-2 iteratif; panjang x harus pangkat dua
+func FFT(x []complex128) []complex128 {
+	n := len(x)
+	out := make([]complex128, n)
+	bits := 0
+	for 1<<bits < n {
+		bits++
+	}
+	for i := range x {
+		out[reverseBits(i, bits)] = x[i]
+	}
+	for size := 2; size <= n; size <<= 1 {
+		step := cmplx.Exp(complex(0, -2*math.Pi/float64(size)))
+		for start := 0; start < n; start += size {
+			w := complex(1, 0)
+			for k := 0; k < size/2; k++ {
+				a, b := out[start+k], w*out[start+k+size/2]
+				out[start+k] = a + b
+				out[start+k+size/2] = a - b
+				w *= step
+			}
+		}
+	}
+	return out
+}
+
+func reverseBits(v, bits int) int {
+	r := 0
+	for i := 0; i < bits; i++ {
+		r = r<<1 | (v>>i)&1
+	}
+	return r
+}
+
+// NextPow2 adalah pangkat dua terkecil >= n
+func NextPow2(n int) int {
+	p := 1
+	for p < n {
+		p <<= 1
+	}
+	return p
+}
+
+// STFTParams mengatur STFT satu deret
+type STFTParams struct {
+	Window   int    // panjang segmen (sampel)
+	Hop      int    // pergeseran antarsegmen (sampel)
+	WindowFn string // hann, hamming, blackman, rect
+}
+
+// Spectrogram adalah daya STFT [frame][bin] untuk bin 0..NFFT/2
+type Spectrogram struct {
+	NFFT   int
+	Starts []int       // indeks sampel awal tiap frame
+	Power  [][]float64 // |X|² ternormalisasi energi jendela
+}
+
+// STFT menghitung spektrogram daya satu deret tanpa NaN. Rata-rata tiap
+// segmen dibuang lebih dulu agar komponen statis (DC) tidak mendominasi.
+func STFT(x []float64, p STFTParams) (*Spectrogram, error) {
+	if p.Window < 2 || p.Hop < 1 {
+		return nil, errors.New("window must be >= 2 and hop >= 1")
+	}
+	if len(x) < p.Window {
+		return nil, fmt.Errorf("series has %d samples; window needs %d", len(x), p.Window)
+	}
+	win, err := WindowFunc(p.WindowFn, p.Window)
+	if err != nil {
+		return nil, err
+	}
+	energy := 0.0
+	for _, v := range win {
+		energy += v * v
+	}
+
+	nfft := NextPow2(p.Window)
+	sp := &Spectrogram{NFFT: nfft}
+	buf := make([]complex128, nfft)
+	for start := 0; start+p.Window <= len(x); start += p.Hop {
+		seg := x[start : start+p.Window]
+		mean := 0.0
+		for _, v := range seg {
+			mean += v
+		}
+		mean /= float64(len(seg))
+		for i := range buf {
+			buf[i] = 0
+		}
+		for i, v := range seg {
+			buf[i] = complex((v-mean)*win[i], 0)
+		}
+		spec := FFT(buf)
+		row := make([]float64, nfft/2+1)
+		for k := range row {
+			a := cmplx.Abs(spec[k])
+			row[k] = a * a / energy
+		}
+		sp.Starts = append(sp.Starts, start)
+		sp.Power = append(sp.Power, row)
+	}
+	return sp, nil
+}
+
+// FillGaps mengembalikan salinan x dengan NaN diisi interpolasi linear
+// (ujung memakai nilai valid terdekat). ok=false bila tidak ada sampel valid.
+func FillGaps(x []float64) ([]float64, bool) {
+	out := make([]float64, len(x))
+	copy(out, x)
+	_, ok := fillGaps(out)
+	return out, ok
+}
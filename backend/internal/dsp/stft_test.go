@@ -0,0 +1,118 @@
+package dsp
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+)
+
+// naiveDFT adalah DFT O(n²) sebagai pembanding FFT
+func naiveDFT(x []complex128) []complex128 {
+	n := len(x)
+	out := make([]complex128, n)
+	for k := range out {
+		for t, v := range x {
+			out[k] += v * cmplx.Exp(complex(0, -2*math.Pi*float64(k*t)/float64(n)))
+		}
+	}
+	return out
+}
+
+func TestFFT(t *testing.T) {
+	// bandingkan dengan DFT naif untuk semua ukuran pangkat dua sampai 64
+	for n := 1; n <= 64; n *= 2 {
+		x := make([]complex128, n)
+		for i := range x {
+			x[i] = complex(math.Sin(float64(i*i)+0.3), math.Cos(float64(3*i)))
+		}
+		got, want := FFT(x), naiveDFT(x)
+		for k := range want {
+			if cmplx.Abs(got[k]-want[k]) > 1e-9*float64(n) {
+				t.Fatalf("n=%d: bin %d = %v, want %v", n, k, got[k], want[k])
+			}
+		}
+	}
+
+	impulse := FFT([]complex128{1, 0, 0, 0})
+	for k, v := range impulse {
+		if v != 1 {
+			t.Errorf("impulse bin %d = %v, want 1", k, v)
+		}
+	}
+}
+
+func TestNextPow2(t *testing.T) {
+	cases := []struct{ in, want int }{{0, 1}, {1, 1}, {2, 2}, {3, 4}, {30, 32}, {64, 64}, {65, 128}}
+	for _, tc := range cases {
+		if got := NextPow2(tc.in); got != tc.want {
+			t.Errorf("NextPow2(%d) = %d, want %d", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestSTFTPeak(t *testing.T) {
+	const fs = 100.0
+	cases := []struct {
+		name     string
+		freq     float64
+		window   int
+		hop      int
+		windowFn string
+		frames   int
+	}{
+		{"hann 10 Hz", 10, 64, 16, WindowHann, 13},
+		{"hamming 25 Hz", 25, 128, 64, WindowHamming, 3},
+		{"rect 12.5 Hz", 12.5, 32, 32, WindowRectangular, 8},
+		{"blackman non pow2 window", 20, 50, 25, WindowBlackman, 9},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			x := make([]float64, 256)
+			for i := range x {
+				x[i] = 5 + math.Sin(2*math.Pi*tc.freq*float64(i)/fs)
+			}
+			sp, err := STFT(x, STFTParams{Window: tc.window, Hop: tc.hop, WindowFn: tc.windowFn})
+			if err != nil {
+				t.Fatalf("STFT: %v", err)
+			}
+			if len(sp.Power) != tc.frames {
+				t.Fatalf("got %d frames, want %d", len(sp.Power), tc.frames)
+			}
+			if sp.NFFT != NextPow2(tc.window) {
+				t.Fatalf("nfft %d, want %d", sp.NFFT, NextPow2(tc.window))
+			}
+			res := fs / float64(sp.NFFT)
+			for f, row := range sp.Power {
+				peak := 0
+				for k := range row {
+					if row[k] > row[peak] {
+						peak = k
+					}
+				}
+				if got := float64(peak) * res; math.Abs(got-tc.freq) > res {
+					t.Fatalf("frame %d peak at %g Hz, want %g Hz", f, got, tc.freq)
+				}
+			}
+		})
+	}
+}
+
+func TestSTFTErrors(t *testing.T) {
+	cases := []struct {
+		name string
+		n    int
+		p    STFTParams
+	}{
+		{"window too small", 10, STFTParams{Window: 1, Hop: 1, WindowFn: WindowHann}},
+		{"zero hop", 10, STFTParams{Window: 4, Hop: 0, WindowFn: WindowHann}},
+		{"series shorter than window", 3, STFTParams{Window: 4, Hop: 1, WindowFn: WindowHann}},
+		{"unknown window", 10, STFTParams{Window: 4, Hop: 1, WindowFn: "kaiser"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := STFT(make([]float64, tc.n), tc.p); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
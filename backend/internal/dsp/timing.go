@@ -0,0 +1,256 @@
+package dsp
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Metode interpolasi resampling
+const (
+	ResampleLinear = "linear"
+	ResampleSpline = "spline" // cubic spline natural per potongan tanpa celah
+)
+
+// TimingGap adalah selang antar paket yang jauh lebih panjang dari median
+type TimingGap struct {
+	After    int     `json:"after_packet"` // paket (1-based) sebelum celah
+	Start    float64 `json:"start_s"`      // detik sejak paket pertama
+	Duration float64 `json:"duration_s"`
+	Missing  int     `json:"missing_packets"` // perkiraan paket yang hilang
+}
+
+// TimingReport merangkum waktu kedatangan paket sebuah capture. Interval
+// dalam detik; jitter adalah sebaran interval di sekitar mediannya.
+type TimingReport struct {
+	Packets         int         `json:"packets"`
+	Timestamped     int         `json:"timestamped"`  // paket dengan timestamp valid
+	OutOfOrder      int         `json:"out_of_order"` // timestamp tidak naik (dibuang)
+	Duration        float64     `json:"duration_s"`
+	MeanRate        float64     `json:"mean_rate_hz"`    // paket / durasi
+	NominalRate     float64     `json:"nominal_rate_hz"` // 1 / median interval
+	MedianInterval  float64     `json:"median_interval_s"`
+	MeanInterval    float64     `json:"mean_interval_s"`
+	MinInterval     float64     `json:"min_interval_s"`
+	MaxInterval     float64     `json:"max_interval_s"`
+	P5Interval      float64     `json:"p5_interval_s"`
+	P95Interval     float64     `json:"p95_interval_s"`
+	JitterStd       float64     `json:"jitter_std_s"`
+	JitterMAD       float64     `json:"jitter_mad_s"` // 1.4826·MAD interval
+	GapFactor       float64     `json:"gap_factor"`
+	Gaps            []TimingGap `json:"gaps"`
+	GapTime         float64     `json:"gap_time_s"`
+	ExpectedPackets int         `json:"expected_packets"` // pada nominal rate
+	LostPackets     int         `json:"lost_packets"`
+	LossPct         float64     `json:"loss_pct"`
+}
+
+// monotonic mengembalikan indeks paket dengan timestamp valid yang naik
+// tegas; sisanya (NaN, duplikat, mundur) dilewati
+func monotonic(ts []float64) (idx []int, outOfOrder int) {
+	last := math.Inf(-1)
+	for i, t := range ts {
+		if math.IsNaN(t) || math.IsInf(t, 0) {
+			continue
+		}
+		if t <= last {
+			outOfOrder++
+			continue
+		}
+		idx = append(idx, i)
+		last = t
+	}
+	return idx, outOfOrder
+}
+
+// AnalyzeTiming menghitung laju paket, jitter, celah, dan perkiraan paket
+// hilang. Interval lebih dari gapFactor × median dianggap celah.
+func AnalyzeTiming(ts []float64, gapFactor float64) (*TimingReport, error) {
+	if gapFactor <= 1 {
+		return nil, errors.New("gap factor must be greater than 1")
+	}
+	idx, outOfOrder := monotonic(ts)
+	if len(idx) < 2 {
+		return nil, errors.New("capture needs at least two valid timestamps")
+	}
+	rep := &TimingReport{
+		Packets:     len(ts),
+		Timestamped: len(idx),
+		OutOfOrder:  outOfOrder,
+		GapFactor:   gapFactor,
+		Gaps:        []TimingGap{},
+	}
+	t0 := ts[idx[0]]
+	intervals := make([]float64, len(idx)-1)
+	for i := 1; i < len(idx); i++ {
+		intervals[i-1] = ts[idx[i]] - ts[idx[i-1]]
+	}
+	sorted := append([]float64(nil), intervals...)
+	sort.Float64s(sorted)
+	med := medianSorted(sorted)
+	quantile := func(q float64) float64 {
+		pos := q * float64(len(sorted)-1)
+		lo := int(math.Floor(pos))
+		hi := min(lo+1, len(sorted)-1)
+		return sorted[lo] + (pos-float64(lo))*(sorted[hi]-sorted[lo])
+	}
+
+	var sum, sum2 float64
+	dev := make([]float64, len(intervals))
+	for i, d := range intervals {
+		sum += d
+		sum2 += d * d
+		dev[i] = math.Abs(d - med)
+	}
+	sort.Float64s(dev)
+	n := float64(len(intervals))
+	rep.Duration = ts[idx[len(idx)-1]] - t0
+	rep.MedianInterval = med
+	rep.MeanInterval = sum / n
+	rep.MinInterval, rep.MaxInterval = sorted[0], sorted[len(sorted)-1]
+	rep.P5Interval, rep.P95Interval = quantile(0.05), quantile(0.95)
+	rep.JitterStd = math.Sqrt(math.Max(0, sum2/n-rep.MeanInterval*rep.MeanInterval))
+	rep.JitterMAD = 1.4826 * medianSorted(dev)
+	rep.MeanRate = float64(len(idx)-1) / rep.Duration
+	rep.NominalRate = 1 / med
+
+	for i, d := range intervals {
+		if d <= gapFactor*med {
+			continue
+		}
+		g := TimingGap{
+			After:    idx[i] + 1,
+			Start:    ts[idx[i]] - t0,
+			Duration: d,
+			Missing:  max(0, int(math.Round(d/med))-1),
+		}
+		rep.Gaps = append(rep.Gaps, g)
+		rep.GapTime += d
+		rep.LostPackets += g.Missing
+	}
+	rep.ExpectedPackets = int(math.Round(rep.Duration/med)) + 1
+	rep.LossPct = 100 * float64(rep.LostPackets) / float64(rep.ExpectedPackets)
+	return rep, nil
+}
+
+// ErrGridTooLong dikembalikan UniformGrid bila grid melebihi batas titik
+var ErrGridTooLong = errors.New("uniform grid too long")
+
+// UniformGrid adalah waktu t0, t0+1/rate, ... sampai t1. Grid yang lebih
+// dari maxLen titik (0 = tanpa batas) ditolak sebelum dialokasikan.
+func UniformGrid(t0, t1, rate float64, maxLen int) ([]float64, error) {
+	if !(rate > 0) || !(t1 >= t0) || math.IsInf(t1-t0, 0) {
+		return nil, fmt.Errorf("invalid grid %g..%g at %g Hz", t0, t1, rate)
+	}
+	n := math.Floor((t1-t0)*rate+1e-9) + 1
+	if maxLen > 0 && n > float64(maxLen) {
+		return nil, fmt.Errorf("%w: %.0f points over %g s at %g Hz (max %d)", ErrGridTooLong, n, t1-t0, rate, maxLen)
+	}
+	grid := make([]float64, int(n))
+	for i := range grid {
+		grid[i] = t0 + float64(i)/rate
+	}
+	return grid, nil
+}
+
+// Resample menginterpolasi sampel (t, x) ke waktu grid. t harus naik tegas
+// (lihat monotonic); sampel NaN dilewati. Titik grid di luar rentang sampel
+// valid, atau di dalam celah antar sampel valid yang lebih panjang dari
+// maxGap detik (0 = tanpa batas), bernilai NaN.
+func Resample(t, x, grid []float64, method string, maxGap float64) ([]float64, error) {
+	if len(t) != len(x) {
+		return nil, fmt.Errorf("time and value length differ (%d vs %d)", len(t), len(x))
+	}
+	var vt, vx []float64
+	for i, v := range x {
+		if !math.IsNaN(v) {
+			vt = append(vt, t[i])
+			vx = append(vx, v)
+		}
+	}
+	out := make([]float64, len(grid))
+	for i := range out {
+		out[i] = math.NaN()
+	}
+	if len(vt) == 0 {
+		return out, nil
+	}
+
+	// Potongan sampel valid yang tidak dipisah celah > maxGap; spline
+	// dihitung per potongan agar tidak berosilasi melintasi celah
+	var m2 []float64
+	switch method {
+	case ResampleLinear:
+	case ResampleSpline:
+		m2 = make([]float64, len(vt))
+		start := 0
+		for i := 1; i <= len(vt); i++ {
+			if i == len(vt) || (maxGap > 0 && vt[i]-vt[i-1] > maxGap) {
+				splineSecondDerivatives(vt[start:i], vx[start:i], m2[start:i])
+				start = i
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown resample method %q (expected %s or %s)", method, ResampleLinear, ResampleSpline)
+	}
+
+	j := 0
+	for i, g := range grid {
+		if g < vt[0] || g > vt[len(vt)-1] {
+			continue
+		}
+		for j+1 < len(vt)-1 && vt[j+1] < g {
+			j++
+		}
+		if len(vt) == 1 {
+			out[i] = vx[0]
+			continue
+		}
+		a, b := j, j+1
+		h := vt[b] - vt[a]
+		if h == 0 {
+			out[i] = vx[a]
+			continue
+		}
+		if maxGap > 0 && h > maxGap {
+			continue
+		}
+		u := (g - vt[a]) / h
+		y := vx[a] + u*(vx[b]-vx[a])
+		if m2 != nil {
+			// suku koreksi cubic spline
+			y += h * h / 6 * ((u*u*u-u)*m2[b] + ((1-u)*(1-u)*(1-u)-(1-u))*m2[a])
+		}
+		out[i] = y
+	}
+	return out, nil
+}
+
+// splineSecondDerivatives mengisi m dengan turunan kedua cubic spline
+// natural (algoritma Thomas untuk sistem tridiagonal)
+func splineSecondDerivatives(t, x, m []float64) {
+	n := len(t)
+	for i := range m {
+		m[i] = 0
+	}
+	if n < 3 {
+		return
+	}
+	c := make([]float64, n) // koefisien atas yang sudah dieliminasi
+	d := make([]float64, n)
+	for i := 1; i < n-1; i++ {
+		h0, h1 := t[i]-t[i-1], t[i+1]-t[i]
+		rhs := 6 * ((x[i+1]-x[i])/h1 - (x[i]-x[i-1])/h0)
+		diag := 2 * (h0 + h1)
+		if i > 1 {
+			diag -= h0 * c[i-1]
+			rhs -= h0 * d[i-1]
+		}
+		c[i] = h1 / diag
+		d[i] = rhs / diag
+	}
+	for i := n - 2; i >= 1; i-- {
+		m[i] = d[i] - c[i]*m[i+1]
+	}
+}
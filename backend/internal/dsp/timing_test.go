@@ -0,0 +1,196 @@
+package dsp
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+// capture100Hz menyusun timestamp 100 Hz dengan jitter ±0.5 ms, 10 paket
+// hilang setelah paket ke-300, satu duplikat, satu paket mundur, dan satu
+// timestamp kosong
+func capture100Hz() []float64 {
+	var ts []float64
+	for i := 0; i < 600; i++ {
+		if i >= 300 && i < 310 {
+			continue
+		}
+		ts = append(ts, float64(i)*0.01+0.0005*math.Sin(float64(7*i)))
+	}
+	ts = append(ts[:100], append([]float64{ts[99]}, ts[100:]...)...)
+	ts = append(ts[:200], append([]float64{ts[150]}, ts[200:]...)...)
+	ts[400] = math.NaN()
+	return ts
+}
+
+func TestAnalyzeTiming(t *testing.T) {
+	ts := capture100Hz()
+	rep, err := AnalyzeTiming(ts, 3)
+	if err != nil {
+		t.Fatalf("AnalyzeTiming: %v", err)
+	}
+	if rep.Packets != len(ts) || rep.Timestamped != len(ts)-3 || rep.OutOfOrder != 2 {
+		t.Fatalf("packets %d timestamped %d out of order %d", rep.Packets, rep.Timestamped, rep.OutOfOrder)
+	}
+	if math.Abs(rep.NominalRate-100) > 2 || math.Abs(rep.MedianInterval-0.01) > 2e-4 {
+		t.Errorf("nominal rate %g Hz, median interval %g s", rep.NominalRate, rep.MedianInterval)
+	}
+	if rep.JitterMAD <= 0 || rep.JitterMAD > 1e-3 {
+		t.Errorf("jitter MAD %g, want sub-millisecond", rep.JitterMAD)
+	}
+
+	if len(rep.Gaps) != 1 {
+		t.Fatalf("gaps %+v, want exactly the dropout", rep.Gaps)
+	}
+	g := rep.Gaps[0]
+	if math.Abs(g.Start-2.99) > 1e-3 || math.Abs(g.Duration-0.11) > 1e-3 || g.Missing != 10 {
+		t.Errorf("gap %+v, want 10 packets missing after 2.99 s", g)
+	}
+	if rep.LostPackets != 10 || rep.ExpectedPackets != 600 {
+		t.Errorf("lost %d of %d expected, want 10 of 600", rep.LostPackets, rep.ExpectedPackets)
+	}
+}
+
+func TestAnalyzeTimingRejects(t *testing.T) {
+	if _, err := AnalyzeTiming([]float64{0, 1, 2}, 1); err == nil {
+		t.Error("gap factor 1: expected error")
+	}
+	if _, err := AnalyzeTiming([]float64{math.NaN(), 1, 1}, 3); err == nil {
+		t.Error("one valid timestamp: expected error")
+	}
+}
+
+func mustGrid(t *testing.T, t0, t1, rate float64) []float64 {
+	t.Helper()
+	grid, err := UniformGrid(t0, t1, rate, 0)
+	if err != nil {
+		t.Fatalf("UniformGrid: %v", err)
+	}
+	return grid
+}
+
+func TestUniformGrid(t *testing.T) {
+	grid := mustGrid(t, 0, 1, 10)
+	if len(grid) != 11 || grid[10] != 1 {
+		t.Fatalf("got %v, want 11 points ending at 1", grid)
+	}
+	// ujung di antara dua sampel tidak ikut; pembulatan float tidak
+	// menghilangkan titik terakhir
+	if grid := mustGrid(t, 0.5, 0.74, 20); len(grid) != 5 || math.Abs(grid[4]-0.7) > 1e-12 {
+		t.Errorf("got %v, want 0.5..0.7", grid)
+	}
+	if grid := mustGrid(t, 0, 0.3, 10); len(grid) != 4 {
+		t.Errorf("got %v, want 4 points", grid)
+	}
+	if grid := mustGrid(t, 2, 2, 100); len(grid) != 1 || grid[0] != 2 {
+		t.Errorf("got %v, want [2]", grid)
+	}
+}
+
+func TestUniformGridLimit(t *testing.T) {
+	if grid, err := UniformGrid(0, 1, 10, 11); err != nil || len(grid) != 11 {
+		t.Errorf("grid exactly at the limit: %d points, %v", len(grid), err)
+	}
+	if _, err := UniformGrid(0, 1, 10, 10); !errors.Is(err, ErrGridTooLong) {
+		t.Errorf("one point over the limit: %v", err)
+	}
+	// timestamp pencilan tidak boleh memicu alokasi raksasa
+	if _, err := UniformGrid(0, 4e9, 10000, 1<<20); !errors.Is(err, ErrGridTooLong) {
+		t.Errorf("outlier timestamp: %v", err)
+	}
+	for _, bad := range [][3]float64{{0, 1, 0}, {1, 0, 10}, {0, math.Inf(1), 10}} {
+		if _, err := UniformGrid(bad[0], bad[1], bad[2], 0); err == nil || errors.Is(err, ErrGridTooLong) {
+			t.Errorf("grid %v: got %v, want an invalid grid error", bad, err)
+		}
+	}
+}
+
+func TestResampleJitteredCapture(t *testing.T) {
+	// deret linear di atas timestamp ber-jitter: kedua metode harus tepat
+	ts := capture100Hz()[:250]
+	idx, _ := monotonic(ts)
+	var tv, xv []float64
+	for _, i := range idx {
+		tv = append(tv, ts[i])
+		xv = append(xv, 3-2*ts[i])
+	}
+	grid := mustGrid(t, tv[0], tv[len(tv)-1], 50)
+	for _, method := range []string{ResampleLinear, ResampleSpline} {
+		got, err := Resample(tv, xv, grid, method, 0)
+		if err != nil {
+			t.Fatalf("Resample %s: %v", method, err)
+		}
+		for i, g := range grid {
+			if math.Abs(got[i]-(3-2*g)) > 1e-9 {
+				t.Fatalf("%s: value at %g = %g, want %g", method, g, got[i], 3-2*g)
+			}
+		}
+	}
+}
+
+func TestResampleMissingData(t *testing.T) {
+	nan := math.NaN()
+	tv := []float64{1, 1.1, 1.2, 1.3, 2.3, 2.4, 2.5}
+	xv := []float64{0, 1, nan, 3, 13, 14, 15}
+	grid := []float64{0.5, 1.05, 1.2, 1.8, 2.45, 3}
+
+	got, err := Resample(tv, xv, grid, ResampleLinear, 0.5)
+	if err != nil {
+		t.Fatalf("Resample: %v", err)
+	}
+	// di luar rentang → NaN; sampel NaN dilewati; celah 1 s > maxGap → NaN
+	want := []float64{nan, 0.5, 2, nan, 14.5, nan}
+	for i := range want {
+		if math.IsNaN(want[i]) != math.IsNaN(got[i]) || (!math.IsNaN(want[i]) && math.Abs(got[i]-want[i]) > 1e-12) {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+
+	// tanpa batas celah, titik di tengah celah diinterpolasi
+	got, _ = Resample(tv, xv, []float64{1.8}, ResampleLinear, 0)
+	if math.Abs(got[0]-8) > 1e-12 {
+		t.Errorf("interpolated %g across the gap, want 8", got[0])
+	}
+
+	got, _ = Resample([]float64{0, 1}, []float64{nan, 7}, []float64{1}, ResampleSpline, 0)
+	if got[0] != 7 {
+		t.Errorf("single valid sample gave %g, want 7", got[0])
+	}
+}
+
+// Spline pada fungsi halus jauh lebih akurat daripada linear
+func TestResampleSplineSmooth(t *testing.T) {
+	var ts, xs []float64
+	for i := 0; i <= 40; i++ {
+		// sampling tidak seragam
+		tv := float64(i)*0.05 + 0.01*math.Sin(float64(i))
+		ts = append(ts, tv)
+		xs = append(xs, math.Sin(2*math.Pi*tv))
+	}
+	grid := mustGrid(t, 0.2, 1.8, 100)
+	maxErr := map[string]float64{}
+	for _, method := range []string{ResampleLinear, ResampleSpline} {
+		got, err := Resample(ts, xs, grid, method, 0)
+		if err != nil {
+			t.Fatalf("Resample %s: %v", method, err)
+		}
+		for i, g := range grid {
+			maxErr[method] = math.Max(maxErr[method], math.Abs(got[i]-math.Sin(2*math.Pi*g)))
+		}
+	}
+	if maxErr[ResampleSpline] > 1e-3 {
+		t.Errorf("spline max error %g, want <= 1e-3", maxErr[ResampleSpline])
+	}
+	if maxErr[ResampleSpline] >= maxErr[ResampleLinear] {
+		t.Errorf("spline error %g not below linear error %g", maxErr[ResampleSpline], maxErr[ResampleLinear])
+	}
+}
+
+func TestResampleErrors(t *testing.T) {
+	if _, err := Resample([]float64{0, 1}, []float64{0}, []float64{0}, ResampleLinear, 0); err == nil {
+		t.Error("expected error for length mismatch")
+	}
+	if _, err := Resample([]float64{0, 1}, []float64{0, 1}, []float64{0}, "nearest", 0); err == nil {
+		t.Error("expected error for unknown method")
+	}
+}
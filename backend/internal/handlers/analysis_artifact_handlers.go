@@ -0,0 +1,360 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/minio/minio-go/v7"
+
+	"cetasense-v2.0/internal/models"
+)
+
+// batas precompute latar belakang
+const (
+	maxPrecomputeJobs = 2
+	precomputeTimeout = 10 * time.Minute
+)
+
+// DefaultPrecomputeKinds adalah analisis yang dihitung bila precompute
+// tidak menyebut jenisnya
+var DefaultPrecomputeKinds = []string{"bnr", "spectrogram", "pca"}
+
+// analysisHandlers memetakan jenis artifact ke handler yang menghasilkannya
+func (h *PlotHandler) analysisHandlers() map[string]http.HandlerFunc {
+	return map[string]http.HandlerFunc{
+		"bnr":         h.GetPlots,
+		"spectrogram": h.GetSpectrogram,
+		"pca":         h.GetPCA,
+		"cir":         h.GetCIR,
+		"aoa-tof":     h.GetAoAToF,
+		"respiration": h.GetRespiration,
+		"timing":      h.GetTiming,
+		"correlation": h.GetCorrelation,
+		"features":    h.GetFeatures,
+	}
+}
+
+// ParseAnalysisKinds memvalidasi daftar jenis analisis (dipisah koma)
+func (h *PlotHandler) ParseAnalysisKinds(v string) ([]string, error) {
+	if strings.TrimSpace(v) == "" {
+		return nil, nil
+	}
+	return h.checkKinds(strings.Split(v, ","))
+}
+
+func (h *PlotHandler) checkKinds(kinds []string) ([]string, error) {
+	known := h.analysisHandlers()
+	var out []string
+	for _, k := range kinds {
+		k = strings.TrimSpace(k)
+		if k == "" {
+			continue
+		}
+		if known[k] == nil {
+			return nil, fmt.Errorf("unknown analysis kind %q", k)
+		}
+		out = append(out, k)
+	}
+	return out, nil
+}
+
+// precomputeResult adalah hasil precompute satu jenis analisis
+type precomputeResult struct {
+	Kind   string `json:"kind"`
+	Status int    `json:"status"`
+	Source string `json:"source,omitempty"` // HIT, ARTIFACT, MISS
+	Error  string `json:"error,omitempty"`
+}
+
+// resultRecorder menampung respons handler analisis yang dipanggil internal
+type resultRecorder struct {
+	header http.Header
+	code   int
+	body   strings.Builder
+}
+
+func (r *resultRecorder) Header() http.Header         { return r.header }
+func (r *resultRecorder) WriteHeader(code int)        { r.code = code }
+func (r *resultRecorder) Write(b []byte) (int, error) { return r.body.Write(b) }
+
+// Precompute menjalankan analisis dengan parameter default untuk sebuah file
+// lewat handler yang sama dengan request biasa, sehingga artifact yang
+// tersimpan memakai kunci yang sama dengan GET berikutnya
+func (h *PlotHandler) Precompute(ctx context.Context, fileID string, kinds []string) []precomputeResult {
+	handlers := h.analysisHandlers()
+	results := make([]precomputeResult, 0, len(kinds))
+	for _, kind := range kinds {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, "/api/plots/"+fileID+"/"+kind, nil)
+		if err != nil {
+			results = append(results, precomputeResult{Kind: kind, Error: err.Error()})
+			continue
+		}
+		req = mux.SetURLVars(req, map[string]string{"id": fileID})
+		rec := &resultRecorder{header: http.Header{}, code: http.StatusOK}
+		handlers[kind](rec, req)
+
+		res := precomputeResult{Kind: kind, Status: rec.code, Source: rec.header.Get("X-Analysis-Cache")}
+		if rec.code != http.StatusOK {
+			var body struct {
+				Error string `json:"error"`
+			}
+			json.Unmarshal([]byte(rec.body.String()), &body)
+			res.Error = body.Error
+		}
+		results = append(results, res)
+	}
+	return results
+}
+
+// PrecomputeAsync menjadwalkan Precompute di latar belakang; paling banyak
+// maxPrecomputeJobs berjalan bersamaan
+func (h *PlotHandler) PrecomputeAsync(fileID string, kinds []string) {
+	if len(kinds) == 0 {
+		return
+	}
+	go func() {
+		h.precompute <- struct{}{}
+		defer func() { <-h.precompute }()
+		ctx, cancel := context.WithTimeout(context.Background(), precomputeTimeout)
+		defer cancel()
+
+		t0 := time.Now()
+		for _, res := range h.Precompute(ctx, fileID, kinds) {
+			if res.Status != http.StatusOK {
+				log.Printf("[Analysis] precompute %s for %s failed (%d): %s", res.Kind, fileID, res.Status, res.Error)
+			}
+		}
+		log.Printf("[Analysis] precompute for %s done in %s (%s)", fileID, time.Since(t0).Round(time.Millisecond), strings.Join(kinds, ","))
+		h.dropListingCache(ctx, fileID)
+	}()
+}
+
+// dropListingCache membuang cache middleware daftar artifact sebuah file
+// yang berubah di luar request tulis
+func (h *PlotHandler) dropListingCache(ctx context.Context, fileID string) {
+	if h.cache == nil {
+		return
+	}
+	keys, err := h.cache.Keys(ctx, "cache:GET:/api/plots/"+fileID+"/artifacts*")
+	if err != nil {
+		log.Printf("[Analysis] cache KEYS error: %v", err)
+		return
+	}
+	for _, key := range keys {
+		if err := h.cache.Del(ctx, key); err != nil {
+			log.Printf("[Analysis] cache DEL error for %s: %v", key, err)
+		}
+	}
+}
+
+// ListArtifacts mendaftar artifact analisis sebuah file (?kind= opsional)
+func (h *PlotHandler) ListArtifacts(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	meta, err := h.loadMeta(ctx, mux.Vars(r)["id"])
+	if err != nil {
+		respondLoadError(w, err)
+		return
+	}
+	artifacts, err := h.artifactRepo.ListForFile(ctx, meta.ID, meta.ContentHash, r.URL.Query().Get("kind"))
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to list artifacts: "+err.Error())
+		return
+	}
+	var total int64
+	for _, a := range artifacts {
+		total += a.SizeBytes
+	}
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"file_id":      meta.ID,
+		"content_hash": meta.ContentHash,
+		"artifacts":    artifacts,
+		"count":        len(artifacts),
+		"size_bytes":   total,
+	})
+}
+
+// InvalidateArtifacts menghapus artifact analisis sebuah file (?kind=
+// opsional) dari MinIO, DB, dan Redis. Artifact dipakai bersama oleh file
+// dengan isi yang sama, jadi ikut terhapus untuk file tersebut.
+func (h *PlotHandler) InvalidateArtifacts(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	meta, err := h.loadMeta(ctx, mux.Vars(r)["id"])
+	if err != nil {
+		respondLoadError(w, err)
+		return
+	}
+	artifacts, err := h.artifactRepo.ListForFile(ctx, meta.ID, meta.ContentHash, r.URL.Query().Get("kind"))
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to list artifacts: "+err.Error())
+		return
+	}
+	if err := h.removeArtifacts(ctx, artifacts); err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to invalidate artifacts: "+err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"message": "Artifacts invalidated",
+		"removed": len(artifacts),
+	})
+}
+
+// PrecomputeArtifacts menjadwalkan precompute analisis untuk sebuah file.
+// Body opsional {"kinds": [...]}; default DefaultPrecomputeKinds.
+func (h *PlotHandler) PrecomputeArtifacts(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	meta, err := h.loadMeta(ctx, mux.Vars(r)["id"])
+	if err != nil {
+		respondLoadError(w, err)
+		return
+	}
+	var req struct {
+		Kinds []string `json:"kinds"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+			return
+		}
+	}
+	kinds, err := h.checkKinds(req.Kinds)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if len(kinds) == 0 {
+		kinds = DefaultPrecomputeKinds
+	}
+	h.PrecomputeAsync(meta.ID, kinds)
+	respondJSON(w, http.StatusAccepted, map[string]interface{}{
+		"message": "Precompute scheduled",
+		"file_id": meta.ID,
+		"kinds":   kinds,
+	})
+}
+
+// RemoveFileArtifacts membuang artifact file yang dihapus. Artifact yang
+// dicatat atas hash isi hanya dibuang bila tidak ada upload lain dengan isi
+// yang sama; bila ada, file_id-nya dipindahkan ke upload tersebut.
+func (h *PlotHandler) RemoveFileArtifacts(ctx context.Context, f *models.CSI_File) {
+	if h.artifactRepo == nil {
+		return
+	}
+	hash, shared := f.ContentHash, false
+	if hash != "" {
+		other, err := h.csvRepo.GetByContentHash(ctx, hash)
+		if err == nil && other.ID != f.ID {
+			shared = true
+			if n, err := h.artifactRepo.ReassignFile(ctx, f.ID, other.ID, hash); err != nil {
+				log.Printf("[Analysis] reassign artifacts of %s error: %v", f.ID, err)
+			} else if n > 0 {
+				log.Printf("[Analysis] %d artifacts of %s reassigned to %s", n, f.ID, other.ID)
+			}
+		} else if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			// tidak bisa dipastikan: anggap artifact hash masih dipakai
+			log.Printf("[Analysis] GetByContentHash error: %v", err)
+			shared = true
+		}
+	}
+	listHash := hash
+	if shared {
+		listHash = ""
+	}
+	artifacts, err := h.artifactRepo.ListForFile(ctx, f.ID, listHash, "")
+	if err != nil {
+		log.Printf("[Analysis] list artifacts for %s error: %v", f.ID, err)
+		return
+	}
+	if shared {
+		artifacts = withoutContentHash(artifacts, hash)
+	}
+	if err := h.removeArtifacts(ctx, artifacts); err != nil {
+		log.Printf("[Analysis] remove artifacts for %s error: %v", f.ID, err)
+	}
+}
+
+// withoutContentHash membuang artifact berhash isi hash dari daftar
+func withoutContentHash(artifacts []*models.AnalysisArtifact, hash string) []*models.AnalysisArtifact {
+	kept := artifacts[:0]
+	for _, a := range artifacts {
+		if a.ContentHash != hash {
+			kept = append(kept, a)
+		}
+	}
+	return kept
+}
+
+// removeArtifacts menghapus objek, catatan DB, dan salinan Redis artifact
+func (h *PlotHandler) removeArtifacts(ctx context.Context, artifacts []*models.AnalysisArtifact) error {
+	keys := make([]string, 0, len(artifacts))
+	for _, a := range artifacts {
+		if err := h.minioClient.RemoveObject(ctx, h.bucketName, a.ObjectPath, minio.RemoveObjectOptions{}); err != nil {
+			log.Printf("[Analysis] RemoveObject %s error: %v", a.ObjectPath, err)
+		}
+		if h.cache != nil {
+			if err := h.cache.Del(ctx, a.Key); err != nil {
+				log.Printf("[Analysis] cache DEL error for %s: %v", a.Key, err)
+			}
+		}
+		keys = append(keys, a.Key)
+	}
+	return h.artifactRepo.Delete(ctx, keys...)
+}
+
+// CleanupArtifacts membuang artifact yang tidak dipakai selama ttl (0 =
+// tanpa TTL), lalu artifact yang paling lama tidak dipakai sampai total
+// ukurannya paling banyak maxBytes (0 = tanpa batas)
+func (h *PlotHandler) CleanupArtifacts(ctx context.Context, ttl time.Duration, maxBytes int64) (int, error) {
+	if h.artifactRepo == nil {
+		return 0, nil
+	}
+	removed := 0
+	if ttl > 0 {
+		stale, err := h.artifactRepo.ListUnused(ctx, ttl)
+		if err != nil {
+			return 0, err
+		}
+		if err := h.removeArtifacts(ctx, stale); err != nil {
+			return 0, err
+		}
+		removed += len(stale)
+	}
+	if maxBytes > 0 {
+		over, err := h.artifactRepo.ListOverBudget(ctx, maxBytes)
+		if err != nil {
+			return removed, err
+		}
+		if err := h.removeArtifacts(ctx, over); err != nil {
+			return removed, err
+		}
+		removed += len(over)
+	}
+	return removed, nil
+}
+
+// RunArtifactJanitor menjalankan CleanupArtifacts secara periodik sampai
+// ctx dibatalkan
+func (h *PlotHandler) RunArtifactJanitor(ctx context.Context, interval, ttl time.Duration, maxBytes int64) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := h.CleanupArtifacts(ctx, ttl, maxBytes)
+			if err != nil {
+				log.Printf("[Analysis] artifact cleanup error: %v", err)
+			} else if n > 0 {
+				log.Printf("[Analysis] removed %d expired analysis artifacts", n)
+			}
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package handlers
+
+import (
+	"testing"
+
+	"cetasense-v2.0/internal/models"
+)
+
+func TestWithoutContentHash(t *testing.T) {
+	artifacts := []*models.AnalysisArtifact{
+		{Key: "shared-plot", ContentHash: "h1"},
+		{Key: "legacy", ContentHash: ""},
+		{Key: "shared-pca", ContentHash: "h1"},
+		{Key: "stale", ContentHash: "h0"},
+	}
+	got := withoutContentHash(artifacts, "h1")
+	if len(got) != 2 || got[0].Key != "legacy" || got[1].Key != "stale" {
+		keys := make([]string, len(got))
+		for i, a := range got {
+			keys[i] = a.Key
+		}
+		t.Errorf("kept %v, want [legacy stale]", keys)
+	}
+}
@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/minio/minio-go/v7"
+
+	"cetasense-v2.0/internal/models"
+)
+
+// umur hasil analisis di Redis; kuncinya memuat hash isi file sehingga
+// hasil tidak pernah basi, TTL hanya membatasi pemakaian memori. Salinan
+// permanennya disimpan sebagai artifact di MinIO.
+const analysisCacheTTL = 24 * time.Hour
+
+// Nilai header X-Analysis-Cache
+const (
+	analysisCacheHit      = "HIT"      // dari Redis
+	analysisCacheArtifact = "ARTIFACT" // dari artifact MinIO
+	analysisCacheMiss     = "MISS"     // dihitung ulang
+)
+
+// analysisRef mengidentifikasi satu hasil analisis
+type analysisRef struct {
+	Key    string // analysis:<jenis>:<sha256>
+	Kind   string
+	Params string
+	Meta   *models.CSI_File
+	Filter *appliedFilter
+}
+
+// analysisKey menyusun kunci cache analysis:<jenis>:<sha256> dari identitas
+// isi file, layout, versi filter yang diterapkan, dan parameter kanonik
+func analysisKey(kind string, meta *models.CSI_File, filter *appliedFilter, params string) *analysisRef {
+	layout := "auto"
+	if meta.Layout != nil {
+		b, _ := json.Marshal(meta.Layout)
+		layout = string(b)
+	}
+	applied := "raw"
+	if filter != nil && filter.Applied {
+		applied = fmt.Sprintf("filter:%s@%d", filter.ID, filter.Version)
+	} else {
+		filter = nil
+	}
+	sum := sha256.Sum256([]byte(contentIdentity(meta) + "|" + layout + "|" + applied + "|" + params))
+	return &analysisRef{
+		Key:    "analysis:" + kind + ":" + hex.EncodeToString(sum[:]),
+		Kind:   kind,
+		Params: params,
+		Meta:   meta,
+		Filter: filter,
+	}
+}
+
+// contentIdentity adalah hash isi file, atau id untuk upload lama tanpa hash
+func contentIdentity(meta *models.CSI_File) string {
+	if meta.ContentHash != "" {
+		return meta.ContentHash
+	}
+	return "id:" + meta.ID
+}
+
+// artifactPath adalah object key artifact di MinIO
+func artifactPath(ref *analysisRef) string {
+	dir := ref.Meta.ContentHash
+	if dir == "" {
+		dir = "id-" + ref.Meta.ID
+	}
+	sum := ref.Key[len(ref.Key)-64:]
+	return fmt.Sprintf("Analysis-Artifacts/%s/%s/%s.json", dir, ref.Kind, sum)
+}
+
+// cachedAnalysis menulis hasil analisis dari Redis atau artifact MinIO bila
+// ada
+func (h *PlotHandler) cachedAnalysis(ctx context.Context, w http.ResponseWriter, ref *analysisRef) bool {
+	if h.cache != nil {
+		blob, err := h.cache.Get(ctx, ref.Key)
+		if err == nil {
+			writeAnalysis(w, analysisCacheHit, blob)
+			return true
+		}
+		if err != redis.Nil {
+			log.Printf("[Analysis] cache GET error for %s: %v", ref.Key, err)
+		}
+	}
+
+	blob, ok := h.loadArtifact(ctx, ref)
+	if !ok {
+		return false
+	}
+	if h.cache != nil {
+		if err := h.cache.Set(ctx, ref.Key, blob, analysisCacheTTL); err != nil {
+			log.Printf("[Analysis] cache SET error for %s: %v", ref.Key, err)
+		}
+	}
+	writeAnalysis(w, analysisCacheArtifact, blob)
+	return true
+}
+
+// loadArtifact membaca artifact permanen; catatan yang objeknya hilang
+// dihapus supaya hasilnya dihitung ulang
+func (h *PlotHandler) loadArtifact(ctx context.Context, ref *analysisRef) ([]byte, bool) {
+	if h.artifactRepo == nil {
+		return nil, false
+	}
+	art, err := h.artifactRepo.Get(ctx, ref.Key)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("[Analysis] artifact lookup error for %s: %v", ref.Key, err)
+		}
+		return nil, false
+	}
+	obj, err := h.minioClient.GetObject(ctx, h.bucketName, art.ObjectPath, minio.GetObjectOptions{})
+	if err == nil {
+		var blob []byte
+		blob, err = io.ReadAll(obj)
+		obj.Close()
+		if err == nil {
+			if err := h.artifactRepo.Touch(ctx, ref.Key); err != nil {
+				log.Printf("[Analysis] artifact touch error for %s: %v", ref.Key, err)
+			}
+			return blob, true
+		}
+	}
+	log.Printf("[Analysis] artifact %s unreadable (%v); recomputing", art.ObjectPath, err)
+	if err := h.artifactRepo.Delete(ctx, ref.Key); err != nil {
+		log.Printf("[Analysis] artifact delete error for %s: %v", ref.Key, err)
+	}
+	return nil, false
+}
+
+// respondAnalysis menulis hasil analisis lalu menyimpannya ke Redis dan
+// sebagai artifact permanen
+func (h *PlotHandler) respondAnalysis(ctx context.Context, w http.ResponseWriter, ref *analysisRef, payload interface{}) {
+	blob, err := json.Marshal(payload)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to encode result: "+err.Error())
+		return
+	}
+	writeAnalysis(w, analysisCacheMiss, blob)
+
+	if h.cache != nil {
+		if err := h.cache.Set(ctx, ref.Key, blob, analysisCacheTTL); err != nil {
+			log.Printf("[Analysis] cache SET error for %s: %v", ref.Key, err)
+		}
+	}
+	if err := h.storeArtifact(ctx, ref, blob); err != nil {
+		log.Printf("[Analysis] artifact store error for %s: %v", ref.Key, err)
+	}
+}
+
+// storeArtifact mengunggah hasil analisis ke MinIO dan mencatatnya
+func (h *PlotHandler) storeArtifact(ctx context.Context, ref *analysisRef, blob []byte) error {
+	if h.artifactRepo == nil {
+		return nil
+	}
+	path := artifactPath(ref)
+	if _, err := h.minioClient.PutObject(ctx, h.bucketName, path, bytes.NewReader(blob), int64(len(blob)),
+		minio.PutObjectOptions{ContentType: "application/json"}); err != nil {
+		return err
+	}
+	art := &models.AnalysisArtifact{
+		Key:         ref.Key,
+		Kind:        ref.Kind,
+		FileID:      ref.Meta.ID,
+		ContentHash: ref.Meta.ContentHash,
+		Params:      ref.Params,
+		ObjectPath:  path,
+		SizeBytes:   int64(len(blob)),
+	}
+	if ref.Filter != nil {
+		art.FilterID, art.FilterVersion = ref.Filter.ID, ref.Filter.Version
+	}
+	return h.artifactRepo.Upsert(ctx, art)
+}
+
+func writeAnalysis(w http.ResponseWriter, source string, blob []byte) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Analysis-Cache", source)
+	w.WriteHeader(http.StatusOK)
+	w.Write(blob)
+}
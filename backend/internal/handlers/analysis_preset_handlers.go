@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+
+	"cetasense-v2.0/internal/models"
+	"cetasense-v2.0/internal/repositories"
+)
+
+type AnalysisPresetHandler struct {
+	repo     *repositories.AnalysisPresetRepository
+	validate *validator.Validate
+}
+
+func NewAnalysisPresetHandler(repo *repositories.AnalysisPresetRepository) *AnalysisPresetHandler {
+	return &AnalysisPresetHandler{repo: repo, validate: validator.New()}
+}
+
+// decodePreset membaca body preset; field yang tidak dikirim diisi dari
+// base (default untuk preset baru, nilai lama untuk update)
+func (h *AnalysisPresetHandler) decodePreset(r *http.Request, base models.AnalysisPreset) (*models.AnalysisPreset, error) {
+	p := &models.AnalysisPreset{Name: base.Name, Params: base.Params}
+	if err := json.NewDecoder(r.Body).Decode(p); err != nil {
+		return nil, errors.New("Invalid request payload: " + err.Error())
+	}
+	if err := h.validate.Struct(p); err != nil {
+		return nil, errors.New("Validation error: " + err.Error())
+	}
+	return p, nil
+}
+
+// nameTaken cek apakah nama preset sudah dipakai preset lain
+func (h *AnalysisPresetHandler) nameTaken(r *http.Request, name, selfID string) (bool, error) {
+	other, err := h.repo.GetByName(r.Context(), name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return other.ID != selfID, nil
+}
+
+// CreatePreset menyimpan preset parameter BNR bernama
+func (h *AnalysisPresetHandler) CreatePreset(w http.ResponseWriter, r *http.Request) {
+	preset, err := h.decodePreset(r, models.AnalysisPreset{Params: models.DefaultBNRParams()})
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	taken, err := h.nameTaken(r, preset.Name, "")
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to check preset name: "+err.Error())
+		return
+	}
+	if taken {
+		respondError(w, http.StatusConflict, "Preset name already exists")
+		return
+	}
+	preset.GenerateID()
+	if err := h.repo.Create(r.Context(), preset); err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to create preset: "+err.Error())
+		return
+	}
+	created, err := h.repo.GetByID(r.Context(), preset.ID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to load preset: "+err.Error())
+		return
+	}
+	respondJSON(w, http.StatusCreated, created)
+}
+
+// UpdatePreset mengganti nama dan/atau parameter preset
+func (h *AnalysisPresetHandler) UpdatePreset(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	current, err := h.repo.GetByID(r.Context(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondError(w, http.StatusNotFound, "Preset not found")
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to load preset: "+err.Error())
+		return
+	}
+	preset, err := h.decodePreset(r, *current)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	preset.ID = id
+	taken, err := h.nameTaken(r, preset.Name, id)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to check preset name: "+err.Error())
+		return
+	}
+	if taken {
+		respondError(w, http.StatusConflict, "Preset name already exists")
+		return
+	}
+	if err := h.repo.Update(r.Context(), preset); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondError(w, http.StatusNotFound, "Preset not found")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "Failed to update preset: "+err.Error())
+		return
+	}
+	updated, err := h.repo.GetByID(r.Context(), id)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to load preset: "+err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, updated)
+}
+
+// GetAllPresets mendaftar semua preset beserta parameter default
+func (h *AnalysisPresetHandler) GetAllPresets(w http.ResponseWriter, r *http.Request) {
+	presets, err := h.repo.GetAll(r.Context())
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to get presets: "+err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"defaults": models.DefaultBNRParams(),
+		"presets":  presets,
+	})
+}
+
+// GetPresetByID mengambil satu preset
+func (h *AnalysisPresetHandler) GetPresetByID(w http.ResponseWriter, r *http.Request) {
+	preset, err := h.repo.GetByID(r.Context(), mux.Vars(r)["id"])
+	if errors.Is(err, sql.ErrNoRows) {
+		respondError(w, http.StatusNotFound, "Preset not found")
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to get preset: "+err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, preset)
+}
+
+// DeletePreset menghapus preset
+func (h *AnalysisPresetHandler) DeletePreset(w http.ResponseWriter, r *http.Request) {
+	if err := h.repo.Delete(r.Context(), mux.Vars(r)["id"]); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondError(w, http.StatusNotFound, "Preset not found")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "Failed to delete preset: "+err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]string{"message": "Preset deleted successfully"})
+}
@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"cetasense-v2.0/internal/dsp"
+	"cetasense-v2.0/internal/models"
+)
+
+// default parameter MUSIC: Intel 5300 di kanal 64 (5.32 GHz), 30 subcarrier
+// berkelompok 4 × 312.5 kHz (bila layout tidak mencatat spacing), antena
+// berjarak setengah panjang gelombang
+const (
+	defaultCarrierHz    = 5.32e9
+	defaultSubcarrierHz = 1.25e6
+	defaultMUSICPackets = 10
+	maxMUSICPackets     = 50
+	defaultMUSICPeaks   = 5
+	maxMUSICPeaks       = 20
+	directPathWindowDB  = 10.0 // puncak kandidat jalur langsung: <= 10 dB di bawah puncak
+	spectrumDecimalsDB  = 100  // spektrum dibulatkan ke 0.01 dB
+)
+
+type musicParams struct {
+	Offset   int
+	Packets  int
+	Sources  int
+	Peaks    int
+	Settings dsp.MUSICParams
+}
+
+func (p *musicParams) canonical() string {
+	return fmt.Sprintf("off=%d;n=%d;src=%d;peaks=%d;f=%g;df=%g;d=%g;grid=%d/%d",
+		p.Offset, p.Packets, p.Sources, p.Peaks,
+		p.Settings.Frequency, p.Settings.SubcarrierSpacing, p.Settings.AntennaSpacing,
+		len(ThetaScan), len(TauScan))
+}
+
+// parseMUSICParams memvalidasi query; jarak subcarrier default diambil dari
+// layout capture bila tersedia
+func parseMUSICParams(r *http.Request, layout *models.CSILayout) (*musicParams, error) {
+	p := &musicParams{}
+	var err error
+	if p.Offset, err = queryInt(r, "offset", 0); err != nil || p.Offset < 0 {
+		return nil, fmt.Errorf("offset must be a non-negative integer")
+	}
+	if p.Packets, err = queryInt(r, "packets", defaultMUSICPackets); err != nil || p.Packets < 1 || p.Packets > maxMUSICPackets {
+		return nil, fmt.Errorf("packets must be an integer between 1 and %d", maxMUSICPackets)
+	}
+	if p.Sources, err = queryInt(r, "sources", 0); err != nil || p.Sources < 0 {
+		return nil, fmt.Errorf("sources must be a non-negative integer (0 = auto)")
+	}
+	if p.Peaks, err = queryInt(r, "peaks", defaultMUSICPeaks); err != nil || p.Peaks < 1 || p.Peaks > maxMUSICPeaks {
+		return nil, fmt.Errorf("peaks must be an integer between 1 and %d", maxMUSICPeaks)
+	}
+	s := &p.Settings
+	if s.Frequency, err = queryFloat(r, "frequency", defaultCarrierHz); err != nil || s.Frequency <= 0 {
+		return nil, fmt.Errorf("frequency must be a positive number (Hz)")
+	}
+	spacing := defaultSubcarrierHz
+	if layout != nil && layout.Spacing() > 0 {
+		spacing = layout.Spacing()
+	}
+	if s.SubcarrierSpacing, err = queryFloat(r, "subcarrier_spacing", spacing); err != nil || s.SubcarrierSpacing <= 0 {
+		return nil, fmt.Errorf("subcarrier_spacing must be a positive number (Hz)")
+	}
+	halfWavelength := 299792458.0 / s.Frequency / 2
+	if s.AntennaSpacing, err = queryFloat(r, "antenna_spacing", halfWavelength); err != nil || s.AntennaSpacing <= 0 {
+		return nil, fmt.Errorf("antenna_spacing must be a positive number (m)")
+	}
+	s.Sources = p.Sources
+	return p, nil
+}
+
+// GetAoAToF mengestimasi AoA dan ToF gabungan dengan MUSIC ala SpotFi
+// (spatial smoothing antena × subcarrier) pada jendela paket
+// [offset, offset+packets) di atas grid ThetaScan (derajat) × TauScan
+// (detik). ToF bersifat relatif: kemiringan fase tiap paket disamakan ke
+// median jendela sehingga offset waktu (STO) yang sama tetap tercakup.
+func (h *PlotHandler) GetAoAToF(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := mux.Vars(r)["id"]
+	meta, err := h.loadMeta(ctx, id)
+	if err != nil {
+		respondLoadError(w, err)
+		return
+	}
+	params, err := parseMUSICParams(r, meta.Layout)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	key := analysisKey("aoa-tof", meta, nil, params.canonical())
+	if h.cachedAnalysis(ctx, w, key) {
+		return
+	}
+
+	csi, err := h.loadMatrix(ctx, meta)
+	if err != nil {
+		respondLoadError(w, err)
+		return
+	}
+	if csi.Phase == nil {
+		respondError(w, http.StatusUnprocessableEntity, "CSI layout has no phase data (encoding "+csi.Layout.Encoding+")")
+		return
+	}
+	if params.Offset >= csi.Packets {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("offset %d is beyond the last packet (%d packets)", params.Offset, csi.Packets))
+		return
+	}
+	end := min(params.Offset+params.Packets, csi.Packets)
+
+	// CSI kompleks per paket; paket dengan sel NaN dilewati
+	C, S := csi.Layout.Streams, csi.Layout.Subcarriers
+	var packets [][][]complex128
+	var used []int
+	for p := params.Offset; p < end; p++ {
+		hp := make([][]complex128, C)
+		valid := true
+		for c := 0; c < C && valid; c++ {
+			hp[c] = make([]complex128, S)
+			for s := 0; s < S; s++ {
+				a, ph := csi.Amplitude[c][s][p], csi.Phase[c][s][p]
+				if math.IsNaN(a) || math.IsNaN(ph) {
+					valid = false
+					break
+				}
+				hp[c][s] = complex(a, 0) * cmplx.Exp(complex(0, ph))
+			}
+		}
+		if valid {
+			packets = append(packets, hp)
+			used = append(used, p)
+		}
+	}
+	if len(packets) == 0 {
+		respondError(w, http.StatusUnprocessableEntity, "No complete packets in the selected window")
+		return
+	}
+
+	res, err := dsp.MUSICAoAToF(packets, ThetaScan, TauScan, params.Settings)
+	if err != nil {
+		respondError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	type peakResp struct {
+		ThetaDeg float64 `json:"theta_deg"`
+		TauNs    float64 `json:"tau_ns"`
+		PowerDB  float64 `json:"power_db"`
+	}
+	var peaks []peakResp
+	var direct *peakResp
+	for _, pk := range dsp.FindPeaks2D(res.Spectrum, params.Peaks) {
+		peak := peakResp{ThetaDeg: ThetaScan[pk.I], TauNs: TauScan[pk.J] * 1e9, PowerDB: pk.Value}
+		peaks = append(peaks, peak)
+		// Jalur langsung: puncak kuat dengan ToF terkecil
+		if pk.Value >= -directPathWindowDB && (direct == nil || peak.TauNs < direct.TauNs) {
+			p := peak
+			direct = &p
+		}
+	}
+
+	for _, row := range res.Spectrum {
+		for t := range row {
+			row[t] = math.Round(row[t]*spectrumDecimalsDB) / spectrumDecimalsDB
+		}
+	}
+	tauNs := make([]float64, len(TauScan))
+	for i, v := range TauScan {
+		tauNs[i] = v * 1e9
+	}
+	h.respondAnalysis(ctx, w, key, map[string]interface{}{
+		"meta": map[string]interface{}{
+			"method":             "joint AoA/ToF MUSIC with spatial smoothing (SpotFi)",
+			"packets":            csi.Packets,
+			"window":             []int{params.Offset, end},
+			"packets_used":       used,
+			"snapshots":          res.Snapshots,
+			"subarray":           res.SubarrayShape,
+			"sources":            res.Sources,
+			"eigenvalues":        res.Eigenvalues,
+			"frequency":          params.Settings.Frequency,
+			"subcarrier_spacing": params.Settings.SubcarrierSpacing,
+			"antenna_spacing":    params.Settings.AntennaSpacing,
+			"theta_reference":    "degrees from the array axis (cos model), 0-180",
+			"tof":                "relative to the window's median sampling offset",
+			"units":              "dB relative to the spectrum peak",
+			"shape":              []int{len(ThetaScan), len(TauScan)},
+			"layout":             csi.Layout,
+		},
+		"theta_deg":   ThetaScan,
+		"tau_ns":      tauNs,
+		"spectrum":    res.Spectrum,
+		"peaks":       peaks,
+		"direct_path": direct,
+	})
+}
@@ -0,0 +1,650 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+
+	"cetasense-v2.0/internal/dsp"
+	"cetasense-v2.0/internal/models"
+	"cetasense-v2.0/internal/repositories"
+	"cetasense-v2.0/internal/services"
+)
+
+// Nilai ?room_baseline= pada analisis (selain mode baseline)
+const (
+	baselineAuto = "auto" // mode yang tersimpan di baseline ruangan
+	baselineOff  = "off"
+)
+
+// appliedBaseline melaporkan baseline ruangan yang diterapkan pada data.
+// Filtered berarti profil amplitudonya dihitung dari data yang melewati
+// pipeline filter; Applied false (dengan Reason) berarti baseline ada tapi
+// dilewati karena tidak cocok dengan data analisis.
+type appliedBaseline struct {
+	ID        string `json:"id"`
+	RuanganID string `json:"ruangan_id"`
+	Version   int    `json:"version"`
+	Mode      string `json:"mode"`
+	Filtered  bool   `json:"filtered"`
+	Applied   bool   `json:"applied"`
+	Reason    string `json:"reason,omitempty"`
+
+	profile *models.BaselineProfile
+}
+
+// canonical adalah representasi untuk kunci cache
+func (b *appliedBaseline) canonical() string {
+	if b == nil {
+		return "bl=none"
+	}
+	if !b.Applied {
+		return fmt.Sprintf("bl=%s@%d:skipped", b.ID, b.Version)
+	}
+	return fmt.Sprintf("bl=%s@%d:%s", b.ID, b.Version, b.Mode)
+}
+
+// filteredLabel menamai jenis amplitudo untuk pesan mismatch
+func filteredLabel(filtered bool) string {
+	if filtered {
+		return "filtered"
+	}
+	return "raw"
+}
+
+// resolveBaseline menentukan baseline yang diterapkan pada amplitudo file
+// meta setelah filter (nil bila file tidak punya filter). Profil baseline
+// hanya sebanding dengan data yang sama-sama filtered atau raw: pada mode
+// auto baseline yang tidak cocok dilewati (Applied false beserta
+// alasannya), sedangkan subtract/normalize yang dipaksa gagal 422.
+func (h *PlotHandler) resolveBaseline(ctx context.Context, meta *models.CSI_File, mode string, filter *appliedFilter) (*appliedBaseline, error) {
+	b, err := h.resolvePhaseBaseline(ctx, meta, mode)
+	if b == nil || err != nil {
+		return b, err
+	}
+	return matchBaselineFilter(b, mode, filter)
+}
+
+// matchBaselineFilter mencocokkan baseline b dengan status filter data
+// analisis; lihat resolveBaseline
+func matchBaselineFilter(b *appliedBaseline, mode string, filter *appliedFilter) (*appliedBaseline, error) {
+	filtered := filter != nil && filter.Applied
+	if b.Filtered == filtered {
+		return b, nil
+	}
+	reason := fmt.Sprintf("Room baseline v%d was computed from %s amplitude, this analysis uses %s amplitude",
+		b.Version, filteredLabel(b.Filtered), filteredLabel(filtered))
+	if mode == models.BaselineModeSubtract || mode == models.BaselineModeNormalize {
+		return nil, &loadError{http.StatusUnprocessableEntity, reason}
+	}
+	b.Applied, b.Reason = false, reason
+	return b, nil
+}
+
+// resolvePhaseBaseline menentukan baseline ruangan file meta tanpa
+// memeriksa filter (offset fase tidak dipengaruhi filter amplitudo).
+// mode kosong/auto memakai mode baseline terbaru ruangan file (tidak ada
+// baseline atau mode none berarti tidak diterapkan); off mematikannya;
+// subtract/normalize memaksa mode tersebut dan gagal bila ruangan belum
+// punya baseline.
+func (h *PlotHandler) resolvePhaseBaseline(ctx context.Context, meta *models.CSI_File, mode string) (*appliedBaseline, error) {
+	switch mode {
+	case "", baselineAuto:
+		mode = ""
+	case baselineOff, models.BaselineModeNone:
+		return nil, nil
+	case models.BaselineModeSubtract, models.BaselineModeNormalize:
+	default:
+		return nil, &loadError{http.StatusBadRequest, "room_baseline must be auto, off, subtract or normalize"}
+	}
+	if h.baselineRepo == nil || meta.RuanganID == "" {
+		if mode != "" {
+			return nil, &loadError{http.StatusUnprocessableEntity, "Room has no baseline"}
+		}
+		return nil, nil
+	}
+	b, err := h.baselineRepo.Latest(ctx, meta.RuanganID)
+	if errors.Is(err, sql.ErrNoRows) {
+		if mode != "" {
+			return nil, &loadError{http.StatusUnprocessableEntity, "Room has no baseline"}
+		}
+		return nil, nil
+	}
+	if err != nil {
+		return nil, &loadError{http.StatusInternalServerError, "Failed to load room baseline: " + err.Error()}
+	}
+	if mode == "" {
+		if b.Mode == models.BaselineModeNone {
+			return nil, nil
+		}
+		mode = b.Mode
+	}
+	return &appliedBaseline{ID: b.ID, RuanganID: b.RuanganID, Version: b.Version, Mode: mode,
+		Filtered: baselineFiltered(b), Applied: true, profile: b.Profile}, nil
+}
+
+// baselineFiltered melaporkan apakah amplitudo baseline b benar-benar
+// melewati filter: baseline filtered yang tidak satu pun sumbernya punya
+// pipeline filter sama dengan raw
+func baselineFiltered(b *models.RoomBaseline) bool {
+	if !b.Filtered {
+		return false
+	}
+	for _, src := range b.Sources {
+		if src.FilterID != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// profileValue membaca satu sel profil; NaN bila null
+func profileValue(x [][]*float64, c, s int) float64 {
+	if x == nil || x[c][s] == nil {
+		return math.NaN()
+	}
+	return *x[c][s]
+}
+
+func checkBaselineShape(csi *services.CSIMatrix, b *appliedBaseline) error {
+	if b.profile.Streams != csi.Layout.Streams || b.profile.Subcarriers != csi.Layout.Subcarriers {
+		return &loadError{http.StatusUnprocessableEntity, fmt.Sprintf(
+			"Room baseline v%d was computed for %d streams × %d subcarriers, capture has %d × %d",
+			b.Version, b.profile.Streams, b.profile.Subcarriers, csi.Layout.Streams, csi.Layout.Subcarriers)}
+	}
+	return nil
+}
+
+// applyBaseline menerapkan baseline ruangan pada amplitudo. normalize
+// membagi dengan median baseline per subcarrier; subtract mengurangkan
+// median baseline lalu menambahkan median level stream tersebut, sehingga
+// profil statis per subcarrier hilang tapi amplitudo tetap positif dan
+// bersatuan sama untuk analisis yang memperlakukan nilai <= 0 sebagai
+// dropout. Dropout dan subcarrier tanpa baseline menjadi NaN.
+func applyBaseline(csi *services.CSIMatrix, b *appliedBaseline) error {
+	if b == nil || !b.Applied {
+		return nil
+	}
+	if err := checkBaselineShape(csi, b); err != nil {
+		return err
+	}
+	for c := range csi.Amplitude {
+		level := 0.0
+		if b.Mode == models.BaselineModeSubtract {
+			medians := make([]float64, len(csi.Amplitude[c]))
+			for s := range medians {
+				medians[s] = profileValue(b.profile.Median, c, s)
+			}
+			level = medianIgnoreNaN(medians)
+		}
+		for s, series := range csi.Amplitude[c] {
+			med := profileValue(b.profile.Median, c, s)
+			for p, v := range series {
+				if math.IsNaN(v) || v <= 0 || !(med > 0) {
+					series[p] = math.NaN()
+					continue
+				}
+				if b.Mode == models.BaselineModeNormalize {
+					v /= med
+				} else if v = v - med + level; v <= 0 {
+					v = math.NaN()
+				}
+				series[p] = v
+			}
+		}
+	}
+	return nil
+}
+
+// removeBaselinePhase mengurangkan offset fase baseline dari fase
+// tersanitasi (hasil di-wrap ke [-π, π]); tidak berubah bila baseline tidak
+// punya profil fase
+func removeBaselinePhase(phase [][][]float64, b *appliedBaseline) {
+	if b == nil || !b.Applied || b.profile.PhaseOffset == nil {
+		return
+	}
+	for c := range phase {
+		for s, series := range phase[c] {
+			off := profileValue(b.profile.PhaseOffset, c, s)
+			if math.IsNaN(off) {
+				continue
+			}
+			for p, v := range series {
+				series[p] = math.Remainder(v-off, 2*math.Pi)
+			}
+		}
+	}
+}
+
+// computeBaseline menghitung profil baseline dari gabungan paket file-file
+// ruangan roomID (masing-masing melewati pipeline filternya kecuali raw)
+func (h *PlotHandler) computeBaseline(ctx context.Context, roomID string, fileIDs []string, raw bool) (*models.RoomBaseline, error) {
+	b := &models.RoomBaseline{RuanganID: roomID, Filtered: !raw}
+	var amps [][][]float64 // sampel valid gabungan per [stream][subcarrier]
+	var cosSum, sinSum [][]float64
+	var phaseN [][]int
+	hasPhase := true
+	seen := map[string]bool{}
+	for _, id := range fileIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		meta, err := h.loadMeta(ctx, id)
+		if err != nil {
+			var le *loadError
+			if errors.As(err, &le) && le.code == http.StatusNotFound {
+				return nil, &loadError{http.StatusBadRequest, "Baseline file " + id + " not found"}
+			}
+			return nil, err
+		}
+		if meta.RuanganID != roomID {
+			return nil, &loadError{http.StatusBadRequest, "File " + meta.FileName + " belongs to another room"}
+		}
+		filter, err := h.fileFilter(ctx, meta, raw)
+		if err != nil {
+			return nil, err
+		}
+		csi, _, err := h.prepareMatrix(ctx, meta, filter, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		C, S := csi.Layout.Streams, csi.Layout.Subcarriers
+		if b.Layout == nil {
+			b.Layout = &csi.Layout
+			amps = make([][][]float64, C)
+			cosSum, sinSum, phaseN = make([][]float64, C), make([][]float64, C), make([][]int, C)
+			for c := range amps {
+				amps[c] = make([][]float64, S)
+				cosSum[c], sinSum[c], phaseN[c] = make([]float64, S), make([]float64, S), make([]int, S)
+			}
+		} else if err := diffLayoutError(*b.Layout, csi.Layout); err != nil {
+			return nil, &loadError{http.StatusUnprocessableEntity, "File " + meta.FileName + ": " + err.Error()}
+		}
+
+		for c := 0; c < C; c++ {
+			for s := 0; s < S; s++ {
+				for _, v := range csi.Amplitude[c][s] {
+					if !math.IsNaN(v) && v > 0 {
+						amps[c][s] = append(amps[c][s], v)
+					}
+				}
+			}
+		}
+		if csi.Phase == nil {
+			hasPhase = false
+		} else if hasPhase {
+			sanitized, _ := dsp.SanitizePhase(csi.Phase, csi.Layout.SubcarrierIndices())
+			for c := 0; c < C; c++ {
+				for s := 0; s < S; s++ {
+					for _, v := range sanitized[c][s] {
+						if !math.IsNaN(v) {
+							cosSum[c][s] += math.Cos(v)
+							sinSum[c][s] += math.Sin(v)
+							phaseN[c][s]++
+						}
+					}
+				}
+			}
+		}
+
+		src := models.BaselineSource{FileID: meta.ID, FileName: meta.FileName, ContentHash: meta.ContentHash, Packets: csi.Packets}
+		if filter != nil && filter.Applied {
+			src.FilterID, src.FilterVersion = filter.ID, filter.Version
+		}
+		b.Sources = append(b.Sources, src)
+		b.Packets += csi.Packets
+	}
+	if b.Packets == 0 {
+		return nil, &loadError{http.StatusUnprocessableEntity, "Baseline files hold no packets"}
+	}
+
+	C, S := b.Layout.Streams, b.Layout.Subcarriers
+	p := &models.BaselineProfile{
+		Streams:     C,
+		Subcarriers: S,
+		Median:      make([][]*float64, C),
+		Spread:      make([][]*float64, C),
+		ValidPct:    make([][]float64, C),
+	}
+	if hasPhase {
+		p.PhaseOffset, p.PhaseSpread = make([][]*float64, C), make([][]*float64, C)
+	}
+	for c := 0; c < C; c++ {
+		med, spread := make([]float64, S), make([]float64, S)
+		p.ValidPct[c] = make([]float64, S)
+		for s := 0; s < S; s++ {
+			x := amps[c][s]
+			sort.Float64s(x)
+			med[s] = percentileSorted(x, 50)
+			dev := make([]float64, len(x))
+			for i, v := range x {
+				dev[i] = math.Abs(v - med[s])
+			}
+			sort.Float64s(dev)
+			spread[s] = 1.4826 * percentileSorted(dev, 50)
+			p.ValidPct[c][s] = 100 * float64(len(x)) / float64(b.Packets)
+		}
+		p.Median[c], p.Spread[c] = nullable(med), nullable(spread)
+		if hasPhase {
+			offset, phaseSpread := make([]float64, S), make([]float64, S)
+			for s := 0; s < S; s++ {
+				offset[s], phaseSpread[s] = math.NaN(), math.NaN()
+				if n := float64(phaseN[c][s]); n > 0 {
+					offset[s] = math.Atan2(sinSum[c][s], cosSum[c][s])
+					R := math.Hypot(sinSum[c][s], cosSum[c][s]) / n
+					phaseSpread[s] = math.Sqrt(-2 * math.Log(math.Max(R, 1e-12)))
+				}
+			}
+			p.PhaseOffset[c], p.PhaseSpread[c] = nullable(offset), nullable(phaseSpread)
+		}
+	}
+	b.Profile = p
+	return b, nil
+}
+
+type BaselineHandler struct {
+	plots    *PlotHandler
+	repo     *repositories.BaselineRepository
+	rooms    *repositories.RuanganRepository
+	validate *validator.Validate
+}
+
+func NewBaselineHandler(plots *PlotHandler, repo *repositories.BaselineRepository, rooms *repositories.RuanganRepository) *BaselineHandler {
+	return &BaselineHandler{plots: plots, repo: repo, rooms: rooms, validate: validator.New()}
+}
+
+// decodeBaselineRequest membaca body opsional dan memvalidasinya
+func (h *BaselineHandler) decodeBaselineRequest(w http.ResponseWriter, r *http.Request) (*models.RoomBaselineRequest, bool) {
+	req := &models.RoomBaselineRequest{}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+			return nil, false
+		}
+	}
+	if err := h.validate.Struct(req); err != nil {
+		respondError(w, http.StatusBadRequest, "Validation error: "+err.Error())
+		return nil, false
+	}
+	return req, true
+}
+
+// createVersion menghitung dan menyimpan versi baseline baru. prev adalah
+// versi terakhir (nil bila belum ada) yang mode dan filtered-nya dipakai
+// bila request tidak mengisinya.
+func (h *BaselineHandler) createVersion(w http.ResponseWriter, r *http.Request, roomID string, req *models.RoomBaselineRequest, fileIDs []string, prev *models.RoomBaseline) {
+	ctx := r.Context()
+	mode, filtered := models.BaselineModeNone, true
+	if prev != nil {
+		mode, filtered = prev.Mode, prev.Filtered
+	}
+	if req.Mode != "" {
+		mode = req.Mode
+	}
+	if req.Filtered != nil {
+		filtered = *req.Filtered
+	}
+	b, err := h.plots.computeBaseline(ctx, roomID, fileIDs, !filtered)
+	if err != nil {
+		respondLoadError(w, err)
+		return
+	}
+	b.Mode = mode
+	b.GenerateID()
+	if err := h.repo.Create(ctx, b); err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to save room baseline: "+err.Error())
+		return
+	}
+	respondJSON(w, http.StatusCreated, b)
+}
+
+// roomExists menulis 404 bila ruangan tidak ada
+func (h *BaselineHandler) roomExists(w http.ResponseWriter, r *http.Request, roomID string) bool {
+	if _, err := h.rooms.GetByID(r.Context(), roomID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondError(w, http.StatusNotFound, "Room not found")
+		} else {
+			respondError(w, http.StatusInternalServerError, "Failed to load room: "+err.Error())
+		}
+		return false
+	}
+	return true
+}
+
+// version mengambil satu versi baseline ruangan (0 = terbaru); menulis
+// 404 bila tidak ada
+func (h *BaselineHandler) version(w http.ResponseWriter, r *http.Request, roomID string, version int) (*models.RoomBaseline, bool) {
+	var b *models.RoomBaseline
+	var err error
+	if version == 0 {
+		b, err = h.repo.Latest(r.Context(), roomID)
+	} else {
+		b, err = h.repo.GetVersion(r.Context(), roomID, version)
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		if version == 0 {
+			respondError(w, http.StatusNotFound, "Room has no baseline")
+		} else {
+			respondError(w, http.StatusNotFound, fmt.Sprintf("Room baseline version %d not found", version))
+		}
+		return nil, false
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to load room baseline: "+err.Error())
+		return nil, false
+	}
+	return b, true
+}
+
+// MarkBaseline menandai upload (file_ids) sebagai baseline statis ruangan
+// dan menyimpan profilnya sebagai versi baru
+func (h *BaselineHandler) MarkBaseline(w http.ResponseWriter, r *http.Request) {
+	roomID := mux.Vars(r)["id"]
+	req, ok := h.decodeBaselineRequest(w, r)
+	if !ok {
+		return
+	}
+	if len(req.FileIDs) == 0 {
+		respondError(w, http.StatusBadRequest, "file_ids is required")
+		return
+	}
+	if !h.roomExists(w, r, roomID) {
+		return
+	}
+	prev, err := h.repo.Latest(r.Context(), roomID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		respondError(w, http.StatusInternalServerError, "Failed to load room baseline: "+err.Error())
+		return
+	}
+	h.createVersion(w, r, roomID, req, req.FileIDs, prev)
+}
+
+// RecomputeBaseline menghitung ulang baseline dari file versi terakhir
+// (misalnya setelah pipeline filter berubah) sebagai versi baru; body
+// opsional bisa mengganti mode atau filtered
+func (h *BaselineHandler) RecomputeBaseline(w http.ResponseWriter, r *http.Request) {
+	roomID := mux.Vars(r)["id"]
+	req, ok := h.decodeBaselineRequest(w, r)
+	if !ok {
+		return
+	}
+	prev, ok := h.version(w, r, roomID, 0)
+	if !ok {
+		return
+	}
+	fileIDs := req.FileIDs
+	if len(fileIDs) == 0 {
+		for _, src := range prev.Sources {
+			fileIDs = append(fileIDs, src.FileID)
+		}
+	}
+	h.createVersion(w, r, roomID, req, fileIDs, prev)
+}
+
+// GetBaseline mengembalikan baseline terbaru ruangan (atau ?version=)
+// beserta profilnya
+func (h *BaselineHandler) GetBaseline(w http.ResponseWriter, r *http.Request) {
+	version, err := queryInt(r, "version", 0)
+	if err != nil || version < 0 {
+		respondError(w, http.StatusBadRequest, "version must be a positive integer")
+		return
+	}
+	if b, ok := h.version(w, r, mux.Vars(r)["id"], version); ok {
+		respondJSON(w, http.StatusOK, b)
+	}
+}
+
+// GetBaselineVersions mendaftar semua versi baseline ruangan tanpa profil
+func (h *BaselineHandler) GetBaselineVersions(w http.ResponseWriter, r *http.Request) {
+	versions, err := h.repo.ListVersions(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to list room baselines: "+err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, versions)
+}
+
+// baselineChange membandingkan satu subcarrier antara dua versi baseline;
+// selisih = to - from
+type baselineChange struct {
+	Antenna         int      `json:"antenna"`
+	Subcarrier      int      `json:"subcarrier"`
+	FromMedian      *float64 `json:"from_median"`
+	ToMedian        *float64 `json:"to_median"`
+	MedianRatioDB   *float64 `json:"median_ratio_db"`
+	SpreadRatioDB   *float64 `json:"spread_ratio_db"`
+	PhaseOffsetDiff *float64 `json:"phase_offset_diff"` // radian, [-π, π]
+}
+
+// meanMaxAbs merangkum |x| yang valid
+func meanMaxAbs(x []float64) map[string]*float64 {
+	sum, peak, n := 0.0, math.NaN(), 0
+	for _, v := range x {
+		if math.IsNaN(v) {
+			continue
+		}
+		v = math.Abs(v)
+		sum += v
+		n++
+		if math.IsNaN(peak) || v > peak {
+			peak = v
+		}
+	}
+	mean := math.NaN()
+	if n > 0 {
+		mean = sum / float64(n)
+	}
+	return map[string]*float64{"mean_abs": finiteOrNil(mean), "max_abs": finiteOrNil(peak)}
+}
+
+// CompareBaselines membandingkan dua versi baseline ruangan per subcarrier
+// (?from=, ?to=; default versi terbaru dengan versi sebelumnya): rasio
+// median dan spread dalam dB, selisih offset fase, serta perubahan file
+// sumber
+func (h *BaselineHandler) CompareBaselines(w http.ResponseWriter, r *http.Request) {
+	roomID := mux.Vars(r)["id"]
+	toVersion, err := queryInt(r, "to", 0)
+	if err != nil || toVersion < 0 {
+		respondError(w, http.StatusBadRequest, "to must be a positive integer")
+		return
+	}
+	to, ok := h.version(w, r, roomID, toVersion)
+	if !ok {
+		return
+	}
+	fromVersion, err := queryInt(r, "from", to.Version-1)
+	if err != nil || fromVersion < 1 {
+		respondError(w, http.StatusBadRequest, "from must be a positive integer (the room needs at least two baseline versions)")
+		return
+	}
+	from, ok := h.version(w, r, roomID, fromVersion)
+	if !ok {
+		return
+	}
+	fp, tp := from.Profile, to.Profile
+	if fp.Streams != tp.Streams || fp.Subcarriers != tp.Subcarriers {
+		respondError(w, http.StatusUnprocessableEntity, fmt.Sprintf("Baseline v%d has %d streams × %d subcarriers, v%d has %d × %d",
+			from.Version, fp.Streams, fp.Subcarriers, to.Version, tp.Streams, tp.Subcarriers))
+		return
+	}
+
+	var changes []baselineChange
+	var medianDB, spreadDB, phaseDiff []float64
+	hasPhase := fp.PhaseOffset != nil && tp.PhaseOffset != nil
+	for c := 0; c < tp.Streams; c++ {
+		for s := 0; s < tp.Subcarriers; s++ {
+			fm, tm := profileValue(fp.Median, c, s), profileValue(tp.Median, c, s)
+			mdb := ratioDB(tm, fm, false)
+			sdb := ratioDB(profileValue(tp.Spread, c, s), profileValue(fp.Spread, c, s), false)
+			pd := math.NaN()
+			if hasPhase {
+				pd = math.Remainder(profileValue(tp.PhaseOffset, c, s)-profileValue(fp.PhaseOffset, c, s), 2*math.Pi)
+			}
+			medianDB, spreadDB, phaseDiff = append(medianDB, mdb), append(spreadDB, sdb), append(phaseDiff, pd)
+			changes = append(changes, baselineChange{
+				Antenna:         c + 1,
+				Subcarrier:      s + 1,
+				FromMedian:      finiteOrNil(fm),
+				ToMedian:        finiteOrNil(tm),
+				MedianRatioDB:   finiteOrNil(mdb),
+				SpreadRatioDB:   finiteOrNil(sdb),
+				PhaseOffsetDiff: finiteOrNil(pd),
+			})
+		}
+	}
+
+	// Perubahan file sumber: ditambah, dihapus, atau isi/filter berbeda
+	fromSources := map[string]models.BaselineSource{}
+	for _, src := range from.Sources {
+		fromSources[src.FileID] = src
+	}
+	added, changed := []string{}, []string{}
+	for _, src := range to.Sources {
+		old, ok := fromSources[src.FileID]
+		switch {
+		case !ok:
+			added = append(added, src.FileID)
+		case old.ContentHash != src.ContentHash || old.FilterID != src.FilterID || old.FilterVersion != src.FilterVersion:
+			changed = append(changed, src.FileID)
+		}
+		delete(fromSources, src.FileID)
+	}
+	removed := []string{}
+	for id := range fromSources {
+		removed = append(removed, id)
+	}
+	sort.Strings(removed)
+
+	summary := map[string]interface{}{
+		"median_ratio_db": meanMaxAbs(medianDB),
+		"spread_ratio_db": meanMaxAbs(spreadDB),
+	}
+	if hasPhase {
+		summary["phase_offset_diff"] = meanMaxAbs(phaseDiff)
+	}
+	strip := func(b *models.RoomBaseline) models.RoomBaseline {
+		out := *b
+		out.Profile = nil
+		return out
+	}
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"from":    strip(from),
+		"to":      strip(to),
+		"summary": summary,
+		"sources": map[string]interface{}{
+			"added":   added,
+			"removed": removed,
+			"changed": changed,
+		},
+		"subcarriers": changes,
+	})
+}
@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"testing"
+
+	"cetasense-v2.0/internal/models"
+	"cetasense-v2.0/internal/services"
+)
+
+func ptr(v float64) *float64 { return &v }
+
+// testBaseline: 1 stream × 3 subcarrier dengan median 2, 4, null
+func testBaseline(mode string) *appliedBaseline {
+	return &appliedBaseline{
+		ID:      "b1",
+		Version: 3,
+		Mode:    mode,
+		Applied: true,
+		profile: &models.BaselineProfile{
+			Streams:     1,
+			Subcarriers: 3,
+			Median:      [][]*float64{{ptr(2), ptr(4), nil}},
+			PhaseOffset: [][]*float64{{ptr(3), nil, ptr(0)}},
+		},
+	}
+}
+
+func testMatrix(amp ...[]float64) *services.CSIMatrix {
+	return &services.CSIMatrix{
+		Layout:    models.CSILayout{Streams: 1, Subcarriers: len(amp)},
+		Packets:   len(amp[0]),
+		Amplitude: [][][]float64{amp},
+	}
+}
+
+func TestApplyBaselineNormalize(t *testing.T) {
+	csi := testMatrix([]float64{2, 3, 0}, []float64{8, math.NaN(), 2}, []float64{5, 5, 5})
+	if err := applyBaseline(csi, testBaseline(models.BaselineModeNormalize)); err != nil {
+		t.Fatalf("applyBaseline: %v", err)
+	}
+	got := csi.Amplitude[0]
+	want := [][]float64{{1, 1.5, math.NaN()}, {2, math.NaN(), 0.5}, {math.NaN(), math.NaN(), math.NaN()}}
+	for s := range want {
+		for p := range want[s] {
+			if math.IsNaN(want[s][p]) != math.IsNaN(got[s][p]) || (!math.IsNaN(want[s][p]) && got[s][p] != want[s][p]) {
+				t.Fatalf("amplitude = %v, want %v", got, want)
+			}
+		}
+	}
+}
+
+func TestApplyBaselineSubtract(t *testing.T) {
+	// level stream = median(2, 4) = 3; nilai yang jatuh <= 0 menjadi dropout
+	csi := testMatrix([]float64{2, 5, 0.5}, []float64{4, 1, 0.5}, []float64{1, 1, 1})
+	if err := applyBaseline(csi, testBaseline(models.BaselineModeSubtract)); err != nil {
+		t.Fatalf("applyBaseline: %v", err)
+	}
+	sc0, sc1 := csi.Amplitude[0][0], csi.Amplitude[0][1]
+	if sc0[0] != 3 || sc0[1] != 6 || sc0[2] != 1.5 {
+		t.Errorf("subcarrier 0 = %v, want [3 6 1.5]", sc0)
+	}
+	if sc1[0] != 3 || !math.IsNaN(sc1[1]) || !math.IsNaN(sc1[2]) {
+		t.Errorf("subcarrier 1 = %v, want [3 NaN NaN]", sc1)
+	}
+	if applyBaseline(csi, nil) != nil {
+		t.Error("nil baseline should be a no-op")
+	}
+}
+
+func TestApplyBaselineShapeMismatch(t *testing.T) {
+	csi := testMatrix([]float64{1}, []float64{1})
+	err := applyBaseline(csi, testBaseline(models.BaselineModeNormalize))
+	var le *loadError
+	if !errors.As(err, &le) || le.code != http.StatusUnprocessableEntity {
+		t.Fatalf("got %v, want a 422 loadError", err)
+	}
+	if csi.Amplitude[0][0][0] != 1 {
+		t.Error("data changed despite the shape mismatch")
+	}
+}
+
+func TestRemoveBaselinePhase(t *testing.T) {
+	phase := [][][]float64{{{-3, 0}, {1, 2}, {0.5, -0.5}}}
+	removeBaselinePhase(phase, testBaseline(models.BaselineModeSubtract))
+	// -3 - 3 = -6 → -6 + 2π
+	if math.Abs(phase[0][0][0]-(2*math.Pi-6)) > 1e-12 || phase[0][0][1] != -3 {
+		t.Errorf("subcarrier 0 = %v", phase[0][0])
+	}
+	if phase[0][1][0] != 1 || phase[0][2][1] != -0.5 {
+		t.Errorf("subcarriers without offset changed: %v", phase[0])
+	}
+}
+
+func TestResolveBaselineWithoutRepository(t *testing.T) {
+	h := &PlotHandler{}
+	meta := &models.CSI_File{RuanganID: "r1"}
+	for _, mode := range []string{"", baselineAuto, baselineOff, models.BaselineModeNone} {
+		if b, err := h.resolveBaseline(context.Background(), meta, mode, nil); b != nil || err != nil {
+			t.Errorf("mode %q: got %v, %v", mode, b, err)
+		}
+	}
+	var le *loadError
+	if _, err := h.resolveBaseline(context.Background(), meta, models.BaselineModeSubtract, nil); !errors.As(err, &le) || le.code != http.StatusUnprocessableEntity {
+		t.Errorf("forced subtract without baseline: %v", err)
+	}
+	if _, err := h.resolveBaseline(context.Background(), meta, "median", nil); !errors.As(err, &le) || le.code != http.StatusBadRequest {
+		t.Errorf("unknown mode: %v", err)
+	}
+}
+
+func TestMatchBaselineFilter(t *testing.T) {
+	on := &appliedFilter{ID: "f1", Applied: true}
+	off := &appliedFilter{ID: "f1", Applied: false}
+	cases := []struct {
+		name     string
+		filtered bool
+		mode     string
+		filter   *appliedFilter
+		applied  bool
+		status   int // 0 bila tidak error
+	}{
+		{"filtered baseline, filtered data", true, "", on, true, 0},
+		{"raw baseline, raw data", false, "", off, true, 0},
+		{"raw baseline, file without filter", false, baselineAuto, nil, true, 0},
+		{"filtered baseline, raw data", true, "", off, false, 0},
+		{"raw baseline, filtered data", false, baselineAuto, on, false, 0},
+		{"forced subtract on mismatch", true, models.BaselineModeSubtract, off, false, http.StatusUnprocessableEntity},
+		{"forced normalize on mismatch", false, models.BaselineModeNormalize, on, false, http.StatusUnprocessableEntity},
+	}
+	for _, tc := range cases {
+		b := testBaseline(models.BaselineModeSubtract)
+		b.Filtered = tc.filtered
+		got, err := matchBaselineFilter(b, tc.mode, tc.filter)
+		if tc.status != 0 {
+			var le *loadError
+			if !errors.As(err, &le) || le.code != tc.status {
+				t.Errorf("%s: got %v, want a %d loadError", tc.name, err, tc.status)
+			}
+			continue
+		}
+		if err != nil || got == nil {
+			t.Fatalf("%s: got %v, %v", tc.name, got, err)
+		}
+		if got.Applied != tc.applied || (got.Reason != "") == tc.applied {
+			t.Errorf("%s: applied=%v reason=%q, want applied=%v", tc.name, got.Applied, got.Reason, tc.applied)
+		}
+	}
+}
+
+func TestSkippedBaselineLeavesData(t *testing.T) {
+	b := testBaseline(models.BaselineModeNormalize)
+	b.Applied, b.Reason = false, "mismatch"
+	// bentuk profil 1 × 3 tidak cocok, tapi baseline yang dilewati tidak diperiksa
+	csi := testMatrix([]float64{2, 4}, []float64{6, 8})
+	if err := applyBaseline(csi, b); err != nil {
+		t.Fatalf("applyBaseline: %v", err)
+	}
+	if csi.Amplitude[0][0][0] != 2 || csi.Amplitude[0][1][1] != 8 {
+		t.Errorf("skipped baseline changed amplitude: %v", csi.Amplitude)
+	}
+	if got := b.canonical(); got != "bl=b1@3:skipped" {
+		t.Errorf("canonical = %q", got)
+	}
+}
+
+func TestBaselineFiltered(t *testing.T) {
+	withFilter := []models.BaselineSource{{FileID: "a"}, {FileID: "b", FilterID: "f1"}}
+	noFilter := []models.BaselineSource{{FileID: "a"}}
+	for _, tc := range []struct {
+		filtered bool
+		sources  []models.BaselineSource
+		want     bool
+	}{
+		{true, withFilter, true},
+		{true, noFilter, false},
+		{false, withFilter, false},
+	} {
+		if got := baselineFiltered(&models.RoomBaseline{Filtered: tc.filtered, Sources: tc.sources}); got != tc.want {
+			t.Errorf("Filtered=%v sources=%v: got %v, want %v", tc.filtered, tc.sources, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,318 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"cetasense-v2.0/internal/dsp"
+)
+
+// Penyelarasan delay antar paket sebelum PDP dirata-rata
+const (
+	cirAlignToF  = "tof"  // kemiringan fase (STO) tiap paket dibuang, delay relatif pusat energi
+	cirAlignNone = "none" // IFFT apa adanya, delay 0..1/Δf
+)
+
+// batas parameter CIR/PDP
+const (
+	defaultCIRPackets = 100
+	maxCIRPackets     = 1000
+	defaultCIRPadding = 4
+	maxCIRNFFT        = 4096
+	defaultCIRFloorDB = 20.0
+)
+
+// cirParams adalah parameter /api/plots/{id}/cir yang sudah divalidasi
+type cirParams struct {
+	Offset      int
+	Packets     int
+	Streams     []int // 0-based
+	Padding     int
+	WindowFn    string
+	Align       string
+	ThresholdDB float64
+	Spacing     float64 // Hz
+	Bandwidth   float64 // Hz
+	Source      string  // asal spacing/bandwidth: layout atau query
+}
+
+// canonical adalah representasi parameter untuk kunci cache
+func (p *cirParams) canonical() string {
+	return fmt.Sprintf("off=%d;n=%d;st=%v;pad=%d;fn=%s;align=%s;thr=%g;df=%g;bw=%g",
+		p.Offset, p.Packets, p.Streams, p.Padding, p.WindowFn, p.Align, p.ThresholdDB, p.Spacing, p.Bandwidth)
+}
+
+// parseCIRParams memvalidasi query; spacing dan bandwidth diambil dari
+// layout bila tidak di-override lewat query
+func parseCIRParams(r *http.Request, sel *subcarrierSelection) (*cirParams, error) {
+	q := r.URL.Query()
+	p := &cirParams{WindowFn: q.Get("window_fn"), Align: q.Get("align"), Source: "layout"}
+	var err error
+	if p.Offset, err = queryInt(r, "offset", 0); err != nil || p.Offset < 0 {
+		return nil, fmt.Errorf("offset must be a non-negative integer")
+	}
+	if p.Packets, err = queryInt(r, "packets", defaultCIRPackets); err != nil || p.Packets < 1 || p.Packets > maxCIRPackets {
+		return nil, fmt.Errorf("packets must be an integer between 1 and %d", maxCIRPackets)
+	}
+	p.Streams = sel.Streams
+	if p.Padding, err = queryInt(r, "padding", defaultCIRPadding); err != nil || p.Padding < 1 || p.Padding > 64 {
+		return nil, fmt.Errorf("padding must be an integer between 1 and 64")
+	}
+	if p.WindowFn == "" {
+		p.WindowFn = dsp.WindowHann
+	}
+	if _, err := dsp.WindowFunc(p.WindowFn, 1); err != nil {
+		return nil, err
+	}
+	if p.Align == "" {
+		p.Align = cirAlignToF
+	}
+	if p.Align != cirAlignToF && p.Align != cirAlignNone {
+		return nil, fmt.Errorf("align must be tof or none")
+	}
+	if p.ThresholdDB, err = queryFloat(r, "threshold_db", defaultCIRFloorDB); err != nil || p.ThresholdDB <= 0 || p.ThresholdDB > 100 {
+		return nil, fmt.Errorf("threshold_db must be a number in (0, 100]")
+	}
+
+	p.Spacing, p.Bandwidth = sel.Layout.Spacing(), sel.Layout.BandwidthHz
+	if q.Has("subcarrier_spacing") || q.Has("bandwidth") {
+		p.Source = "query"
+	}
+	if p.Spacing, err = queryFloat(r, "subcarrier_spacing", p.Spacing); err != nil || p.Spacing < 0 {
+		return nil, fmt.Errorf("subcarrier_spacing must be a positive number (Hz)")
+	}
+	if p.Bandwidth, err = queryFloat(r, "bandwidth", p.Bandwidth); err != nil || p.Bandwidth < 0 {
+		return nil, fmt.Errorf("bandwidth must be a positive number (Hz)")
+	}
+	if p.Spacing == 0 && p.Bandwidth > 0 {
+		p.Spacing = p.Bandwidth / float64(sel.Layout.Subcarriers)
+	}
+	return p, nil
+}
+
+// GetCIR menghitung respons impuls kanal (IFFT CFR per paket), power delay
+// profile rata-rata pada jendela paket [offset, offset+packets), dan RMS
+// delay spread per antena. Jarak subcarrier dan lebar kanal diambil dari
+// layout capture (bandwidth_hz / subcarrier_spacing_hz) dan boleh
+// di-override lewat query; subcarrier dianggap berjarak sama.
+func (h *PlotHandler) GetCIR(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := mux.Vars(r)["id"]
+	meta, err := h.loadMeta(ctx, id)
+	if err != nil {
+		respondLoadError(w, err)
+		return
+	}
+	sel, err := h.resolveSelection(ctx, meta, r.URL.Query())
+	if err != nil {
+		respondLoadError(w, err)
+		return
+	}
+	params, err := parseCIRParams(r, sel)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if params.Spacing == 0 {
+		respondError(w, http.StatusUnprocessableEntity,
+			"Subcarrier spacing is unknown: declare subcarrier_spacing_hz or bandwidth_hz in the layout, or pass subcarrier_spacing")
+		return
+	}
+	key := analysisKey("cir", meta, nil, params.canonical())
+	if h.cachedAnalysis(ctx, w, key) {
+		return
+	}
+
+	csi, err := h.loadMatrix(ctx, meta)
+	if err != nil {
+		respondLoadError(w, err)
+		return
+	}
+	if csi.Phase == nil {
+		respondError(w, http.StatusUnprocessableEntity, "CSI layout has no phase data (encoding "+csi.Layout.Encoding+")")
+		return
+	}
+	if params.Offset >= csi.Packets {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("offset %d is beyond the last packet (%d packets)", params.Offset, csi.Packets))
+		return
+	}
+	end := min(params.Offset+params.Packets, csi.Packets)
+
+	S := csi.Layout.Subcarriers
+	nfft := min(dsp.NextPow2(S*params.Padding), maxCIRNFFT)
+	window, _ := dsp.WindowFunc(params.WindowFn, S)
+	if params.Bandwidth == 0 {
+		params.Bandwidth = float64(S) * params.Spacing
+	}
+	step := 1 / (float64(nfft) * params.Spacing)
+	delays := make([]float64, nfft)
+	for i := range delays {
+		n := i
+		if params.Align == cirAlignToF {
+			n = i - nfft/2
+		}
+		delays[i] = float64(n) * step
+	}
+
+	// CFR kompleks per paket; paket dengan sel NaN pada antena tertentu
+	// dilewati untuk antena itu
+	pdp := make([][]float64, len(params.Streams))
+	counts := make([]int, len(params.Streams))
+	for i := range pdp {
+		pdp[i] = make([]float64, nfft)
+	}
+	for p := params.Offset; p < end; p++ {
+		hp := make([][]complex128, len(params.Streams))
+		for i, c := range params.Streams {
+			row := make([]complex128, S)
+			for s := 0; s < S; s++ {
+				a, ph := csi.Amplitude[c][s][p], csi.Phase[c][s][p]
+				if math.IsNaN(a) || math.IsNaN(ph) {
+					row = nil
+					break
+				}
+				row[s] = complex(a, 0) * cmplx.Exp(complex(0, ph))
+			}
+			hp[i] = row
+		}
+		if params.Align == cirAlignToF {
+			// Satu kemiringan untuk semua antena paket ini, seperti MUSIC
+			var valid [][]complex128
+			for _, row := range hp {
+				if row != nil {
+					valid = append(valid, row)
+				}
+			}
+			if len(valid) == 0 {
+				continue
+			}
+			slope := dsp.ToFSlope(valid)
+			for i, row := range hp {
+				if row != nil {
+					hp[i] = dsp.AlignToF([][]complex128{row}, slope)[0]
+				}
+			}
+		}
+		for i, row := range hp {
+			if row == nil {
+				continue
+			}
+			cir, err := dsp.CIR(row, nfft, window)
+			if err != nil {
+				respondError(w, http.StatusUnprocessableEntity, err.Error())
+				return
+			}
+			if params.Align == cirAlignToF {
+				cir = dsp.CenterDelay(cir)
+			}
+			for n, v := range cir {
+				pdp[i][n] += real(v)*real(v) + imag(v)*imag(v)
+			}
+			counts[i]++
+		}
+	}
+
+	type antennaResp struct {
+		Antenna         int        `json:"antenna"`
+		Packets         int        `json:"packets"`
+		PDP             []*float64 `json:"pdp_db"`
+		RMSDelaySpread  *float64   `json:"rms_delay_spread_ns"`
+		MeanExcessDelay *float64   `json:"mean_excess_delay_ns"`
+		MaxExcessDelay  *float64   `json:"max_excess_delay_ns"`
+	}
+	// PDP dalam dB relatif terhadap tap terkuat antena itu
+	toDB := func(x []float64) []float64 {
+		peak := 0.0
+		for _, v := range x {
+			peak = math.Max(peak, v)
+		}
+		out := make([]float64, len(x))
+		for n, v := range x {
+			out[n] = math.NaN()
+			if peak > 0 && v > 0 {
+				out[n] = math.Round(10*math.Log10(v/peak)*spectrumDecimalsDB) / spectrumDecimalsDB
+			}
+		}
+		return out
+	}
+	ns := func(v float64) *float64 {
+		v = math.Round(v*1e9*spectrumDecimalsDB) / spectrumDecimalsDB
+		return &v
+	}
+	antennas := make([]antennaResp, len(params.Streams))
+	avg := make([]float64, nfft)
+	used := 0
+	for i, c := range params.Streams {
+		a := antennaResp{Antenna: c + 1, Packets: counts[i]}
+		if counts[i] > 0 {
+			for n := range pdp[i] {
+				pdp[i][n] /= float64(counts[i])
+				avg[n] += pdp[i][n]
+			}
+			used++
+			if st, ok := dsp.DelaySpread(pdp[i], delays, params.ThresholdDB); ok {
+				a.RMSDelaySpread = ns(st.RMSDelaySpread)
+				a.MeanExcessDelay = ns(st.MeanExcessDelay)
+				a.MaxExcessDelay = ns(st.MaxExcessDelay)
+			}
+		}
+		a.PDP = nullable(toDB(pdp[i]))
+		antennas[i] = a
+	}
+	if used == 0 {
+		respondError(w, http.StatusUnprocessableEntity, "No complete packets in the selected window")
+		return
+	}
+
+	// Rata-rata antena
+	var average map[string]interface{}
+	for n := range avg {
+		avg[n] /= float64(used)
+	}
+	if st, ok := dsp.DelaySpread(avg, delays, params.ThresholdDB); ok {
+		average = map[string]interface{}{
+			"pdp_db":               nullable(toDB(avg)),
+			"rms_delay_spread_ns":  ns(st.RMSDelaySpread),
+			"mean_excess_delay_ns": ns(st.MeanExcessDelay),
+			"max_excess_delay_ns":  ns(st.MaxExcessDelay),
+		}
+	}
+
+	delayNs := make([]float64, nfft)
+	for i, v := range delays {
+		delayNs[i] = *ns(v)
+	}
+	delayRef := "seconds after the first FFT tap (includes the sampling time offset)"
+	if params.Align == cirAlignToF {
+		delayRef = "relative to each packet's phase-slope centre; sampling time offset removed"
+	}
+	h.respondAnalysis(ctx, w, key, map[string]interface{}{
+		"meta": map[string]interface{}{
+			"method":                "IFFT of the CFR per packet, PDP averaged over the window",
+			"packets":               csi.Packets,
+			"window":                []int{params.Offset, end},
+			"streams":               oneBased(params.Streams),
+			"nfft":                  nfft,
+			"padding":               params.Padding,
+			"window_fn":             params.WindowFn,
+			"align":                 params.Align,
+			"threshold_db":          params.ThresholdDB,
+			"bandwidth_hz":          params.Bandwidth,
+			"subcarrier_spacing_hz": params.Spacing,
+			"spacing_source":        params.Source,
+			"delay_step_ns":         *ns(step),
+			"delay_resolution_ns":   *ns(1 / (float64(S) * params.Spacing)),
+			"max_delay_ns":          *ns(1 / params.Spacing),
+			"delay_reference":       delayRef,
+			"units":                 "dB relative to the strongest tap",
+			"layout":                csi.Layout,
+		},
+		"delay_ns": delayNs,
+		"antennas": antennas,
+		"average":  average,
+	})
+}
@@ -0,0 +1,332 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+
+	"github.com/gorilla/mux"
+
+	"cetasense-v2.0/internal/models"
+)
+
+// default ambang redundansi korelasi; sama dengan max_abs_corr ranking BNR
+var defaultRedundancyThreshold = models.DefaultBNRParams().MaxAbsCorrThreshold
+
+// batas jumlah deret (stream × subcarrier) matriks korelasi; di atasnya
+// ?streams= / ?subcarriers= harus mempersempit pilihan
+const maxCorrelationVariables = 512
+
+// correlationParams adalah parameter /api/plots/{id}/correlation yang sudah
+// divalidasi
+type correlationParams struct {
+	Streams     []int // 0-based
+	Subcarriers []int // 0-based
+	Offset      int
+	Packets     int // 0 = sampai akhir capture
+	Threshold   float64
+	Raw         bool
+	Resample    *resampleParams
+}
+
+func (p *correlationParams) canonical() string {
+	return fmt.Sprintf("st=%v;sc=%v;off=%d;n=%d;thr=%g;raw=%t;%s",
+		p.Streams, p.Subcarriers, p.Offset, p.Packets, p.Threshold, p.Raw, p.Resample.canonical())
+}
+
+func parseCorrelationParams(r *http.Request, sel *subcarrierSelection) (*correlationParams, error) {
+	q := r.URL.Query()
+	p := &correlationParams{Raw: q.Get("filtered") == "false"}
+	var err error
+	p.Streams, p.Subcarriers = sel.Streams, sel.Subcarriers
+	if n := len(p.Streams) * len(p.Subcarriers); n > maxCorrelationVariables {
+		return nil, fmt.Errorf("selection has %d series (max %d); narrow it with streams= and/or subcarriers=", n, maxCorrelationVariables)
+	}
+	if p.Offset, err = queryInt(r, "offset", 0); err != nil || p.Offset < 0 {
+		return nil, fmt.Errorf("offset must be a non-negative integer")
+	}
+	if p.Packets, err = queryInt(r, "packets", 0); err != nil || p.Packets < 0 {
+		return nil, fmt.Errorf("packets must be a non-negative integer (0 = until the end)")
+	}
+	if p.Threshold, err = queryFloat(r, "redundancy_threshold", defaultRedundancyThreshold); err != nil {
+		return nil, err
+	}
+	if p.Threshold <= 0 || p.Threshold > 1 {
+		return nil, fmt.Errorf("redundancy_threshold must be in (0, 1]")
+	}
+	if p.Resample, err = parseResample(r); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// pairSummary merangkum korelasi antara dua antena (atau di dalam satu
+// antena bila AntennaA == AntennaB). Nilai berupa |ρ| dan null bila tidak
+// ada pasangan valid.
+type pairSummary struct {
+	AntennaA int `json:"antenna_a"`
+	AntennaB int `json:"antenna_b"`
+	// Antena berbeda: pasangan subcarrier yang sama. Antena sama: pasangan
+	// subcarrier bertetangga dalam daftar terpilih.
+	MatchedMean   *float64 `json:"matched_mean"`
+	MatchedMedian *float64 `json:"matched_median"`
+	MatchedMin    *float64 `json:"matched_min"`
+	// Semua pasangan blok (tanpa diagonal untuk antena sama)
+	BlockMean      *float64 `json:"block_mean"`
+	RedundantPairs int      `json:"redundant_pairs"` // |ρ| >= ambang
+	Pairs          int      `json:"pairs"`
+}
+
+// centeredSeries mengisi sampel masked dengan median deret lalu
+// mengurangkan rata-ratanya; norm adalah panjang vektor hasilnya. Korelasi
+// Pearson dua deret menjadi dot(a, b) / (norm_a · norm_b), sama dengan
+// pearsonCorrIgnoreNaN tanpa mengisi ulang deret untuk setiap pasangan.
+func centeredSeries(x []float64) (z []float64, norm float64) {
+	z = fillNaNWithMedian(x)
+	mean := 0.0
+	for _, v := range z {
+		mean += v
+	}
+	mean /= float64(len(z))
+	for i := range z {
+		z[i] -= mean
+		norm += z[i] * z[i]
+	}
+	return z, math.Sqrt(norm)
+}
+
+// summarizeAbs mengisi statistik dari daftar |ρ|
+func summarizeAbs(matched, block []float64, threshold float64, s *pairSummary) {
+	if len(matched) > 0 {
+		sort.Float64s(matched)
+		mean := 0.0
+		for _, v := range matched {
+			mean += v
+		}
+		mean /= float64(len(matched))
+		med := percentileSorted(matched, 50)
+		s.MatchedMean, s.MatchedMedian, s.MatchedMin = &mean, &med, &matched[0]
+	}
+	if len(block) > 0 {
+		mean := 0.0
+		for _, v := range block {
+			mean += v
+			if v >= threshold {
+				s.RedundantPairs++
+			}
+		}
+		mean /= float64(len(block))
+		s.BlockMean = &mean
+	}
+	s.Pairs = len(block)
+}
+
+// correlationMatrix menghitung korelasi Pearson semua pasangan deret.
+// Baris/kolom deret yang tidak punya sampel valid bernilai NaN.
+func correlationMatrix(series [][]float64, valid []bool) [][]float64 {
+	// Setiap deret diisi dan dipusatkan sekali; korelasi tiap pasangan
+	// tinggal satu dot product
+	n := len(series)
+	centered := make([][]float64, n)
+	norms := make([]float64, n)
+	for i := range series {
+		if valid[i] {
+			centered[i], norms[i] = centeredSeries(series[i])
+		}
+	}
+	corr := make([][]float64, n)
+	for i := range corr {
+		corr[i] = make([]float64, n)
+	}
+	for i := 0; i < n; i++ {
+		corr[i][i] = math.NaN()
+		if valid[i] {
+			corr[i][i] = 1
+		}
+		for j := i + 1; j < n; j++ {
+			rho := math.NaN()
+			switch {
+			case !valid[i] || !valid[j]:
+			case norms[i] == 0 || norms[j] == 0:
+				// deret konstan: tidak berkorelasi
+				rho = 0
+			default:
+				dot := 0.0
+				for k, v := range centered[i] {
+					dot += v * centered[j][k]
+				}
+				rho = dot / (norms[i] * norms[j])
+			}
+			corr[i][j], corr[j][i] = rho, rho
+		}
+	}
+	return corr
+}
+
+// antennaPairSummaries merangkum matriks korelasi per pasangan antena.
+// Variabel berurutan stream-major: streams × subcarriers.
+func antennaPairSummaries(corr [][]float64, streams []int, subcarriers int, threshold float64) []pairSummary {
+	S := subcarriers
+	var pairs []pairSummary
+	for a := range streams {
+		for b := a; b < len(streams); b++ {
+			sum := pairSummary{AntennaA: streams[a] + 1, AntennaB: streams[b] + 1}
+			var matched, block []float64
+			for i := 0; i < S; i++ {
+				for j := 0; j < S; j++ {
+					if a == b && j <= i {
+						continue
+					}
+					rho := math.Abs(corr[a*S+i][b*S+j])
+					if math.IsNaN(rho) {
+						continue
+					}
+					block = append(block, rho)
+					if (a != b && i == j) || (a == b && j == i+1) {
+						matched = append(matched, rho)
+					}
+				}
+			}
+			summarizeAbs(matched, block, threshold, &sum)
+			pairs = append(pairs, sum)
+		}
+	}
+	return pairs
+}
+
+// GetCorrelation menghitung matriks korelasi Pearson amplitudo antar semua
+// deret stream × subcarrier terpilih (korelasi sama dengan yang dipakai
+// ranking BNR), pada jendela paket [offset, offset+packets), beserta
+// ringkasan per pasangan antena dan daftar subcarrier redundan.
+func (h *PlotHandler) GetCorrelation(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	meta, err := h.loadMeta(ctx, mux.Vars(r)["id"])
+	if err != nil {
+		respondLoadError(w, err)
+		return
+	}
+	sel, err := h.resolveSelection(ctx, meta, r.URL.Query())
+	if err != nil {
+		respondLoadError(w, err)
+		return
+	}
+	params, err := parseCorrelationParams(r, sel)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	filter, err := h.fileFilter(ctx, meta, params.Raw)
+	if err != nil {
+		respondLoadError(w, err)
+		return
+	}
+	bl, err := h.resolveBaseline(ctx, meta, r.URL.Query().Get("room_baseline"), filter)
+	if err != nil {
+		respondLoadError(w, err)
+		return
+	}
+	key := analysisKey("correlation", meta, filter, params.canonical()+";"+bl.canonical())
+	if h.cachedAnalysis(ctx, w, key) {
+		return
+	}
+	csi, resampled, err := h.prepareMatrix(ctx, meta, filter, params.Resample, bl)
+	if err != nil {
+		respondLoadError(w, err)
+		return
+	}
+	if params.Offset >= csi.Packets {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("offset %d is beyond the last packet (%d packets)", params.Offset, csi.Packets))
+		return
+	}
+	end := csi.Packets
+	if params.Packets > 0 {
+		end = min(params.Offset+params.Packets, csi.Packets)
+	}
+	if end-params.Offset < 3 {
+		respondError(w, http.StatusBadRequest, "Packet window must hold at least 3 packets")
+		return
+	}
+
+	// Deret amplitudo per variabel; nilai <= 0 dianggap dropout
+	type variable struct {
+		Antenna    int `json:"antenna"`
+		Subcarrier int `json:"subcarrier"`
+	}
+	var vars []variable
+	var series [][]float64
+	var valid []bool
+	for _, c := range params.Streams {
+		for _, s := range params.Subcarriers {
+			x := make([]float64, end-params.Offset)
+			ok := false
+			for i, v := range csi.Amplitude[c][s][params.Offset:end] {
+				if math.IsNaN(v) || v <= 0 {
+					v = math.NaN()
+				} else {
+					ok = true
+				}
+				x[i] = v
+			}
+			vars = append(vars, variable{Antenna: c + 1, Subcarrier: s + 1})
+			series = append(series, x)
+			valid = append(valid, ok)
+		}
+	}
+
+	n := len(series)
+	corr := correlationMatrix(series, valid)
+	matrix := make([][]*float64, n)
+	for i := range corr {
+		matrix[i] = nullable(corr[i])
+	}
+
+	pairs := antennaPairSummaries(corr, params.Streams, len(params.Subcarriers), params.Threshold)
+
+	// Subcarrier redundan: jumlah deret lain dengan |ρ| >= ambang
+	type redundancy struct {
+		variable
+		Count  int      `json:"redundant_with"`
+		MaxAbs *float64 `json:"max_abs_corr"`
+	}
+	redundant := make([]redundancy, n)
+	for i := range corr {
+		redundant[i].variable = vars[i]
+		maxAbs := math.NaN()
+		for j, rho := range corr[i] {
+			if j == i || math.IsNaN(rho) {
+				continue
+			}
+			if math.IsNaN(maxAbs) || math.Abs(rho) > maxAbs {
+				maxAbs = math.Abs(rho)
+			}
+			if math.Abs(rho) >= params.Threshold {
+				redundant[i].Count++
+			}
+		}
+		if !math.IsNaN(maxAbs) {
+			redundant[i].MaxAbs = &maxAbs
+		}
+	}
+
+	h.respondAnalysis(ctx, w, key, map[string]interface{}{
+		"meta": map[string]interface{}{
+			"method":               "Pearson (masked samples filled with the series median)",
+			"streams":              oneBased(params.Streams),
+			"subcarriers":          oneBased(params.Subcarriers),
+			"offset":               params.Offset,
+			"window_packets":       end - params.Offset,
+			"redundancy_threshold": params.Threshold,
+			"shape":                []int{n, n},
+			"packets":              csi.Packets,
+			"layout":               csi.Layout,
+			"filter":               filter,
+			"resample":             resampled,
+			"baseline":             bl,
+		},
+		"variables":     vars,
+		"matrix":        matrix,
+		"antenna_pairs": pairs,
+		"redundancy":    redundant,
+	})
+}
@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"math"
+	"net/http/httptest"
+	"testing"
+
+	"cetasense-v2.0/internal/models"
+)
+
+func TestCorrelationMatrix(t *testing.T) {
+	n := 50
+	base, other := make([]float64, n), make([]float64, n)
+	for i := range base {
+		base[i] = 10 + math.Sin(float64(i)/3)
+		other[i] = 10 + math.Cos(float64(i)*2.1)
+	}
+	scaled, mirrored := make([]float64, n), make([]float64, n)
+	for i, v := range base {
+		scaled[i] = 3*v + 1
+		mirrored[i] = 30 - v
+	}
+	// sampel masked diisi median, bukan dibuang
+	scaled[7] = math.NaN()
+	dead := make([]float64, n)
+	for i := range dead {
+		dead[i] = math.NaN()
+	}
+
+	series := [][]float64{base, scaled, mirrored, other, dead}
+	corr := correlationMatrix(series, []bool{true, true, true, true, false})
+
+	if corr[0][0] != 1 || !math.IsNaN(corr[4][4]) || !math.IsNaN(corr[0][4]) {
+		t.Fatalf("diagonal/dead series: %v %v %v", corr[0][0], corr[4][4], corr[0][4])
+	}
+	if corr[0][1] < 0.98 || math.Abs(corr[0][2]+1) > 1e-12 {
+		t.Errorf("ρ(base, scaled) = %g, ρ(base, mirrored) = %g", corr[0][1], corr[0][2])
+	}
+	if math.Abs(corr[0][3]) > 0.05 {
+		t.Errorf("ρ(base, other) = %g, want about 0 for another frequency", corr[0][3])
+	}
+	for i := range corr {
+		for j := range corr {
+			if !math.IsNaN(corr[i][j]) && corr[i][j] != corr[j][i] {
+				t.Fatalf("matrix not symmetric at %d,%d", i, j)
+			}
+		}
+	}
+	if !math.IsNaN(scaled[7]) {
+		t.Error("correlationMatrix modified its input series")
+	}
+}
+
+// Deret dipusatkan sekali per deret; hasilnya harus sama dengan Pearson
+// per pasangan
+func TestCorrelationMatrixMatchesPearson(t *testing.T) {
+	n := 40
+	series := make([][]float64, 4)
+	for v := range series {
+		series[v] = make([]float64, n)
+		for i := range series[v] {
+			series[v][i] = 5 + math.Sin(float64(i*(v+1))/5) + 0.1*float64(i%(v+3))
+		}
+		series[v][3*v] = math.NaN()
+	}
+	flat := make([]float64, n)
+	for i := range flat {
+		flat[i] = 7
+	}
+	series = append(series, flat)
+
+	corr := correlationMatrix(series, []bool{true, true, true, true, true})
+	for i := 0; i < 4; i++ {
+		for j := i + 1; j < 4; j++ {
+			if want := pearsonCorrIgnoreNaN(series[i], series[j]); math.Abs(corr[i][j]-want) > 1e-9 {
+				t.Errorf("ρ(%d, %d) = %g, pearson gives %g", i, j, corr[i][j], want)
+			}
+		}
+		if corr[i][4] != 0 {
+			t.Errorf("ρ(%d, constant) = %g, want 0", i, corr[i][4])
+		}
+	}
+}
+
+func TestAntennaPairSummaries(t *testing.T) {
+	// 2 antena × 3 subcarrier, stream-major
+	nan := math.NaN()
+	corr := [][]float64{
+		{1, 0.9, 0.1, 0.95, 0.2, 0.3},
+		{0.9, 1, 0.4, 0.5, -0.97, 0.2},
+		{0.1, 0.4, 1, 0.1, 0.1, nan},
+		{0.95, 0.5, 0.1, 1, 0.6, 0.1},
+		{0.2, -0.97, 0.1, 0.6, 1, 0.2},
+		{0.3, 0.2, nan, 0.1, 0.2, 1},
+	}
+	pairs := antennaPairSummaries(corr, []int{0, 2}, 3, 0.9)
+	if len(pairs) != 3 {
+		t.Fatalf("got %d pairs, want (1,1), (1,3), (3,3)", len(pairs))
+	}
+
+	within := pairs[0]
+	if within.AntennaA != 1 || within.AntennaB != 1 || within.Pairs != 3 || within.RedundantPairs != 1 {
+		t.Errorf("antenna 1 with itself: %+v", within)
+	}
+	// subcarrier bertetangga: (0,1)=0.9 dan (1,2)=0.4
+	if math.Abs(*within.MatchedMean-0.65) > 1e-12 || *within.MatchedMin != 0.4 {
+		t.Errorf("neighbour mean %g min %g", *within.MatchedMean, *within.MatchedMin)
+	}
+
+	cross := pairs[1]
+	if cross.AntennaA != 1 || cross.AntennaB != 3 || cross.Pairs != 8 || cross.RedundantPairs != 2 {
+		t.Errorf("antenna 1 vs 3: %+v", cross)
+	}
+	// subcarrier sama: 0.95, 0.97 (|ρ|); pasangan (2,2) NaN dilewati
+	if math.Abs(*cross.MatchedMean-0.96) > 1e-12 || math.Abs(*cross.MatchedMedian-0.96) > 1e-12 {
+		t.Errorf("matched mean %g median %g", *cross.MatchedMean, *cross.MatchedMedian)
+	}
+}
+
+// correlationRequest menjalankan resolveSelection seperti GetCorrelation
+// untuk file berlayout C stream × S subcarrier
+func correlationRequest(query string, C, S int) (*correlationParams, error) {
+	r := httptest.NewRequest("GET", "/api/plots/x/correlation?"+query, nil)
+	meta := &models.CSI_File{Layout: &models.CSILayout{Streams: C, Subcarriers: S}}
+	sel, err := (&PlotHandler{}).resolveSelection(r.Context(), meta, r.URL.Query())
+	if err != nil {
+		return nil, err
+	}
+	return parseCorrelationParams(r, sel)
+}
+
+func TestParseCorrelationParams(t *testing.T) {
+	p, err := correlationRequest("streams=1,3&subcarriers=2-4&offset=10&packets=100&redundancy_threshold=0.8&filtered=false", 3, 30)
+	if err != nil {
+		t.Fatalf("parseCorrelationParams: %v", err)
+	}
+	if len(p.Streams) != 2 || p.Streams[1] != 2 || len(p.Subcarriers) != 3 || p.Subcarriers[0] != 1 {
+		t.Errorf("selection %v × %v", p.Streams, p.Subcarriers)
+	}
+	if p.Offset != 10 || p.Packets != 100 || p.Threshold != 0.8 || !p.Raw || p.Resample != nil {
+		t.Errorf("params %+v", p)
+	}
+
+	defaults, err := correlationRequest("", 2, 4)
+	if err != nil {
+		t.Fatalf("parseCorrelationParams: %v", err)
+	}
+	if len(defaults.Streams)*len(defaults.Subcarriers) != 8 || defaults.Threshold != defaultRedundancyThreshold {
+		t.Errorf("defaults %+v", defaults)
+	}
+
+	// 20 × 30 = 600 deret melebihi batas; memilih subcarrier menurunkannya
+	if _, err := correlationRequest("", 20, 30); err == nil {
+		t.Error("600 series: expected error")
+	}
+	if _, err := correlationRequest("subcarriers=1-25", 20, 30); err != nil {
+		t.Errorf("500 series: %v", err)
+	}
+
+	for _, q := range []string{"redundancy_threshold=0", "redundancy_threshold=1.5", "offset=-1", "streams=4", "packets=x"} {
+		if _, err := correlationRequest(q, 3, 30); err == nil {
+			t.Errorf("%s: expected error", q)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math"
+	"net/http"
+	"net/url"
+
+	"github.com/minio/minio-go/v7"
+
+	"cetasense-v2.0/internal/dsp"
+	"cetasense-v2.0/internal/models"
+	"cetasense-v2.0/internal/services"
+)
+
+// loadError membawa status HTTP untuk kegagalan saat memuat file CSI
+type loadError struct {
+	code int
+	msg  string
+}
+
+func (e *loadError) Error() string { return e.msg }
+
+// respondLoadError menulis error dari loadCSI dengan status yang sesuai
+func respondLoadError(w http.ResponseWriter, err error) {
+	var le *loadError
+	if errors.As(err, &le) {
+		respondError(w, le.code, le.msg)
+		return
+	}
+	respondError(w, http.StatusInternalServerError, err.Error())
+}
+
+// loadCSI mengambil metadata dan isi file dari MinIO lalu memetakan
+// kolomnya lewat layout tersimpan (atau hasil auto-detect untuk upload lama).
+func (h *PlotHandler) loadCSI(ctx context.Context, id string) (*models.CSI_File, *services.CSIMatrix, error) {
+	meta, err := h.loadMeta(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	m, err := h.loadMatrix(ctx, meta)
+	if err != nil {
+		return nil, nil, err
+	}
+	return meta, m, nil
+}
+
+// loadMeta mengambil metadata file CSI
+func (h *PlotHandler) loadMeta(ctx context.Context, id string) (*models.CSI_File, error) {
+	meta, err := h.csvRepo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &loadError{http.StatusNotFound, "CSV not found"}
+		}
+		return nil, &loadError{http.StatusInternalServerError, "Failed to load CSV metadata: " + err.Error()}
+	}
+	return meta, nil
+}
+
+// loadMatrix mengambil isi file dari MinIO dan memetakannya lewat layout
+func (h *PlotHandler) loadMatrix(ctx context.Context, meta *models.CSI_File) (*services.CSIMatrix, error) {
+	obj, err := h.minioClient.GetObject(ctx, h.bucketName, meta.ObjectPath, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, &loadError{http.StatusInternalServerError, "Failed to fetch CSV"}
+	}
+	defer obj.Close()
+
+	rows, err := services.ReadCSIRows(obj, meta.Layout)
+	if err != nil {
+		return nil, &loadError{http.StatusBadRequest, "Parse CSV: " + err.Error()}
+	}
+	layout, err := services.ResolveLayout(meta.Layout, rows)
+	if err != nil {
+		return nil, &loadError{http.StatusUnprocessableEntity, err.Error()}
+	}
+	m, err := services.ExtractCSI(rows.Rows, layout)
+	if err != nil {
+		return nil, &loadError{http.StatusUnprocessableEntity, err.Error()}
+	}
+	return m, nil
+}
+
+// resolveLayout melengkapi meta.Layout untuk upload lama tanpa layout
+// tersimpan dengan menebaknya dari isi file. loadMatrix berikutnya memakai
+// layout yang sama sebagai deklarasi, sehingga batas validasi parameter
+// dan kunci cache mengikuti bentuk data yang sebenarnya.
+func (h *PlotHandler) resolveLayout(ctx context.Context, meta *models.CSI_File) error {
+	if meta.Layout != nil {
+		return nil
+	}
+	obj, err := h.minioClient.GetObject(ctx, h.bucketName, meta.ObjectPath, minio.GetObjectOptions{})
+	if err != nil {
+		return &loadError{http.StatusInternalServerError, "Failed to fetch CSV"}
+	}
+	defer obj.Close()
+
+	rows, err := services.ReadCSIRows(obj, nil)
+	if err != nil {
+		return &loadError{http.StatusBadRequest, "Parse CSV: " + err.Error()}
+	}
+	layout, err := services.ResolveLayout(nil, rows)
+	if err != nil {
+		return &loadError{http.StatusUnprocessableEntity, err.Error()}
+	}
+	meta.Layout = &layout
+	return nil
+}
+
+// subcarrierSelection adalah ?streams= dan ?subcarriers= (0-based, terurut)
+// yang sudah divalidasi terhadap layout file
+type subcarrierSelection struct {
+	Layout      *models.CSILayout
+	Streams     []int
+	Subcarriers []int
+}
+
+// resolveSelection memastikan layout file diketahui (lihat resolveLayout)
+// lalu mem-parse pilihan stream dan subcarrier terhadap batas layout itu
+func (h *PlotHandler) resolveSelection(ctx context.Context, meta *models.CSI_File, q url.Values) (*subcarrierSelection, error) {
+	if err := h.resolveLayout(ctx, meta); err != nil {
+		return nil, err
+	}
+	sel := &subcarrierSelection{Layout: meta.Layout}
+	var err error
+	if sel.Streams, err = parseIndexList(q.Get("streams"), meta.Layout.Streams); err != nil {
+		return nil, &loadError{http.StatusBadRequest, "streams: " + err.Error()}
+	}
+	if sel.Subcarriers, err = parseIndexList(q.Get("subcarriers"), meta.Layout.Subcarriers); err != nil {
+		return nil, &loadError{http.StatusBadRequest, "subcarriers: " + err.Error()}
+	}
+	return sel, nil
+}
+
+// appliedFilter melaporkan pipeline filter yang dijalankan pada data
+type appliedFilter struct {
+	ID         string               `json:"id"`
+	NamaFilter string               `json:"nama_filter"`
+	Version    int                  `json:"version"`
+	Pipeline   []models.FilterStage `json:"pipeline"`
+	Applied    bool                 `json:"applied"`
+}
+
+// fileFilter mengambil filter milik file dan menandai apakah pipeline-nya
+// akan dijalankan. nil bila file tidak punya filter.
+func (h *PlotHandler) fileFilter(ctx context.Context, meta *models.CSI_File, raw bool) (*appliedFilter, error) {
+	filter, err := h.filterRepo.GetByID(ctx, meta.FilterID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, &loadError{http.StatusInternalServerError, "Failed to load filter: " + err.Error()}
+	}
+	return &appliedFilter{
+		ID:         filter.ID,
+		NamaFilter: filter.NamaFilter,
+		Version:    filter.Version,
+		Pipeline:   filter.Pipeline,
+		Applied:    !raw && len(filter.Pipeline) > 0,
+	}, nil
+}
+
+// applyFilter menjalankan pipeline filter pada deret waktu amplitudo tiap
+// subcarrier bila f.Applied. Amplitudo <= 0 dianggap dropout (NaN) agar
+// tidak ikut diratakan.
+func applyFilter(csi *services.CSIMatrix, f *appliedFilter) error {
+	if f == nil || !f.Applied {
+		return nil
+	}
+	pipeline, err := dsp.NewPipeline(f.Pipeline)
+	if err != nil {
+		return &loadError{http.StatusUnprocessableEntity, "Filter " + f.NamaFilter + " is invalid: " + err.Error()}
+	}
+	for c := range csi.Amplitude {
+		for s, series := range csi.Amplitude[c] {
+			for p, v := range series {
+				if v <= 0 {
+					series[p] = math.NaN()
+				}
+			}
+			csi.Amplitude[c][s] = pipeline.Apply(series)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,292 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+
+	"github.com/gorilla/mux"
+
+	"cetasense-v2.0/internal/dsp"
+	"cetasense-v2.0/internal/models"
+	"cetasense-v2.0/internal/services"
+)
+
+// Kriteria ranking sensitivitas subcarrier
+const (
+	diffRankKS          = "ks"
+	diffRankWasserstein = "wasserstein"
+	diffRankMedian      = "median"   // |selisih median| dalam dB
+	diffRankVariance    = "variance" // |rasio variansi| dalam dB
+	diffRankBNR         = "bnr"      // |selisih median BNR| dalam dB
+)
+
+const defaultDiffTop = 10
+
+// captureStats adalah statistik amplitudo satu subcarrier pada satu capture
+type captureStats struct {
+	Median    *float64 `json:"median"`
+	Variance  *float64 `json:"variance"`
+	BNRMedian *float64 `json:"bnr_median_db"`
+	ValidPct  float64  `json:"valid_pct"`
+}
+
+// subcarrierDiff membandingkan satu stream × subcarrier antara baseline dan
+// target; selisih = target - baseline
+type subcarrierDiff struct {
+	Antenna         int          `json:"antenna"`
+	Subcarrier      int          `json:"subcarrier"`
+	Baseline        captureStats `json:"baseline"`
+	Target          captureStats `json:"target"`
+	MedianDiff      *float64     `json:"median_diff"`
+	MedianRatioDB   *float64     `json:"median_ratio_db"`
+	VarianceDiff    *float64     `json:"variance_diff"`
+	VarianceRatioDB *float64     `json:"variance_ratio_db"`
+	BNRDiff         *float64     `json:"bnr_diff_db"`
+	KS              *float64     `json:"ks"`
+	Wasserstein     *float64     `json:"wasserstein"`
+	Score           *float64     `json:"score"` // nilai kriteria rank_by
+	Rank            int          `json:"rank,omitempty"`
+}
+
+// diffLayoutError memeriksa kedua capture bisa dibandingkan per subcarrier
+// menurut layout masing-masing
+func diffLayoutError(base, target models.CSILayout) error {
+	if base.Streams != target.Streams || base.Subcarriers != target.Subcarriers {
+		return fmt.Errorf("layouts do not align: baseline has %d streams × %d subcarriers, target has %d × %d",
+			base.Streams, base.Subcarriers, target.Streams, target.Subcarriers)
+	}
+	if a, b := base.Spacing(), target.Spacing(); a > 0 && b > 0 && math.Abs(a-b) > 1e-6*a {
+		return fmt.Errorf("layouts do not align: subcarrier spacing %g Hz vs %g Hz", a, b)
+	}
+	return nil
+}
+
+// amplitudeValid mengembalikan amplitudo dengan dropout (<= 0) sebagai NaN
+// dan persentase sampel valid
+func amplitudeValid(x []float64) ([]float64, float64) {
+	out := make([]float64, len(x))
+	n := 0
+	for i, v := range x {
+		if math.IsNaN(v) || v <= 0 {
+			out[i] = math.NaN()
+			continue
+		}
+		out[i] = v
+		n++
+	}
+	pct := 0.0
+	if len(x) > 0 {
+		pct = 100 * float64(n) / float64(len(x))
+	}
+	return out, pct
+}
+
+func ratioDB(target, base float64, power bool) float64 {
+	if !(target > 0) || !(base > 0) {
+		return math.NaN()
+	}
+	if power {
+		return 10 * math.Log10(target/base)
+	}
+	return 20 * math.Log10(target/base)
+}
+
+// GetDiff membandingkan capture {id} (target, misalnya ruangan berisi)
+// dengan ?baseline= (misalnya ruangan kosong) per stream × subcarrier:
+// selisih median amplitudo, variansi, dan median BNR, jarak distribusi
+// amplitudo (KS dan Wasserstein-1), serta ranking subcarrier yang paling
+// sensitif terhadap perubahan (?rank_by=, ?top=). Parameter BNR/preset sama
+// dengan /api/plots/{id}; pipeline filter masing-masing file diterapkan.
+func (h *PlotHandler) GetDiff(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	q := r.URL.Query()
+	baseID := q.Get("baseline")
+	if baseID == "" {
+		respondError(w, http.StatusBadRequest, "baseline is required")
+		return
+	}
+	rankBy := q.Get("rank_by")
+	if rankBy == "" {
+		rankBy = diffRankKS
+	}
+	switch rankBy {
+	case diffRankKS, diffRankWasserstein, diffRankMedian, diffRankVariance, diffRankBNR:
+	default:
+		respondError(w, http.StatusBadRequest, "rank_by must be ks, wasserstein, median, variance or bnr")
+		return
+	}
+	top, err := queryInt(r, "top", defaultDiffTop)
+	if err != nil || top < 1 || top > maxSTFTWindow {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("top must be an integer between 1 and %d", maxSTFTWindow))
+		return
+	}
+	cfg, presetName, err := h.resolveBNRParams(r)
+	if err != nil {
+		respondLoadError(w, err)
+		return
+	}
+	raw := q.Get("filtered") == "false"
+
+	target, err := h.loadMeta(ctx, mux.Vars(r)["id"])
+	if err != nil {
+		respondLoadError(w, err)
+		return
+	}
+	base, err := h.loadMeta(ctx, baseID)
+	if err != nil {
+		var le *loadError
+		if errors.As(err, &le) && le.code == http.StatusNotFound {
+			respondError(w, http.StatusNotFound, "Baseline CSV not found")
+			return
+		}
+		respondLoadError(w, err)
+		return
+	}
+	if base.Layout != nil && target.Layout != nil {
+		if err := diffLayoutError(*base.Layout, *target.Layout); err != nil {
+			respondError(w, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+	}
+	targetFilter, err := h.fileFilter(ctx, target, raw)
+	if err != nil {
+		respondLoadError(w, err)
+		return
+	}
+	baseFilter, err := h.fileFilter(ctx, base, raw)
+	if err != nil {
+		respondLoadError(w, err)
+		return
+	}
+
+	// Kunci memuat identitas baseline (isi, layout, filter) di samping
+	// identitas target yang dicakup analysisKey
+	bnrParams, _ := json.Marshal(cfg)
+	baseRef := analysisKey("diff", base, baseFilter, "")
+	key := analysisKey("diff", target, targetFilter,
+		fmt.Sprintf("base=%s;bnr=%s;preset=%s;rank=%s;top=%d;raw=%t", baseRef.Key, bnrParams, presetName, rankBy, top, raw))
+	if h.cachedAnalysis(ctx, w, key) {
+		return
+	}
+
+	targetCSI, _, err := h.prepareMatrix(ctx, target, targetFilter, nil, nil)
+	if err != nil {
+		respondLoadError(w, err)
+		return
+	}
+	baseCSI, _, err := h.prepareMatrix(ctx, base, baseFilter, nil, nil)
+	if err != nil {
+		respondLoadError(w, err)
+		return
+	}
+	if err := diffLayoutError(baseCSI.Layout, targetCSI.Layout); err != nil {
+		respondError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	baseBNR, targetBNR := rankBNR(baseCSI, cfg), rankBNR(targetCSI, cfg)
+	stats := func(csi *services.CSIMatrix, bnr *bnrRanking, c, s int) ([]float64, captureStats, float64, float64, float64) {
+		x, pct := amplitudeValid(csi.Amplitude[c][s])
+		med, sd, bnrMed := medianIgnoreNaN(x), stdIgnoreNaN(x), medianIgnoreNaN(bnr.BNR[c][s])
+		variance := sd * sd
+		return x, captureStats{
+			Median:    finiteOrNil(med),
+			Variance:  finiteOrNil(variance),
+			BNRMedian: finiteOrNil(bnrMed),
+			ValidPct:  pct,
+		}, med, variance, bnrMed
+	}
+
+	C, S := targetCSI.Layout.Streams, targetCSI.Layout.Subcarriers
+	diffs := make([]subcarrierDiff, 0, C*S)
+	for c := 0; c < C; c++ {
+		for s := 0; s < S; s++ {
+			bx, bs, bMed, bVar, bBNR := stats(baseCSI, baseBNR, c, s)
+			tx, ts, tMed, tVar, tBNR := stats(targetCSI, targetBNR, c, s)
+			d := subcarrierDiff{
+				Antenna:         c + 1,
+				Subcarrier:      s + 1,
+				Baseline:        bs,
+				Target:          ts,
+				MedianDiff:      finiteOrNil(tMed - bMed),
+				MedianRatioDB:   finiteOrNil(ratioDB(tMed, bMed, false)),
+				VarianceDiff:    finiteOrNil(tVar - bVar),
+				VarianceRatioDB: finiteOrNil(ratioDB(tVar, bVar, true)),
+				BNRDiff:         finiteOrNil(tBNR - bBNR),
+				KS:              finiteOrNil(dsp.KSStatistic(bx, tx)),
+				Wasserstein:     finiteOrNil(dsp.Wasserstein1(bx, tx)),
+			}
+			switch rankBy {
+			case diffRankKS:
+				d.Score = d.KS
+			case diffRankWasserstein:
+				d.Score = d.Wasserstein
+			case diffRankMedian:
+				d.Score = absOrNil(d.MedianRatioDB)
+			case diffRankVariance:
+				d.Score = absOrNil(d.VarianceRatioDB)
+			case diffRankBNR:
+				d.Score = absOrNil(d.BNRDiff)
+			}
+			diffs = append(diffs, d)
+		}
+	}
+
+	// Ranking sensitivitas: skor menurun, subcarrier tanpa skor di akhir
+	order := make([]int, len(diffs))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := diffs[order[i]].Score, diffs[order[j]].Score
+		if a == nil || b == nil {
+			return a != nil
+		}
+		return *a > *b
+	})
+	ranked := make([]subcarrierDiff, 0, top)
+	for rank, i := range order {
+		if diffs[i].Score == nil || rank >= top {
+			break
+		}
+		diffs[i].Rank = rank + 1
+		ranked = append(ranked, diffs[i])
+	}
+
+	capture := func(meta *models.CSI_File, csi *services.CSIMatrix, filter *appliedFilter) map[string]interface{} {
+		return map[string]interface{}{
+			"id":        meta.ID,
+			"file_name": meta.FileName,
+			"packets":   csi.Packets,
+			"layout":    csi.Layout,
+			"filter":    filter,
+		}
+	}
+	h.respondAnalysis(ctx, w, key, map[string]interface{}{
+		"meta": map[string]interface{}{
+			"baseline":    capture(base, baseCSI, baseFilter),
+			"target":      capture(target, targetCSI, targetFilter),
+			"difference":  "target - baseline",
+			"rank_by":     rankBy,
+			"top":         top,
+			"params":      cfg,
+			"preset":      presetName,
+			"channels":    C,
+			"subcarriers": S,
+		},
+		"ranking":     ranked,
+		"subcarriers": diffs,
+	})
+}
+
+func absOrNil(v *float64) *float64 {
+	if v == nil {
+		return nil
+	}
+	a := math.Abs(*v)
+	return &a
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// GetFeatureSets mendaftar set fitur yang tersedia beserta dokumentasi
+// tiap fitur dan parameter ekstraksinya
+func (h *PlotHandler) GetFeatureSets(w http.ResponseWriter, r *http.Request) {
+	sets := make([]map[string]interface{}, 0, len(featureSets))
+	for _, s := range featureSets {
+		sets = append(sets, map[string]interface{}{
+			"id":  s.ID(),
+			"set": s,
+		})
+	}
+	def, _ := lookupFeatureSet("")
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"default": def.ID(),
+		"sets":    sets,
+	})
+}
+
+// GetFeatures mengekstrak vektor fitur set (?set=nama[@versi]) per jendela
+// capture (?window=, ?hop= dalam paket; window 0 = seluruh capture).
+// Data melewati pipeline filter, resampling opsional, dan baseline ruangan
+// seperti analisis lain; hasil yang sama dipakai input job "features".
+func (h *PlotHandler) GetFeatures(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	q := r.URL.Query()
+	set, err := lookupFeatureSet(q.Get("set"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	window, err := queryInt(r, "window", 0)
+	if err != nil || window < 0 {
+		respondError(w, http.StatusBadRequest, "window must be a non-negative integer")
+		return
+	}
+	hop, err := queryInt(r, "hop", 0)
+	if err != nil || hop < 0 {
+		respondError(w, http.StatusBadRequest, "hop must be a non-negative integer")
+		return
+	}
+	fs, err := queryFloat(r, "fs", 0)
+	if err != nil || fs < 0 {
+		respondError(w, http.StatusBadRequest, "fs must be a positive number")
+		return
+	}
+	rs, err := parseResample(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	raw := q.Get("filtered") == "false"
+
+	meta, err := h.loadMeta(ctx, mux.Vars(r)["id"])
+	if err != nil {
+		respondLoadError(w, err)
+		return
+	}
+	filter, err := h.fileFilter(ctx, meta, raw)
+	if err != nil {
+		respondLoadError(w, err)
+		return
+	}
+	bl, err := h.resolveBaseline(ctx, meta, q.Get("room_baseline"), filter)
+	if err != nil {
+		respondLoadError(w, err)
+		return
+	}
+	key := analysisKey("features", meta, filter, fmt.Sprintf("set=%s;window=%d;hop=%d;fs=%g;raw=%t;%s;%s",
+		set.ID(), window, hop, fs, raw, rs.canonical(), bl.canonical()))
+	if h.cachedAnalysis(ctx, w, key) {
+		return
+	}
+	csi, resampled, err := h.prepareMatrix(ctx, meta, filter, rs, bl)
+	if err != nil {
+		respondLoadError(w, err)
+		return
+	}
+	table, err := computeFeatures(csi, set, window, hop, fs)
+	if err != nil {
+		respondLoadError(w, err)
+		return
+	}
+
+	h.respondAnalysis(ctx, w, key, map[string]interface{}{
+		"meta": map[string]interface{}{
+			"set":           set.ID(),
+			"window":        table.Window,
+			"hop":           table.Hop,
+			"windows":       len(table.Windows),
+			"feature_count": len(table.Names),
+			"fs":            table.FS,
+			"fs_source":     table.FSSource,
+			"packets":       csi.Packets,
+			"layout":        csi.Layout,
+			"filter":        filter,
+			"resample":      resampled,
+			"baseline":      bl,
+		},
+		"feature_names": table.Names,
+		"windows":       table.Windows,
+	})
+}
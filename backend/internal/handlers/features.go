@@ -0,0 +1,403 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+
+	"cetasense-v2.0/internal/dsp"
+	"cetasense-v2.0/internal/models"
+	"cetasense-v2.0/internal/services"
+)
+
+// Grup fitur yang bisa dipakai sebuah set
+const (
+	featureGroupAmplitude = "amplitude"
+	featureGroupBNR       = "bnr"
+	featureGroupPCA       = "pca"
+	featureGroupDoppler   = "doppler"
+	featureGroupRSSI      = "rssi"
+)
+
+const (
+	defaultFeatureSet = "csi"
+	minFeatureWindow  = 8
+	maxFeatureWindows = 2000
+)
+
+// featureDoc mendokumentasikan satu fitur. Name berupa pola: {a} antena
+// dan {s} subcarrier (1-based), {k} komponen PCA; fitur per subcarrier
+// diurutkan stream-major seperti matriks CSI.
+type featureDoc struct {
+	Name        string `json:"name"`
+	Unit        string `json:"unit,omitempty"`
+	Description string `json:"description"`
+}
+
+type featureGroup struct {
+	Name     string       `json:"name"`
+	Features []featureDoc `json:"features"`
+}
+
+// featureSet adalah definisi vektor fitur yang versinya dibekukan: set
+// yang sudah dirilis tidak boleh diubah, perubahan daftar fitur atau cara
+// hitung berarti versi baru. Nilai fitur yang tidak bisa dihitung (tanpa
+// sampel valid, band di atas Nyquist, layout tanpa RSSI) berupa null.
+type featureSet struct {
+	Name        string         `json:"name"`
+	Version     int            `json:"version"`
+	Description string         `json:"description"`
+	Groups      []featureGroup `json:"groups"`
+	// Parameter ekstraksi yang menjadi bagian definisi set
+	BNR           models.BNRParams `json:"bnr_params"`
+	PCAComponents int              `json:"pca_components"`
+	DopplerBands  [][2]float64     `json:"doppler_bands_hz"` // [lo, hi)
+}
+
+// ID adalah <nama>@<versi>
+func (s *featureSet) ID() string {
+	return fmt.Sprintf("%s@%d", s.Name, s.Version)
+}
+
+func (s *featureSet) hasGroup(name string) bool {
+	for _, g := range s.Groups {
+		if g.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// featureSets adalah registri set fitur; tambahkan versi baru, jangan ubah
+// yang lama
+var featureSets = []*featureSet{
+	{
+		Name:        "csi",
+		Version:     1,
+		Description: "Per-subcarrier amplitude statistics and BNR, PCA energies, Doppler band powers of the first principal component, and RSSI statistics",
+		Groups: []featureGroup{
+			{Name: featureGroupAmplitude, Features: []featureDoc{
+				{Name: "amp_mean_a{a}_s{s}", Description: "Mean amplitude (dropouts excluded)"},
+				{Name: "amp_std_a{a}_s{s}", Description: "Amplitude standard deviation"},
+				{Name: "amp_median_a{a}_s{s}", Description: "Median amplitude"},
+				{Name: "amp_iqr_a{a}_s{s}", Description: "Amplitude interquartile range (p75 - p25)"},
+				{Name: "amp_valid_pct_a{a}_s{s}", Unit: "%", Description: "Share of packets with a valid amplitude"},
+			}},
+			{Name: featureGroupBNR, Features: []featureDoc{
+				{Name: "bnr_median_a{a}_s{s}", Unit: "dB", Description: "Median BNR within the window (bnr_params)"},
+			}},
+			{Name: featureGroupPCA, Features: []featureDoc{
+				{Name: "pca_energy_{k}", Description: "Explained variance ratio of principal component k over all subcarrier amplitude series"},
+				{Name: "pca_total_variance", Description: "Total amplitude variance (sum of eigenvalues)"},
+			}},
+			{Name: featureGroupDoppler, Features: []featureDoc{
+				{Name: "doppler_{lo}_{hi}hz", Description: "Share of PC1 power (DC excluded) in the band [lo, hi) Hz"},
+				{Name: "doppler_peak_hz", Unit: "Hz", Description: "Frequency of the strongest PC1 bin (DC excluded)"},
+			}},
+			{Name: featureGroupRSSI, Features: []featureDoc{
+				{Name: "rssi_mean", Unit: "dBm", Description: "Mean RSSI"},
+				{Name: "rssi_std", Unit: "dB", Description: "RSSI standard deviation"},
+				{Name: "rssi_min", Unit: "dBm", Description: "Minimum RSSI"},
+				{Name: "rssi_max", Unit: "dBm", Description: "Maximum RSSI"},
+			}},
+		},
+		// nilai dibekukan: set berversi tidak boleh ikut berubah bila
+		// default BNR berubah
+		BNR: models.BNRParams{
+			PercentileBaseline:  15.0,
+			DropoutRatio:        5e-4,
+			ClipDbLo:            -20.0,
+			ClipDbHi:            20.0,
+			MinGapSubcarrier:    3,
+			MaxAbsCorrThreshold: 0.8,
+			TopK:                5,
+		},
+		PCAComponents: 5,
+		DopplerBands:  [][2]float64{{0.1, 0.5}, {0.5, 1}, {1, 2}, {2, 5}, {5, 10}, {10, 20}, {20, 50}},
+	},
+}
+
+// lookupFeatureSet mencari set "<nama>@<versi>"; tanpa versi berarti versi
+// terbaru, kosong berarti set default
+func lookupFeatureSet(ref string) (*featureSet, error) {
+	if ref == "" {
+		ref = defaultFeatureSet
+	}
+	name, version, pinned := strings.Cut(ref, "@")
+	var found *featureSet
+	for _, s := range featureSets {
+		if s.Name != name {
+			continue
+		}
+		if pinned && fmt.Sprint(s.Version) == version {
+			return s, nil
+		}
+		if !pinned && (found == nil || s.Version > found.Version) {
+			found = s
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("unknown feature set %q", ref)
+	}
+	return found, nil
+}
+
+// bandName adalah nama fitur band Doppler, misalnya doppler_0.5_1hz
+func bandName(b [2]float64) string {
+	return fmt.Sprintf("doppler_%g_%ghz", b[0], b[1])
+}
+
+// names menjabarkan nama fitur set untuk layout C stream × S subcarrier,
+// dalam urutan yang sama dengan nilai hasil extract
+func (s *featureSet) names(C, S int) []string {
+	var out []string
+	perSubcarrier := func(docs []featureDoc) {
+		for _, d := range docs {
+			for c := 1; c <= C; c++ {
+				for sc := 1; sc <= S; sc++ {
+					out = append(out, strings.NewReplacer("{a}", fmt.Sprint(c), "{s}", fmt.Sprint(sc)).Replace(d.Name))
+				}
+			}
+		}
+	}
+	for _, g := range s.Groups {
+		switch g.Name {
+		case featureGroupAmplitude, featureGroupBNR:
+			perSubcarrier(g.Features)
+		case featureGroupPCA:
+			for k := 1; k <= s.PCAComponents; k++ {
+				out = append(out, fmt.Sprintf("pca_energy_%d", k))
+			}
+			out = append(out, "pca_total_variance")
+		case featureGroupDoppler:
+			for _, b := range s.DopplerBands {
+				out = append(out, bandName(b))
+			}
+			out = append(out, "doppler_peak_hz")
+		case featureGroupRSSI:
+			for _, d := range g.Features {
+				out = append(out, d.Name)
+			}
+		}
+	}
+	return out
+}
+
+// extract menghitung vektor fitur satu jendela capture (urutan sama dengan
+// names). fs dipakai untuk band Doppler.
+func (s *featureSet) extract(win *services.CSIMatrix, fs float64) []float64 {
+	C, S := win.Layout.Streams, win.Layout.Subcarriers
+	var out []float64
+
+	// PCA dipakai bersama oleh grup pca dan doppler
+	var pca *dsp.PCAResult
+	var obs [][]float64
+	if s.hasGroup(featureGroupPCA) || s.hasGroup(featureGroupDoppler) {
+		series, _ := selectSeries(win, seq(C), seq(S))
+		if len(series) > 0 && win.Packets >= 2 {
+			obs = transpose(series)
+			pca, _ = dsp.PCA(obs)
+		}
+	}
+
+	for _, g := range s.Groups {
+		switch g.Name {
+		case featureGroupAmplitude:
+			stats := make([][5]float64, 0, C*S)
+			for c := 0; c < C; c++ {
+				for sc := 0; sc < S; sc++ {
+					x := make([]float64, 0, win.Packets)
+					for _, v := range win.Amplitude[c][sc] {
+						if !math.IsNaN(v) && v > 0 {
+							x = append(x, v)
+						}
+					}
+					sort.Float64s(x)
+					st := [5]float64{math.NaN(), math.NaN(), math.NaN(), math.NaN(), 0}
+					if len(x) > 0 {
+						mean := 0.0
+						for _, v := range x {
+							mean += v
+						}
+						st[0] = mean / float64(len(x))
+						st[1] = stdIgnoreNaN(x)
+						st[2] = percentileSorted(x, 50)
+						st[3] = percentileSorted(x, 75) - percentileSorted(x, 25)
+					}
+					if win.Packets > 0 {
+						st[4] = 100 * float64(len(x)) / float64(win.Packets)
+					}
+					stats = append(stats, st)
+				}
+			}
+			for k := range g.Features {
+				for _, st := range stats {
+					out = append(out, st[k])
+				}
+			}
+		case featureGroupBNR:
+			ranking := rankBNR(win, s.BNR)
+			for c := 0; c < C; c++ {
+				for sc := 0; sc < S; sc++ {
+					out = append(out, medianIgnoreNaN(ranking.BNR[c][sc]))
+				}
+			}
+		case featureGroupPCA:
+			total := math.NaN()
+			for k := 0; k < s.PCAComponents; k++ {
+				v := math.NaN()
+				if pca != nil && k < len(pca.ExplainedRatio) {
+					v = pca.ExplainedRatio[k]
+				}
+				out = append(out, v)
+			}
+			if pca != nil {
+				total = 0
+				for _, ev := range pca.Eigenvalues {
+					total += ev
+				}
+			}
+			out = append(out, total)
+		case featureGroupDoppler:
+			out = append(out, dopplerBands(pca, obs, fs, s.DopplerBands)...)
+		case featureGroupRSSI:
+			out = append(out, rssiStats(win.RSSI)...)
+		}
+	}
+	return out
+}
+
+// dopplerBands menghitung porsi daya spektrum PC1 per band dan frekuensi
+// puncaknya; NaN bila PCA gagal, deret terlalu pendek, atau band melewati
+// Nyquist
+func dopplerBands(pca *dsp.PCAResult, obs [][]float64, fs float64, bands [][2]float64) []float64 {
+	out := make([]float64, len(bands)+1)
+	for i := range out {
+		out[i] = math.NaN()
+	}
+	if pca == nil || len(obs) < minFeatureWindow {
+		return out
+	}
+	scores := pca.Project(obs, 0)
+	sp, err := dsp.STFT(scores, dsp.STFTParams{Window: len(scores), Hop: len(scores), WindowFn: "hann"})
+	if err != nil || len(sp.Power) == 0 {
+		return out
+	}
+	power := sp.Power[0]
+	binHz := fs / float64(sp.NFFT)
+	total, peak, peakBin := 0.0, -1.0, 0
+	for k := 1; k < len(power); k++ {
+		total += power[k]
+		if power[k] > peak {
+			peak, peakBin = power[k], k
+		}
+	}
+	if total <= 0 {
+		return out
+	}
+	for i, b := range bands {
+		if b[1] > fs/2 {
+			continue
+		}
+		sum := 0.0
+		for k := 1; k < len(power); k++ {
+			if f := float64(k) * binHz; f >= b[0] && f < b[1] {
+				sum += power[k]
+			}
+		}
+		out[i] = sum / total
+	}
+	out[len(bands)] = float64(peakBin) * binHz
+	return out
+}
+
+// rssiStats adalah mean, std, min, max RSSI; NaN bila tidak ada RSSI
+func rssiStats(rssi []float64) []float64 {
+	mean, lo, hi, n := 0.0, math.Inf(1), math.Inf(-1), 0
+	for _, v := range rssi {
+		if math.IsNaN(v) {
+			continue
+		}
+		mean += v
+		lo, hi = math.Min(lo, v), math.Max(hi, v)
+		n++
+	}
+	if n == 0 {
+		return []float64{math.NaN(), math.NaN(), math.NaN(), math.NaN()}
+	}
+	return []float64{mean / float64(n), stdIgnoreNaN(rssi), lo, hi}
+}
+
+// seq adalah 0..n-1
+func seq(n int) []int {
+	out := make([]int, n)
+	for i := range out {
+		out[i] = i
+	}
+	return out
+}
+
+// featureWindow adalah vektor fitur satu jendela; paket 1-based inklusif
+type featureWindow struct {
+	StartPacket int        `json:"start_packet"`
+	EndPacket   int        `json:"end_packet"`
+	StartTime   float64    `json:"start_s"`
+	EndTime     float64    `json:"end_s"`
+	Values      []*float64 `json:"values"`
+	raw         []float64  // nilai dengan NaN, untuk CSV job
+}
+
+// featureTable adalah hasil ekstraksi fitur satu capture
+type featureTable struct {
+	Set      *featureSet
+	Names    []string
+	Window   int
+	Hop      int
+	FS       float64
+	FSSource string
+	Windows  []featureWindow
+}
+
+// computeFeatures memotong capture menjadi jendela window paket dengan
+// pergeseran hop (window 0 = seluruh capture) lalu menghitung vektor fitur
+// set tiap jendela. Dipakai oleh endpoint fitur dan input job lokalisasi.
+func computeFeatures(csi *services.CSIMatrix, set *featureSet, window, hop int, fsRequested float64) (*featureTable, error) {
+	if window == 0 {
+		window = csi.Packets
+	}
+	if hop == 0 {
+		hop = window
+	}
+	if window < minFeatureWindow || window > csi.Packets {
+		return nil, &loadError{http.StatusBadRequest, fmt.Sprintf("window must be between %d and %d packets (0 = whole capture)", minFeatureWindow, csi.Packets)}
+	}
+	if hop < 1 {
+		return nil, &loadError{http.StatusBadRequest, "hop must be a positive integer"}
+	}
+	if n := (csi.Packets-window)/hop + 1; n > maxFeatureWindows {
+		return nil, &loadError{http.StatusBadRequest, fmt.Sprintf("window/hop yield %d windows; at most %d are allowed", n, maxFeatureWindows)}
+	}
+	fs, fsSource := samplingRate(fsRequested, csi)
+	t := &featureTable{
+		Set:      set,
+		Names:    set.names(csi.Layout.Streams, csi.Layout.Subcarriers),
+		Window:   window,
+		Hop:      hop,
+		FS:       fs,
+		FSSource: fsSource,
+	}
+	for start := 0; start+window <= csi.Packets; start += hop {
+		values := set.extract(csi.Slice(start, start+window), fs)
+		t.Windows = append(t.Windows, featureWindow{
+			StartPacket: start + 1,
+			EndPacket:   start + window,
+			StartTime:   packetTime(csi, start, fs, fsSource),
+			EndTime:     packetTime(csi, start+window-1, fs, fsSource),
+			Values:      nullable(values),
+			raw:         values,
+		})
+	}
+	return t, nil
+}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strings"
+	"testing"
+
+	"cetasense-v2.0/internal/models"
+	"cetasense-v2.0/internal/services"
+)
+
+// swayCapture: 1 stream × 2 subcarrier, 50 Hz, kedua subcarrier berayun
+// 5 Hz; subcarrier 2 dropout di paket pertama
+func swayCapture(packets int) *services.CSIMatrix {
+	const fs = 50.0
+	csi := &services.CSIMatrix{
+		Layout:     models.CSILayout{Streams: 1, Subcarriers: 2},
+		Packets:    packets,
+		Amplitude:  [][][]float64{{make([]float64, packets), make([]float64, packets)}},
+		Timestamps: make([]float64, packets),
+	}
+	for p := 0; p < packets; p++ {
+		t := float64(p) / fs
+		csi.Timestamps[p] = 100 + t
+		csi.Amplitude[0][0][p] = 10 + math.Sin(2*math.Pi*5*t)
+		csi.Amplitude[0][1][p] = 20 + 2*math.Sin(2*math.Pi*5*t+0.3)
+	}
+	csi.Amplitude[0][1][0] = 0
+	return csi
+}
+
+func TestLookupFeatureSet(t *testing.T) {
+	for _, ref := range []string{"", "csi", "csi@1"} {
+		s, err := lookupFeatureSet(ref)
+		if err != nil || s.ID() != "csi@1" {
+			t.Errorf("%q resolved to %v, %v", ref, s, err)
+		}
+	}
+	for _, ref := range []string{"csi@2", "wifi@1", "csi@"} {
+		if _, err := lookupFeatureSet(ref); err == nil {
+			t.Errorf("%q: expected error", ref)
+		}
+	}
+}
+
+func TestFeatureNames(t *testing.T) {
+	set, _ := lookupFeatureSet("csi@1")
+	names := set.names(2, 3)
+	// 5 statistik amplitudo + BNR per subcarrier, PCA, band Doppler + puncak, RSSI
+	want := 6*2*3 + set.PCAComponents + 1 + len(set.DopplerBands) + 1 + 4
+	if len(names) != want {
+		t.Fatalf("%d names, want %d", len(names), want)
+	}
+	if names[0] != "amp_mean_a1_s1" || names[2] != "amp_mean_a1_s3" || names[3] != "amp_mean_a2_s1" || names[6] != "amp_std_a1_s1" {
+		t.Errorf("per-subcarrier names not stream-major: %v", names[:7])
+	}
+	if names[len(names)-1] != "rssi_max" || names[36] != "pca_energy_1" {
+		t.Errorf("unexpected group order: %v", names[30:])
+	}
+	seen := map[string]bool{}
+	for _, n := range names {
+		if seen[n] || strings.ContainsAny(n, "{}") {
+			t.Errorf("name %q duplicated or not expanded", n)
+		}
+		seen[n] = true
+	}
+}
+
+func TestExtractFeatures(t *testing.T) {
+	set, _ := lookupFeatureSet("")
+	csi := swayCapture(100)
+	names := set.names(1, 2)
+	values := set.extract(csi, 50)
+	if len(values) != len(names) {
+		t.Fatalf("%d values for %d names", len(values), len(names))
+	}
+	byName := map[string]float64{}
+	for i, n := range names {
+		byName[n] = values[i]
+	}
+
+	if math.Abs(byName["amp_mean_a1_s1"]-10) > 1e-9 || math.Abs(byName["amp_mean_a1_s2"]-20) > 0.05 {
+		t.Errorf("amplitude means %g / %g", byName["amp_mean_a1_s1"], byName["amp_mean_a1_s2"])
+	}
+	if math.Abs(byName["amp_std_a1_s2"]-2*byName["amp_std_a1_s1"]) > 0.05 {
+		t.Errorf("amplitude std %g / %g, want a 1:2 ratio", byName["amp_std_a1_s1"], byName["amp_std_a1_s2"])
+	}
+	if byName["amp_valid_pct_a1_s1"] != 100 || byName["amp_valid_pct_a1_s2"] != 99 {
+		t.Errorf("valid share %g / %g, want 100 / 99", byName["amp_valid_pct_a1_s1"], byName["amp_valid_pct_a1_s2"])
+	}
+	if byName["pca_energy_1"] < 0.95 || !math.IsNaN(byName["pca_energy_3"]) {
+		t.Errorf("PCA energies %g, %g; want one dominant component of two", byName["pca_energy_1"], byName["pca_energy_3"])
+	}
+
+	// bin STFT 50/NFFT Hz; puncak harus jatuh di dekat 5 Hz
+	if peak := byName["doppler_peak_hz"]; math.Abs(peak-5) > 0.5 {
+		t.Errorf("Doppler peak at %g Hz, want about 5", peak)
+	}
+	if byName["doppler_2_5hz"]+byName["doppler_5_10hz"] < 0.8 {
+		t.Errorf("bands around 5 Hz hold only %g of the power", byName["doppler_2_5hz"]+byName["doppler_5_10hz"])
+	}
+	if !math.IsNaN(byName["doppler_20_50hz"]) {
+		t.Error("band above Nyquist should be null")
+	}
+	for _, n := range []string{"rssi_mean", "rssi_std", "rssi_min", "rssi_max"} {
+		if !math.IsNaN(byName[n]) {
+			t.Errorf("%s = %g without an RSSI column", n, byName[n])
+		}
+	}
+}
+
+func TestComputeFeaturesWindows(t *testing.T) {
+	set, _ := lookupFeatureSet("csi@1")
+	csi := swayCapture(100)
+
+	table, err := computeFeatures(csi, set, 40, 20, 0)
+	if err != nil {
+		t.Fatalf("computeFeatures: %v", err)
+	}
+	if table.FSSource != "timestamps" || math.Abs(table.FS-50) > 1e-6 {
+		t.Errorf("sampling rate %g from %s", table.FS, table.FSSource)
+	}
+	if len(table.Windows) != 4 {
+		t.Fatalf("%d windows, want 4", len(table.Windows))
+	}
+	last := table.Windows[3]
+	// waktu relatif terhadap paket pertama capture
+	if last.StartPacket != 61 || last.EndPacket != 100 || math.Abs(last.StartTime-1.2) > 1e-9 || math.Abs(last.EndTime-1.98) > 1e-9 {
+		t.Errorf("last window %+v", last)
+	}
+	if len(last.Values) != len(table.Names) || last.Values[len(last.Values)-1] != nil {
+		t.Error("values should line up with names and carry nulls for missing RSSI")
+	}
+
+	whole, err := computeFeatures(csi, set, 0, 0, 0)
+	if err != nil || len(whole.Windows) != 1 || whole.Window != 100 {
+		t.Errorf("window 0 should cover the whole capture: %+v, %v", whole, err)
+	}
+
+	var le *loadError
+	for _, bad := range [][2]int{{4, 1}, {101, 1}, {20, -1}} {
+		if _, err := computeFeatures(csi, set, bad[0], bad[1], 0); !errors.As(err, &le) || le.code != http.StatusBadRequest {
+			t.Errorf("window %d hop %d: %v", bad[0], bad[1], err)
+		}
+	}
+	if _, err := computeFeatures(swayCapture(maxFeatureWindows+minFeatureWindow), set, minFeatureWindow, 1, 0); err == nil {
+		t.Error("too many windows: expected error")
+	}
+}
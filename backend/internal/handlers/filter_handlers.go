@@ -1,9 +1,12 @@
 package handlers
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
 
+	"cetasense-v2.0/internal/dsp"
 	"cetasense-v2.0/internal/models"
 	"cetasense-v2.0/internal/repositories"
 	"github.com/go-playground/validator/v10"
@@ -35,8 +38,17 @@ func (h *FilterHandler) CreateFilter(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := dsp.ValidatePipeline(request.Pipeline); err != nil {
+		http.Error(w, "Validation error: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	filter := models.Filter{
 		NamaFilter: request.NamaFilter,
+		Pipeline:   request.Pipeline,
+	}
+	if filter.Pipeline == nil {
+		filter.Pipeline = []models.FilterStage{}
 	}
 	filter.GenerateID()
 
@@ -64,12 +76,23 @@ func (h *FilterHandler) UpdateFilter(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := dsp.ValidatePipeline(request.Pipeline); err != nil {
+		http.Error(w, "Validation error: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Pipeline yang tidak dikirim tetap; pipeline yang berubah menaikkan versi
 	filter := models.Filter{
 		ID:         id,
 		NamaFilter: request.NamaFilter,
+		Pipeline:   request.Pipeline,
 	}
 
 	if err := h.repo.Update(r.Context(), &filter); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Filter not found", http.StatusNotFound)
+			return
+		}
 		http.Error(w, "Failed to update filter: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -106,3 +129,20 @@ func (h *FilterHandler) GetAllFilter(w http.ResponseWriter, r *http.Request) {
 
 	respondJSON(w, http.StatusOK, filters)
 }
+
+// GetFilterVersions mengembalikan riwayat pipeline sebuah filter
+func (h *FilterHandler) GetFilterVersions(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	versions, err := h.repo.GetVersions(r.Context(), id)
+	if err != nil {
+		http.Error(w, "Failed to get filter versions: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if len(versions) == 0 {
+		http.Error(w, "Filter not found", http.StatusNotFound)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, versions)
+}
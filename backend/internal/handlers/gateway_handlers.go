@@ -16,6 +16,17 @@ type LocalizeRequest struct {
 	IDData    string `json:"id_data"`
 	IDMetode  string `json:"id_metode"`
 	IDRuangan string `json:"id_ruangan"`
+	// Input yang dipakai worker: raw (default), filtered, sanitized_phase,
+	// atau features
+	Input string `json:"input,omitempty"`
+	// Baseline ruangan untuk input filtered/sanitized_phase: kosong/auto
+	// (mode tersimpan), off, subtract, atau normalize
+	RoomBaseline string `json:"room_baseline,omitempty"`
+	// Untuk input features: set fitur (nama[@versi], kosong = default) dan
+	// jendela/pergeseran dalam paket (0 = seluruh capture)
+	FeatureSet    string `json:"feature_set,omitempty"`
+	FeatureWindow int    `json:"feature_window,omitempty"`
+	FeatureHop    int    `json:"feature_hop,omitempty"`
 }
 
 type LocalizeResponse struct {
@@ -24,7 +35,7 @@ type LocalizeResponse struct {
 	IDRuangan string `json:"id_ruangan"`
 }
 
-func NewLocalizeHandler(cfg *config.Config) http.HandlerFunc {
+func NewLocalizeHandler(cfg *config.Config, plots *PlotHandler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var req LocalizeRequest
 		reqID := r.Context().Value(middleware.ReqIDKey).(string)
@@ -32,9 +43,19 @@ func NewLocalizeHandler(cfg *config.Config) http.HandlerFunc {
 			http.Error(w, "Invalid request payload", http.StatusBadRequest)
 			return
 		}
+		if req.Input == "" {
+			req.Input = JobInputRaw
+		}
 		jobID := uuid.New().String()
+		// Input turunan (data terfilter, fase tersanitasi) disiapkan sebelum job dikirim
+		input, err := plots.PrepareJobInput(r.Context(), jobID, &req)
+		if err != nil {
+			respondLoadError(w, err)
+			return
+		}
 		conn, ch, err := rabbit.NewChannel(cfg)
 		if err != nil {
+			plots.RemoveJobInput(r.Context(), input)
 			http.Error(w, "Failed to connect to RabbitMQ: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
@@ -48,12 +69,32 @@ func NewLocalizeHandler(cfg *config.Config) http.HandlerFunc {
 			}
 		}()
 
+		var filterVersion *int
+		if input.Filter != nil && input.Filter.Applied {
+			filterVersion = &input.Filter.Version
+		}
+		var featureSet *string
+		if input.FeatureSet != "" {
+			featureSet = &input.FeatureSet
+		}
+		var baselineVersion *int
+		var baselineMode *string
+		if input.Baseline != nil && input.Baseline.Applied {
+			baselineVersion, baselineMode = &input.Baseline.Version, &input.Baseline.Mode
+		}
 		body, _ := json.Marshal(map[string]interface{}{
 			"req_id":     reqID,
 			"job_id":     jobID,
 			"id_data":    req.IDData,
 			"id_metode":  req.IDMetode,
 			"id_ruangan": req.IDRuangan,
+			"input":      req.Input,
+			// kosong bila worker memakai file CSI asli
+			"input_object_path": input.ObjectPath,
+			"filter_version":    filterVersion,
+			"baseline_version":  baselineVersion,
+			"baseline_mode":     baselineMode,
+			"feature_set":       featureSet,
 		})
 
 		err = ch.PublishWithContext(r.Context(), "", "lok_requests", false, false,
@@ -63,13 +104,18 @@ func NewLocalizeHandler(cfg *config.Config) http.HandlerFunc {
 			})
 		fmt.Println("Published message to RabbitMQ for localization job:", string(body))
 		if err != nil {
+			plots.RemoveJobInput(r.Context(), input)
 			http.Error(w, "Failed to publish message to RabbitMQ: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
-		jsonResponse := map[string]string{
-			"message": "Localization job started successfully",
-			"job_id":  jobID,
-			"status":  "queued",
+		jsonResponse := map[string]interface{}{
+			"message":     "Localization job started successfully",
+			"job_id":      jobID,
+			"status":      "queued",
+			"input":       req.Input,
+			"filter":      input.Filter,
+			"baseline":    input.Baseline,
+			"feature_set": featureSet,
 		}
 		w.Header().Set("Content-Type", "application/json")
 		if err := json.NewEncoder(w).Encode(jsonResponse); err != nil {
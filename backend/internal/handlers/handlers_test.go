@@ -0,0 +1,8 @@
+package handlers
+
+import "os"
+
+// init() paket ini memuat theta_scan.csv dan tau_scan.csv dari working
+// directory server. Variabel paket diinisialisasi sebelum init(), jadi
+// test pindah dulu ke cmd/server agar file tersebut ditemukan.
+var _ = func() error { return os.Chdir("../../cmd/server") }()
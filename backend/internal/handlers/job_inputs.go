@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/minio/minio-go/v7"
+
+	"cetasense-v2.0/internal/dsp"
+	"cetasense-v2.0/internal/models"
+	"cetasense-v2.0/internal/services"
+)
+
+// Jenis input job lokalisasi
+const (
+	JobInputRaw            = "raw"             // blok amplitudo tanpa filter dan baseline
+	JobInputFiltered       = "filtered"        // amplitudo setelah pipeline filter file
+	JobInputSanitizedPhase = "sanitized_phase" // fase tersanitasi per paket
+	JobInputFeatures       = "features"        // vektor fitur per jendela (set fitur berversi)
+)
+
+// jobInputDropout adalah nilai sel dropout pada CSV input job amplitudo dan
+// fase. Model lokalisasi dilatih dengan CSV capture asli, yang mencatat
+// dropout sebagai amplitudo 0, sehingga NaN tidak pernah dikirim ke worker.
+const jobInputDropout = 0.0
+
+// Bentuk blok amplitudo yang dibaca worker untuk input raw dan filtered
+// (reshape per paket menjadi stream × subcarrier)
+const (
+	jobInputStreams     = 3
+	jobInputSubcarriers = 30
+)
+
+// checkJobInputShape menolak layout yang tidak bisa dibaca worker sebagai
+// blok amplitudo 3 × 30
+func checkJobInputShape(l *models.CSILayout) error {
+	if l.Streams != jobInputStreams || l.Subcarriers != jobInputSubcarriers {
+		return &loadError{http.StatusUnprocessableEntity, fmt.Sprintf(
+			"input raw and filtered need a %d×%d capture; this file is %d×%d (use input features)",
+			jobInputStreams, jobInputSubcarriers, l.Streams, l.Subcarriers)}
+	}
+	return nil
+}
+
+// jobInput adalah input job lokalisasi yang sudah disiapkan
+type jobInput struct {
+	Kind       string
+	ObjectPath string           // kosong berarti worker membaca file CSI asli
+	Filter     *appliedFilter   // nil bila input tidak melewati filter
+	Baseline   *appliedBaseline // nil bila baseline ruangan tidak diterapkan
+	FeatureSet string           // ID set fitur untuk input features
+}
+
+// jobInputPath adalah object key input turunan untuk sebuah job
+func jobInputPath(jobID, input string) string {
+	return fmt.Sprintf("Job-Inputs/%s/%s.csv", jobID, input)
+}
+
+// PrepareJobInput menyiapkan input job lokalisasi untuk data dataID.
+// Input raw dan filtered berisi blok amplitudo 3 × 30 (amp_<stream>_<sc>);
+// filtered juga melewati pipeline filter dan baseline ruangan. File yang
+// punya layout tersimpan selalu ditulis ulang karena kolomnya (timestamp,
+// fase, RSSI) tidak bisa dibaca worker; hanya file lama tanpa layout yang
+// dipakai apa adanya bila tidak ada yang mengubah data. Input
+// sanitized_phase dikurangi offset fase baseline. Input features berisi satu baris per jendela dari computeFeatures, sama
+// dengan /api/plots/{id}/features. RoomBaseline mengikuti ?room_baseline=
+// analisis (kosong = mode tersimpan).
+func (h *PlotHandler) PrepareJobInput(ctx context.Context, jobID string, req *LocalizeRequest) (*jobInput, error) {
+	kind, dataID, baselineMode := req.Input, req.IDData, req.RoomBaseline
+	in := &jobInput{Kind: kind}
+	var matrix [][][]float64
+	var prefix string
+	var table *featureTable
+	switch kind {
+	case JobInputRaw, JobInputFiltered:
+		meta, err := h.loadMeta(ctx, dataID)
+		if err != nil {
+			return nil, err
+		}
+		if meta.Layout != nil {
+			if err := checkJobInputShape(meta.Layout); err != nil {
+				return nil, err
+			}
+		}
+		if kind == JobInputFiltered {
+			if in.Filter, err = h.fileFilter(ctx, meta, false); err != nil {
+				return nil, err
+			}
+			if in.Baseline, err = h.resolveBaseline(ctx, meta, baselineMode, in.Filter); err != nil {
+				return nil, err
+			}
+		}
+		if meta.Layout == nil && (in.Filter == nil || !in.Filter.Applied) && (in.Baseline == nil || !in.Baseline.Applied) {
+			return in, nil
+		}
+		csi, _, err := h.prepareMatrix(ctx, meta, in.Filter, nil, in.Baseline)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkJobInputShape(&csi.Layout); err != nil {
+			return nil, err
+		}
+		matrix, prefix = csi.Amplitude, "amp"
+	case JobInputSanitizedPhase:
+		meta, csi, err := h.loadCSI(ctx, dataID)
+		if err != nil {
+			return nil, err
+		}
+		if csi.Phase == nil {
+			return nil, &loadError{http.StatusUnprocessableEntity, "CSI layout has no phase data (encoding " + csi.Layout.Encoding + ")"}
+		}
+		if in.Baseline, err = h.resolvePhaseBaseline(ctx, meta, baselineMode); err != nil {
+			return nil, err
+		}
+		if in.Baseline != nil {
+			if err := checkBaselineShape(csi, in.Baseline); err != nil {
+				return nil, err
+			}
+		}
+		matrix, _ = dsp.SanitizePhase(csi.Phase, csi.Layout.SubcarrierIndices())
+		removeBaselinePhase(matrix, in.Baseline)
+		prefix = "phase"
+	case JobInputFeatures:
+		set, err := lookupFeatureSet(req.FeatureSet)
+		if err != nil {
+			return nil, &loadError{http.StatusBadRequest, err.Error()}
+		}
+		if req.FeatureWindow < 0 || req.FeatureHop < 0 {
+			return nil, &loadError{http.StatusBadRequest, "feature_window and feature_hop must be non-negative"}
+		}
+		meta, err := h.loadMeta(ctx, dataID)
+		if err != nil {
+			return nil, err
+		}
+		if in.Filter, err = h.fileFilter(ctx, meta, false); err != nil {
+			return nil, err
+		}
+		if in.Baseline, err = h.resolveBaseline(ctx, meta, baselineMode, in.Filter); err != nil {
+			return nil, err
+		}
+		csi, _, err := h.prepareMatrix(ctx, meta, in.Filter, nil, in.Baseline)
+		if err != nil {
+			return nil, err
+		}
+		if table, err = computeFeatures(csi, set, req.FeatureWindow, req.FeatureHop, 0); err != nil {
+			return nil, err
+		}
+		in.FeatureSet = set.ID()
+	default:
+		return nil, &loadError{http.StatusBadRequest, fmt.Sprintf("Unknown input %q (expected %s, %s, %s or %s)",
+			kind, JobInputRaw, JobInputFiltered, JobInputSanitizedPhase, JobInputFeatures)}
+	}
+
+	objectPath := jobInputPath(jobID, kind)
+	pr, pw := io.Pipe()
+	go func() {
+		if table != nil {
+			pw.CloseWithError(writeFeatureCSV(pw, table))
+			return
+		}
+		pw.CloseWithError(services.WriteMatrixCSV(pw, prefix, matrix, jobInputDropout))
+	}()
+	_, err := h.minioClient.PutObject(ctx, h.bucketName, objectPath, pr, -1,
+		minio.PutObjectOptions{ContentType: "text/csv", PartSize: uploadPartSize})
+	pr.CloseWithError(err)
+	if err != nil {
+		return nil, &loadError{http.StatusInternalServerError, "Failed to store job input: " + err.Error()}
+	}
+	log.Printf("Job %s input %s stored at %s", jobID, kind, objectPath)
+	in.ObjectPath = objectPath
+	return in, nil
+}
+
+// RemoveJobInput menghapus input turunan yang tidak jadi dipakai (best effort)
+func (h *PlotHandler) RemoveJobInput(ctx context.Context, in *jobInput) {
+	if in.ObjectPath == "" {
+		return
+	}
+	if err := h.minioClient.RemoveObject(ctx, h.bucketName, in.ObjectPath, minio.RemoveObjectOptions{}); err != nil {
+		log.Printf("MinIO RemoveObject(%s) error: %v", in.ObjectPath, err)
+	}
+}
+
+// writeFeatureCSV menulis tabel fitur satu baris per jendela dengan kolom
+// start_packet, end_packet, start_s, end_s diikuti nama fitur
+func writeFeatureCSV(w io.Writer, t *featureTable) error {
+	header := append([]string{"start_packet", "end_packet", "start_s", "end_s"}, t.Names...)
+	rows := make([][]float64, len(t.Windows))
+	for i, win := range t.Windows {
+		rows[i] = append([]float64{float64(win.StartPacket), float64(win.EndPacket), win.StartTime, win.EndTime}, win.raw...)
+	}
+	return services.WriteTableCSV(w, header, rows)
+}
@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"cetasense-v2.0/internal/services"
+)
+
+func TestCheckJobInputShape(t *testing.T) {
+	intel := services.CaptureLayout(3, 30)
+	if err := checkJobInputShape(&intel); err != nil {
+		t.Errorf("3×30 capture rejected: %v", err)
+	}
+	var le *loadError
+	for _, shape := range [][2]int{{2, 30}, {1, 64}, {3, 56}} {
+		l := services.CaptureLayout(shape[0], shape[1])
+		if err := checkJobInputShape(&l); !errors.As(err, &le) || le.code != http.StatusUnprocessableEntity {
+			t.Errorf("%d×%d: got %v, want a 422 loadError", shape[0], shape[1], err)
+		}
+	}
+}
+
+func TestPrepareJobInputUnknownKind(t *testing.T) {
+	_, err := (&PlotHandler{}).PrepareJobInput(context.Background(), "job", &LocalizeRequest{Input: "spectrogram"})
+	var le *loadError
+	if !errors.As(err, &le) || le.code != http.StatusBadRequest {
+		t.Errorf("got %v, want a 400 loadError", err)
+	}
+}
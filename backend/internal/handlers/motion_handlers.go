@@ -0,0 +1,318 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+
+	"cetasense-v2.0/internal/dsp"
+	"cetasense-v2.0/internal/models"
+	"cetasense-v2.0/internal/repositories"
+	"cetasense-v2.0/internal/services"
+)
+
+// default deteksi gerakan
+const (
+	defaultMotionWindow  = 64
+	defaultMotionK       = 3
+	defaultMotionMinSegs = 2
+	maxMotionEvents      = 1000
+)
+
+// Sumber threshold deteksi gerakan
+const (
+	motionThresholdQuery       = "query"
+	motionThresholdCalibration = "calibration"
+	motionThresholdAuto        = "auto"
+)
+
+type MotionHandler struct {
+	plots    *PlotHandler
+	repo     *repositories.MotionRepository
+	validate *validator.Validate
+}
+
+func NewMotionHandler(plots *PlotHandler, repo *repositories.MotionRepository) *MotionHandler {
+	return &MotionHandler{plots: plots, repo: repo, validate: validator.New()}
+}
+
+// motionDefaults mengisi field request yang kosong
+func motionDefaults(req *models.MotionRequest) {
+	if req.Method == "" {
+		req.Method = dsp.MotionVariance
+	}
+	if req.Window == 0 {
+		req.Window = defaultMotionWindow
+	}
+	if req.Hop == 0 {
+		req.Hop = max(1, req.Window/2)
+	}
+	if req.K == 0 {
+		req.K = defaultMotionK
+	}
+	if req.MinSegmentWindows == 0 {
+		req.MinSegmentWindows = defaultMotionMinSegs
+	}
+}
+
+// motionThreshold menentukan threshold: dari request, dari skor jendela
+// yang seluruhnya berada di rentang kalibrasi, atau otomatis dari separuh
+// skor terendah (diasumsikan capture sebagian besar diam)
+func motionThreshold(req *models.MotionRequest, scores *dsp.MotionScores) (threshold, scale float64, source string, err error) {
+	_, scale = dsp.RobustThreshold(scores.Scores, req.K)
+	switch {
+	case req.Threshold != nil:
+		return *req.Threshold, scale, motionThresholdQuery, nil
+	case req.Calibration != nil:
+		lo, hi := req.Calibration.StartPacket-1, req.Calibration.EndPacket
+		var ref []float64
+		for i, start := range scores.Starts {
+			if start >= lo && start+req.Window <= hi {
+				ref = append(ref, scores.Scores[i])
+			}
+		}
+		if len(ref) == 0 {
+			return 0, 0, "", fmt.Errorf("calibration range %d-%d does not contain a full window of %d packets",
+				req.Calibration.StartPacket, req.Calibration.EndPacket, req.Window)
+		}
+		threshold, scale = dsp.RobustThreshold(ref, req.K)
+		return threshold, scale, motionThresholdCalibration, nil
+	default:
+		sorted := append([]float64(nil), scores.Scores...)
+		sort.Float64s(sorted)
+		threshold, scale = dsp.RobustThreshold(sorted[:(len(sorted)+1)/2], req.K)
+		return threshold, scale, motionThresholdAuto, nil
+	}
+}
+
+// packetTime adalah waktu paket p (detik sejak awal capture)
+func packetTime(csi *services.CSIMatrix, p int, fs float64, fsSource string) float64 {
+	if fsSource == "timestamps" && p < len(csi.Timestamps) && !math.IsNaN(csi.Timestamps[p]) && !math.IsNaN(csi.Timestamps[0]) {
+		return csi.Timestamps[p] - csi.Timestamps[0]
+	}
+	return float64(p) / fs
+}
+
+// DetectMotion menjalankan detektor gerakan jendela geser pada amplitudo
+// sebuah capture dan mengembalikan skor per jendela serta timeline segmen
+// motion/static. Segmen disimpan sebagai event ruangan file tersebut
+// kecuali persist=false.
+func (h *MotionHandler) DetectMotion(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	req := models.MotionRequest{}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+			return
+		}
+	}
+	if err := h.validate.Struct(req); err != nil {
+		respondError(w, http.StatusBadRequest, "Validation error: "+err.Error())
+		return
+	}
+	motionDefaults(&req)
+	if req.Hop > req.Window {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("hop must be between 1 and window (%d)", req.Window))
+		return
+	}
+
+	rs, err := newResampleParams(req.Resample, req.ResampleRate, req.ResampleMaxGap)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	raw := req.Filtered != nil && !*req.Filtered
+	meta, err := h.plots.loadMeta(ctx, mux.Vars(r)["id"])
+	if err != nil {
+		respondLoadError(w, err)
+		return
+	}
+	filter, err := h.plots.fileFilter(ctx, meta, raw)
+	if err != nil {
+		respondLoadError(w, err)
+		return
+	}
+	bl, err := h.plots.resolveBaseline(ctx, meta, req.RoomBaseline, filter)
+	if err != nil {
+		respondLoadError(w, err)
+		return
+	}
+	csi, resampled, err := h.plots.prepareMatrix(ctx, meta, filter, rs, bl)
+	if err != nil {
+		respondLoadError(w, err)
+		return
+	}
+	streams, err := parseIndexList(req.Streams, csi.Layout.Streams)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "streams: "+err.Error())
+		return
+	}
+	subcarriers, err := parseIndexList(req.Subcarriers, csi.Layout.Subcarriers)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "subcarriers: "+err.Error())
+		return
+	}
+	if req.Calibration != nil && req.Calibration.EndPacket > csi.Packets {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("calibration end_packet must be at most %d", csi.Packets))
+		return
+	}
+
+	series, _ := selectSeries(csi, streams, subcarriers)
+	if len(series) == 0 {
+		respondError(w, http.StatusUnprocessableEntity, "Selected subcarriers have no valid samples")
+		return
+	}
+	scores, err := dsp.MotionStatistic(series, req.Method, req.Window, req.Hop)
+	if err != nil {
+		respondError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	threshold, scale, source, err := motionThreshold(&req, scores)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	probs, segs := dsp.SegmentMotion(scores.Scores, threshold, scale, req.MinSegmentWindows)
+
+	// Batas segmen di tengah overlap jendela; segmen terakhir sampai akhir
+	// capture
+	fs, fsSource := samplingRate(req.FS, csi)
+	run := &models.MotionRun{
+		FileID:    meta.ID,
+		RuanganID: meta.RuanganID,
+		Method:    req.Method,
+		Threshold: threshold,
+		Source:    source,
+	}
+	edge := func(win int) int {
+		if win >= len(scores.Starts) {
+			return csi.Packets
+		}
+		if win == 0 {
+			return 0
+		}
+		return scores.Starts[win] + (req.Window-req.Hop)/2
+	}
+	for _, s := range segs {
+		state := models.MotionStateStatic
+		if s.Motion {
+			state = models.MotionStateMotion
+		}
+		start, end := edge(s.Start), edge(s.End)
+		run.Events = append(run.Events, &models.MotionEvent{
+			RuanganID:   meta.RuanganID,
+			FileID:      meta.ID,
+			State:       state,
+			StartPacket: start + 1,
+			EndPacket:   end,
+			StartTime:   packetTime(csi, start, fs, fsSource),
+			EndTime:     packetTime(csi, end-1, fs, fsSource),
+			Confidence:  s.Confidence,
+			PeakScore:   s.PeakScore,
+		})
+	}
+
+	persist := req.Persist == nil || *req.Persist
+	if persist {
+		params, err := json.Marshal(req)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "Failed to encode parameters: "+err.Error())
+			return
+		}
+		run.Params = string(params)
+		run.GenerateID()
+		if err := h.repo.CreateRun(ctx, run); err != nil {
+			respondError(w, http.StatusInternalServerError, "Failed to save motion events: "+err.Error())
+			return
+		}
+	}
+
+	times := make([]float64, len(scores.Starts))
+	for i, start := range scores.Starts {
+		times[i] = packetTime(csi, start+req.Window/2, fs, fsSource)
+	}
+	motionWindows := 0
+	for _, s := range segs {
+		if s.Motion {
+			motionWindows += s.End - s.Start
+		}
+	}
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"meta": map[string]interface{}{
+			"run_id":              run.ID,
+			"persisted":           persist,
+			"method":              req.Method,
+			"window":              req.Window,
+			"hop":                 req.Hop,
+			"k":                   req.K,
+			"min_segment_windows": req.MinSegmentWindows,
+			"calibration":         req.Calibration,
+			"threshold":           threshold,
+			"threshold_source":    source,
+			"scale":               scale,
+			"streams":             oneBased(streams),
+			"subcarriers":         oneBased(subcarriers),
+			"series":              len(series),
+			"fs":                  fs,
+			"fs_source":           fsSource,
+			"packets":             csi.Packets,
+			"windows":             len(scores.Starts),
+			"motion_ratio":        float64(motionWindows) / float64(len(scores.Starts)),
+			"ruangan_id":          meta.RuanganID,
+			"layout":              csi.Layout,
+			"filter":              filter,
+			"resample":            resampled,
+			"baseline":            bl,
+		},
+		"times":       times,
+		"scores":      scores.Scores,
+		"probability": probs,
+		"segments":    run.Events,
+	})
+}
+
+// GetRoomMotionEvents mendaftar event gerakan tersimpan sebuah ruangan
+// (?file_id=, ?state=motion|static, ?limit= opsional)
+func (h *MotionHandler) GetRoomMotionEvents(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	state := q.Get("state")
+	if state != "" && state != models.MotionStateMotion && state != models.MotionStateStatic {
+		respondError(w, http.StatusBadRequest, "state must be motion or static")
+		return
+	}
+	limit, err := queryInt(r, "limit", 200)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if limit < 1 || limit > maxMotionEvents {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxMotionEvents))
+		return
+	}
+	roomID := mux.Vars(r)["id"]
+	events, err := h.repo.ListEvents(r.Context(), roomID, q.Get("file_id"), state, limit)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to get motion events: "+err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"ruangan_id": roomID,
+		"events":     events,
+		"count":      len(events),
+	})
+}
+
+// RemoveFileEvents membuang run dan event gerakan file yang dihapus
+func (h *MotionHandler) RemoveFileEvents(ctx context.Context, f *models.CSI_File) {
+	if err := h.repo.DeleteForFile(ctx, f.ID); err != nil {
+		log.Printf("[Motion] remove events for %s error: %v", f.ID, err)
+	}
+}
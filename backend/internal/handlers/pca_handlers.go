@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"cetasense-v2.0/internal/dsp"
+)
+
+// pcaParams adalah parameter /api/plots/{id}/pca yang sudah divalidasi
+type pcaParams struct {
+	Streams     []int // 0-based
+	Subcarriers []int // 0-based
+	Components  int
+	DropFirst   bool
+	Raw         bool
+	Resample    *resampleParams
+}
+
+func (p *pcaParams) canonical() string {
+	return fmt.Sprintf("st=%v;sc=%v;n=%d;drop=%t;raw=%t;%s", p.Streams, p.Subcarriers, p.Components, p.DropFirst, p.Raw, p.Resample.canonical())
+}
+
+func parsePCAParams(r *http.Request, sel *subcarrierSelection) (*pcaParams, error) {
+	q := r.URL.Query()
+	p := &pcaParams{
+		DropFirst: q.Get("drop_first") == "true",
+		Raw:       q.Get("filtered") == "false",
+	}
+	var err error
+	p.Streams, p.Subcarriers = sel.Streams, sel.Subcarriers
+	if p.Components, err = queryInt(r, "components", 3); err != nil {
+		return nil, err
+	}
+	if p.Components < 1 || p.Components > maxPCAComponent {
+		return nil, fmt.Errorf("components must be between 1 and %d", maxPCAComponent)
+	}
+	if p.Resample, err = parseResample(r); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// GetPCA menjalankan PCA (kovarians + dekomposisi eigen) atas matriks
+// amplitudo tersanitasi [packet][stream×subcarrier]: pipeline filter file
+// sudah diterapkan dan dropout diisi interpolasi. Mengembalikan rasio
+// variansi, deret waktu N komponen pertama, dan loading per antena dan
+// subcarrier. drop_first=true membuang komponen pertama (jalur statis)
+// sehingga komponen yang dikembalikan dimulai dari PC2.
+func (h *PlotHandler) GetPCA(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := mux.Vars(r)["id"]
+	meta, err := h.loadMeta(ctx, id)
+	if err != nil {
+		respondLoadError(w, err)
+		return
+	}
+	sel, err := h.resolveSelection(ctx, meta, r.URL.Query())
+	if err != nil {
+		respondLoadError(w, err)
+		return
+	}
+	params, err := parsePCAParams(r, sel)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	filter, err := h.fileFilter(ctx, meta, params.Raw)
+	if err != nil {
+		respondLoadError(w, err)
+		return
+	}
+	bl, err := h.resolveBaseline(ctx, meta, r.URL.Query().Get("room_baseline"), filter)
+	if err != nil {
+		respondLoadError(w, err)
+		return
+	}
+	key := analysisKey("pca", meta, filter, params.canonical()+";"+bl.canonical())
+	if h.cachedAnalysis(ctx, w, key) {
+		return
+	}
+
+	csi, resampled, err := h.prepareMatrix(ctx, meta, filter, params.Resample, bl)
+	if err != nil {
+		respondLoadError(w, err)
+		return
+	}
+
+	vars, refs := selectSeries(csi, params.Streams, params.Subcarriers)
+	first := 0
+	if params.DropFirst {
+		first = 1
+	}
+	if len(vars) < first+1 {
+		respondError(w, http.StatusUnprocessableEntity, "Selected subcarriers have too few valid series for PCA")
+		return
+	}
+	obs := transpose(vars)
+	pca, err := dsp.PCA(obs)
+	if err != nil {
+		respondError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	C, S := csi.Layout.Streams, csi.Layout.Subcarriers
+	type componentResp struct {
+		Component      int          `json:"component"` // 1-based, sesuai urutan PCA penuh
+		Eigenvalue     float64      `json:"eigenvalue"`
+		ExplainedRatio float64      `json:"explained_ratio"`
+		Series         []float64    `json:"series"`
+		Loadings       [][]*float64 `json:"loadings"` // [antenna][subcarrier], null = tidak dipilih
+	}
+	last := min(first+params.Components, len(pca.Components))
+	components := make([]componentResp, 0, last-first)
+	for k := first; k < last; k++ {
+		loadings := make([][]*float64, C)
+		for c := range loadings {
+			loadings[c] = make([]*float64, S)
+		}
+		for j, ref := range refs {
+			v := pca.Components[k][j]
+			loadings[ref.Stream][ref.Subcarrier] = &v
+		}
+		components = append(components, componentResp{
+			Component:      k + 1,
+			Eigenvalue:     pca.Eigenvalues[k],
+			ExplainedRatio: pca.ExplainedRatio[k],
+			Series:         pca.Project(obs, k),
+			Loadings:       loadings,
+		})
+	}
+
+	// Rasio variansi tanpa komponen yang dibuang, untuk membandingkan
+	// komponen dinamis satu sama lain
+	var remaining []float64
+	if params.DropFirst {
+		rest := 1 - pca.ExplainedRatio[0]
+		for _, ratio := range pca.ExplainedRatio[1:] {
+			if rest > 0 {
+				remaining = append(remaining, ratio/rest)
+			} else {
+				remaining = append(remaining, 0)
+			}
+		}
+	}
+
+	used := make([][2]int, len(refs))
+	for j, ref := range refs {
+		used[j] = [2]int{ref.Stream + 1, ref.Subcarrier + 1}
+	}
+	m := map[string]interface{}{
+		"method":      "PCA over sanitized amplitude (covariance + eigendecomposition)",
+		"streams":     oneBased(params.Streams),
+		"subcarriers": oneBased(params.Subcarriers),
+		"series":      len(vars),
+		"series_used": used,
+		"packets":     csi.Packets,
+		"drop_first":  params.DropFirst,
+		"layout":      csi.Layout,
+		"filter":      filter,
+		"resample":    resampled,
+		"baseline":    bl,
+	}
+	resp := map[string]interface{}{
+		"meta":            m,
+		"explained_ratio": pca.ExplainedRatio,
+		"components":      components,
+	}
+	if params.DropFirst {
+		resp["dropped"] = map[string]interface{}{
+			"component":       1,
+			"explained_ratio": pca.ExplainedRatio[0],
+		}
+		resp["explained_ratio_without_first"] = remaining
+	}
+	h.respondAnalysis(ctx, w, key, resp)
+}
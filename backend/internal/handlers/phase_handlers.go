@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"cetasense-v2.0/internal/dsp"
+)
+
+// batas jumlah paket per respons fase
+const (
+	defaultPhasePackets = 200
+	maxPhasePackets     = 2000
+)
+
+// GetPhase mengembalikan fase mentah dan fase tersanitasi (unwrap lintas
+// subcarrier lalu suku linear STO/CFO dibuang) per antena. Matriks berbentuk
+// [packet][subcarrier]; jendela paket diatur lewat ?offset= dan ?limit=.
+func (h *PlotHandler) GetPhase(w http.ResponseWriter, r *http.Request) {
+	offset, err := queryInt(r, "offset", 0)
+	if err != nil || offset < 0 {
+		respondError(w, http.StatusBadRequest, "offset must be a non-negative integer")
+		return
+	}
+	limit, err := queryInt(r, "limit", defaultPhasePackets)
+	if err != nil || limit < 1 || limit > maxPhasePackets {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("limit must be an integer between 1 and %d", maxPhasePackets))
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+	_, csi, err := h.loadCSI(r.Context(), id)
+	if err != nil {
+		respondLoadError(w, err)
+		return
+	}
+	if csi.Phase == nil {
+		respondError(w, http.StatusUnprocessableEntity, "CSI layout has no phase data (encoding "+csi.Layout.Encoding+")")
+		return
+	}
+
+	P := csi.Packets
+	from := min(offset, P)
+	to := min(from+limit, P)
+	window := make([][][]float64, len(csi.Phase))
+	for c := range csi.Phase {
+		window[c] = make([][]float64, len(csi.Phase[c]))
+		for s := range csi.Phase[c] {
+			window[c][s] = csi.Phase[c][s][from:to]
+		}
+	}
+	indices := csi.Layout.SubcarrierIndices()
+	sanitized, fits := dsp.SanitizePhase(window, indices)
+
+	type antennaResp struct {
+		Antenna   int          `json:"antenna"`
+		Raw       [][]*float64 `json:"raw"`
+		Sanitized [][]*float64 `json:"sanitized"`
+		Slope     []float64    `json:"slope"`
+		Intercept []float64    `json:"intercept"`
+	}
+	S := csi.Layout.Subcarriers
+	antennas := make([]antennaResp, len(window))
+	for c := range window {
+		a := antennaResp{Antenna: c + 1}
+		for p := 0; p < to-from; p++ {
+			raw := make([]float64, S)
+			clean := make([]float64, S)
+			for s := 0; s < S; s++ {
+				raw[s] = window[c][s][p]
+				clean[s] = sanitized[c][s][p]
+			}
+			a.Raw = append(a.Raw, nullable(raw))
+			a.Sanitized = append(a.Sanitized, nullable(clean))
+			a.Slope = append(a.Slope, fits[c][p].Slope)
+			a.Intercept = append(a.Intercept, fits[c][p].Intercept)
+		}
+		antennas[c] = a
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"meta": map[string]interface{}{
+			"method":             "unwrap across subcarriers, remove least-squares linear term over OFDM subcarrier index (null/DC bins excluded)",
+			"subcarrier_indices": nullable(indices),
+			"antennas":           len(window),
+			"subcarriers":        S,
+			"packets":            P,
+			"offset":             from,
+			"returned":           to - from,
+			"layout":             csi.Layout,
+			"units":              "rad",
+			"matrix_shape":       "[packet][subcarrier]",
+		},
+		"antennas": antennas,
+	})
+}
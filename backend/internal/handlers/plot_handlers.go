@@ -1,30 +1,47 @@
 package handlers
 
 import (
-	"encoding/csv"
-	"io"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"math"
 	"net/http"
 	"sort"
 	"strconv"
-	"strings"
 
 	"github.com/gorilla/mux"
 	"github.com/minio/minio-go/v7"
 
+	"github.com/go-playground/validator/v10"
+
+	"cetasense-v2.0/cache"
+	"cetasense-v2.0/internal/dsp"
+	"cetasense-v2.0/internal/models"
 	"cetasense-v2.0/internal/repositories"
+	"cetasense-v2.0/internal/services"
 )
 
 // ---------------- Handler wiring ----------------
 
 type PlotHandler struct {
-	csvRepo     *repositories.CSVFileRepository
-	minioClient *minio.Client
-	bucketName  string
+	csvRepo    *repositories.CSVFileRepository
+	filterRepo *repositories.FilterRepository
+	presetRepo *repositories.AnalysisPresetRepository
+	// artifact analisis permanen; nil menonaktifkan penyimpanan artifact
+	artifactRepo *repositories.AnalysisArtifactRepository
+	// baseline ruangan; nil menonaktifkan penerapan baseline
+	baselineRepo *repositories.BaselineRepository
+	cache        *cache.Client // hasil analisis; nil menonaktifkan cache
+	minioClient  *minio.Client
+	bucketName   string
+	// semaphore precompute latar belakang
+	precompute chan struct{}
 }
 
-func NewPlotHandler(csvRepo *repositories.CSVFileRepository, minioClient *minio.Client, bucket string) *PlotHandler {
-	return &PlotHandler{csvRepo: csvRepo, minioClient: minioClient, bucketName: bucket}
+func NewPlotHandler(csvRepo *repositories.CSVFileRepository, filterRepo *repositories.FilterRepository, presetRepo *repositories.AnalysisPresetRepository, artifactRepo *repositories.AnalysisArtifactRepository, baselineRepo *repositories.BaselineRepository, cacheClient *cache.Client, minioClient *minio.Client, bucket string) *PlotHandler {
+	return &PlotHandler{csvRepo: csvRepo, filterRepo: filterRepo, presetRepo: presetRepo, artifactRepo: artifactRepo, baselineRepo: baselineRepo,
+		cache: cacheClient, minioClient: minioClient, bucketName: bucket, precompute: make(chan struct{}, maxPrecomputeJobs)}
 }
 
 func (h *PlotHandler) ListCSV(w http.ResponseWriter, r *http.Request) {
@@ -38,16 +55,137 @@ func (h *PlotHandler) ListCSV(w http.ResponseWriter, r *http.Request) {
 
 // ---------------- BNR CONFIG ----------------
 
-const (
-	numChannels         = 3
-	numSubcarriers      = 30
-	percentileBaseline  = 15.0 // perc-15 per subcarrier (lintas waktu)
-	dropoutRatio        = 5e-4 // A/N < 5e-4 => masked
-	clipDbLo            = -20.0
-	clipDbHi            = 20.0
-	minGapSubcarrier    = 3   // non-redundant spacing antar subcarrier
-	maxAbsCorrThreshold = 0.8 // non-redundant korelasi |rho| < 0.8
-)
+var bnrValidate = validator.New()
+
+// bnrQueryParams memetakan nama query ke field BNRParams (float atau int)
+var bnrQueryParams = []struct {
+	name  string
+	field func(p *models.BNRParams) any
+}{
+	{"percentile_baseline", func(p *models.BNRParams) any { return &p.PercentileBaseline }},
+	{"dropout_ratio", func(p *models.BNRParams) any { return &p.DropoutRatio }},
+	{"clip_db_lo", func(p *models.BNRParams) any { return &p.ClipDbLo }},
+	{"clip_db_hi", func(p *models.BNRParams) any { return &p.ClipDbHi }},
+	{"min_gap_subcarrier", func(p *models.BNRParams) any { return &p.MinGapSubcarrier }},
+	{"max_abs_corr", func(p *models.BNRParams) any { return &p.MaxAbsCorrThreshold }},
+	{"top_k", func(p *models.BNRParams) any { return &p.TopK }},
+}
+
+// resolveBNRParams menyusun parameter efektif: default, lalu preset
+// (?preset=<nama atau id>), lalu query per parameter. Mengembalikan nama
+// preset yang dipakai ("" bila tidak ada).
+func (h *PlotHandler) resolveBNRParams(r *http.Request) (models.BNRParams, string, error) {
+	params := models.DefaultBNRParams()
+	q := r.URL.Query()
+	presetName := ""
+	if ref := q.Get("preset"); ref != "" {
+		preset, err := h.presetRepo.GetByName(r.Context(), ref)
+		if errors.Is(err, sql.ErrNoRows) {
+			preset, err = h.presetRepo.GetByID(r.Context(), ref)
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			return params, "", &loadError{http.StatusNotFound, "Analysis preset not found: " + ref}
+		}
+		if err != nil {
+			return params, "", &loadError{http.StatusInternalServerError, "Failed to load analysis preset: " + err.Error()}
+		}
+		params, presetName = preset.Params, preset.Name
+	}
+	for _, qp := range bnrQueryParams {
+		v := q.Get(qp.name)
+		if v == "" {
+			continue
+		}
+		switch f := qp.field(&params).(type) {
+		case *float64:
+			n, err := strconv.ParseFloat(v, 64)
+			if err != nil || math.IsNaN(n) || math.IsInf(n, 0) {
+				return params, "", &loadError{http.StatusBadRequest, fmt.Sprintf("%s must be a number", qp.name)}
+			}
+			*f = n
+		case *int:
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return params, "", &loadError{http.StatusBadRequest, fmt.Sprintf("%s must be an integer", qp.name)}
+			}
+			*f = n
+		}
+	}
+	if err := bnrValidate.Struct(params); err != nil {
+		return params, "", &loadError{http.StatusBadRequest, "Invalid analysis parameters: " + err.Error()}
+	}
+	return params, presetName, nil
+}
+
+// ---------------- Downsampling ----------------
+
+const maxPlotPoints = 1_000_000
+
+// parseDownsample membaca ?max_points= (0 = tanpa downsampling) dan
+// ?downsample=lttb|minmax
+func parseDownsample(r *http.Request) (int, string, error) {
+	maxPoints, err := queryInt(r, "max_points", 0)
+	if err != nil || maxPoints < 0 || maxPoints > maxPlotPoints {
+		return 0, "", fmt.Errorf("max_points must be an integer between 0 and %d", maxPlotPoints)
+	}
+	method := r.URL.Query().Get("downsample")
+	if method == "" {
+		method = dsp.DownsampleLTTB
+	}
+	switch method {
+	case dsp.DownsampleLTTB:
+		if maxPoints > 0 && maxPoints < 3 {
+			return 0, "", fmt.Errorf("max_points must be at least 3 for lttb")
+		}
+	case dsp.DownsampleMinMax:
+		if maxPoints > 0 && maxPoints < 2 {
+			return 0, "", fmt.Errorf("max_points must be at least 2 for minmax")
+		}
+	default:
+		return 0, "", fmt.Errorf("downsample must be lttb or minmax")
+	}
+	return maxPoints, method, nil
+}
+
+// downsampleSeries memperkecil deret BNR; celah masked menjadi null
+func downsampleSeries(x []float64, maxPoints int, method string) ([]*float64, []int) {
+	var samples []dsp.Sample
+	if method == dsp.DownsampleMinMax {
+		samples = dsp.MinMax(x, maxPoints)
+	} else {
+		samples = dsp.LTTB(x, maxPoints)
+	}
+	series := make([]*float64, len(samples))
+	index := make([]int, len(samples))
+	for i, sm := range samples {
+		index[i] = sm.Index
+		if !sm.Gap {
+			v := x[sm.Index]
+			series[i] = &v
+		}
+	}
+	return series, index
+}
+
+// downsampleMeta melaporkan panjang asli dan faktor desimasi (sampel asli
+// per bucket)
+func downsampleMeta(n, maxPoints int, method string, applied bool) map[string]interface{} {
+	out := map[string]interface{}{
+		"applied":           applied,
+		"original_length":   n,
+		"max_points":        maxPoints,
+		"decimation_factor": 1.0,
+	}
+	if applied {
+		buckets := maxPoints
+		if method == dsp.DownsampleMinMax {
+			buckets = maxPoints / 2
+		}
+		out["method"] = method
+		out["decimation_factor"] = float64(n) / float64(buckets)
+	}
+	return out
+}
 
 // ---------------- Small utilities ----------------
 
@@ -193,153 +331,176 @@ func pearsonCorrIgnoreNaN(a, b []float64) float64 {
 	return num / den
 }
 
-// parsing helpers (lebih toleran terhadap koma desimal / header opsional)
-func parseFloatLoose(s string) (float64, bool) {
-	if v, err := strconv.ParseFloat(s, 64); err == nil {
-		return v, true
-	}
-	s2 := strings.ReplaceAll(s, ",", ".")
-	if v, err := strconv.ParseFloat(s2, 64); err == nil {
-		return v, true
-	}
-	return math.NaN(), false
-}
+// ---------------- Core: BNR Top-k per Channel ----------------
 
-func readFloatRow(row []string) ([]float64, float64) {
-	vals := make([]float64, len(row))
-	ok := 0
-	for i, cell := range row {
-		v, okv := parseFloatLoose(cell)
-		if okv {
-			ok++
-		}
-		vals[i] = v
+// GetPlots memilih top-k subcarrier per channel berdasarkan BNR. Parameter
+// analisis bisa diatur lewat query (lihat bnrQueryParams) atau preset
+// bernama (?preset=); meta.params berisi nilai efektif yang dipakai.
+// ?max_points= memperkecil series untuk plot (LTTB atau min-max) tanpa
+// mengubah ranking dan statistik.
+func (h *PlotHandler) GetPlots(w http.ResponseWriter, r *http.Request) {
+	// 0) Parameter efektif: default < preset < query
+	cfg, presetName, err := h.resolveBNRParams(r)
+	if err != nil {
+		respondLoadError(w, err)
+		return
 	}
-	return vals, float64(ok) / float64(len(row))
-}
-
-// pilih jendela 90 kolom berurutan yang paling “sehat” (valid ratio tertinggi)
-func pick90ConsecutiveCols(data [][]float64) []int {
-	if len(data) == 0 {
-		return nil
-	}
-	nCols := len(data[0])
-	// valid ratio per kolom
-	valid := make([]float64, nCols)
-	for j := 0; j < nCols; j++ {
-		c := 0
-		for i := 0; i < len(data); i++ {
-			if !math.IsNaN(data[i][j]) {
-				c++
-			}
-		}
-		valid[j] = float64(c) / float64(len(data))
-	}
-	// cari window 90 dengan rata-rata valid terbesar
-	bestStart, bestScore := 0, -1.0
-	if nCols >= 90 {
-		for start := 0; start <= nCols-90; start++ {
-			sum := 0.0
-			for j := start; j < start+90; j++ {
-				sum += valid[j]
-			}
-			score := sum / 90.0
-			if score > bestScore {
-				bestScore = score
-				bestStart = start
-			}
-		}
-		idx := make([]int, 90)
-		for k := 0; k < 90; k++ {
-			idx[k] = bestStart + k
-		}
-		return idx
+	maxPoints, method, err := parseDownsample(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
 	}
-	// fallback (harusnya tidak terjadi)
-	idx := make([]int, nCols)
-	for k := 0; k < nCols; k++ {
-		idx[k] = k
+	rs, err := parseResample(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
 	}
-	return idx
-}
 
-// ---------------- Core: BNR Top-5 per Channel ----------------
-
-func (h *PlotHandler) GetPlots(w http.ResponseWriter, r *http.Request) {
-	// 1) Metadata CSV
+	// 1-3) Metadata, ambil objek dari MinIO, parse, petakan lewat layout,
+	// resampling opsional ke grid waktu seragam (?resample=), lalu jalankan
+	// pipeline filter (?filtered=false untuk data mentah)
+	// Hasil yang sama (isi file, layout, filter, parameter) diambil dari
+	// cache/artifact tanpa mengunduh file
+	ctx := r.Context()
 	id := mux.Vars(r)["id"]
-	meta, err := h.csvRepo.GetByID(r.Context(), id)
+	raw := r.URL.Query().Get("filtered") == "false"
+	meta, err := h.loadMeta(ctx, id)
 	if err != nil {
-		respondError(w, http.StatusNotFound, "CSV not found")
+		respondLoadError(w, err)
 		return
 	}
-
-	// 2) Ambil objek CSV dari MinIO
-	obj, err := h.minioClient.GetObject(r.Context(), h.bucketName, meta.ObjectPath, minio.GetObjectOptions{})
+	filter, err := h.fileFilter(ctx, meta, raw)
 	if err != nil {
-		respondError(w, http.StatusInternalServerError, "Failed to fetch CSV")
+		respondLoadError(w, err)
 		return
 	}
-	defer obj.Close()
-
-	// 3) Parse CSV → [][]float64 (baris=paket, kolom=data)
-	reader := csv.NewReader(obj)
-
-	first, err := reader.Read()
+	params, _ := json.Marshal(cfg)
+	bl, err := h.resolveBaseline(ctx, meta, r.URL.Query().Get("room_baseline"), filter)
+	if err != nil {
+		respondLoadError(w, err)
+		return
+	}
+	// nama preset ikut di kunci karena ikut dikembalikan di respons
+	key := analysisKey("bnr", meta, filter,
+		fmt.Sprintf("%s;preset=%s;max_points=%d;downsample=%s;raw=%t;%s;%s", params, presetName, maxPoints, method, raw, rs.canonical(), bl.canonical()))
+	if h.cachedAnalysis(ctx, w, key) {
+		return
+	}
+	csi, resampled, err := h.prepareMatrix(ctx, meta, filter, rs, bl)
 	if err != nil {
-		respondError(w, http.StatusInternalServerError, "Read CSV: "+err.Error())
+		respondLoadError(w, err)
 		return
 	}
-	vals, ratio := readFloatRow(first)
 
-	data := make([][]float64, 0, 1024)
-	// jika baris pertama lebih mirip data (>=60% cell ter-parse), masukkan sebagai data pertama
-	if ratio > 0.6 {
-		data = append(data, vals)
+	// 4-6) BNR dB dan ranking top-k per channel (lihat rankBNR)
+	P := csi.Packets
+	C := csi.Layout.Streams
+	S := csi.Layout.Subcarriers
+	ranking := rankBNR(csi, cfg)
+
+	// Payload top-k per channel
+	type scStat struct {
+		Channel    int        `json:"channel"`
+		Rank       int        `json:"rank"`
+		Subcarrier int        `json:"subcarrier"`
+		Series     []*float64 `json:"series"` // null untuk masked
+		// indeks paket (0-based) tiap titik series bila di-downsample
+		Index    []int   `json:"index,omitempty"`
+		Median   float64 `json:"median"`
+		P90      float64 `json:"p90"`
+		Std      float64 `json:"std"`
+		ValidPct float64 `json:"validPct"`
 	}
-	for {
-		row, err := reader.Read()
-		if err == io.EOF {
-			break
+
+	type channelResp struct {
+		Channel int      `json:"channel"`
+		Top5    []scStat `json:"top5"` // nama lama dipertahankan; berisi top_k subcarrier
+	}
+
+	var results []channelResp
+	var indices [][]int // 1-based untuk kemudahan UI
+	downsampled := maxPoints > 0 && maxPoints < P
+
+	for c := 0; c < C; c++ {
+		chosen := ranking.Chosen[c]
+
+		// siapkan payload channel
+		chRes := channelResp{Channel: c + 1}
+		for rank, s := range chosen {
+			ser := ranking.BNR[c][s]
+			stats := scStat{
+				Channel:    c + 1,
+				Rank:       rank + 1,
+				Subcarrier: s + 1,
+				Series:     ranking.Masked[c][s],
+				Median:     medianIgnoreNaN(ser),
+				P90:        p90IgnoreNaN(ser),
+				Std:        stdIgnoreNaN(ser),
+				ValidPct:   ranking.ValidPct[c][s],
+			}
+			// statistik tetap dari deret penuh; hanya series yang diperkecil
+			if downsampled {
+				stats.Series, stats.Index = downsampleSeries(ser, maxPoints, method)
+			}
+			chRes.Top5 = append(chRes.Top5, stats)
 		}
-		if err != nil {
-			respondError(w, http.StatusInternalServerError, "Parse CSV: "+err.Error())
-			return
+		results = append(results, chRes)
+
+		idx1 := make([]int, 0, len(chosen))
+		for _, s := range chosen {
+			idx1 = append(idx1, s+1) // 1-based
 		}
-		v, _ := readFloatRow(row)
-		data = append(data, v)
-	}
-	if len(data) == 0 {
-		respondError(w, http.StatusBadRequest, "Empty CSV")
-		return
+		indices = append(indices, idx1)
 	}
 
-	P := len(data)
-	nCols := len(data[0])
-	if nCols < numChannels*numSubcarriers {
-		respondError(w, http.StatusBadRequest, "CSV must contain at least 90 numeric columns (3×30)")
-		return
+	// 7) Response JSON
+	resp := map[string]interface{}{
+		"meta": map[string]interface{}{
+			"method":      "Band-to-Noise Ratio robust-σ (Median Absolute Deviation)",
+			"clipDb":      []float64{cfg.ClipDbLo, cfg.ClipDbHi},
+			"params":      cfg,
+			"preset":      presetName,
+			"channels":    C,
+			"subcarriers": S,
+			"packets":     P,
+			"ranking":     "RAW median BNR",
+			"layout":      csi.Layout,
+			"filter":      filter,
+			"resample":    resampled,
+			"baseline":    bl,
+			"downsample":  downsampleMeta(P, maxPoints, method, downsampled),
+		},
+		"indices1based": indices,
+		"channels":      results,
 	}
 
-	// 4) Pilih 90 kolom berurutan terbaik lalu bentuk amplitudo [C][S][P]
-	colIdx := pick90ConsecutiveCols(data)
-	if len(colIdx) < numChannels*numSubcarriers {
-		respondError(w, http.StatusBadRequest, "CSV must contain at least 90 usable numeric columns")
-		return
-	}
+	h.respondAnalysis(ctx, w, key, resp)
+}
 
-	C := numChannels
-	S := numSubcarriers
+// bnrRanking adalah hasil BNR per subcarrier dan top-k per channel
+type bnrRanking struct {
+	BNR      [][][]float64  // dB [C][S][P], NaN untuk masked
+	Masked   [][][]*float64 // sama dengan BNR, nil untuk masked (JSON)
+	ValidPct [][]float64
+	Chosen   [][]int // subcarrier terpilih per channel (0-based), urut rank
+}
 
+// rankBNR menghitung BNR robust tiap subcarrier dan memilih top-k
+// subcarrier per channel: urut median BNR, dengan jarak minimal dan batas
+// korelasi antar-subcarrier terpilih (dilonggarkan bila kurang dari top_k)
+func rankBNR(csi *services.CSIMatrix, cfg models.BNRParams) *bnrRanking {
+	P := csi.Packets
+	C := csi.Layout.Streams
+	S := csi.Layout.Subcarriers
+
+	// Amplitudo [C][S][P] sesuai layout; nilai <= 0 dianggap dropout
 	amp := make([][][]float64, C)
 	for c := 0; c < C; c++ {
 		amp[c] = make([][]float64, S)
 		for s := 0; s < S; s++ {
 			amp[c][s] = make([]float64, P)
-
-			col := colIdx[c*S+s] // mapping blok 30-30-30 per channel
 			for p := 0; p < P; p++ {
-				v := data[p][col]
+				v := csi.Amplitude[c][s][p]
 				if math.IsNaN(v) || v <= 0 {
 					v = math.NaN()
 				}
@@ -347,7 +508,7 @@ func (h *PlotHandler) GetPlots(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 	}
-	// 5) Hitung BNR dB (robust-sigma/MAD) dan siapkan statistik
+	// Hitung BNR dB (robust-sigma/MAD) dan siapkan statistik
 	bnr := make([][][]float64, C)        // nilai dB (NaN untuk masked)
 	bnrMasked := make([][][]*float64, C) // untuk JSON (nil = masked)
 	validPct := make([][]float64, C)
@@ -364,6 +525,14 @@ func (h *PlotHandler) GetPlots(w http.ResponseWriter, r *http.Request) {
 		for s := 0; s < S; s++ {
 			// robust sigma per subcarrier (MAD*1.4826, fallback std)
 			sigma := robustSigmaIgnoreNaN(amp[c][s])
+			// baseline dropout: persentil amplitudo subcarrier lintas waktu
+			valid := make([]float64, 0, P)
+			for _, v := range amp[c][s] {
+				if !math.IsNaN(v) {
+					valid = append(valid, v)
+				}
+			}
+			baseline := percentile(valid, cfg.PercentileBaseline)
 			series := make([]float64, P)
 			seriesMasked := make([]*float64, P)
 			validCount := 0
@@ -375,6 +544,11 @@ func (h *PlotHandler) GetPlots(w http.ResponseWriter, r *http.Request) {
 					seriesMasked[p] = nil
 					continue
 				}
+				if baseline > 0 && val/baseline < cfg.DropoutRatio {
+					series[p] = math.NaN()
+					seriesMasked[p] = nil
+					continue
+				}
 				ratio := math.Abs(val) / sigma
 				if ratio <= 0 {
 					series[p] = math.NaN()
@@ -382,7 +556,7 @@ func (h *PlotHandler) GetPlots(w http.ResponseWriter, r *http.Request) {
 					continue
 				}
 				db := 20.0 * math.Log10(ratio)
-				if db < clipDbLo {
+				if db < cfg.ClipDbLo {
 					series[p] = math.NaN()
 					seriesMasked[p] = nil
 					continue
@@ -401,26 +575,8 @@ func (h *PlotHandler) GetPlots(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	// 6) Ranking Top-5 per channel (RAW median BNR) dengan non-redundan
-	type scStat struct {
-		Channel    int        `json:"channel"`
-		Rank       int        `json:"rank"`
-		Subcarrier int        `json:"subcarrier"`
-		Series     []*float64 `json:"series"` // null untuk masked
-		Median     float64    `json:"median"`
-		P90        float64    `json:"p90"`
-		Std        float64    `json:"std"`
-		ValidPct   float64    `json:"validPct"`
-	}
-
-	type channelResp struct {
-		Channel int      `json:"channel"`
-		Top5    []scStat `json:"top5"`
-	}
-
-	var results []channelResp
-	var indices [][]int // 1-based untuk kemudahan UI
-
+	// Ranking top-k per channel (RAW median BNR) dengan non-redundan
+	rank := &bnrRanking{BNR: bnr, Masked: bnrMasked, ValidPct: validPct, Chosen: make([][]int, C)}
 	for c := 0; c < C; c++ {
 		// kandidat diurutkan berdasarkan median BNR menurun
 		type pair struct {
@@ -433,7 +589,7 @@ func (h *PlotHandler) GetPlots(w http.ResponseWriter, r *http.Request) {
 		}
 		sort.Slice(cands, func(i, j int) bool { return cands[i].score > cands[j].score })
 
-		chosen := make([]int, 0, 5)
+		chosen := make([]int, 0, cfg.TopK)
 		for _, p := range cands {
 			if math.IsNaN(p.score) {
 				continue
@@ -441,7 +597,7 @@ func (h *PlotHandler) GetPlots(w http.ResponseWriter, r *http.Request) {
 			// cek jarak minimal antarsubcarrier
 			okGap := true
 			for _, jj := range chosen {
-				if absInt(p.s-jj) < minGapSubcarrier {
+				if absInt(p.s-jj) < cfg.MinGapSubcarrier {
 					okGap = false
 					break
 				}
@@ -453,7 +609,7 @@ func (h *PlotHandler) GetPlots(w http.ResponseWriter, r *http.Request) {
 			okCorr := true
 			for _, jj := range chosen {
 				rho := math.Abs(pearsonCorrIgnoreNaN(seriesStore[c][p.s], seriesStore[c][jj]))
-				if math.IsNaN(rho) || rho >= maxAbsCorrThreshold {
+				if math.IsNaN(rho) || rho >= cfg.MaxAbsCorrThreshold {
 					okCorr = false
 					break
 				}
@@ -462,29 +618,29 @@ func (h *PlotHandler) GetPlots(w http.ResponseWriter, r *http.Request) {
 				continue
 			}
 			chosen = append(chosen, p.s)
-			if len(chosen) == 5 {
+			if len(chosen) == cfg.TopK {
 				break
 			}
 		}
-		// fallback relax (gap lalu korelasi) bila belum cukup 5
-		if len(chosen) < 5 {
+		// fallback relax (gap lalu korelasi) bila belum cukup top_k
+		if len(chosen) < cfg.TopK {
 			for _, p := range cands {
 				found := false
 				for _, jj := range chosen {
-					if jj == p.s || absInt(jj-p.s) < minGapSubcarrier {
+					if jj == p.s || absInt(jj-p.s) < cfg.MinGapSubcarrier {
 						found = true
 						break
 					}
 				}
 				if !found && !math.IsNaN(p.score) {
 					chosen = append(chosen, p.s)
-					if len(chosen) == 5 {
+					if len(chosen) == cfg.TopK {
 						break
 					}
 				}
 			}
 		}
-		if len(chosen) < 5 {
+		if len(chosen) < cfg.TopK {
 			for _, p := range cands {
 				seen := false
 				for _, jj := range chosen {
@@ -495,51 +651,14 @@ func (h *PlotHandler) GetPlots(w http.ResponseWriter, r *http.Request) {
 				}
 				if !seen && !math.IsNaN(p.score) {
 					chosen = append(chosen, p.s)
-					if len(chosen) == 5 {
+					if len(chosen) == cfg.TopK {
 						break
 					}
 				}
 			}
 		}
 
-		// siapkan payload channel
-		chRes := channelResp{Channel: c + 1}
-		for rank, s := range chosen {
-			ser := bnr[c][s]
-			stats := scStat{
-				Channel:    c + 1,
-				Rank:       rank + 1,
-				Subcarrier: s + 1,
-				Series:     bnrMasked[c][s],
-				Median:     medianIgnoreNaN(ser),
-				P90:        p90IgnoreNaN(ser),
-				Std:        stdIgnoreNaN(ser),
-				ValidPct:   validPct[c][s],
-			}
-			chRes.Top5 = append(chRes.Top5, stats)
-		}
-		results = append(results, chRes)
-
-		idx1 := make([]int, 0, len(chosen))
-		for _, s := range chosen {
-			idx1 = append(idx1, s+1) // 1-based
-		}
-		indices = append(indices, idx1)
+		rank.Chosen[c] = chosen
 	}
-
-	// 7) Response JSON
-	resp := map[string]interface{}{
-		"meta": map[string]interface{}{
-			"method":      "Band-to-Noise Ratio robust-σ (Median Absolute Deviation)",
-			"clipDb":      []float64{clipDbLo, clipDbHi},
-			"channels":    numChannels,
-			"subcarriers": numSubcarriers,
-			"packets":     P,
-			"ranking":     "RAW median BNR",
-		},
-		"indices1based": indices,
-		"channels":      results,
-	}
-
-	respondJSON(w, http.StatusOK, resp)
+	return rank
 }
@@ -0,0 +1,270 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"cetasense-v2.0/internal/dsp"
+	"cetasense-v2.0/internal/models"
+)
+
+// batas parameter estimasi napas
+const (
+	defaultBreathingLow    = 0.1 // Hz, 6 napas/menit
+	defaultBreathingHigh   = 0.5 // Hz, 30 napas/menit
+	defaultBreathingWindow = 30  // detik
+	defaultBreathingOrder  = 2
+	maxBreathingWindow     = 600
+)
+
+// respirationParams adalah parameter /api/plots/{id}/respiration yang sudah
+// divalidasi
+type respirationParams struct {
+	Method     string
+	Low, High  float64
+	Order      int
+	Window     float64 // detik
+	Hop        float64 // detik
+	Start, End float64 // detik sejak awal capture; End 0 = sampai akhir
+	FS         float64 // 0 = dari timestamp
+	BNR        models.BNRParams
+	Preset     string
+	Raw        bool
+	Resample   *resampleParams
+}
+
+func (p *respirationParams) canonical() string {
+	bnr, _ := json.Marshal(p.BNR)
+	return fmt.Sprintf("m=%s;band=%g-%g;ord=%d;win=%g;hop=%g;range=%g-%g;fs=%g;bnr=%s;preset=%s;raw=%t;%s",
+		p.Method, p.Low, p.High, p.Order, p.Window, p.Hop, p.Start, p.End, p.FS, bnr, p.Preset, p.Raw, p.Resample.canonical())
+}
+
+func (h *PlotHandler) parseRespirationParams(r *http.Request) (*respirationParams, error) {
+	q := r.URL.Query()
+	p := &respirationParams{
+		Method: q.Get("method"),
+		Raw:    q.Get("filtered") == "false",
+	}
+	if p.Method == "" {
+		p.Method = dsp.BreathingSpectral
+	}
+	if p.Method != dsp.BreathingSpectral && p.Method != dsp.BreathingZeroCrossing {
+		return nil, &loadError{http.StatusBadRequest, "method must be spectral or zero_crossing"}
+	}
+	var err error
+	bad := func(err error) error { return &loadError{http.StatusBadRequest, err.Error()} }
+	if p.Low, err = queryFloat(r, "low", defaultBreathingLow); err != nil {
+		return nil, bad(err)
+	}
+	if p.High, err = queryFloat(r, "high", defaultBreathingHigh); err != nil {
+		return nil, bad(err)
+	}
+	if p.Low <= 0 || p.High <= p.Low {
+		return nil, bad(fmt.Errorf("band must satisfy 0 < low < high"))
+	}
+	if p.Order, err = queryInt(r, "order", defaultBreathingOrder); err != nil {
+		return nil, bad(err)
+	}
+	if p.Window, err = queryFloat(r, "window", defaultBreathingWindow); err != nil {
+		return nil, bad(err)
+	}
+	// satu jendela harus memuat paling sedikit dua siklus frekuensi terendah
+	if p.Window < 2/p.Low || p.Window > maxBreathingWindow {
+		return nil, bad(fmt.Errorf("window must be between %g and %d seconds", 2/p.Low, maxBreathingWindow))
+	}
+	if p.Hop, err = queryFloat(r, "hop", p.Window/2); err != nil {
+		return nil, bad(err)
+	}
+	if p.Hop <= 0 || p.Hop > p.Window {
+		return nil, bad(fmt.Errorf("hop must be between 0 and window (%g s)", p.Window))
+	}
+	if p.Start, err = queryFloat(r, "start", 0); err != nil {
+		return nil, bad(err)
+	}
+	if p.End, err = queryFloat(r, "end", 0); err != nil {
+		return nil, bad(err)
+	}
+	if p.Start < 0 || p.End < 0 || (p.End > 0 && p.End <= p.Start) {
+		return nil, bad(fmt.Errorf("time range must satisfy 0 <= start < end"))
+	}
+	if p.FS, err = queryFloat(r, "fs", 0); err != nil {
+		return nil, bad(err)
+	}
+	if p.FS < 0 {
+		return nil, bad(fmt.Errorf("fs must be positive"))
+	}
+	if p.Resample, err = parseResample(r); err != nil {
+		return nil, bad(err)
+	}
+	if p.BNR, p.Preset, err = h.resolveBNRParams(r); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// breathingWindow adalah estimasi satu jendela timeline
+type breathingWindow struct {
+	Start      float64  `json:"start_s"`
+	End        float64  `json:"end_s"`
+	RateBPM    *float64 `json:"rate_bpm"` // null bila tidak ada puncak di pita
+	Confidence float64  `json:"confidence"`
+}
+
+// GetRespiration memperkirakan laju napas: subcarrier dipilih dengan ranking
+// BNR (top_k per channel, parameter BNR/preset sama dengan /api/plots/{id})
+// pada rentang waktu ?start=&end= (detik sejak awal capture), amplitudonya
+// difilter band-pass low–high Hz, lalu laju dihitung untuk seluruh rentang
+// dan per jendela geser lewat puncak spektrum atau zero crossing.
+func (h *PlotHandler) GetRespiration(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	params, err := h.parseRespirationParams(r)
+	if err != nil {
+		respondLoadError(w, err)
+		return
+	}
+	meta, err := h.loadMeta(ctx, mux.Vars(r)["id"])
+	if err != nil {
+		respondLoadError(w, err)
+		return
+	}
+	filter, err := h.fileFilter(ctx, meta, params.Raw)
+	if err != nil {
+		respondLoadError(w, err)
+		return
+	}
+	bl, err := h.resolveBaseline(ctx, meta, r.URL.Query().Get("room_baseline"), filter)
+	if err != nil {
+		respondLoadError(w, err)
+		return
+	}
+	key := analysisKey("respiration", meta, filter, params.canonical()+";"+bl.canonical())
+	if h.cachedAnalysis(ctx, w, key) {
+		return
+	}
+	csi, resampled, err := h.prepareMatrix(ctx, meta, filter, params.Resample, bl)
+	if err != nil {
+		respondLoadError(w, err)
+		return
+	}
+
+	fs, fsSource := samplingRate(params.FS, csi)
+	if fsSource == "packet_index" {
+		respondError(w, http.StatusUnprocessableEntity, "Sampling rate is unknown: capture has no timestamps, pass fs")
+		return
+	}
+	if params.High >= fs/2 {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("high must be below fs/2 (%g Hz)", fs/2))
+		return
+	}
+
+	// Rentang paket [lo, hi) untuk rentang waktu
+	lo, hi := 0, csi.Packets
+	for lo < csi.Packets && packetTime(csi, lo, fs, fsSource) < params.Start {
+		lo++
+	}
+	if params.End > 0 {
+		for hi > lo && packetTime(csi, hi-1, fs, fsSource) > params.End {
+			hi--
+		}
+	}
+	segment := csi.Slice(lo, hi)
+	if float64(segment.Packets)/fs < 2/params.Low {
+		respondError(w, http.StatusUnprocessableEntity,
+			fmt.Sprintf("Time range holds %d packets (%.1f s); at least %g s is needed", segment.Packets, float64(segment.Packets)/fs, 2/params.Low))
+		return
+	}
+
+	// Subcarrier paling sensitif per channel dari ranking BNR rentang ini
+	ranking := rankBNR(segment, params.BNR)
+	var series [][]float64
+	var used []seriesRef
+	for c, chosen := range ranking.Chosen {
+		vars, refs := selectSeries(segment, []int{c}, chosen)
+		for i, x := range vars {
+			y, err := dsp.Bandpass(x, params.Order, params.Low, params.High, fs)
+			if err != nil {
+				respondError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			series = append(series, y)
+			used = append(used, refs[i])
+		}
+	}
+	if len(series) == 0 {
+		respondError(w, http.StatusUnprocessableEntity, "No subcarrier has valid samples in the time range")
+		return
+	}
+
+	overall, err := dsp.BreathingRate(series, fs, params.Low, params.High, params.Method)
+	if err != nil {
+		respondError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	n := segment.Packets
+	winLen := min(n, int(math.Round(params.Window*fs)))
+	hopLen := max(1, int(math.Round(params.Hop*fs)))
+	timeline := []breathingWindow{}
+	for start := 0; start+winLen <= n; start += hopLen {
+		part := make([][]float64, len(series))
+		for i, x := range series {
+			part[i] = x[start : start+winLen]
+		}
+		est, err := dsp.BreathingRate(part, fs, params.Low, params.High, params.Method)
+		if err != nil {
+			respondError(w, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+		timeline = append(timeline, breathingWindow{
+			Start:      packetTime(csi, lo+start, fs, fsSource),
+			End:        packetTime(csi, lo+start+winLen-1, fs, fsSource),
+			RateBPM:    finiteOrNil(est.RateBPM),
+			Confidence: est.Confidence,
+		})
+	}
+
+	selected := make([]map[string]int, len(used))
+	for i, ref := range used {
+		selected[i] = map[string]int{"channel": ref.Stream + 1, "subcarrier": ref.Subcarrier + 1}
+	}
+	h.respondAnalysis(ctx, w, key, map[string]interface{}{
+		"meta": map[string]interface{}{
+			"method":       params.Method,
+			"band_hz":      []float64{params.Low, params.High},
+			"order":        params.Order,
+			"window_s":     params.Window,
+			"hop_s":        params.Hop,
+			"start_s":      packetTime(csi, lo, fs, fsSource),
+			"end_s":        packetTime(csi, hi-1, fs, fsSource),
+			"start_packet": lo + 1,
+			"end_packet":   hi,
+			"fs":           fs,
+			"fs_source":    fsSource,
+			"params":       params.BNR,
+			"preset":       params.Preset,
+			"ranking":      "RAW median BNR",
+			"selected":     selected,
+			"packets":      csi.Packets,
+			"layout":       csi.Layout,
+			"filter":       filter,
+			"resample":     resampled,
+			"baseline":     bl,
+		},
+		"rate_bpm":   finiteOrNil(overall.RateBPM),
+		"frequency":  finiteOrNil(overall.FreqHz),
+		"confidence": overall.Confidence,
+		"windows":    timeline,
+	})
+}
+
+// finiteOrNil mengubah NaN/Inf menjadi null untuk JSON
+func finiteOrNil(v float64) *float64 {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return nil
+	}
+	return &v
+}
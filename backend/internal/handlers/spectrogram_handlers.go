@@ -0,0 +1,410 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+
+	"github.com/gorilla/mux"
+
+	"cetasense-v2.0/internal/dsp"
+	"cetasense-v2.0/internal/services"
+)
+
+// Sumber deret spektrogram
+const (
+	spectrogramSourceSubcarriers = "subcarriers"
+	spectrogramSourcePCA         = "pca"
+)
+
+// batas parameter spektrogram
+const (
+	maxSTFTWindow     = 4096
+	maxPCAComponent   = 10
+	maxTimeBins       = 2000
+	maxFreqBins       = 1024
+	defaultSTFTWindow = 128
+)
+
+// spectrogramParams adalah parameter /api/plots/{id}/spectrogram yang
+// sudah divalidasi
+type spectrogramParams struct {
+	Source      string
+	Streams     []int // 0-based
+	Subcarriers []int // 0-based
+	Component   int   // 1-based, hanya untuk source=pca
+	Window      int
+	Hop         int
+	WindowFn    string
+	FS          float64 // 0 = dari timestamp
+	FMin, FMax  float64 // FMax 0 = fs/2
+	TimeBins    int
+	FreqBins    int
+	Scale       string
+	Raw         bool
+	Resample    *resampleParams
+}
+
+// canonical adalah representasi parameter untuk kunci cache
+func (p *spectrogramParams) canonical() string {
+	return fmt.Sprintf("src=%s;st=%v;sc=%v;comp=%d;win=%d;hop=%d;fn=%s;fs=%g;f=%g-%g;bins=%dx%d;scale=%s;raw=%t;%s",
+		p.Source, p.Streams, p.Subcarriers, p.Component, p.Window, p.Hop, p.WindowFn,
+		p.FS, p.FMin, p.FMax, p.TimeBins, p.FreqBins, p.Scale, p.Raw, p.Resample.canonical())
+}
+
+func parseSpectrogramParams(r *http.Request, sel *subcarrierSelection) (*spectrogramParams, error) {
+	q := r.URL.Query()
+	p := &spectrogramParams{
+		Source:   q.Get("source"),
+		WindowFn: q.Get("window_fn"),
+		Scale:    q.Get("scale"),
+		Raw:      q.Get("filtered") == "false",
+	}
+	if p.Source == "" {
+		p.Source = spectrogramSourceSubcarriers
+	}
+	if p.Source != spectrogramSourceSubcarriers && p.Source != spectrogramSourcePCA {
+		return nil, fmt.Errorf("source must be subcarriers or pca")
+	}
+	if p.WindowFn == "" {
+		p.WindowFn = dsp.WindowHann
+	}
+	if _, err := dsp.WindowFunc(p.WindowFn, 1); err != nil {
+		return nil, err
+	}
+	if p.Scale == "" {
+		p.Scale = "db"
+	}
+	if p.Scale != "db" && p.Scale != "linear" {
+		return nil, fmt.Errorf("scale must be db or linear")
+	}
+
+	var err error
+	p.Streams, p.Subcarriers = sel.Streams, sel.Subcarriers
+	if p.Source == spectrogramSourcePCA {
+		if p.Component, err = queryInt(r, "component", 1); err != nil {
+			return nil, err
+		}
+		if p.Component < 1 || p.Component > maxPCAComponent || p.Component > len(p.Streams)*len(p.Subcarriers) {
+			return nil, fmt.Errorf("component must be between 1 and %d", min(maxPCAComponent, len(p.Streams)*len(p.Subcarriers)))
+		}
+	}
+
+	if p.Window, err = queryInt(r, "window", defaultSTFTWindow); err != nil {
+		return nil, err
+	}
+	if p.Window < 4 || p.Window > maxSTFTWindow {
+		return nil, fmt.Errorf("window must be between 4 and %d", maxSTFTWindow)
+	}
+	if p.Hop, err = queryInt(r, "hop", max(1, p.Window/4)); err != nil {
+		return nil, err
+	}
+	if p.Hop < 1 || p.Hop > p.Window {
+		return nil, fmt.Errorf("hop must be between 1 and window (%d)", p.Window)
+	}
+	if p.FS, err = queryFloat(r, "fs", 0); err != nil {
+		return nil, err
+	}
+	if p.FS < 0 {
+		return nil, fmt.Errorf("fs must be positive")
+	}
+	if p.FMin, err = queryFloat(r, "fmin", 0); err != nil {
+		return nil, err
+	}
+	if p.FMax, err = queryFloat(r, "fmax", 0); err != nil {
+		return nil, err
+	}
+	if p.FMin < 0 || p.FMax < 0 || (p.FMax > 0 && p.FMax <= p.FMin) {
+		return nil, fmt.Errorf("frequency range must satisfy 0 <= fmin < fmax")
+	}
+	if p.TimeBins, err = queryInt(r, "max_time_bins", 200); err != nil {
+		return nil, err
+	}
+	if p.TimeBins < 1 || p.TimeBins > maxTimeBins {
+		return nil, fmt.Errorf("max_time_bins must be between 1 and %d", maxTimeBins)
+	}
+	if p.FreqBins, err = queryInt(r, "max_freq_bins", 128); err != nil {
+		return nil, err
+	}
+	if p.FreqBins < 1 || p.FreqBins > maxFreqBins {
+		return nil, fmt.Errorf("max_freq_bins must be between 1 and %d", maxFreqBins)
+	}
+	if p.Resample, err = parseResample(r); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// samplingRate mengembalikan fs dari parameter, dari median selisih
+// timestamp, atau 1 (satuan paket) bila keduanya tidak tersedia
+func samplingRate(requested float64, csi *services.CSIMatrix) (float64, string) {
+	if requested > 0 {
+		return requested, "query"
+	}
+	var diffs []float64
+	for i := 1; i < len(csi.Timestamps); i++ {
+		if d := csi.Timestamps[i] - csi.Timestamps[i-1]; d > 0 && !math.IsNaN(d) {
+			diffs = append(diffs, d)
+		}
+	}
+	if len(diffs) > 0 {
+		sort.Float64s(diffs)
+		if med := diffs[len(diffs)/2]; med > 0 {
+			return 1 / med, "timestamps"
+		}
+	}
+	return 1, "packet_index"
+}
+
+// GetSpectrogram menghitung STFT amplitudo pada subcarrier terpilih (daya
+// dirata-rata lintas deret) atau pada satu komponen PCA, lalu mengembalikan
+// matriks magnitudo [time][freq] yang sudah diperkecil untuk heatmap.
+// Hasil untuk parameter yang sama di-cache per isi file.
+func (h *PlotHandler) GetSpectrogram(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := mux.Vars(r)["id"]
+	meta, err := h.loadMeta(ctx, id)
+	if err != nil {
+		respondLoadError(w, err)
+		return
+	}
+	sel, err := h.resolveSelection(ctx, meta, r.URL.Query())
+	if err != nil {
+		respondLoadError(w, err)
+		return
+	}
+	params, err := parseSpectrogramParams(r, sel)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	filter, err := h.fileFilter(ctx, meta, params.Raw)
+	if err != nil {
+		respondLoadError(w, err)
+		return
+	}
+	bl, err := h.resolveBaseline(ctx, meta, r.URL.Query().Get("room_baseline"), filter)
+	if err != nil {
+		respondLoadError(w, err)
+		return
+	}
+	key := analysisKey("spectrogram", meta, filter, params.canonical()+";"+bl.canonical())
+	if h.cachedAnalysis(ctx, w, key) {
+		return
+	}
+
+	csi, resampled, err := h.prepareMatrix(ctx, meta, filter, params.Resample, bl)
+	if err != nil {
+		respondLoadError(w, err)
+		return
+	}
+
+	// Deret masukan STFT
+	var series [][]float64
+	var explained float64
+	vars, _ := selectSeries(csi, params.Streams, params.Subcarriers)
+	if len(vars) == 0 {
+		respondError(w, http.StatusUnprocessableEntity, "Selected subcarriers have no valid samples")
+		return
+	}
+	if params.Source == spectrogramSourcePCA {
+		if params.Component > len(vars) {
+			respondError(w, http.StatusUnprocessableEntity,
+				fmt.Sprintf("Only %d subcarrier series have valid samples; component %d is unavailable", len(vars), params.Component))
+			return
+		}
+		obs := transpose(vars)
+		pca, err := dsp.PCA(obs)
+		if err != nil {
+			respondError(w, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+		series = [][]float64{pca.Project(obs, params.Component-1)}
+		explained = pca.ExplainedRatio[params.Component-1]
+	} else {
+		series = vars
+	}
+
+	// Rata-rata daya STFT lintas deret
+	var power [][]float64
+	var spec *dsp.Spectrogram
+	for _, x := range series {
+		spec, err = dsp.STFT(x, dsp.STFTParams{Window: params.Window, Hop: params.Hop, WindowFn: params.WindowFn})
+		if err != nil {
+			respondError(w, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+		if power == nil {
+			power = make([][]float64, len(spec.Power))
+			for t := range power {
+				power[t] = make([]float64, len(spec.Power[t]))
+			}
+		}
+		for t, row := range spec.Power {
+			for k, v := range row {
+				power[t][k] += v / float64(len(series))
+			}
+		}
+	}
+
+	fs, fsSource := samplingRate(params.FS, csi)
+	fmax := params.FMax
+	if fmax == 0 || fmax > fs/2 {
+		fmax = fs / 2
+	}
+	var bins []int
+	var freqs []float64
+	for k := 0; k <= spec.NFFT/2; k++ {
+		f := float64(k) * fs / float64(spec.NFFT)
+		if f >= params.FMin && f <= fmax {
+			bins = append(bins, k)
+			freqs = append(freqs, f)
+		}
+	}
+	if len(bins) == 0 {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("No frequency bins between %g and %g Hz (resolution %g Hz)", params.FMin, fmax, fs/float64(spec.NFFT)))
+		return
+	}
+
+	times := make([]float64, len(spec.Starts))
+	for t, start := range spec.Starts {
+		center := start + params.Window/2
+		if fsSource == "timestamps" && center < len(csi.Timestamps) && !math.IsNaN(csi.Timestamps[center]) {
+			times[t] = csi.Timestamps[center] - csi.Timestamps[0]
+		} else {
+			times[t] = float64(center) / fs
+		}
+	}
+
+	// Perkecil ke max_time_bins × max_freq_bins dengan rata-rata blok
+	tGroups := groupRanges(len(times), params.TimeBins)
+	fGroups := groupRanges(len(bins), params.FreqBins)
+	outTimes := make([]float64, len(tGroups))
+	for i, g := range tGroups {
+		outTimes[i] = meanRange(times, g)
+	}
+	outFreqs := make([]float64, len(fGroups))
+	for j, g := range fGroups {
+		outFreqs[j] = meanRange(freqs, g)
+	}
+	magnitude := make([][]float64, len(tGroups))
+	for i, tg := range tGroups {
+		row := make([]float64, len(fGroups))
+		for j, fg := range fGroups {
+			sum, n := 0.0, 0
+			for t := tg[0]; t < tg[1]; t++ {
+				for b := fg[0]; b < fg[1]; b++ {
+					sum += power[t][bins[b]]
+					n++
+				}
+			}
+			pw := sum / float64(n)
+			if params.Scale == "db" {
+				row[j] = 10 * math.Log10(math.Max(pw, 1e-20))
+			} else {
+				row[j] = math.Sqrt(pw)
+			}
+		}
+		magnitude[i] = row
+	}
+
+	m := map[string]interface{}{
+		"source":      params.Source,
+		"streams":     oneBased(params.Streams),
+		"subcarriers": oneBased(params.Subcarriers),
+		"series":      len(series),
+		"window":      params.Window,
+		"hop":         params.Hop,
+		"window_fn":   params.WindowFn,
+		"nfft":        spec.NFFT,
+		"fs":          fs,
+		"fs_source":   fsSource,
+		"fmin":        params.FMin,
+		"fmax":        fmax,
+		"frames":      len(times),
+		"scale":       params.Scale,
+		"shape":       []int{len(outTimes), len(outFreqs)},
+		"packets":     csi.Packets,
+		"layout":      csi.Layout,
+		"filter":      filter,
+		"resample":    resampled,
+		"baseline":    bl,
+	}
+	if params.Source == spectrogramSourcePCA {
+		m["component"] = params.Component
+		m["explained_ratio"] = explained
+	}
+	h.respondAnalysis(ctx, w, key, map[string]interface{}{
+		"meta":        m,
+		"times":       outTimes,
+		"frequencies": outFreqs,
+		"magnitude":   magnitude,
+	})
+}
+
+// seriesRef menunjuk satu deret amplitudo (0-based)
+type seriesRef struct {
+	Stream     int
+	Subcarrier int
+}
+
+// selectSeries mengambil deret amplitudo stream × subcarrier terpilih dengan
+// dropout (NaN atau <= 0) terisi interpolasi; deret tanpa sampel valid
+// dilewati
+func selectSeries(csi *services.CSIMatrix, streams, subcarriers []int) ([][]float64, []seriesRef) {
+	var vars [][]float64
+	var refs []seriesRef
+	buf := make([]float64, csi.Packets)
+	for _, c := range streams {
+		for _, s := range subcarriers {
+			for p, v := range csi.Amplitude[c][s] {
+				if v <= 0 {
+					v = math.NaN()
+				}
+				buf[p] = v
+			}
+			if filled, ok := dsp.FillGaps(buf); ok {
+				vars = append(vars, filled)
+				refs = append(refs, seriesRef{c, s})
+			}
+		}
+	}
+	return vars, refs
+}
+
+// transpose mengubah [var][obs] menjadi [obs][var]
+func transpose(x [][]float64) [][]float64 {
+	if len(x) == 0 {
+		return nil
+	}
+	out := make([][]float64, len(x[0]))
+	for i := range out {
+		out[i] = make([]float64, len(x))
+		for j := range x {
+			out[i][j] = x[j][i]
+		}
+	}
+	return out
+}
+
+// groupRanges membagi n elemen menjadi paling banyak k blok berurutan
+// [awal, akhir) yang ukurannya hampir sama
+func groupRanges(n, k int) [][2]int {
+	if k > n {
+		k = n
+	}
+	out := make([][2]int, k)
+	for i := 0; i < k; i++ {
+		out[i] = [2]int{i * n / k, (i + 1) * n / k}
+	}
+	return out
+}
+
+func meanRange(x []float64, g [2]int) float64 {
+	sum := 0.0
+	for i := g[0]; i < g[1]; i++ {
+		sum += x[i]
+	}
+	return sum / float64(g[1]-g[0])
+}
@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"cetasense-v2.0/internal/dsp"
+	"cetasense-v2.0/internal/models"
+	"cetasense-v2.0/internal/services"
+)
+
+// batas analisis timing
+const (
+	defaultGapFactor = 3
+	maxResampleRate  = 10000
+	// grid resampling paling banyak maxResampleFactor × jumlah paket asli
+	// dan tidak lebih dari maxResamplePackets titik, supaya satu timestamp
+	// outlier tidak membuat grid raksasa
+	maxResampleFactor  = 16
+	maxResamplePackets = 2_000_000
+)
+
+// resampleParams adalah opsi resampling ke grid waktu seragam untuk
+// analisis domain waktu (?resample=linear|spline); nil berarti indeks paket
+// dipakai sebagai waktu
+type resampleParams struct {
+	Method string
+	Rate   float64 // Hz; 0 = laju nominal capture (1 / median interval)
+	MaxGap float64 // detik; 0 = gap_factor × median interval
+}
+
+func newResampleParams(method string, rate, maxGap float64) (*resampleParams, error) {
+	if method == "" {
+		if rate != 0 || maxGap != 0 {
+			return nil, fmt.Errorf("resample_rate and resample_max_gap need resample=%s or %s", dsp.ResampleLinear, dsp.ResampleSpline)
+		}
+		return nil, nil
+	}
+	if method != dsp.ResampleLinear && method != dsp.ResampleSpline {
+		return nil, fmt.Errorf("resample must be %s or %s", dsp.ResampleLinear, dsp.ResampleSpline)
+	}
+	if rate < 0 || rate > maxResampleRate {
+		return nil, fmt.Errorf("resample_rate must be between 0 and %d Hz", maxResampleRate)
+	}
+	if maxGap < 0 {
+		return nil, fmt.Errorf("resample_max_gap must be positive")
+	}
+	return &resampleParams{Method: method, Rate: rate, MaxGap: maxGap}, nil
+}
+
+// parseResample membaca ?resample=, ?resample_rate=, ?resample_max_gap=
+func parseResample(r *http.Request) (*resampleParams, error) {
+	rate, err := queryFloat(r, "resample_rate", 0)
+	if err != nil {
+		return nil, err
+	}
+	maxGap, err := queryFloat(r, "resample_max_gap", 0)
+	if err != nil {
+		return nil, err
+	}
+	return newResampleParams(r.URL.Query().Get("resample"), rate, maxGap)
+}
+
+// canonical adalah representasi untuk kunci cache
+func (p *resampleParams) canonical() string {
+	if p == nil {
+		return "rs=none"
+	}
+	return fmt.Sprintf("rs=%s@%g;gap=%g", p.Method, p.Rate, p.MaxGap)
+}
+
+// resampleMatrix menjalankan resampling bila diminta dan mengembalikan
+// ringkasannya untuk meta respons (nil bila tidak diminta)
+func resampleMatrix(csi *services.CSIMatrix, p *resampleParams) (*services.CSIMatrix, map[string]interface{}, error) {
+	if p == nil {
+		return csi, nil, nil
+	}
+	if csi.Timestamps == nil {
+		return nil, nil, &loadError{http.StatusUnprocessableEntity, "Capture has no timestamps; resampling needs a layout with a timestamp column"}
+	}
+	timing, err := dsp.AnalyzeTiming(csi.Timestamps, defaultGapFactor)
+	if err != nil {
+		return nil, nil, &loadError{http.StatusUnprocessableEntity, err.Error()}
+	}
+	rate, maxGap := p.Rate, p.MaxGap
+	if rate == 0 {
+		rate = timing.NominalRate
+	}
+	if maxGap == 0 {
+		maxGap = defaultGapFactor * timing.MedianInterval
+	}
+	limit := min(maxResamplePackets, maxResampleFactor*csi.Packets)
+	out, err := services.ResampleMatrix(csi, rate, p.Method, maxGap, limit)
+	if errors.Is(err, dsp.ErrGridTooLong) {
+		return nil, nil, &loadError{http.StatusUnprocessableEntity,
+			"Resample: " + err.Error() + "; lower resample_rate or check the capture for outlier timestamps"}
+	}
+	if err != nil {
+		return nil, nil, &loadError{http.StatusUnprocessableEntity, "Resample: " + err.Error()}
+	}
+	return out, map[string]interface{}{
+		"method":         p.Method,
+		"rate_hz":        rate,
+		"max_gap_s":      maxGap,
+		"source_packets": csi.Packets,
+		"packets":        out.Packets,
+	}, nil
+}
+
+// prepareMatrix memuat file, meresampling bila diminta, menjalankan
+// pipeline filter (filter bekerja pada grid seragam bila resampling aktif),
+// lalu menerapkan baseline ruangan bila ada
+func (h *PlotHandler) prepareMatrix(ctx context.Context, meta *models.CSI_File, filter *appliedFilter, rs *resampleParams, bl *appliedBaseline) (*services.CSIMatrix, map[string]interface{}, error) {
+	csi, err := h.loadMatrix(ctx, meta)
+	if err != nil {
+		return nil, nil, err
+	}
+	csi, info, err := resampleMatrix(csi, rs)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := applyFilter(csi, filter); err != nil {
+		return nil, nil, err
+	}
+	if err := applyBaseline(csi, bl); err != nil {
+		return nil, nil, err
+	}
+	return csi, info, nil
+}
+
+// GetTiming melaporkan waktu kedatangan paket sebuah capture: laju paket,
+// jitter interval, celah (interval > gap_factor × median), dan perkiraan
+// paket yang hilang
+func (h *PlotHandler) GetTiming(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	gapFactor, err := queryFloat(r, "gap_factor", defaultGapFactor)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if gapFactor <= 1 || gapFactor > 1000 {
+		respondError(w, http.StatusBadRequest, "gap_factor must be greater than 1 and at most 1000")
+		return
+	}
+	meta, err := h.loadMeta(ctx, mux.Vars(r)["id"])
+	if err != nil {
+		respondLoadError(w, err)
+		return
+	}
+	key := analysisKey("timing", meta, nil, fmt.Sprintf("gap=%g", gapFactor))
+	if h.cachedAnalysis(ctx, w, key) {
+		return
+	}
+	csi, err := h.loadMatrix(ctx, meta)
+	if err != nil {
+		respondLoadError(w, err)
+		return
+	}
+	if csi.Timestamps == nil {
+		respondError(w, http.StatusUnprocessableEntity, "Capture has no timestamps; timing analysis needs a layout with a timestamp column")
+		return
+	}
+	report, err := dsp.AnalyzeTiming(csi.Timestamps, gapFactor)
+	if err != nil {
+		respondError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	h.respondAnalysis(ctx, w, key, map[string]interface{}{
+		"meta": map[string]interface{}{
+			"packets": csi.Packets,
+			"layout":  csi.Layout,
+		},
+		"timing": report,
+	})
+}
@@ -0,0 +1,314 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+
+	"cetasense-v2.0/internal/models"
+	"cetasense-v2.0/internal/repositories"
+	"cetasense-v2.0/internal/services"
+)
+
+// Status per file pada laporan upload arsip
+const (
+	archiveFileValid        = "valid"
+	archiveFileInvalid      = "invalid"
+	archiveFileCreated      = "created"
+	archiveFileDuplicate    = "duplicate" // isi sama dengan upload lain; record lama dipakai
+	archiveFileFailed       = "failed"
+	archiveFileRolledBack   = "rolled_back"
+	archiveFileNotProcessed = "not_processed"
+)
+
+// archiveFileResult adalah satu baris laporan upload arsip
+type archiveFileResult struct {
+	File        string                 `json:"file"`
+	NamaRuangan string                 `json:"nama_ruangan"`
+	NamaFilter  string                 `json:"nama_filter"`
+	Labels      map[string]string      `json:"labels,omitempty"`
+	Status      string                 `json:"status"`
+	Error       string                 `json:"error,omitempty"`
+	Profile     *models.CSIProfile     `json:"profile,omitempty"`
+	Upload      map[string]interface{} `json:"upload,omitempty"`
+
+	path   string // path di dalam arsip
+	params ingestParams
+	result *ingestResult
+}
+
+// HandleArchiveUpload menerima arsip zip/tar.gz berisi capture dan sebuah
+// manifest (manifest.json atau manifest.csv) yang memetakan setiap file ke
+// ruangan, filter, dan label. Seluruh manifest divalidasi lebih dulu, lalu
+// semua CSI_File dibuat dalam satu transaksi: semua berhasil atau tidak ada
+// yang tersimpan.
+func (h *UploadHandler) HandleArchiveUpload(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if r.ContentLength > h.cfg.UploadMaxBytes {
+		respondIngestError(w, h.streamError(&http.MaxBytesError{Limit: h.cfg.UploadMaxBytes}, nil))
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, h.cfg.UploadMaxBytes)
+	clearDeadlines(w)
+
+	// zip butuh akses acak, jadi arsip ditampung dulu di file sementara
+	tmp, archiveName, err := spoolArchive(r)
+	if err != nil {
+		respondIngestError(w, h.streamError(err, nil))
+		return
+	}
+	if tmp == nil {
+		respondError(w, http.StatusBadRequest, "Failed to get file: archive is required")
+		return
+	}
+	defer func() {
+		tmp.Close()
+		os.Remove(tmp.Name())
+	}()
+
+	head := make([]byte, 4)
+	n, _ := tmp.ReadAt(head, 0)
+	kind := services.DetectArchive(head[:n])
+	if kind == "" {
+		respondError(w, http.StatusBadRequest, "Unsupported archive: expected zip or tar.gz")
+		return
+	}
+	idx, err := services.ScanArchive(tmp, kind)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid archive: "+err.Error())
+		return
+	}
+
+	report, unlisted := h.validateManifest(r, idx)
+	resp := map[string]interface{}{
+		"archive":  archiveName,
+		"manifest": idx.ManifestPath,
+		"files":    report,
+	}
+	if len(unlisted) > 0 {
+		resp["unlisted"] = unlisted
+	}
+	for _, item := range report {
+		if item.Status == archiveFileInvalid {
+			resp["error"] = "Archive validation failed; nothing was stored"
+			respondJSON(w, http.StatusBadRequest, resp)
+			return
+		}
+	}
+
+	// Proses file sesuai urutan di arsip; objek MinIO dibuat per file, DB
+	// belum disentuh
+	byPath := make(map[string]*archiveFileResult, len(report))
+	for _, item := range report {
+		byPath[item.path] = item
+	}
+	// kunci dedupe sama dengan findDuplicate: isi, ruangan, dan filter
+	dedupeKey := func(item *archiveFileResult, hash string) string {
+		return hash + "/" + item.params.Ruangan.ID + "/" + item.params.Filter.ID
+	}
+	byHash := map[string]*archiveFileResult{}
+	var processErr error
+	walkErr := services.WalkArchive(tmp, kind, func(name string, size int64, rd io.Reader) error {
+		item, ok := byPath[name]
+		if !ok {
+			return nil
+		}
+		stored, err := h.streamToStorage(ctx, rd, path.Base(name))
+		if err == nil {
+			// Duplikat di dalam arsip yang sama belum ada di DB
+			if first, dup := byHash[dedupeKey(item, stored.Hash)]; dup {
+				h.removeObjects(ctx, stored.ObjectPath)
+				item.result = &ingestResult{File: first.result.File, Duplicate: true}
+				return nil
+			}
+			item.params.Stored = stored
+			item.result, err = h.prepare(ctx, item.params)
+		}
+		if err == nil && !item.result.Duplicate {
+			byHash[dedupeKey(item, stored.Hash)] = item
+		}
+		if err != nil {
+			item.Status = archiveFileFailed
+			item.Error = err.Error()
+			var ie *ingestError
+			if errors.As(err, &ie) {
+				item.Profile = ie.profile
+			}
+			processErr = err
+			return err
+		}
+		return nil
+	})
+	if walkErr != nil && processErr == nil {
+		processErr = &ingestError{code: http.StatusBadRequest, msg: "Failed to read archive: " + walkErr.Error()}
+	}
+
+	var files []*models.CSI_File
+	for _, item := range report {
+		if item.result != nil && !item.result.Duplicate {
+			files = append(files, item.result.File)
+		}
+	}
+	if processErr == nil {
+		if err := h.csvRepo.CreateMany(ctx, files); err != nil {
+			log.Printf("CSVFileRepository.CreateMany error: %v", err)
+			code := http.StatusInternalServerError
+			if errors.Is(err, repositories.ErrDuplicateContent) {
+				// upload lain dengan isi yang sama tersimpan lebih dulu
+				code = http.StatusConflict
+			}
+			processErr = &ingestError{code: code, msg: "Failed to save metadata: " + err.Error()}
+		}
+	}
+
+	if processErr != nil {
+		// Batalkan semuanya: hapus objek yang sudah dibuat untuk arsip ini
+		for _, item := range report {
+			switch {
+			case item.result != nil && item.result.Duplicate:
+				item.Status = archiveFileNotProcessed
+			case item.result != nil:
+				f := item.result.File
+				h.removeObjects(ctx, f.ObjectPath, f.RawObjectPath)
+				item.Status = archiveFileRolledBack
+			case item.Status == archiveFileValid:
+				item.Status = archiveFileNotProcessed
+			}
+		}
+		code := http.StatusInternalServerError
+		var ie *ingestError
+		if errors.As(processErr, &ie) {
+			code = ie.code
+		}
+		resp["error"] = "Archive upload failed; nothing was stored: " + processErr.Error()
+		respondJSON(w, code, resp)
+		return
+	}
+
+	if h.onIngested != nil {
+		for _, f := range files {
+			h.onIngested(f.ID)
+		}
+	}
+	for _, item := range report {
+		item.Status = archiveFileCreated
+		if item.result.Duplicate {
+			item.Status = archiveFileDuplicate
+		}
+		item.Upload = uploadResponse(item.result)
+	}
+	resp["created"] = len(files)
+	resp["duplicates"] = len(report) - len(files)
+	log.Printf("Archive %s ingested: %d files", archiveName, len(files))
+	respondJSON(w, http.StatusOK, resp)
+}
+
+// spoolArchive menyalin part "archive" dari form multipart ke file sementara.
+// Mengembalikan file nil bila form tidak berisi arsip.
+func spoolArchive(r *http.Request) (*os.File, string, error) {
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return nil, "", err
+	}
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return nil, "", nil
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		if part.FormName() != "archive" || part.FileName() == "" {
+			part.Close()
+			continue
+		}
+		tmp, err := os.CreateTemp("", "cetasense-archive-*")
+		if err != nil {
+			part.Close()
+			return nil, "", err
+		}
+		_, err = io.Copy(tmp, part)
+		part.Close()
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+			return nil, "", err
+		}
+		return tmp, filepath.Base(part.FileName()), nil
+	}
+}
+
+// validateManifest memeriksa setiap entry manifest terhadap isi arsip dan
+// master data, lalu mengembalikan laporan per file serta file arsip yang
+// tidak disebut manifest
+func (h *UploadHandler) validateManifest(r *http.Request, idx *services.ArchiveIndex) ([]*archiveFileResult, []string) {
+	sizes := make(map[string]int64, len(idx.Files))
+	for _, f := range idx.Files {
+		sizes[f.Name] = f.Size
+	}
+	seenPath := map[string]string{}
+
+	report := make([]*archiveFileResult, 0, len(idx.Manifest))
+	for _, e := range idx.Manifest {
+		item := &archiveFileResult{
+			File:        e.File,
+			NamaRuangan: e.NamaRuangan,
+			NamaFilter:  e.NamaFilter,
+			Labels:      e.Labels,
+			Status:      archiveFileValid,
+		}
+		report = append(report, item)
+		invalid := func(format string, args ...any) {
+			item.Status = archiveFileInvalid
+			item.Error = fmt.Sprintf(format, args...)
+		}
+
+		if e.File == "" {
+			invalid("file is required")
+			continue
+		}
+		item.path = idx.ResolveManifestPath(e.File)
+		size, ok := sizes[item.path]
+		switch {
+		case !ok:
+			invalid("file %s not found in archive", item.path)
+			continue
+		case seenPath[item.path] != "":
+			invalid("file listed more than once")
+			continue
+		case size == 0:
+			invalid("file is empty")
+			continue
+		case size > h.cfg.UploadMaxBytes:
+			invalid("file is %d bytes; the maximum is %d", size, h.cfg.UploadMaxBytes)
+			continue
+		}
+		seenPath[item.path] = e.File
+
+		params, err := h.ingestParamsFromFields(r.Context(), map[string]string{
+			"nama_ruangan": e.NamaRuangan,
+			"nama_filter":  e.NamaFilter,
+			"layout":       e.LayoutString(),
+			"nexmon_chip":  e.NexmonChip,
+		})
+		if err != nil {
+			invalid("%s", err.Error())
+			continue
+		}
+		params.Labels = e.Labels
+		item.params = params
+	}
+
+	var unlisted []string
+	for _, f := range idx.Files {
+		if seenPath[f.Name] == "" {
+			unlisted = append(unlisted, f.Name)
+		}
+	}
+	return report, unlisted
+}
@@ -1,25 +1,29 @@
 package handlers
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
-	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"path/filepath"
 	"time"
 
-	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"github.com/minio/minio-go/v7"
 
 	"cetasense-v2.0/config"
 	"cetasense-v2.0/internal/models"
 	"cetasense-v2.0/internal/repositories"
+	"cetasense-v2.0/internal/services"
 )
 
-// UploadHandler handles CSV uploads: save file to MinIO and metadata to MariaDB
-// using a repository for persistence.
+// jumlah byte awal yang dipakai untuk mendeteksi format upload
+const sniffLen = 4096
+
+// UploadHandler handles CSV uploads (and binary CSI captures that are
+// normalized to CSV): save file to MinIO and metadata to MariaDB using a
+// repository for persistence.
 type UploadHandler struct {
 	csvRepo     *repositories.CSVFileRepository
 	minioClient *minio.Client
@@ -27,6 +31,12 @@ type UploadHandler struct {
 	cfg         *config.Config
 	ruanganRepo *repositories.RuanganRepository
 	filterRepo  *repositories.FilterRepository
+	sessionRepo *repositories.UploadSessionRepository
+
+	// hook analisis: dipanggil setelah upload baru tercatat dan setelah
+	// upload dihapus (opsional)
+	onIngested func(fileID string)
+	onDeleted  func(ctx context.Context, f *models.CSI_File)
 }
 
 // NewUploadHandler constructs a new UploadHandler
@@ -37,6 +47,7 @@ func NewUploadHandler(
 	cfg *config.Config,
 	ruanganRepo *repositories.RuanganRepository,
 	filterRepo *repositories.FilterRepository,
+	sessionRepo *repositories.UploadSessionRepository,
 ) *UploadHandler {
 	return &UploadHandler{
 		csvRepo:     csvRepo,
@@ -45,91 +56,149 @@ func NewUploadHandler(
 		cfg:         cfg,
 		ruanganRepo: ruanganRepo,
 		filterRepo:  filterRepo,
+		sessionRepo: sessionRepo,
 	}
 }
 
-// HandleUpload receives a CSV file, uploads to MinIO, and saves metadata to DB
+// SetAnalysisHooks memasang hook precompute dan pembersihan artifact analisis
+func (h *UploadHandler) SetAnalysisHooks(onIngested func(fileID string), onDeleted func(ctx context.Context, f *models.CSI_File)) {
+	h.onIngested, h.onDeleted = onIngested, onDeleted
+}
+
+// qualityThresholds membaca ambang kualitas upload dari konfigurasi
+func (h *UploadHandler) qualityThresholds() services.QualityThresholds {
+	return services.QualityThresholds{
+		MinPackets:      h.cfg.UploadMinPackets,
+		MinParseRatio:   h.cfg.UploadMinParseRatio,
+		MaxMeanDropout:  h.cfg.UploadMaxMeanDropout,
+		MinPacketRateHz: h.cfg.UploadMinPacketRateHz,
+	}
+}
+
+// batas ukuran field form non-file pada upload multipart
+const maxFormFieldBytes = 64 << 10
+
+// HandleUpload receives a CSV file or a binary CSI capture, streams it to
+// MinIO, and saves metadata to DB. Body tidak pernah ditahan utuh di memori;
+// ukuran maksimum diatur lewat UPLOAD_MAX_BYTES.
 func (h *UploadHandler) HandleUpload(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	// Get form values
-	namaRuangan := r.FormValue("nama_ruangan")
-	namaFilter := r.FormValue("nama_filter")
+	if r.ContentLength > h.cfg.UploadMaxBytes {
+		respondIngestError(w, h.streamError(&http.MaxBytesError{Limit: h.cfg.UploadMaxBytes}, nil))
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, h.cfg.UploadMaxBytes)
+	clearDeadlines(w)
 
-	// Parse multipart form (limit: 10 MB)
-	if err := r.ParseMultipartForm(10 << 20); err != nil {
+	mr, err := r.MultipartReader()
+	if err != nil {
 		respondError(w, http.StatusBadRequest, "Failed to parse form: "+err.Error())
 		return
 	}
-	log.Printf("Form parsed successfully, ruangan: %s, filter: %s", namaRuangan, namaFilter)
-	// Retrieve ruangan info
-	ruangan, err := h.ruanganRepo.GetRuanganByNama(ctx, namaRuangan)
-	if err != nil {
-		log.Printf("GetRuanganByNama error: %v", err)
-		respondError(w, http.StatusBadRequest, "Invalid ruangan: "+err.Error())
+
+	// File bisa datang sebelum field lain (frontend mengirim csv_file lebih
+	// dulu), jadi file di-stream dulu lalu field divalidasi setelahnya
+	fields := map[string]string{}
+	var stored *storedUpload
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if stored != nil {
+				h.removeObjects(ctx, stored.ObjectPath)
+			}
+			respondIngestError(w, h.streamError(err, nil))
+			return
+		}
+		name := part.FormName()
+		if name == "csv_file" && part.FileName() != "" {
+			if stored != nil {
+				part.Close()
+				continue
+			}
+			stored, err = h.streamToStorage(ctx, part, filepath.Base(part.FileName()))
+			part.Close()
+			if err != nil {
+				respondIngestError(w, err)
+				return
+			}
+			continue
+		}
+		value, err := io.ReadAll(io.LimitReader(part, maxFormFieldBytes))
+		part.Close()
+		if err != nil {
+			if stored != nil {
+				h.removeObjects(ctx, stored.ObjectPath)
+			}
+			respondIngestError(w, h.streamError(err, nil))
+			return
+		}
+		fields[name] = string(value)
+	}
+	if stored == nil {
+		respondError(w, http.StatusBadRequest, "Failed to get file: csv_file is required")
 		return
 	}
-	// Retrieve filter info
-	filter, err := h.filterRepo.GetFilterByNama(ctx, namaFilter)
+	log.Printf("Form parsed successfully, ruangan: %s, filter: %s", fields["nama_ruangan"], fields["nama_filter"])
+
+	params, err := h.ingestParamsFromFields(ctx, fields)
 	if err != nil {
-		log.Printf("GetFilterByNama error: %v", err)
-		respondError(w, http.StatusBadRequest, "Invalid filter: "+err.Error())
+		h.removeObjects(ctx, stored.ObjectPath)
+		respondIngestError(w, err)
 		return
 	}
-	// Retrieve CSV file from the form
-	file, header, err := r.FormFile("csv_file")
+	params.Stored = stored
+
+	res, err := h.ingest(ctx, params)
 	if err != nil {
-		log.Printf("FormFile error: %v", err)
-		respondError(w, http.StatusBadRequest, "Failed to get file: "+err.Error())
+		respondIngestError(w, err)
 		return
 	}
-	defer file.Close()
-	// Read file into buffer for upload
-	buf, err := io.ReadAll(file)
+	respondJSON(w, http.StatusOK, uploadResponse(res))
+}
+
+// clearDeadlines melepas Read/WriteTimeout server untuk request upload yang
+// bisa berjalan lama di jaringan lambat
+func clearDeadlines(w http.ResponseWriter) {
+	rc := http.NewResponseController(w)
+	if err := rc.SetReadDeadline(time.Time{}); err != nil {
+		log.Printf("SetReadDeadline error: %v", err)
+	}
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		log.Printf("SetWriteDeadline error: %v", err)
+	}
+}
+
+// ingestParamsFromFields memvalidasi field form upload (nama_ruangan,
+// nama_filter, layout, nexmon_chip)
+func (h *UploadHandler) ingestParamsFromFields(ctx context.Context, fields map[string]string) (ingestParams, error) {
+	var p ingestParams
+	ruangan, err := h.ruanganRepo.GetRuanganByNama(ctx, fields["nama_ruangan"])
 	if err != nil {
-		log.Printf("ReadAll error: %v", err)
-		respondError(w, http.StatusInternalServerError, "Failed to read file: "+err.Error())
-		return
+		log.Printf("GetRuanganByNama error: %v", err)
+		return p, &ingestError{code: http.StatusBadRequest, msg: "Invalid ruangan: " + err.Error()}
 	}
-	// Generate unique ID and object path
-	fileID := uuid.New().String()
-	namaFile := header.Filename
-	objectPath := fmt.Sprintf("Data-Parameter/%s", namaFile)
+	filter, err := h.filterRepo.GetFilterByNama(ctx, fields["nama_filter"])
+	if err != nil {
+		log.Printf("GetFilterByNama error: %v", err)
+		return p, &ingestError{code: http.StatusBadRequest, msg: "Invalid filter: " + err.Error()}
+	}
+	p.Ruangan, p.Filter = ruangan, filter
 
-	// Upload to MinIO
-	_, err = h.minioClient.PutObject(ctx, h.bucketName, objectPath,
-		bytes.NewReader(buf), int64(len(buf)), minio.PutObjectOptions{ContentType: "text/csv"})
+	// Deskriptor layout kolom opsional (JSON)
+	if raw := fields["layout"]; raw != "" {
+		if p.Declared, err = services.ParseLayout(raw); err != nil {
+			return p, &ingestError{code: http.StatusBadRequest, msg: err.Error()}
+		}
+	}
+	nexmonDecoding, err := services.NexmonDecodingFromForm(fields["nexmon_chip"])
 	if err != nil {
-		log.Printf("MinIO PutObject error: %v", err)
-		respondError(w, http.StatusInternalServerError, "Failed to upload file to storage: "+err.Error())
-		return
+		return p, &ingestError{code: http.StatusBadRequest, msg: err.Error()}
 	}
-	log.Printf("File %s uploaded successfully to bucket %s", objectPath, h.bucketName)
-	// Save metadata to MariaDB via repository
-	fileMeta := &models.CSI_File{
-		ID:          fileID,
-		FileName:    header.Filename,
-		ObjectPath:  objectPath,
-		CreatedAt:   time.Now().Format(time.RFC3339),
-		RuanganID:   ruangan.ID,
-		FilterID:    filter.ID,
-		NamaRuangan: ruangan.NamaRuangan,
-		NamaFilter:  filter.NamaFilter,
-	}
-	if err := h.csvRepo.Create(ctx, fileMeta); err != nil {
-		log.Printf("CSVFileRepository.Create error: %v", err)
-		respondError(w, http.StatusInternalServerError, "Failed to save metadata: "+err.Error())
-		return
-	}
-	log.Printf("Metadata for %s saved successfully", header.Filename)
-	// Respond with JSON
-	respondJSON(w, http.StatusOK, map[string]interface{}{
-		"file_id":     fileID,
-		"file_name":   header.Filename,
-		"object_path": objectPath,
-		"ruangan_id":  ruangan.ID,
-		"filter_id":   filter.ID,
-		"created_at":  fileMeta.CreatedAt,
-	})
+	p.Decode = services.DecodeOptions{NexmonDecoding: nexmonDecoding}
+	return p, nil
 }
 
 // GetAllUploads mengembalikan list semua file CSV yang sudah di-upload
@@ -178,6 +247,11 @@ func (h *UploadHandler) DeleteUpload(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	log.Printf("File deleted from MinIO: %s", fileMeta.ObjectPath)
+	if fileMeta.RawObjectPath != "" {
+		if err := h.minioClient.RemoveObject(ctx, h.bucketName, fileMeta.RawObjectPath, minio.RemoveObjectOptions{}); err != nil {
+			log.Printf("MinIO RemoveObject (raw) error: %v", err)
+		}
+	}
 
 	// 3. Delete metadata from DB
 	if err := h.csvRepo.Delete(ctx, fileID); err != nil {
@@ -185,6 +259,9 @@ func (h *UploadHandler) DeleteUpload(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	log.Printf("Metadata deleted for file ID: %s", fileID)
+	if h.onDeleted != nil {
+		h.onDeleted(ctx, fileMeta)
+	}
 
 	// 4. Respond with JSON
 	respondJSON(w, http.StatusOK, map[string]interface{}{
@@ -233,3 +310,58 @@ func (h *UploadHandler) UpdateName(w http.ResponseWriter, r *http.Request) {
 		"message": "File name updated successfully",
 	})
 }
+
+// UpdateLayout mendeklarasikan ulang layout kolom sebuah upload
+func (h *UploadHandler) UpdateLayout(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	fileID := mux.Vars(r)["id"]
+	if fileID == "" {
+		respondError(w, http.StatusBadRequest, "File ID is required")
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Failed to read body: "+err.Error())
+		return
+	}
+	layout, err := services.ParseLayout(string(body))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	fileMeta, err := h.csvRepo.GetByID(ctx, fileID)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "File not found: "+err.Error())
+		return
+	}
+	// Cek layout terhadap isi file yang tersimpan dan profilkan ulang
+	obj, err := h.minioClient.GetObject(ctx, h.bucketName, fileMeta.ObjectPath, minio.GetObjectOptions{})
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch file: "+err.Error())
+		return
+	}
+	defer obj.Close()
+	res, err := services.ProfileCSVStream(obj, layout)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Parse CSV: "+err.Error())
+		return
+	}
+	if res.LayoutErr != nil {
+		respondError(w, http.StatusBadRequest, "Invalid layout: "+res.LayoutErr.Error())
+		return
+	}
+	services.EvaluateProfile(res.Profile, h.qualityThresholds(), true)
+
+	if err := h.csvRepo.UpdateLayout(ctx, fileID, layout, res.Profile); err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to update layout: "+err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"message": "Layout updated successfully",
+		"layout":  layout,
+		"profile": res.Profile,
+		"quality": res.Profile.Quality,
+	})
+}
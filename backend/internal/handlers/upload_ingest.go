@@ -0,0 +1,374 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/minio/minio-go/v7"
+
+	"cetasense-v2.0/internal/models"
+	"cetasense-v2.0/internal/repositories"
+	"cetasense-v2.0/internal/services"
+)
+
+// ukuran part multipart MinIO untuk upload dengan ukuran tak diketahui
+const uploadPartSize = 16 << 20
+
+// storedUpload adalah file yang sudah di-stream ke MinIO tapi belum
+// diproses (normalisasi, layout, profiling) atau dicatat di DB
+type storedUpload struct {
+	FileID     string // ID CSI_File yang akan dibuat; juga prefix object key
+	FileName   string
+	Format     string
+	ObjectPath string
+	Size       int64
+	Hash       string // SHA-256 hex
+}
+
+// ingestParams adalah input pipeline ingest untuk satu file
+type ingestParams struct {
+	Stored   *storedUpload
+	Ruangan  *models.Ruangan
+	Filter   *models.Filter
+	Declared *models.CSILayout
+	Decode   services.DecodeOptions
+	Labels   map[string]string
+}
+
+// ingestResult adalah hasil pipeline ingest. Bila Duplicate, File adalah
+// record lama dengan isi yang sama dan tidak ada yang disimpan.
+type ingestResult struct {
+	File          *models.CSI_File
+	Packets       int
+	LayoutWarning string
+	Duplicate     bool
+}
+
+// ingestError membawa status HTTP dan (bila ada) profil yang ditolak
+type ingestError struct {
+	code    int
+	msg     string
+	profile *models.CSIProfile
+}
+
+func (e *ingestError) Error() string { return e.msg }
+
+// respondIngestError menulis error pipeline ingest dengan status yang sesuai
+func respondIngestError(w http.ResponseWriter, err error) {
+	var ie *ingestError
+	if errors.As(err, &ie) {
+		if ie.profile != nil {
+			respondJSON(w, ie.code, map[string]interface{}{
+				"error":   ie.msg,
+				"profile": ie.profile,
+			})
+			return
+		}
+		respondError(w, ie.code, ie.msg)
+		return
+	}
+	respondError(w, http.StatusInternalServerError, err.Error())
+}
+
+// trackingReader menyimpan error baca pertama; minio-go tidak selalu
+// meneruskan error reader (misalnya *http.MaxBytesError) apa adanya
+type trackingReader struct {
+	r   io.Reader
+	err error
+}
+
+func (t *trackingReader) Read(p []byte) (int, error) {
+	n, err := t.r.Read(p)
+	if err != nil && err != io.EOF && t.err == nil {
+		t.err = err
+	}
+	return n, err
+}
+
+// streamToStorage men-stream file langsung ke MinIO sambil menghitung
+// SHA-256. Format dideteksi dari byte awal; object key memakai ID file baru
+// sehingga upload dengan nama sama tidak saling menimpa.
+func (h *UploadHandler) streamToStorage(ctx context.Context, r io.Reader, fileName string) (*storedUpload, error) {
+	tracked := &trackingReader{r: r}
+	br := bufio.NewReaderSize(tracked, sniffLen)
+	head, err := br.Peek(sniffLen)
+	if err != nil && err != io.EOF {
+		return nil, h.streamError(err, tracked)
+	}
+	if len(head) == 0 {
+		return nil, &ingestError{code: http.StatusBadRequest, msg: "Uploaded file is empty"}
+	}
+
+	fileID := uuid.New().String()
+	format := services.DetectFormat(fileName, head)
+	objectPath, contentType := storagePath(fileID, fileName, format)
+
+	hasher := sha256.New()
+	info, err := h.minioClient.PutObject(ctx, h.bucketName, objectPath, io.TeeReader(br, hasher), -1,
+		minio.PutObjectOptions{ContentType: contentType, PartSize: uploadPartSize})
+	if err != nil {
+		log.Printf("MinIO PutObject error: %v", err)
+		return nil, h.streamError(err, tracked)
+	}
+	log.Printf("File %s streamed to bucket %s (%d bytes)", objectPath, h.bucketName, info.Size)
+
+	return &storedUpload{
+		FileID:     fileID,
+		FileName:   fileName,
+		Format:     format,
+		ObjectPath: objectPath,
+		Size:       info.Size,
+		Hash:       hex.EncodeToString(hasher.Sum(nil)),
+	}, nil
+}
+
+// storagePath menentukan object key dan content type file yang di-upload.
+// Semua objek sebuah upload berada di bawah Data-Parameter/<file_id>/;
+// capture biner disimpan di raw/ dan versi ternormalisasinya di
+// normalizedPath.
+func storagePath(fileID, fileName, format string) (objectPath, contentType string) {
+	if format != services.FormatCSV {
+		return fmt.Sprintf("Data-Parameter/%s/raw/%s", fileID, fileName), "application/octet-stream"
+	}
+	return fmt.Sprintf("Data-Parameter/%s/%s", fileID, fileName), "text/csv"
+}
+
+// normalizedPath adalah object key CSV ternormalisasi sebuah capture biner
+func normalizedPath(fileID, fileName string) string {
+	base := strings.TrimSuffix(fileName, filepath.Ext(fileName))
+	return fmt.Sprintf("Data-Parameter/%s/%s.csv", fileID, base)
+}
+
+// streamError memetakan error saat streaming ke status HTTP; melewati batas
+// ukuran upload menjadi 413
+func (h *UploadHandler) streamError(err error, tracked *trackingReader) error {
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) || (tracked != nil && errors.As(tracked.err, &maxErr)) {
+		return &ingestError{
+			code: http.StatusRequestEntityTooLarge,
+			msg:  fmt.Sprintf("Upload exceeds the maximum size of %d bytes", h.cfg.UploadMaxBytes),
+		}
+	}
+	if tracked != nil && tracked.err != nil {
+		return &ingestError{code: http.StatusBadRequest, msg: "Failed to read upload: " + tracked.err.Error()}
+	}
+	return &ingestError{code: http.StatusInternalServerError, msg: "Failed to upload file to storage: " + err.Error()}
+}
+
+// removeObjects menghapus objek MinIO (best effort) saat ingest dibatalkan
+func (h *UploadHandler) removeObjects(ctx context.Context, paths ...string) {
+	for _, p := range paths {
+		if p == "" {
+			continue
+		}
+		if err := h.minioClient.RemoveObject(ctx, h.bucketName, p, minio.RemoveObjectOptions{}); err != nil {
+			log.Printf("MinIO RemoveObject %s error: %v", p, err)
+		}
+	}
+}
+
+// ingest memproses file yang sudah tersimpan di MinIO: normalisasi capture
+// biner, menentukan layout, profiling kualitas, lalu mencatat CSI_File.
+// Bila gagal, semua objek yang dibuat untuk file ini dihapus.
+func (h *UploadHandler) ingest(ctx context.Context, p ingestParams) (*ingestResult, error) {
+	res, err := h.prepare(ctx, p)
+	if err != nil || res.Duplicate {
+		return res, err
+	}
+	if err := h.csvRepo.Create(ctx, res.File); err != nil {
+		log.Printf("CSVFileRepository.Create error: %v", err)
+		h.removeObjects(ctx, res.File.ObjectPath, res.File.RawObjectPath)
+		if errors.Is(err, repositories.ErrDuplicateContent) {
+			// upload identik paralel menang lebih dulu; pakai record-nya
+			existing, lookupErr := h.csvRepo.GetDuplicate(ctx, res.File.ContentHash, res.File.RuanganID, res.File.FilterID)
+			if lookupErr == nil {
+				return &ingestResult{File: existing, Duplicate: true}, nil
+			}
+			log.Printf("CSVFileRepository.GetDuplicate error: %v", lookupErr)
+			return nil, &ingestError{code: http.StatusConflict, msg: err.Error()}
+		}
+		return nil, &ingestError{code: http.StatusInternalServerError, msg: "Failed to save metadata: " + err.Error()}
+	}
+	log.Printf("Metadata for %s saved successfully", res.File.FileName)
+	if h.onIngested != nil {
+		h.onIngested(res.File.ID)
+	}
+	return res, nil
+}
+
+// prepare menjalankan ingest tanpa menulis ke DB sehingga bisa dipakai
+// juga oleh upload massal yang mencatat semua file dalam satu transaksi
+func (h *UploadHandler) prepare(ctx context.Context, p ingestParams) (*ingestResult, error) {
+	st := p.Stored
+	if existing := h.findDuplicate(ctx, st, p.Ruangan.ID, p.Filter.ID); existing != nil {
+		return &ingestResult{File: existing, Duplicate: true}, nil
+	}
+	file := &models.CSI_File{
+		ID:          st.FileID,
+		FileName:    st.FileName,
+		ObjectPath:  st.ObjectPath,
+		CreatedAt:   time.Now().Format(time.RFC3339),
+		RuanganID:   p.Ruangan.ID,
+		FilterID:    p.Filter.ID,
+		NamaRuangan: p.Ruangan.NamaRuangan,
+		NamaFilter:  p.Filter.NamaFilter,
+		Format:      st.Format,
+		ContentHash: st.Hash,
+		SizeBytes:   st.Size,
+		Labels:      p.Labels,
+	}
+	res := &ingestResult{File: file}
+
+	fail := func(code int, msg string, profile *models.CSIProfile) (*ingestResult, error) {
+		h.removeObjects(ctx, st.ObjectPath, file.RawObjectPath)
+		if file.ObjectPath != st.ObjectPath {
+			h.removeObjects(ctx, file.ObjectPath)
+		}
+		return nil, &ingestError{code: code, msg: msg, profile: profile}
+	}
+
+	obj, err := h.minioClient.GetObject(ctx, h.bucketName, st.ObjectPath, minio.GetObjectOptions{})
+	if err != nil {
+		return fail(http.StatusInternalServerError, "Failed to read stored file: "+err.Error(), nil)
+	}
+	defer obj.Close()
+
+	var capture *services.Capture
+	if st.Format == services.FormatCSV {
+		// CSV: pakai layout yang dideklarasikan, atau auto-detect sebagai
+		// fallback. CSV yang tidak bisa ditebak tetap diterima tanpa layout.
+		sp, err := services.ProfileCSVStream(obj, p.Declared)
+		switch {
+		case err != nil:
+			if p.Declared != nil {
+				return fail(http.StatusBadRequest, "Invalid layout: "+err.Error(), nil)
+			}
+			file.Profile = &models.CSIProfile{
+				Quality: models.QualityFlagged,
+				Issues:  []string{"failed to parse: " + err.Error()},
+			}
+		case sp.LayoutErr != nil && p.Declared != nil:
+			return fail(http.StatusBadRequest, "Invalid layout: "+sp.LayoutErr.Error(), nil)
+		default:
+			if sp.LayoutErr != nil {
+				res.LayoutWarning = sp.LayoutErr.Error()
+				log.Printf("Layout auto-detect failed for %s: %v", st.FileName, sp.LayoutErr)
+			}
+			file.Layout = sp.Layout
+			file.Profile = sp.Profile
+			services.EvaluateProfile(file.Profile, h.qualityThresholds(), file.Layout != nil)
+		}
+	} else {
+		capture, err = services.DecodeCapture(st.Format, obj, p.Decode)
+		if err != nil {
+			log.Printf("DecodeCapture(%s) error: %v", st.Format, err)
+			return fail(http.StatusBadRequest, "Failed to decode "+st.Format+" capture: "+err.Error(), nil)
+		}
+		res.Packets = capture.Packets()
+		// layout capture biner ditentukan oleh parser, deklarasi user diabaikan
+		file.Layout, file.Profile = services.ProfileCapture(capture)
+		services.EvaluateProfile(file.Profile, h.qualityThresholds(), true)
+		file.RawObjectPath = st.ObjectPath
+		file.ObjectPath = normalizedPath(st.FileID, st.FileName)
+	}
+	file.Quality = file.Profile.Quality
+
+	if file.Quality != models.QualityOK {
+		log.Printf("Upload %s failed quality checks: %v", st.FileName, file.Profile.Issues)
+		if h.cfg.UploadQualityAction == "reject" {
+			file.Profile.Quality = models.QualityRejected
+			return fail(http.StatusUnprocessableEntity, "Upload rejected by quality checks", file.Profile)
+		}
+	}
+
+	if capture != nil {
+		if err := h.putNormalized(ctx, capture, file.ObjectPath); err != nil {
+			return fail(http.StatusInternalServerError, "Failed to store normalized capture: "+err.Error(), nil)
+		}
+	}
+	return res, nil
+}
+
+// findDuplicate mencari upload lama dengan SHA-256 yang sama untuk ruangan
+// dan filter yang sama. Bila ada, objek yang baru di-stream dihapus dan
+// record lama dipakai; isi yang sama di ruangan/filter lain tetap menjadi
+// upload baru. Kegagalan query tidak menggagalkan upload; unique index
+// uq_data_csv_content tetap menjaga duplikat yang lolos dari pengecekan ini.
+func (h *UploadHandler) findDuplicate(ctx context.Context, st *storedUpload, ruanganID, filterID string) *models.CSI_File {
+	if st.Hash == "" {
+		return nil
+	}
+	existing, err := h.csvRepo.GetDuplicate(ctx, st.Hash, ruanganID, filterID)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("CSVFileRepository.GetDuplicate error: %v", err)
+		}
+		return nil
+	}
+	log.Printf("Upload %s duplicates file %s (sha256 %s)", st.FileName, existing.ID, st.Hash)
+	h.removeObjects(ctx, st.ObjectPath)
+	return existing
+}
+
+// putNormalized men-stream CSV ternormalisasi sebuah capture biner ke MinIO
+func (h *UploadHandler) putNormalized(ctx context.Context, capture *services.Capture, objectPath string) error {
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(services.WriteNormalizedCSV(pw, capture))
+	}()
+	_, err := h.minioClient.PutObject(ctx, h.bucketName, objectPath, pr, -1,
+		minio.PutObjectOptions{ContentType: "text/csv", PartSize: uploadPartSize})
+	pr.CloseWithError(err)
+	if err != nil {
+		return err
+	}
+	log.Printf("Normalized capture stored at %s (%d packets)", objectPath, capture.Packets())
+	return nil
+}
+
+// uploadResponse adalah payload JSON standar untuk satu upload
+func uploadResponse(res *ingestResult) map[string]interface{} {
+	f := res.File
+	resp := map[string]interface{}{
+		"file_id":      f.ID,
+		"file_name":    f.FileName,
+		"object_path":  f.ObjectPath,
+		"ruangan_id":   f.RuanganID,
+		"filter_id":    f.FilterID,
+		"created_at":   f.CreatedAt,
+		"format":       f.Format,
+		"content_hash": f.ContentHash,
+		"size_bytes":   f.SizeBytes,
+		"profile":      f.Profile,
+		"quality":      f.Quality,
+	}
+	if f.RawObjectPath != "" {
+		resp["raw_object_path"] = f.RawObjectPath
+		resp["packets"] = res.Packets
+	}
+	if f.Layout != nil {
+		resp["layout"] = f.Layout
+	}
+	if res.LayoutWarning != "" {
+		resp["layout_warning"] = res.LayoutWarning
+	}
+	if len(f.Labels) > 0 {
+		resp["labels"] = f.Labels
+	}
+	if res.Duplicate {
+		resp["duplicate"] = true
+	}
+	return resp
+}
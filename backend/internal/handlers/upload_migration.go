@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"path"
+
+	"github.com/minio/minio-go/v7"
+
+	"cetasense-v2.0/internal/models"
+	"cetasense-v2.0/internal/repositories"
+)
+
+// nama named lock MariaDB untuk migrasi object key
+const objectKeyMigrationLock = "cetasense_object_key_migration"
+
+// MigrateLegacyObjectKeys memindahkan upload lama yang masih disimpan di
+// Data-Parameter/<nama file> ke Data-Parameter/<id>/ sekaligus mengisi hash
+// dan ukuran isinya. Setiap record mendapat salinan objeknya sendiri (dua
+// record bisa menunjuk objek yang sama karena saling menimpa); objek lama
+// baru dihapus setelah tidak ada record yang menunjuknya. Idempotent dan
+// hanya dijalankan oleh satu replika gateway.
+func (h *UploadHandler) MigrateLegacyObjectKeys(ctx context.Context) error {
+	ran, err := h.csvRepo.WithLock(ctx, objectKeyMigrationLock, func() error {
+		files, err := h.csvRepo.ListLegacyObjectKeys(ctx)
+		if err != nil {
+			return err
+		}
+		if len(files) == 0 {
+			return nil
+		}
+		log.Printf("Migrating %d uploads to per-file object keys", len(files))
+		migrated := 0
+		for _, f := range files {
+			if err := h.migrateObjectKey(ctx, f); err != nil {
+				// Record yang gagal tetap di key lama dan dicoba lagi saat start berikutnya
+				log.Printf("Object key migration for %s (%s) failed: %v", f.ID, f.ObjectPath, err)
+				continue
+			}
+			migrated++
+		}
+		log.Printf("Object key migration done: %d of %d uploads moved", migrated, len(files))
+		return nil
+	})
+	if err == nil && !ran {
+		log.Printf("Object key migration is running on another instance; skipped")
+	}
+	return err
+}
+
+func (h *UploadHandler) migrateObjectKey(ctx context.Context, f *models.CSI_File) error {
+	oldObject, oldRaw := f.ObjectPath, f.RawObjectPath
+
+	// Hash upload biner dihitung dari file aslinya, sama seperti saat ingest
+	hashed := oldObject
+	if oldRaw != "" {
+		hashed = oldRaw
+	}
+	hash, size, err := h.hashObject(ctx, hashed)
+	if err != nil {
+		return fmt.Errorf("hash %s: %w", hashed, err)
+	}
+
+	newObject := fmt.Sprintf("Data-Parameter/%s/%s", f.ID, path.Base(oldObject))
+	if err := h.copyObject(ctx, oldObject, newObject); err != nil {
+		return err
+	}
+	newRaw := ""
+	if oldRaw != "" {
+		newRaw = fmt.Sprintf("Data-Parameter/%s/raw/%s", f.ID, path.Base(oldRaw))
+		if err := h.copyObject(ctx, oldRaw, newRaw); err != nil {
+			h.removeObjects(ctx, newObject)
+			return err
+		}
+	}
+
+	f.ObjectPath, f.RawObjectPath = newObject, newRaw
+	f.ContentHash, f.SizeBytes = hash, size
+	err = h.csvRepo.UpdateStorage(ctx, f)
+	if errors.Is(err, repositories.ErrDuplicateContent) {
+		err = h.resolveHashCollision(ctx, f)
+	}
+	if err != nil {
+		h.removeObjects(ctx, newObject, newRaw)
+		return fmt.Errorf("update record: %w", err)
+	}
+
+	for _, old := range []string{oldObject, oldRaw} {
+		if old == "" {
+			continue
+		}
+		refs, err := h.csvRepo.CountObjectReferences(ctx, old)
+		if err != nil {
+			log.Printf("CountObjectReferences(%s) error: %v", old, err)
+			continue
+		}
+		if refs == 0 {
+			h.removeObjects(ctx, old)
+		}
+	}
+	log.Printf("Upload %s moved %s -> %s", f.ID, oldObject, newObject)
+	return nil
+}
+
+// resolveHashCollision menangani upload lama yang isinya sama dengan upload
+// lain di ruangan dan filter yang sama: upload paling awal memegang hash.
+// Upload yang lebih baru (mis. diterima sebelum migrasi selesai karena
+// upload lama belum punya hash) dilepas hash-nya; bila yang memegang hash
+// justru lebih awal, record ini dipindahkan tanpa hash.
+func (h *UploadHandler) resolveHashCollision(ctx context.Context, f *models.CSI_File) error {
+	released, err := h.csvRepo.ReleaseLaterDuplicates(ctx, f.ID, f.ContentHash)
+	if err != nil {
+		return err
+	}
+	if released > 0 {
+		log.Printf("Upload %s keeps content hash; released it from %d later duplicate(s)", f.ID, released)
+		err = h.csvRepo.UpdateStorage(ctx, f)
+		if !errors.Is(err, repositories.ErrDuplicateContent) {
+			return err
+		}
+	}
+	log.Printf("Upload %s has the same content as an earlier upload; migrating without content hash", f.ID)
+	f.ContentHash = ""
+	return h.csvRepo.UpdateStorage(ctx, f)
+}
+
+// hashObject menghitung SHA-256 dan ukuran sebuah objek secara streaming
+func (h *UploadHandler) hashObject(ctx context.Context, objectPath string) (string, int64, error) {
+	obj, err := h.minioClient.GetObject(ctx, h.bucketName, objectPath, minio.GetObjectOptions{})
+	if err != nil {
+		return "", 0, err
+	}
+	defer obj.Close()
+	hasher := sha256.New()
+	n, err := io.Copy(hasher, obj)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), n, nil
+}
+
+// copyObject menyalin objek di sisi server (ComposeObject mendukung objek
+// di atas 5 GiB)
+func (h *UploadHandler) copyObject(ctx context.Context, src, dst string) error {
+	_, err := h.minioClient.ComposeObject(ctx,
+		minio.CopyDestOptions{Bucket: h.bucketName, Object: dst},
+		minio.CopySrcOptions{Bucket: h.bucketName, Object: src},
+	)
+	if err != nil {
+		return fmt.Errorf("copy %s -> %s: %w", src, dst, err)
+	}
+	return nil
+}
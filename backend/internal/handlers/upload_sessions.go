@@ -0,0 +1,470 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/minio/minio-go/v7"
+
+	"cetasense-v2.0/internal/models"
+	"cetasense-v2.0/internal/repositories"
+	"cetasense-v2.0/internal/services"
+)
+
+// batas jumlah part multipart upload S3/MinIO
+const maxUploadParts = 10000
+
+// createSessionRequest adalah body POST /api/upload/sessions. Layout boleh
+// berupa objek JSON atau string JSON seperti pada form /api/upload.
+type createSessionRequest struct {
+	FileName    string          `json:"file_name"`
+	Size        int64           `json:"size"`
+	NamaRuangan string          `json:"nama_ruangan"`
+	NamaFilter  string          `json:"nama_filter"`
+	Layout      json.RawMessage `json:"layout,omitempty"`
+	NexmonChip  string          `json:"nexmon_chip,omitempty"`
+}
+
+// sessionState adalah payload JSON status sebuah sesi upload
+func sessionState(s *models.UploadSession) map[string]interface{} {
+	resp := map[string]interface{}{
+		"session_id":      s.ID,
+		"file_name":       s.FileName,
+		"size":            s.Size,
+		"chunk_size":      s.ChunkSize,
+		"chunks":          s.Chunks(),
+		"received_chunks": s.ReceivedChunks,
+		"offset":          s.Offset,
+		"status":          s.Status,
+		"expires_at":      s.ExpiresAt.Format(time.RFC3339),
+	}
+	if s.ReceivedChunks < s.Chunks() {
+		resp["next_chunk"] = s.ReceivedChunks + 1
+	}
+	if s.Format != "" {
+		resp["format"] = s.Format
+	}
+	return resp
+}
+
+// sessionFields mengembalikan field form yang disimpan di sesi dalam bentuk
+// yang sama dengan form multipart /api/upload
+func sessionFields(s *models.UploadSession) map[string]string {
+	return map[string]string{
+		"nama_ruangan": s.NamaRuangan,
+		"nama_filter":  s.NamaFilter,
+		"layout":       s.Layout,
+		"nexmon_chip":  s.NexmonChip,
+	}
+}
+
+// getSession mengambil sesi dari URL dan menulis 404 bila tidak ada atau
+// sudah kedaluwarsa
+func (h *UploadHandler) getSession(w http.ResponseWriter, r *http.Request) (*models.UploadSession, bool) {
+	id := mux.Vars(r)["id"]
+	s, err := h.sessionRepo.GetByID(r.Context(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondError(w, http.StatusNotFound, "Upload session not found or expired")
+		return nil, false
+	}
+	if err != nil {
+		log.Printf("UploadSessionRepository.GetByID error: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to fetch upload session: "+err.Error())
+		return nil, false
+	}
+	return s, true
+}
+
+// respondSessionConflict menulis 409 beserta status sesi terkini agar
+// client bisa melanjutkan dari offset yang benar
+func respondSessionConflict(w http.ResponseWriter, s *models.UploadSession, msg string) {
+	resp := sessionState(s)
+	resp["error"] = msg
+	respondJSON(w, http.StatusConflict, resp)
+}
+
+// CreateUploadSession memulai upload bertahap. Field form divalidasi di
+// awal agar client tidak mengirim ratusan MB untuk ruangan yang salah.
+func (h *UploadHandler) CreateUploadSession(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	var req createSessionRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxFormFieldBytes)).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid JSON format")
+		return
+	}
+	fileName := filepath.Base(req.FileName)
+	if req.FileName == "" || fileName == "." || fileName == "/" {
+		respondError(w, http.StatusBadRequest, "file_name is required")
+		return
+	}
+	if req.Size <= 0 {
+		respondError(w, http.StatusBadRequest, "size must be positive")
+		return
+	}
+	if req.Size > h.cfg.UploadMaxBytes {
+		respondIngestError(w, h.streamError(&http.MaxBytesError{Limit: h.cfg.UploadMaxBytes}, nil))
+		return
+	}
+
+	layout := ""
+	if len(req.Layout) > 0 && string(req.Layout) != "null" {
+		layout = string(req.Layout)
+		var s string
+		if json.Unmarshal(req.Layout, &s) == nil {
+			layout = s
+		}
+	}
+	fields := map[string]string{
+		"nama_ruangan": req.NamaRuangan,
+		"nama_filter":  req.NamaFilter,
+		"layout":       layout,
+		"nexmon_chip":  req.NexmonChip,
+	}
+	if _, err := h.ingestParamsFromFields(ctx, fields); err != nil {
+		respondIngestError(w, err)
+		return
+	}
+
+	now := time.Now().UTC()
+	s := &models.UploadSession{
+		ID:          uuid.New().String(),
+		FileName:    fileName,
+		Size:        req.Size,
+		ChunkSize:   h.cfg.UploadChunkBytes,
+		NamaRuangan: req.NamaRuangan,
+		NamaFilter:  req.NamaFilter,
+		Layout:      layout,
+		NexmonChip:  req.NexmonChip,
+		Status:      models.UploadSessionOpen,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(h.cfg.UploadSessionTTL),
+	}
+	if s.Chunks() > maxUploadParts {
+		respondError(w, http.StatusRequestEntityTooLarge,
+			fmt.Sprintf("File needs %d chunks of %d bytes; the maximum is %d", s.Chunks(), s.ChunkSize, maxUploadParts))
+		return
+	}
+	if err := h.sessionRepo.Create(ctx, s); err != nil {
+		log.Printf("UploadSessionRepository.Create error: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to create upload session: "+err.Error())
+		return
+	}
+	log.Printf("Upload session %s created for %s (%d bytes, %d chunks)", s.ID, s.FileName, s.Size, s.Chunks())
+	respondJSON(w, http.StatusCreated, sessionState(s))
+}
+
+// GetUploadSession mengembalikan offset dan chunk berikutnya yang diharapkan
+func (h *UploadHandler) GetUploadSession(w http.ResponseWriter, r *http.Request) {
+	s, ok := h.getSession(w, r)
+	if !ok {
+		return
+	}
+	respondJSON(w, http.StatusOK, sessionState(s))
+}
+
+// PutUploadChunk menerima chunk ke-n (body mentah) dan meneruskannya sebagai
+// part ke-n multipart upload MinIO. Chunk harus berurutan karena hash isi
+// file dihitung bertahap; chunk yang sudah diterima dijawab 409 dengan
+// offset terkini.
+func (h *UploadHandler) PutUploadChunk(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	n, err := strconv.Atoi(mux.Vars(r)["n"])
+	if err != nil || n < 1 {
+		respondError(w, http.StatusBadRequest, "Chunk number must be a positive integer")
+		return
+	}
+	s, ok := h.getSession(w, r)
+	if !ok {
+		return
+	}
+	if s.Status != models.UploadSessionOpen {
+		respondSessionConflict(w, s, "Upload session is being finalized")
+		return
+	}
+	if n > s.Chunks() {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("Chunk %d out of range (file has %d chunks)", n, s.Chunks()))
+		return
+	}
+	if n != s.ReceivedChunks+1 {
+		respondSessionConflict(w, s, fmt.Sprintf("Expected chunk %d, got %d", s.ReceivedChunks+1, n))
+		return
+	}
+	want := s.ChunkLength(n)
+	if r.ContentLength >= 0 && r.ContentLength != want {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("Chunk %d must be %d bytes, got %d", n, want, r.ContentLength))
+		return
+	}
+	clearDeadlines(w)
+
+	hasher := sha256.New()
+	if len(s.HashState) > 0 {
+		if err := hasher.(encoding.BinaryUnmarshaler).UnmarshalBinary(s.HashState); err != nil {
+			respondError(w, http.StatusInternalServerError, "Corrupt upload session hash state: "+err.Error())
+			return
+		}
+	}
+
+	core := minio.Core{Client: h.minioClient}
+	tracked := &trackingReader{r: io.LimitReader(r.Body, want)}
+	var body io.Reader = tracked
+	createdUpload := false
+	if n == 1 {
+		// Multipart upload dibuat saat chunk pertama karena object key
+		// bergantung pada format yang dideteksi dari isi file
+		br := bufio.NewReaderSize(tracked, sniffLen)
+		head, err := br.Peek(int(min(want, sniffLen)))
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "Failed to read chunk: "+err.Error())
+			return
+		}
+		var contentType string
+		s.Format = services.DetectFormat(s.FileName, head)
+		// ID sesi sekaligus menjadi ID CSI_File hasil finalisasi
+		s.ObjectPath, contentType = storagePath(s.ID, s.FileName, s.Format)
+		s.UploadID, err = core.NewMultipartUpload(ctx, h.bucketName, s.ObjectPath,
+			minio.PutObjectOptions{ContentType: contentType})
+		if err != nil {
+			log.Printf("MinIO NewMultipartUpload error: %v", err)
+			respondError(w, http.StatusInternalServerError, "Failed to start upload in storage: "+err.Error())
+			return
+		}
+		createdUpload = true
+		body = br
+	}
+	abort := func() {
+		if createdUpload {
+			h.abortMultipart(ctx, s)
+		}
+	}
+
+	part, err := core.PutObjectPart(ctx, h.bucketName, s.ObjectPath, s.UploadID, n,
+		io.TeeReader(body, hasher), want, minio.PutObjectPartOptions{})
+	if err == nil && part.Size != want {
+		err = fmt.Errorf("stored %d of %d bytes", part.Size, want)
+	}
+	if err != nil {
+		abort()
+		log.Printf("MinIO PutObjectPart(%s, %d) error: %v", s.ID, n, err)
+		if tracked.err != nil || errors.Is(err, io.ErrUnexpectedEOF) {
+			respondError(w, http.StatusBadRequest, fmt.Sprintf("Failed to read chunk %d: %v", n, err))
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "Failed to store chunk: "+err.Error())
+		return
+	}
+
+	state, err := hasher.(encoding.BinaryMarshaler).MarshalBinary()
+	if err != nil {
+		abort()
+		respondError(w, http.StatusInternalServerError, "Failed to save hash state: "+err.Error())
+		return
+	}
+	prev := s.ReceivedChunks
+	s.ReceivedChunks = n
+	s.Offset += want
+	s.HashState = state
+	s.ExpiresAt = time.Now().UTC().Add(h.cfg.UploadSessionTTL)
+	if err := h.sessionRepo.AdvanceChunk(ctx, s, prev); err != nil {
+		abort()
+		if errors.Is(err, repositories.ErrSessionConflict) {
+			respondError(w, http.StatusConflict, "Chunk was uploaded concurrently; query the session offset and retry")
+			return
+		}
+		log.Printf("UploadSessionRepository.AdvanceChunk error: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to update upload session: "+err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, sessionState(s))
+}
+
+// CompleteUploadSession menyatukan semua part lalu menjalankan pipeline
+// ingest yang sama dengan /api/upload; responsnya pun sama.
+func (h *UploadHandler) CompleteUploadSession(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	s, ok := h.getSession(w, r)
+	if !ok {
+		return
+	}
+	if s.Status != models.UploadSessionOpen {
+		respondSessionConflict(w, s, "Upload session is already being finalized")
+		return
+	}
+	if s.Offset != s.Size {
+		respondSessionConflict(w, s, fmt.Sprintf("Upload incomplete: %d of %d bytes received", s.Offset, s.Size))
+		return
+	}
+	// Ingest capture besar bisa lama; perpanjang sesi supaya tidak disapu janitor
+	expires := time.Now().UTC().Add(h.cfg.UploadSessionTTL)
+	if err := h.sessionRepo.SetStatus(ctx, s.ID, models.UploadSessionOpen, models.UploadSessionCompleting, expires); err != nil {
+		if errors.Is(err, repositories.ErrSessionConflict) {
+			respondSessionConflict(w, s, "Upload session is already being finalized")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "Failed to update upload session: "+err.Error())
+		return
+	}
+	clearDeadlines(w)
+
+	params, err := h.ingestParamsFromFields(ctx, sessionFields(s))
+	if err != nil {
+		h.reopenSession(ctx, s)
+		respondIngestError(w, err)
+		return
+	}
+
+	core := minio.Core{Client: h.minioClient}
+	listed, err := core.ListObjectParts(ctx, h.bucketName, s.ObjectPath, s.UploadID, 0, maxUploadParts)
+	if err != nil {
+		h.reopenSession(ctx, s)
+		respondError(w, http.StatusInternalServerError, "Failed to list uploaded parts: "+err.Error())
+		return
+	}
+	parts := make([]minio.CompletePart, 0, len(listed.ObjectParts))
+	for _, p := range listed.ObjectParts {
+		if p.PartNumber > s.ReceivedChunks {
+			continue
+		}
+		parts = append(parts, minio.CompletePart{PartNumber: p.PartNumber, ETag: p.ETag})
+	}
+	if len(parts) != s.Chunks() {
+		h.reopenSession(ctx, s)
+		respondError(w, http.StatusInternalServerError,
+			fmt.Sprintf("Storage has %d of %d parts for this upload", len(parts), s.Chunks()))
+		return
+	}
+	if _, err := core.CompleteMultipartUpload(ctx, h.bucketName, s.ObjectPath, s.UploadID, parts, minio.PutObjectOptions{}); err != nil {
+		log.Printf("MinIO CompleteMultipartUpload(%s) error: %v", s.ID, err)
+		h.reopenSession(ctx, s)
+		respondError(w, http.StatusInternalServerError, "Failed to assemble upload: "+err.Error())
+		return
+	}
+
+	hasher := sha256.New()
+	if err := hasher.(encoding.BinaryUnmarshaler).UnmarshalBinary(s.HashState); err != nil {
+		h.removeObjects(ctx, s.ObjectPath)
+		h.dropSession(ctx, s)
+		respondError(w, http.StatusInternalServerError, "Corrupt upload session hash state: "+err.Error())
+		return
+	}
+	params.Stored = &storedUpload{
+		FileID:     s.ID,
+		FileName:   s.FileName,
+		Format:     s.Format,
+		ObjectPath: s.ObjectPath,
+		Size:       s.Size,
+		Hash:       hex.EncodeToString(hasher.Sum(nil)),
+	}
+	log.Printf("Upload session %s assembled at %s (%d bytes)", s.ID, s.ObjectPath, s.Size)
+
+	// Objek sudah utuh; sesi selesai apa pun hasil ingest
+	res, err := h.ingest(ctx, params)
+	h.dropSession(ctx, s)
+	if err != nil {
+		respondIngestError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, uploadResponse(res))
+}
+
+// AbortUploadSession membatalkan upload bertahap dan membuang part-nya
+func (h *UploadHandler) AbortUploadSession(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	s, ok := h.getSession(w, r)
+	if !ok {
+		return
+	}
+	if s.Status != models.UploadSessionOpen {
+		respondSessionConflict(w, s, "Upload session is being finalized")
+		return
+	}
+	h.abortMultipart(ctx, s)
+	h.dropSession(ctx, s)
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"message": "Upload session aborted",
+	})
+}
+
+// reopenSession mengembalikan sesi ke status open setelah finalisasi gagal
+// sehingga client bisa mencoba lagi
+func (h *UploadHandler) reopenSession(ctx context.Context, s *models.UploadSession) {
+	expires := time.Now().UTC().Add(h.cfg.UploadSessionTTL)
+	if err := h.sessionRepo.SetStatus(ctx, s.ID, models.UploadSessionCompleting, models.UploadSessionOpen, expires); err != nil {
+		log.Printf("Reopen upload session %s error: %v", s.ID, err)
+	}
+}
+
+func (h *UploadHandler) dropSession(ctx context.Context, s *models.UploadSession) {
+	if err := h.sessionRepo.Delete(ctx, s.ID); err != nil {
+		log.Printf("UploadSessionRepository.Delete(%s) error: %v", s.ID, err)
+	}
+}
+
+// abortMultipart membuang part yang sudah ter-upload (best effort)
+func (h *UploadHandler) abortMultipart(ctx context.Context, s *models.UploadSession) {
+	if s.UploadID == "" {
+		return
+	}
+	core := minio.Core{Client: h.minioClient}
+	if err := core.AbortMultipartUpload(ctx, h.bucketName, s.ObjectPath, s.UploadID); err != nil {
+		log.Printf("MinIO AbortMultipartUpload(%s) error: %v", s.ID, err)
+	}
+}
+
+// CleanupExpiredSessions menghapus sesi yang kedaluwarsa beserta multipart
+// upload MinIO-nya. Aman dijalankan paralel oleh beberapa replika gateway.
+func (h *UploadHandler) CleanupExpiredSessions(ctx context.Context) (int, error) {
+	now := time.Now().UTC()
+	expired, err := h.sessionRepo.ListExpired(ctx, now, 100)
+	if err != nil {
+		return 0, err
+	}
+	cleaned := 0
+	for _, s := range expired {
+		// Klaim sesi lewat DELETE dulu agar replika lain tidak ikut membersihkan
+		deleted, err := h.sessionRepo.DeleteExpired(ctx, s.ID, now)
+		if err != nil {
+			return cleaned, err
+		}
+		if !deleted {
+			continue
+		}
+		h.abortMultipart(ctx, s)
+		cleaned++
+	}
+	return cleaned, nil
+}
+
+// RunSessionJanitor menjalankan CleanupExpiredSessions secara periodik
+// sampai ctx dibatalkan
+func (h *UploadHandler) RunSessionJanitor(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := h.CleanupExpiredSessions(ctx)
+			if err != nil {
+				log.Printf("Upload session cleanup error: %v", err)
+			} else if n > 0 {
+				log.Printf("Cleaned up %d expired upload sessions", n)
+			}
+		}
+	}
+}
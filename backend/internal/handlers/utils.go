@@ -2,7 +2,11 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
 )
 
 // Helper functions
@@ -17,3 +21,85 @@ func respondJSON(w http.ResponseWriter, code int, payload interface{}) {
 	w.WriteHeader(code)
 	json.NewEncoder(w).Encode(payload)
 }
+
+// queryInt membaca parameter query integer; def bila kosong
+func queryInt(r *http.Request, name string, def int) (int, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be an integer", name)
+	}
+	return n, nil
+}
+
+// nullable mengubah NaN menjadi null agar bisa di-encode ke JSON
+func nullable(x []float64) []*float64 {
+	out := make([]*float64, len(x))
+	for i := range x {
+		if !math.IsNaN(x[i]) && !math.IsInf(x[i], 0) {
+			out[i] = &x[i]
+		}
+	}
+	return out
+}
+
+// queryFloat membaca parameter query float; def bila kosong
+func queryFloat(r *http.Request, name string, def float64) (float64, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return def, nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, fmt.Errorf("%s must be a number", name)
+	}
+	return f, nil
+}
+
+// parseIndexList membaca daftar indeks 1-based seperti "1,3,10-20" dan
+// mengembalikan indeks 0-based terurut tanpa duplikat. Kosong berarti
+// semua indeks 0..n-1.
+func parseIndexList(v string, n int) ([]int, error) {
+	if v == "" {
+		all := make([]int, n)
+		for i := range all {
+			all[i] = i
+		}
+		return all, nil
+	}
+	seen := make([]bool, n)
+	for _, part := range strings.Split(v, ",") {
+		part = strings.TrimSpace(part)
+		lo, hi := part, part
+		if i := strings.Index(part, "-"); i > 0 {
+			lo, hi = part[:i], part[i+1:]
+		}
+		a, errA := strconv.Atoi(strings.TrimSpace(lo))
+		b, errB := strconv.Atoi(strings.TrimSpace(hi))
+		if errA != nil || errB != nil || a < 1 || b < a || b > n {
+			return nil, fmt.Errorf("invalid index %q (expected 1..%d)", part, n)
+		}
+		for i := a; i <= b; i++ {
+			seen[i-1] = true
+		}
+	}
+	var out []int
+	for i, ok := range seen {
+		if ok {
+			out = append(out, i)
+		}
+	}
+	return out, nil
+}
+
+// oneBased mengubah indeks 0-based menjadi 1-based untuk respons
+func oneBased(idx []int) []int {
+	out := make([]int, len(idx))
+	for i, v := range idx {
+		out[i] = v + 1
+	}
+	return out
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// AnalysisArtifact adalah hasil analisis yang disimpan permanen di MinIO.
+// Key diturunkan dari hash isi file, layout, versi filter, dan parameter
+// analisis, sehingga artifact tidak pernah basi; file lain dengan isi yang
+// sama memakai artifact yang sama.
+type AnalysisArtifact struct {
+	Key           string    `json:"key" db:"cache_key"`
+	Kind          string    `json:"kind" db:"kind"`
+	FileID        string    `json:"file_id" db:"file_id"`
+	ContentHash   string    `json:"content_hash,omitempty" db:"content_hash"`
+	Params        string    `json:"params" db:"params"` // parameter kanonik
+	FilterID      string    `json:"filter_id,omitempty" db:"filter_id"`
+	FilterVersion int       `json:"filter_version,omitempty" db:"filter_version"`
+	ObjectPath    string    `json:"object_path" db:"object_path"`
+	SizeBytes     int64     `json:"size_bytes" db:"size_bytes"`
+	Hits          int64     `json:"hits" db:"hits"`
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+	LastUsedAt    time.Time `json:"last_used_at" db:"last_used_at"`
+}
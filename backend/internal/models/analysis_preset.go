@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// BNRParams adalah parameter analisis BNR /api/plots/{id}. Field yang tidak
+// dikirim memakai nilai DefaultBNRParams.
+type BNRParams struct {
+	// Persentil amplitudo per subcarrier (lintas waktu) sebagai baseline dropout
+	PercentileBaseline float64 `json:"percentile_baseline" validate:"gte=0,lte=100"`
+	// Sampel dengan amplitudo/baseline di bawah rasio ini dianggap dropout
+	DropoutRatio float64 `json:"dropout_ratio" validate:"gte=0,lt=1"`
+	// BNR di bawah ClipDbLo di-mask; [ClipDbLo, ClipDbHi] adalah rentang tampilan
+	ClipDbLo float64 `json:"clip_db_lo" validate:"gte=-200,lte=200"`
+	ClipDbHi float64 `json:"clip_db_hi" validate:"gte=-200,lte=200,gtfield=ClipDbLo"`
+	// Jarak minimal antar subcarrier terpilih (non-redundan)
+	MinGapSubcarrier int `json:"min_gap_subcarrier" validate:"gte=0,lte=2048"`
+	// Batas |rho| korelasi deret waktu antar subcarrier terpilih
+	MaxAbsCorrThreshold float64 `json:"max_abs_corr" validate:"gt=0,lte=1"`
+	// Jumlah subcarrier terpilih per channel
+	TopK int `json:"top_k" validate:"min=1,max=64"`
+}
+
+// DefaultBNRParams adalah nilai bawaan analisis BNR
+func DefaultBNRParams() BNRParams {
+	return BNRParams{
+		PercentileBaseline:  15.0, // perc-15 per subcarrier (lintas waktu)
+		DropoutRatio:        5e-4, // A/baseline < 5e-4 => masked
+		ClipDbLo:            -20.0,
+		ClipDbHi:            20.0,
+		MinGapSubcarrier:    3,   // non-redundant spacing antar subcarrier
+		MaxAbsCorrThreshold: 0.8, // non-redundant korelasi |rho| < 0.8
+		TopK:                5,
+	}
+}
+
+// AnalysisPreset adalah kumpulan parameter BNR bernama yang bisa dipakai
+// ulang lewat /api/plots/{id}?preset=<nama>
+type AnalysisPreset struct {
+	ID        string    `json:"id" db:"id"`
+	Name      string    `json:"name" db:"name" validate:"required,min=3,max=255"`
+	Params    BNRParams `json:"params" db:"params"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// GenerateID untuk preset
+func (p *AnalysisPreset) GenerateID() {
+	p.ID = uuid.New().String()
+}
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Mode penerapan baseline ruangan pada analisis dan job
+const (
+	BaselineModeNone      = "none"      // baseline disimpan tapi tidak diterapkan otomatis
+	BaselineModeSubtract  = "subtract"  // amplitudo - median baseline + level stream
+	BaselineModeNormalize = "normalize" // amplitudo / median baseline
+)
+
+// RoomBaselineRequest menandai upload ruangan kosong/diam sebagai baseline
+// ruangan dan menghitung profilnya. FileIDs wajib saat menandai; saat
+// recompute kosong berarti file baseline versi terakhir dipakai lagi.
+type RoomBaselineRequest struct {
+	FileIDs []string `json:"file_ids" validate:"omitempty,min=1,max=32,dive,uuid"`
+	// Mode yang diterapkan otomatis pada analisis/job file ruangan ini;
+	// kosong berarti mode versi terakhir (atau none untuk baseline pertama)
+	Mode     string `json:"mode" validate:"omitempty,oneof=none subtract normalize"`
+	Filtered *bool  `json:"filtered"`
+}
+
+// BaselineSource adalah satu upload yang membentuk baseline, beserta
+// identitas isi dan versi filter saat profil dihitung
+type BaselineSource struct {
+	FileID        string `json:"file_id"`
+	FileName      string `json:"file_name"`
+	ContentHash   string `json:"content_hash,omitempty"`
+	FilterID      string `json:"filter_id,omitempty"`
+	FilterVersion int    `json:"filter_version,omitempty"`
+	Packets       int    `json:"packets"`
+}
+
+// BaselineProfile adalah statistik per stream × subcarrier gabungan semua
+// paket file baseline. Nilai null berarti subcarrier tanpa sampel valid;
+// PhaseOffset/PhaseSpread nil bila layout tidak punya fase.
+type BaselineProfile struct {
+	Streams     int          `json:"streams"`
+	Subcarriers int          `json:"subcarriers"`
+	Median      [][]*float64 `json:"median"`
+	Spread      [][]*float64 `json:"spread"` // 1.4826 × MAD
+	ValidPct    [][]float64  `json:"valid_pct"`
+	// Rata-rata sirkular fase tersanitasi (radian) dan deviasi sirkularnya
+	PhaseOffset [][]*float64 `json:"phase_offset,omitempty"`
+	PhaseSpread [][]*float64 `json:"phase_spread,omitempty"`
+}
+
+// RoomBaseline adalah satu versi baseline ruangan. Versi naik setiap kali
+// baseline ditandai ulang atau dihitung ulang; versi terbaru yang aktif.
+type RoomBaseline struct {
+	ID        string           `json:"id" db:"id"`
+	RuanganID string           `json:"ruangan_id" db:"id_ruangan"`
+	Version   int              `json:"version" db:"version"`
+	Mode      string           `json:"mode" db:"mode"`
+	Filtered  bool             `json:"filtered" db:"filtered"`
+	Sources   []BaselineSource `json:"sources" db:"sources"`
+	Layout    *CSILayout       `json:"layout" db:"layout"`
+	Packets   int              `json:"packets" db:"packets"`
+	Profile   *BaselineProfile `json:"profile,omitempty" db:"profile"`
+	CreatedAt time.Time        `json:"created_at" db:"created_at"`
+}
+
+// Generate ID sebelum insert
+func (b *RoomBaseline) GenerateID() {
+	b.ID = uuid.New().String()
+}
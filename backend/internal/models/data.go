@@ -34,4 +34,18 @@ type CSI_File struct {
 	FilterID    string `json:"filter_id" db:"id_filter" validate:"required,uuid"`
 	NamaRuangan string `json:"nama_ruangan" db:"nama_ruangan" validate:"required"`
 	NamaFilter  string `json:"nama_filter" db:"nama_filter" validate:"required"`
+	// Format sumber upload (csv, intel5300, ...); file non-CSV disimpan
+	// ternormalisasi di ObjectPath dan file aslinya di RawObjectPath
+	Format        string `json:"format" db:"format"`
+	RawObjectPath string `json:"raw_object_path,omitempty" db:"raw_object_path"`
+	// Susunan kolom CSI; nil untuk upload lama yang layout-nya belum diketahui
+	Layout *CSILayout `json:"layout,omitempty" db:"layout"`
+	// Profil kualitas dari saat upload; Quality = ok | flagged
+	Profile *CSIProfile `json:"profile,omitempty" db:"profile"`
+	Quality string      `json:"quality,omitempty" db:"quality"`
+	// SHA-256 isi file yang di-upload (file asli untuk capture biner)
+	ContentHash string `json:"content_hash,omitempty" db:"content_hash"`
+	SizeBytes   int64  `json:"size_bytes,omitempty" db:"size_bytes"`
+	// Label bebas dari manifest upload arsip (misalnya skenario, subjek)
+	Labels map[string]string `json:"labels,omitempty" db:"labels"`
 }
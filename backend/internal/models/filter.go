@@ -6,10 +6,49 @@ import (
 	"github.com/google/uuid"
 )
 
+// Jenis stage pipeline filter
+const (
+	FilterStageHampel        = "hampel"
+	FilterStageButterworth   = "butterworth"
+	FilterStageMovingAverage = "moving_average"
+	FilterStageSavitzkyGolay = "savitzky_golay"
+)
+
+// FilterStage adalah satu tahap pipeline filter. Parameter yang dipakai
+// bergantung pada Type:
+//
+//	hampel:         window (setengah lebar jendela), k (ambang × sigma)
+//	butterworth:    order, cutoff (Hz), fs (Hz) — low-pass, zero-phase
+//	moving_average: n
+//	savitzky_golay: window (ganjil), poly
+type FilterStage struct {
+	Type   string  `json:"type" validate:"required,oneof=hampel butterworth moving_average savitzky_golay"`
+	Window int     `json:"window,omitempty"`
+	K      float64 `json:"k,omitempty"`
+	Order  int     `json:"order,omitempty"`
+	Cutoff float64 `json:"cutoff,omitempty"`
+	FS     float64 `json:"fs,omitempty"`
+	N      int     `json:"n,omitempty"`
+	Poly   int     `json:"poly,omitempty"`
+}
+
 type Filter struct {
-	ID         string    `json:"id" db:"id"`
-	NamaFilter string    `json:"nama_filter" db:"nama_filter" validate:"required,min=3"`
-	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	ID         string `json:"id" db:"id"`
+	NamaFilter string `json:"nama_filter" db:"nama_filter" validate:"required,min=3"`
+	// Pipeline dijalankan berurutan pada deret waktu amplitudo tiap
+	// subcarrier; kosong berarti filter hanya label (data tidak diubah)
+	Pipeline []FilterStage `json:"pipeline" db:"pipeline" validate:"omitempty,max=16,dive"`
+	// Version naik setiap kali pipeline berubah
+	Version   int       `json:"version" db:"version"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// FilterVersion adalah snapshot pipeline sebuah filter
+type FilterVersion struct {
+	FilterID  string        `json:"filter_id"`
+	Version   int           `json:"version"`
+	Pipeline  []FilterStage `json:"pipeline"`
+	CreatedAt string        `json:"created_at"`
 }
 
 // GenerateID untuk filter
@@ -0,0 +1,167 @@
+package models
+
+import "math"
+
+// Susunan kolom CSI
+const (
+	LayoutOrderStreamMajor     = "stream_major"     // semua subcarrier stream 0, lalu stream 1, ...
+	LayoutOrderSubcarrierMajor = "subcarrier_major" // semua stream subcarrier 0, lalu subcarrier 1, ...
+
+	LayoutEncodingAmplitude      = "amplitude"       // hanya kolom amplitudo
+	LayoutEncodingAmplitudePhase = "amplitude_phase" // blok amplitudo lalu blok fase
+	LayoutEncodingRealImag       = "real_imag"       // pasangan (real, imag) per sel
+
+	LayoutSourceDeclared = "declared" // dikirim user saat upload
+	LayoutSourceFormat   = "format"   // dari parser format biner (Intel 5300, ESP32, Nexmon)
+	LayoutSourceInferred = "inferred" // tebakan heuristik 90 kolom berurutan
+)
+
+// CSILayout menjelaskan susunan kolom sebuah file CSI (CSV). Semua indeks
+// kolom 0-based; HasHeader menentukan apakah baris pertama adalah header
+// (hanya file tanpa layout yang header-nya ditebak saat parsing).
+type CSILayout struct {
+	Streams     int    `json:"streams" validate:"required,min=1,max=64"`
+	Subcarriers int    `json:"subcarriers" validate:"required,min=1,max=2048"`
+	Order       string `json:"order" validate:"required,oneof=stream_major subcarrier_major"`
+	Encoding    string `json:"encoding" validate:"required,oneof=amplitude amplitude_phase real_imag"`
+	// Kolom pertama data CSI (amplitudo atau pasangan real/imag)
+	DataOffset int `json:"data_offset" validate:"min=0"`
+	// Kolom pertama blok fase untuk encoding amplitude_phase;
+	// default tepat setelah blok amplitudo
+	PhaseOffset  *int `json:"phase_offset,omitempty" validate:"omitempty,min=0"`
+	TimestampCol *int `json:"timestamp_col,omitempty" validate:"omitempty,min=0"`
+	// Satuan kolom timestamp: s (default), ms, us, ns
+	TimestampUnit string `json:"timestamp_unit,omitempty" validate:"omitempty,oneof=s ms us ns"`
+	RSSICol       *int   `json:"rssi_col,omitempty" validate:"omitempty,min=0"`
+	HasHeader     bool   `json:"has_header"`
+	Source        string `json:"source"`
+	// Lebar kanal dan jarak antar subcarrier (Hz) yang terekam; opsional,
+	// dipakai analisis domain delay (CIR/PDP)
+	BandwidthHz         float64 `json:"bandwidth_hz,omitempty" validate:"min=0"`
+	SubcarrierSpacingHz float64 `json:"subcarrier_spacing_hz,omitempty" validate:"min=0"`
+}
+
+// TimestampScale mengembalikan faktor konversi kolom timestamp ke detik
+func (l *CSILayout) TimestampScale() float64 {
+	switch l.TimestampUnit {
+	case "ms":
+		return 1e-3
+	case "us":
+		return 1e-6
+	case "ns":
+		return 1e-9
+	}
+	return 1
+}
+
+// Cells adalah jumlah sel CSI (stream × subcarrier)
+func (l *CSILayout) Cells() int {
+	return l.Streams * l.Subcarriers
+}
+
+// Column mengembalikan indeks kolom pertama untuk sel (stream, subcarrier).
+// Untuk real_imag, kolom imajiner adalah Column()+1.
+func (l *CSILayout) Column(stream, subcarrier int) int {
+	cell := stream*l.Subcarriers + subcarrier
+	if l.Order == LayoutOrderSubcarrierMajor {
+		cell = subcarrier*l.Streams + stream
+	}
+	if l.Encoding == LayoutEncodingRealImag {
+		return l.DataOffset + 2*cell
+	}
+	return l.DataOffset + cell
+}
+
+// PhaseColumn mengembalikan kolom fase untuk encoding amplitude_phase
+func (l *CSILayout) PhaseColumn(stream, subcarrier int) int {
+	start := l.DataOffset + l.Cells()
+	if l.PhaseOffset != nil {
+		start = *l.PhaseOffset
+	}
+	return start + (l.Column(stream, subcarrier) - l.DataOffset)
+}
+
+// Spacing mengembalikan jarak antar subcarrier (Hz). Bila hanya lebar kanal
+// yang diketahui, subcarrier dianggap menyebar rata di seluruh kanal; 0
+// berarti tidak diketahui.
+func (l *CSILayout) Spacing() float64 {
+	if l.SubcarrierSpacingHz > 0 {
+		return l.SubcarrierSpacingHz
+	}
+	if l.BandwidthHz > 0 && l.Subcarriers > 0 {
+		return l.BandwidthHz / float64(l.Subcarriers)
+	}
+	return 0
+}
+
+// SubcarrierIndices mengembalikan indeks tone OFDM (relatif terhadap DC)
+// tiap kolom subcarrier, dipakai sebagai sumbu x fit fase. Susunan dikenali
+// dari jumlah subcarrier: 30 = grouping Intel 5300 (Ng=2 di 20 MHz, Ng=4 di
+// 40 MHz), 52/56/114 = subcarrier data ESP32 tanpa DC, 64/128/256 = FFT
+// penuh ter-fftshift (Nexmon) dengan DC dan guard band bernilai NaN. nil
+// bila susunan tidak dikenali (indeks dianggap 0..S-1).
+func (l *CSILayout) SubcarrierIndices() []float64 {
+	S := l.Subcarriers
+	var out []float64
+	// span menambahkan lo, lo+step, ..., hi
+	span := func(lo, hi, step int) {
+		for k := lo; k <= hi; k += step {
+			out = append(out, float64(k))
+		}
+	}
+	switch S {
+	case 30:
+		if l.BandwidthHz == 40e6 {
+			span(-58, -2, 4)
+			span(2, 58, 4)
+		} else {
+			span(-28, -2, 2)
+			span(-1, 1, 2)
+			span(3, 27, 2)
+			span(28, 28, 1)
+		}
+	case 52, 56:
+		span(-S/2, -1, 1)
+		span(1, S/2, 1)
+	case 114:
+		span(-58, -2, 1)
+		span(2, 58, 1)
+	case 64, 128, 256:
+		// subcarrier data/pilot terdalam dan terluar (HT20, HT40, VHT80)
+		inner, edge := 1, 28
+		switch S {
+		case 128:
+			inner, edge = 2, 58
+		case 256:
+			inner, edge = 2, 122
+		}
+		for pos := 0; pos < S; pos++ {
+			k := pos - S/2
+			if ak := max(k, -k); ak < inner || ak > edge {
+				out = append(out, math.NaN())
+			} else {
+				out = append(out, float64(k))
+			}
+		}
+	}
+	return out
+}
+
+// MaxColumn adalah indeks kolom terbesar yang dirujuk layout
+func (l *CSILayout) MaxColumn() int {
+	last := l.DataOffset + l.Cells() - 1
+	if l.Encoding == LayoutEncodingRealImag {
+		last = l.DataOffset + 2*l.Cells() - 1
+	}
+	if l.Encoding == LayoutEncodingAmplitudePhase {
+		if p := l.PhaseColumn(0, 0) + l.Cells() - 1; p > last {
+			last = p
+		}
+	}
+	for _, c := range []*int{l.TimestampCol, l.RSSICol} {
+		if c != nil && *c > last {
+			last = *c
+		}
+	}
+	return last
+}
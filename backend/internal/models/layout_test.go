@@ -0,0 +1,52 @@
+package models
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSubcarrierIndices(t *testing.T) {
+	intel := (&CSILayout{Subcarriers: 30}).SubcarrierIndices()
+	want := []float64{-28, -26, -24, -22, -20, -18, -16, -14, -12, -10, -8, -6, -4, -2, -1,
+		1, 3, 5, 7, 9, 11, 13, 15, 17, 19, 21, 23, 25, 27, 28}
+	if len(intel) != len(want) {
+		t.Fatalf("Intel 20 MHz: %v", intel)
+	}
+	for i := range want {
+		if intel[i] != want[i] {
+			t.Fatalf("Intel 20 MHz: %v, want %v", intel, want)
+		}
+	}
+	ht40 := (&CSILayout{Subcarriers: 30, BandwidthHz: 40e6}).SubcarrierIndices()
+	if ht40[0] != -58 || ht40[14] != -2 || ht40[15] != 2 || ht40[29] != 58 {
+		t.Errorf("Intel 40 MHz: %v", ht40)
+	}
+
+	// ESP32: subcarrier data tanpa DC
+	for S, edges := range map[int][4]float64{52: {-26, -1, 1, 26}, 56: {-28, -1, 1, 28}, 114: {-58, -2, 2, 58}} {
+		idx := (&CSILayout{Subcarriers: S}).SubcarrierIndices()
+		if len(idx) != S || idx[0] != edges[0] || idx[S/2-1] != edges[1] || idx[S/2] != edges[2] || idx[S-1] != edges[3] {
+			t.Errorf("%d subcarriers: %v", S, idx)
+		}
+	}
+
+	// FFT penuh: DC dan guard band NaN
+	fft := (&CSILayout{Subcarriers: 64}).SubcarrierIndices()
+	nulls := 0
+	for pos, k := range fft {
+		if math.IsNaN(k) {
+			nulls++
+			continue
+		}
+		if k != float64(pos-32) {
+			t.Fatalf("position %d has tone %g", pos, k)
+		}
+	}
+	if nulls != 8 || !math.IsNaN(fft[32]) || fft[33] != 1 || fft[4] != -28 {
+		t.Errorf("64-point FFT: %d null bins, %v", nulls, fft)
+	}
+
+	if idx := (&CSILayout{Subcarriers: 12}).SubcarrierIndices(); idx != nil {
+		t.Errorf("unknown arrangement gave %v, want nil", idx)
+	}
+}
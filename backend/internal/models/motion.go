@@ -0,0 +1,87 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Status segmen deteksi gerakan
+const (
+	MotionStateMotion = "motion"
+	MotionStateStatic = "static"
+)
+
+// MotionRequest adalah parameter deteksi gerakan pada satu capture. Field
+// kosong memakai default; Streams/Subcarriers berformat indeks 1-based
+// seperti query analisis lain ("1,3,10-20").
+type MotionRequest struct {
+	Method      string  `json:"method" validate:"omitempty,oneof=variance eigen"`
+	Window      int     `json:"window" validate:"omitempty,min=4,max=4096"`
+	Hop         int     `json:"hop" validate:"omitempty,min=1"`
+	Streams     string  `json:"streams"`
+	Subcarriers string  `json:"subcarriers"`
+	FS          float64 `json:"fs" validate:"min=0"`
+	// Resampling ke grid waktu seragam sebelum deteksi (linear | spline);
+	// kosong berarti indeks paket dipakai sebagai waktu. Setelah resampling,
+	// window/hop/kalibrasi dihitung dalam sampel grid.
+	Resample       string   `json:"resample"`
+	ResampleRate   float64  `json:"resample_rate" validate:"min=0"`
+	ResampleMaxGap float64  `json:"resample_max_gap" validate:"min=0"`
+	Threshold      *float64 `json:"threshold" validate:"omitempty,gt=0"`
+	// Rentang paket (1-based, inklusif) yang diketahui kosong/diam untuk
+	// kalibrasi threshold; diabaikan bila Threshold diisi
+	Calibration *MotionCalibration `json:"calibration"`
+	// Pengali σ robust di atas median skor referensi
+	K                 float64 `json:"k" validate:"omitempty,gt=0,max=50"`
+	MinSegmentWindows int     `json:"min_segment_windows" validate:"omitempty,min=1,max=1000"`
+	Filtered          *bool   `json:"filtered"`
+	// Baseline ruangan: kosong/auto (mode tersimpan), off, subtract, normalize
+	RoomBaseline string `json:"room_baseline" validate:"omitempty,oneof=auto off none subtract normalize"`
+	Persist      *bool  `json:"persist"`
+}
+
+// MotionCalibration adalah rentang paket referensi tanpa gerakan
+type MotionCalibration struct {
+	StartPacket int `json:"start_packet" validate:"min=1"`
+	EndPacket   int `json:"end_packet" validate:"gtfield=StartPacket"`
+}
+
+// MotionRun adalah satu eksekusi deteksi gerakan yang disimpan
+type MotionRun struct {
+	ID        string         `json:"id" db:"id"`
+	FileID    string         `json:"file_id" db:"id_data_csv"`
+	RuanganID string         `json:"ruangan_id" db:"id_ruangan"`
+	Method    string         `json:"method" db:"method"`
+	Params    string         `json:"params" db:"params"` // MotionRequest ternormalisasi (JSON)
+	Threshold float64        `json:"threshold" db:"threshold"`
+	Source    string         `json:"threshold_source" db:"threshold_source"`
+	CreatedAt time.Time      `json:"created_at" db:"created_at"`
+	Events    []*MotionEvent `json:"events,omitempty"`
+}
+
+// MotionEvent adalah satu segmen gerakan/diam pada timeline capture.
+// Paket 1-based inklusif; waktu dalam detik sejak awal capture.
+type MotionEvent struct {
+	ID          string    `json:"id" db:"id"`
+	RunID       string    `json:"run_id" db:"run_id"`
+	RuanganID   string    `json:"ruangan_id" db:"id_ruangan"`
+	FileID      string    `json:"file_id" db:"id_data_csv"`
+	State       string    `json:"state" db:"state"`
+	StartPacket int       `json:"start_packet" db:"start_packet"`
+	EndPacket   int       `json:"end_packet" db:"end_packet"`
+	StartTime   float64   `json:"start_s" db:"start_s"`
+	EndTime     float64   `json:"end_s" db:"end_s"`
+	Confidence  float64   `json:"confidence" db:"confidence"`
+	PeakScore   float64   `json:"peak_score" db:"peak_score"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
+
+// Generate ID sebelum insert
+func (r *MotionRun) GenerateID() {
+	r.ID = uuid.New().String()
+	for _, e := range r.Events {
+		e.ID = uuid.New().String()
+		e.RunID = r.ID
+	}
+}
@@ -0,0 +1,30 @@
+package models
+
+// Status kualitas hasil profiling upload
+const (
+	QualityOK       = "ok"
+	QualityFlagged  = "flagged"
+	QualityRejected = "rejected"
+)
+
+// CSIProfile adalah ringkasan kualitas sebuah capture yang dihitung saat
+// upload. Field berbasis layout kosong bila layout file tidak diketahui.
+type CSIProfile struct {
+	Packets    int     `json:"packets"`
+	Columns    int     `json:"columns"`
+	ParseRatio float64 `json:"parse_ratio"` // cell yang berhasil di-parse / total cell
+
+	Streams      int         `json:"streams,omitempty"`
+	Subcarriers  int         `json:"subcarriers,omitempty"`
+	DropoutRatio [][]float64 `json:"dropout_ratio,omitempty"` // [stream][subcarrier], NaN atau <= 0
+	MeanDropout  float64     `json:"mean_dropout"`
+	MaxDropout   float64     `json:"max_dropout"`
+
+	PacketRateHz *float64 `json:"packet_rate_hz,omitempty"`
+	DurationSec  *float64 `json:"duration_s,omitempty"`
+	AmplitudeMin *float64 `json:"amplitude_min,omitempty"`
+	AmplitudeMax *float64 `json:"amplitude_max,omitempty"`
+
+	Quality string   `json:"quality"`
+	Issues  []string `json:"issues,omitempty"`
+}
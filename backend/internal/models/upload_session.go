@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+// Status sesi upload bertahap
+const (
+	UploadSessionOpen       = "open"       // menerima chunk
+	UploadSessionCompleting = "completing" // sedang difinalisasi
+)
+
+// UploadSession adalah upload bertahap (resumable) yang dipetakan ke
+// multipart upload MinIO. Chunk diberi nomor mulai 1 dan harus dikirim
+// berurutan; chunk ke-n menjadi part ke-n.
+type UploadSession struct {
+	ID        string `json:"session_id" db:"id"`
+	FileName  string `json:"file_name" db:"filename"`
+	Size      int64  `json:"size" db:"size_bytes"`
+	ChunkSize int64  `json:"chunk_size" db:"chunk_size"`
+	// Jumlah chunk yang sudah diterima dan byte yang sudah tersimpan
+	ReceivedChunks int   `json:"received_chunks" db:"received_chunks"`
+	Offset         int64 `json:"offset" db:"offset_bytes"`
+
+	// Diisi saat chunk pertama diterima (format dideteksi dari isinya)
+	Format     string `json:"format,omitempty" db:"format"`
+	ObjectPath string `json:"object_path,omitempty" db:"object_path"`
+	UploadID   string `json:"-" db:"upload_id"`
+	// State SHA-256 (encoding.BinaryMarshaler) setelah chunk terakhir
+	HashState []byte `json:"-" db:"hash_state"`
+
+	// Field form upload, divalidasi ulang saat finalisasi
+	NamaRuangan string `json:"nama_ruangan" db:"nama_ruangan"`
+	NamaFilter  string `json:"nama_filter" db:"nama_filter"`
+	Layout      string `json:"layout,omitempty" db:"layout"`
+	NexmonChip  string `json:"nexmon_chip,omitempty" db:"nexmon_chip"`
+
+	Status    string    `json:"status" db:"status"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	ExpiresAt time.Time `json:"expires_at" db:"expires_at"`
+}
+
+// Chunks adalah jumlah total chunk untuk file ini
+func (s *UploadSession) Chunks() int {
+	return int((s.Size + s.ChunkSize - 1) / s.ChunkSize)
+}
+
+// ChunkLength adalah ukuran chunk ke-n (1-based); chunk terakhir bisa lebih kecil
+func (s *UploadSession) ChunkLength(n int) int64 {
+	start := int64(n-1) * s.ChunkSize
+	if rest := s.Size - start; rest < s.ChunkSize {
+		return rest
+	}
+	return s.ChunkSize
+}
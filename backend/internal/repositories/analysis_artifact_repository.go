@@ -0,0 +1,156 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"cetasense-v2.0/internal/models"
+)
+
+type AnalysisArtifactRepository struct {
+	db *sql.DB
+}
+
+func NewAnalysisArtifactRepository(db *sql.DB) *AnalysisArtifactRepository {
+	return &AnalysisArtifactRepository{db: db}
+}
+
+const artifactColumns = `cache_key, kind, file_id, content_hash, params, filter_id, filter_version,
+	object_path, size_bytes, hits, created_at, last_used_at`
+
+func scanArtifact(row interface{ Scan(...any) error }) (*models.AnalysisArtifact, error) {
+	var a models.AnalysisArtifact
+	var hash, filterID sql.NullString
+	var filterVersion sql.NullInt64
+	if err := row.Scan(&a.Key, &a.Kind, &a.FileID, &hash, &a.Params, &filterID, &filterVersion,
+		&a.ObjectPath, &a.SizeBytes, &a.Hits, &a.CreatedAt, &a.LastUsedAt); err != nil {
+		return nil, err
+	}
+	a.ContentHash, a.FilterID, a.FilterVersion = hash.String, filterID.String, int(filterVersion.Int64)
+	return &a, nil
+}
+
+// Upsert mencatat artifact; key yang sudah ada hanya diperbarui objeknya
+func (r *AnalysisArtifactRepository) Upsert(ctx context.Context, a *models.AnalysisArtifact) error {
+	var filterVersion any
+	if a.FilterID != "" {
+		filterVersion = a.FilterVersion
+	}
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO analysis_artifacts
+		  (cache_key, kind, file_id, content_hash, params, filter_id, filter_version, object_path, size_bytes)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+		  object_path = VALUES(object_path),
+		  size_bytes = VALUES(size_bytes),
+		  last_used_at = CURRENT_TIMESTAMP`,
+		a.Key, a.Kind, a.FileID, nullIfEmpty(a.ContentHash), a.Params, nullIfEmpty(a.FilterID),
+		filterVersion, a.ObjectPath, a.SizeBytes)
+	return err
+}
+
+// Get mengambil artifact berdasarkan key (sql.ErrNoRows bila tidak ada)
+func (r *AnalysisArtifactRepository) Get(ctx context.Context, key string) (*models.AnalysisArtifact, error) {
+	return scanArtifact(r.db.QueryRowContext(ctx, `
+		SELECT `+artifactColumns+`
+		FROM analysis_artifacts
+		WHERE cache_key = ?`, key))
+}
+
+// Touch mencatat pemakaian artifact
+func (r *AnalysisArtifactRepository) Touch(ctx context.Context, key string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE analysis_artifacts
+		SET hits = hits + 1, last_used_at = CURRENT_TIMESTAMP
+		WHERE cache_key = ?`, key)
+	return err
+}
+
+// ListForFile mendaftar artifact milik sebuah file: semua artifact dengan
+// hash isi yang sama, atau yang dicatat atas id file untuk upload lama
+// tanpa hash. kind kosong berarti semua jenis.
+func (r *AnalysisArtifactRepository) ListForFile(ctx context.Context, fileID, contentHash, kind string) ([]*models.AnalysisArtifact, error) {
+	query := `
+		SELECT ` + artifactColumns + `
+		FROM analysis_artifacts
+		WHERE (file_id = ? OR (content_hash IS NOT NULL AND content_hash = ?))`
+	args := []any{fileID, contentHash}
+	if kind != "" {
+		query += ` AND kind = ?`
+		args = append(args, kind)
+	}
+	rows, err := r.db.QueryContext(ctx, query+` ORDER BY kind, created_at`, args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanArtifacts(rows)
+}
+
+// scanArtifacts membaca semua baris hasil query artifact
+func scanArtifacts(rows *sql.Rows) ([]*models.AnalysisArtifact, error) {
+	defer rows.Close()
+	artifacts := []*models.AnalysisArtifact{}
+	for rows.Next() {
+		a, err := scanArtifact(rows)
+		if err != nil {
+			return nil, err
+		}
+		artifacts = append(artifacts, a)
+	}
+	return artifacts, rows.Err()
+}
+
+// ListUnused mendaftar artifact yang tidak dipakai selama age
+func (r *AnalysisArtifactRepository) ListUnused(ctx context.Context, age time.Duration) ([]*models.AnalysisArtifact, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+artifactColumns+`
+		FROM analysis_artifacts
+		WHERE last_used_at < NOW() - INTERVAL ? SECOND
+		ORDER BY last_used_at`, int64(age/time.Second))
+	if err != nil {
+		return nil, err
+	}
+	return scanArtifacts(rows)
+}
+
+// ListOverBudget mendaftar artifact yang paling lama tidak dipakai (LRU)
+// sehingga sisanya berukuran total paling banyak maxBytes
+func (r *AnalysisArtifactRepository) ListOverBudget(ctx context.Context, maxBytes int64) ([]*models.AnalysisArtifact, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+artifactColumns+`
+		FROM (
+		  SELECT *, SUM(size_bytes) OVER (ORDER BY last_used_at DESC, cache_key) AS used_bytes
+		  FROM analysis_artifacts
+		) a
+		WHERE used_bytes > ?
+		ORDER BY last_used_at`, maxBytes)
+	if err != nil {
+		return nil, err
+	}
+	return scanArtifacts(rows)
+}
+
+// ReassignFile memindahkan artifact berhash isi contentHash yang dicatat
+// atas upload fromID ke upload toID, sehingga artifact yang dipakai bersama
+// tidak ikut terhapus bersama fromID
+func (r *AnalysisArtifactRepository) ReassignFile(ctx context.Context, fromID, toID, contentHash string) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE analysis_artifacts
+		SET file_id = ?
+		WHERE file_id = ? AND content_hash = ?`, toID, fromID, contentHash)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// Delete menghapus catatan artifact
+func (r *AnalysisArtifactRepository) Delete(ctx context.Context, keys ...string) error {
+	for _, key := range keys {
+		if _, err := r.db.ExecContext(ctx, `DELETE FROM analysis_artifacts WHERE cache_key = ?`, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+)
+
+func TestReassignFileMovesOnlyHashedArtifacts(t *testing.T) {
+	fake, db := newFakeDB()
+	defer db.Close()
+	repo := NewAnalysisArtifactRepository(db)
+
+	n, err := repo.ReassignFile(context.Background(), "old", "survivor", "h1")
+	if err != nil || n != 1 {
+		t.Fatalf("ReassignFile = %d, %v", n, err)
+	}
+	updates := fake.execsMatching("UPDATE analysis_artifacts")
+	if len(updates) != 1 {
+		t.Fatalf("got %d updates, want 1", len(updates))
+	}
+	if u := updates[0]; u.args[0] != "survivor" || u.args[1] != "old" || u.args[2] != "h1" {
+		t.Errorf("args = %v, want [survivor old h1]", u.args)
+	}
+}
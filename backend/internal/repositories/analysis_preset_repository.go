@@ -0,0 +1,112 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"cetasense-v2.0/internal/models"
+)
+
+type AnalysisPresetRepository struct {
+	db *sql.DB
+}
+
+func NewAnalysisPresetRepository(db *sql.DB) *AnalysisPresetRepository {
+	return &AnalysisPresetRepository{db: db}
+}
+
+const presetColumns = "id, name, params, created_at, updated_at"
+
+// scanPreset membaca satu baris preset; params yang tersimpan dilapis di
+// atas default sehingga field baru tetap terisi untuk preset lama
+func scanPreset(row interface{ Scan(...any) error }) (*models.AnalysisPreset, error) {
+	var p models.AnalysisPreset
+	var params string
+	if err := row.Scan(&p.ID, &p.Name, &params, &p.CreatedAt, &p.UpdatedAt); err != nil {
+		return nil, err
+	}
+	p.Params = models.DefaultBNRParams()
+	if err := json.Unmarshal([]byte(params), &p.Params); err != nil {
+		return nil, fmt.Errorf("decode preset %s params: %w", p.ID, err)
+	}
+	return &p, nil
+}
+
+func (r *AnalysisPresetRepository) Create(ctx context.Context, p *models.AnalysisPreset) error {
+	params, err := json.Marshal(p.Params)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.ExecContext(ctx, `
+		INSERT INTO analysis_presets (id, name, params)
+		VALUES (?, ?, ?)`, p.ID, p.Name, string(params))
+	return err
+}
+
+func (r *AnalysisPresetRepository) Update(ctx context.Context, p *models.AnalysisPreset) error {
+	params, err := json.Marshal(p.Params)
+	if err != nil {
+		return err
+	}
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE analysis_presets
+		SET name = ?, params = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?`, p.Name, string(params), p.ID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		// MariaDB melaporkan 0 bila isi tidak berubah; bedakan dengan id tidak ada
+		var one int
+		return r.db.QueryRowContext(ctx, `SELECT 1 FROM analysis_presets WHERE id = ?`, p.ID).Scan(&one)
+	}
+	return nil
+}
+
+func (r *AnalysisPresetRepository) GetByID(ctx context.Context, id string) (*models.AnalysisPreset, error) {
+	return scanPreset(r.db.QueryRowContext(ctx, `
+		SELECT `+presetColumns+`
+		FROM analysis_presets
+		WHERE id = ?`, id))
+}
+
+func (r *AnalysisPresetRepository) GetByName(ctx context.Context, name string) (*models.AnalysisPreset, error) {
+	return scanPreset(r.db.QueryRowContext(ctx, `
+		SELECT `+presetColumns+`
+		FROM analysis_presets
+		WHERE name = ?`, name))
+}
+
+func (r *AnalysisPresetRepository) GetAll(ctx context.Context) ([]*models.AnalysisPreset, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+presetColumns+`
+		FROM analysis_presets
+		ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	presets := []*models.AnalysisPreset{}
+	for rows.Next() {
+		p, err := scanPreset(rows)
+		if err != nil {
+			return nil, err
+		}
+		presets = append(presets, p)
+	}
+	return presets, rows.Err()
+}
+
+func (r *AnalysisPresetRepository) Delete(ctx context.Context, id string) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM analysis_presets WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"cetasense-v2.0/internal/models"
+)
+
+type BaselineRepository struct {
+	db *sql.DB
+}
+
+func NewBaselineRepository(db *sql.DB) *BaselineRepository {
+	return &BaselineRepository{db: db}
+}
+
+// kolom baseline tanpa profil, urutannya harus sama dengan scanBaseline
+const baselineColumns = `id, id_ruangan, version, mode, filtered, sources, layout, packets, created_at`
+
+func scanBaseline(row rowScanner, profile *string) (*models.RoomBaseline, error) {
+	var b models.RoomBaseline
+	var sources, layout string
+	dest := []any{&b.ID, &b.RuanganID, &b.Version, &b.Mode, &b.Filtered, &sources, &layout, &b.Packets, &b.CreatedAt}
+	if profile != nil {
+		dest = append(dest, profile)
+	}
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(sources), &b.Sources); err != nil {
+		return nil, fmt.Errorf("decode sources of baseline %s: %w", b.ID, err)
+	}
+	b.Layout = new(models.CSILayout)
+	if err := json.Unmarshal([]byte(layout), b.Layout); err != nil {
+		return nil, fmt.Errorf("decode layout of baseline %s: %w", b.ID, err)
+	}
+	return &b, nil
+}
+
+// scanBaselineWithProfile membaca baselineColumns diikuti kolom profile
+func scanBaselineWithProfile(row rowScanner) (*models.RoomBaseline, error) {
+	var profile string
+	b, err := scanBaseline(row, &profile)
+	if err != nil {
+		return nil, err
+	}
+	b.Profile = new(models.BaselineProfile)
+	if err := json.Unmarshal([]byte(profile), b.Profile); err != nil {
+		return nil, fmt.Errorf("decode profile of baseline %s: %w", b.ID, err)
+	}
+	return b, nil
+}
+
+// Create menyimpan baseline sebagai versi berikutnya untuk ruangannya;
+// b.Version diisi dengan versi yang tersimpan
+func (r *BaselineRepository) Create(ctx context.Context, b *models.RoomBaseline) error {
+	sources, err := json.Marshal(b.Sources)
+	if err != nil {
+		return err
+	}
+	layout, err := json.Marshal(b.Layout)
+	if err != nil {
+		return err
+	}
+	profile, err := json.Marshal(b.Profile)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	var version int
+	if err := tx.QueryRowContext(ctx, `
+		SELECT COALESCE(MAX(version), 0)
+		FROM room_baselines
+		WHERE id_ruangan = ? FOR UPDATE`, b.RuanganID).Scan(&version); err != nil {
+		return err
+	}
+	b.Version = version + 1
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO room_baselines (id, id_ruangan, version, mode, filtered, sources, layout, packets, profile)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		b.ID, b.RuanganID, b.Version, b.Mode, b.Filtered, string(sources), string(layout), b.Packets, string(profile)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Latest mengambil versi terbaru (aktif) baseline ruangan beserta profilnya
+func (r *BaselineRepository) Latest(ctx context.Context, ruanganID string) (*models.RoomBaseline, error) {
+	row := r.db.QueryRowContext(ctx, `
+		SELECT `+baselineColumns+`, profile
+		FROM room_baselines
+		WHERE id_ruangan = ?
+		ORDER BY version DESC
+		LIMIT 1`, ruanganID)
+	return scanBaselineWithProfile(row)
+}
+
+// GetVersion mengambil satu versi baseline ruangan beserta profilnya
+func (r *BaselineRepository) GetVersion(ctx context.Context, ruanganID string, version int) (*models.RoomBaseline, error) {
+	row := r.db.QueryRowContext(ctx, `
+		SELECT `+baselineColumns+`, profile
+		FROM room_baselines
+		WHERE id_ruangan = ? AND version = ?`, ruanganID, version)
+	return scanBaselineWithProfile(row)
+}
+
+// ListVersions mendaftar semua versi baseline ruangan tanpa profil,
+// terbaru dulu
+func (r *BaselineRepository) ListVersions(ctx context.Context, ruanganID string) ([]*models.RoomBaseline, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+baselineColumns+`
+		FROM room_baselines
+		WHERE id_ruangan = ?
+		ORDER BY version DESC`, ruanganID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := []*models.RoomBaseline{}
+	for rows.Next() {
+		b, err := scanBaseline(rows, nil)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, b)
+	}
+	return versions, rows.Err()
+}
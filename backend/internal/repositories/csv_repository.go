@@ -3,10 +3,23 @@ package repositories
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
 
 	"cetasense-v2.0/internal/models"
 )
 
+// ErrDuplicateContent berarti sudah ada upload dengan isi yang sama untuk
+// ruangan dan filter yang sama (unique index uq_data_csv_content)
+var ErrDuplicateContent = errors.New("file with the same content already exists for this room and filter")
+
+// kode error MySQL/MariaDB untuk pelanggaran unique key
+const mysqlErrDuplicateKey = 1062
+
 type CSVFileRepository struct {
 	db *sql.DB
 }
@@ -19,26 +32,166 @@ func NewCSVFileRepository(db *sql.DB) *CSVFileRepository {
 	return &CSVFileRepository{db: db}
 }
 
+// kolom data_csv yang dibaca oleh GetAll/GetByID, urutannya harus sama
+// dengan scanCSVFile
+const csvFileColumns = `id, filename, object_path, id_ruangan, id_filter, format, raw_object_path, layout, profile, quality, content_hash, size_bytes, labels, created_at`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanCSVFile(row rowScanner) (*models.CSI_File, error) {
+	f := new(models.CSI_File)
+	var rawPath, layout, profile, quality, hash, labels sql.NullString
+	var size sql.NullInt64
+	var createdAt sql.NullTime
+	if err := row.Scan(
+		&f.ID,
+		&f.FileName,
+		&f.ObjectPath,
+		&f.RuanganID,
+		&f.FilterID,
+		&f.Format,
+		&rawPath,
+		&layout,
+		&profile,
+		&quality,
+		&hash,
+		&size,
+		&labels,
+		&createdAt,
+	); err != nil {
+		return nil, err
+	}
+	f.RawObjectPath = rawPath.String
+	if layout.Valid && layout.String != "" {
+		f.Layout = new(models.CSILayout)
+		if err := json.Unmarshal([]byte(layout.String), f.Layout); err != nil {
+			return nil, fmt.Errorf("decode layout of %s: %w", f.ID, err)
+		}
+	}
+	if profile.Valid && profile.String != "" {
+		f.Profile = new(models.CSIProfile)
+		if err := json.Unmarshal([]byte(profile.String), f.Profile); err != nil {
+			return nil, fmt.Errorf("decode profile of %s: %w", f.ID, err)
+		}
+	}
+	f.Quality = quality.String
+	f.ContentHash = hash.String
+	f.SizeBytes = size.Int64
+	if createdAt.Valid {
+		f.CreatedAt = createdAt.Time.Format(time.RFC3339)
+	}
+	if labels.Valid && labels.String != "" {
+		if err := json.Unmarshal([]byte(labels.String), &f.Labels); err != nil {
+			return nil, fmt.Errorf("decode labels of %s: %w", f.ID, err)
+		}
+	}
+	return f, nil
+}
+
+// encodeJSON menyimpan struct opsional sebagai kolom JSON (NULL bila nil)
+func encodeJSON[T any](v *T) (any, error) {
+	if v == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func nullIfEmpty(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// execer adalah *sql.DB atau *sql.Tx
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
 // Save metadata of uploaded CSV
 func (r *CSVFileRepository) Create(ctx context.Context, f *models.CSI_File) error {
+	return insertCSVFile(ctx, r.db, f)
+}
+
+// CreateMany menyimpan beberapa file dalam satu transaksi: semua tersimpan
+// atau tidak sama sekali
+func (r *CSVFileRepository) CreateMany(ctx context.Context, files []*models.CSI_File) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, f := range files {
+		if err := insertCSVFile(ctx, tx, f); err != nil {
+			return fmt.Errorf("insert %s: %w", f.FileName, err)
+		}
+	}
+	return tx.Commit()
+}
+
+func insertCSVFile(ctx context.Context, db execer, f *models.CSI_File) error {
+	format := f.Format
+	if format == "" {
+		format = "csv"
+	}
+	layout, err := encodeJSON(f.Layout)
+	if err != nil {
+		return err
+	}
+	profile, err := encodeJSON(f.Profile)
+	if err != nil {
+		return err
+	}
+	var labels any
+	if len(f.Labels) > 0 {
+		if labels, err = encodeJSON(&f.Labels); err != nil {
+			return err
+		}
+	}
 	query := `
     INSERT INTO data_csv
-      (id, filename, object_path, id_ruangan, id_filter)
-    VALUES (?, ?, ?, ?, ?)`
-	_, err := r.db.ExecContext(ctx, query,
+      (id, filename, object_path, id_ruangan, id_filter, format, raw_object_path, layout, profile, quality,
+       content_hash, size_bytes, labels)
+    VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err = db.ExecContext(ctx, query,
 		f.ID,
 		f.FileName,
 		f.ObjectPath,
 		f.RuanganID,
 		f.FilterID,
+		format,
+		nullIfEmpty(f.RawObjectPath),
+		layout,
+		profile,
+		nullIfEmpty(f.Quality),
+		nullIfEmpty(f.ContentHash),
+		f.SizeBytes,
+		labels,
 	)
+	return duplicateContent(err)
+}
+
+// duplicateContent memetakan pelanggaran unique key (satu-satunya di
+// data_csv selain primary key adalah uq_data_csv_content) ke
+// ErrDuplicateContent
+func duplicateContent(err error) error {
+	var myErr *mysql.MySQLError
+	if errors.As(err, &myErr) && myErr.Number == mysqlErrDuplicateKey {
+		return ErrDuplicateContent
+	}
 	return err
 }
 
 // List semua file CSV (opsional: filter by batch, ruangan, dsb)
 func (r *CSVFileRepository) GetAll(ctx context.Context) ([]*models.CSI_File, error) {
 	rows, err := r.db.QueryContext(ctx, `
-      SELECT id, filename, object_path, id_ruangan, id_filter
+      SELECT `+csvFileColumns+`
       FROM data_csv
       ORDER BY created_at DESC`)
 	if err != nil {
@@ -48,14 +201,8 @@ func (r *CSVFileRepository) GetAll(ctx context.Context) ([]*models.CSI_File, err
 
 	var files []*models.CSI_File
 	for rows.Next() {
-		f := new(models.CSI_File)
-		if err := rows.Scan(
-			&f.ID,
-			&f.FileName,
-			&f.ObjectPath,
-			&f.RuanganID,
-			&f.FilterID,
-		); err != nil {
+		f, err := scanCSVFile(rows)
+		if err != nil {
 			return nil, err
 		}
 		files = append(files, f)
@@ -66,19 +213,9 @@ func (r *CSVFileRepository) GetAll(ctx context.Context) ([]*models.CSI_File, err
 // Cari satu file by ID
 func (r *CSVFileRepository) GetByID(ctx context.Context, id string) (*models.CSI_File, error) {
 	row := r.db.QueryRowContext(ctx, `
-		SELECT id, filename, object_path, id_ruangan, id_filter
+		SELECT `+csvFileColumns+`
 		FROM data_csv WHERE id = ?`, id)
-	f := new(models.CSI_File)
-	if err := row.Scan(
-		&f.ID,
-		&f.FileName,
-		&f.ObjectPath,
-		&f.RuanganID,
-		&f.FilterID,
-	); err != nil {
-		return nil, err
-	}
-	return f, nil
+	return scanCSVFile(row)
 }
 
 func (r *CSVFileRepository) Delete(ctx context.Context, id string) error {
@@ -95,3 +232,134 @@ func (r *CSVFileRepository) UpdateName(ctx context.Context, f *models.CSI_File)
 	_, err := r.db.ExecContext(ctx, query, f.FileName, f.ID)
 	return err
 }
+
+// UpdateLayout menyimpan deskriptor layout kolom beserta profil yang
+// dihitung ulang dengan layout tersebut
+func (r *CSVFileRepository) UpdateLayout(ctx context.Context, id string, l *models.CSILayout, p *models.CSIProfile) error {
+	layout, err := encodeJSON(l)
+	if err != nil {
+		return err
+	}
+	profile, err := encodeJSON(p)
+	if err != nil {
+		return err
+	}
+	quality := ""
+	if p != nil {
+		quality = p.Quality
+	}
+	res, err := r.db.ExecContext(ctx, `
+        UPDATE data_csv
+        SET layout = ?, profile = ?, quality = ?
+        WHERE id = ?`, layout, profile, nullIfEmpty(quality), id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetByContentHash mengembalikan upload paling awal dengan isi yang sama
+func (r *CSVFileRepository) GetByContentHash(ctx context.Context, hash string) (*models.CSI_File, error) {
+	row := r.db.QueryRowContext(ctx, `
+		SELECT `+csvFileColumns+`
+		FROM data_csv WHERE content_hash = ?
+		ORDER BY created_at LIMIT 1`, hash)
+	return scanCSVFile(row)
+}
+
+// GetDuplicate mengembalikan upload dengan isi yang sama untuk ruangan dan
+// filter yang sama; lingkup ini sama dengan unique index uq_data_csv_content
+func (r *CSVFileRepository) GetDuplicate(ctx context.Context, hash, ruanganID, filterID string) (*models.CSI_File, error) {
+	row := r.db.QueryRowContext(ctx, `
+		SELECT `+csvFileColumns+`
+		FROM data_csv
+		WHERE content_hash = ? AND id_ruangan = ? AND id_filter = ?
+		LIMIT 1`, hash, ruanganID, filterID)
+	return scanCSVFile(row)
+}
+
+// ListLegacyObjectKeys mengembalikan upload yang objeknya belum berada di
+// bawah Data-Parameter/<id>/
+func (r *CSVFileRepository) ListLegacyObjectKeys(ctx context.Context) ([]*models.CSI_File, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+csvFileColumns+`
+		FROM data_csv
+		WHERE object_path NOT LIKE CONCAT('Data-Parameter/', id, '/%')
+		ORDER BY created_at`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var files []*models.CSI_File
+	for rows.Next() {
+		f, err := scanCSVFile(rows)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	return files, rows.Err()
+}
+
+// UpdateStorage memindahkan record ke object key baru beserta hash dan
+// ukuran isinya. ErrDuplicateContent bila hash sudah dipakai upload lain di
+// ruangan dan filter yang sama.
+func (r *CSVFileRepository) UpdateStorage(ctx context.Context, f *models.CSI_File) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE data_csv
+		SET object_path = ?, raw_object_path = ?, content_hash = ?, size_bytes = ?
+		WHERE id = ?`,
+		f.ObjectPath, nullIfEmpty(f.RawObjectPath), nullIfEmpty(f.ContentHash), f.SizeBytes, f.ID)
+	return duplicateContent(err)
+}
+
+// ReleaseLaterDuplicates melepas content_hash upload lain dengan isi,
+// ruangan, dan filter yang sama yang dibuat setelah upload id, agar upload
+// paling awal yang memegang hash (aturan yang sama dengan migrasi skema)
+func (r *CSVFileRepository) ReleaseLaterDuplicates(ctx context.Context, id, hash string) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE data_csv d
+		JOIN data_csv f ON f.id = ?
+		SET d.content_hash = NULL
+		WHERE d.content_hash = ? AND d.id_ruangan = f.id_ruangan AND d.id_filter = f.id_filter
+		  AND (d.created_at > f.created_at OR (d.created_at = f.created_at AND d.id > f.id))`,
+		id, hash)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// CountObjectReferences menghitung record yang masih menunjuk ke objek path
+func (r *CSVFileRepository) CountObjectReferences(ctx context.Context, path string) (int, error) {
+	var n int
+	err := r.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM data_csv
+		WHERE object_path = ? OR raw_object_path = ?`, path, path).Scan(&n)
+	return n, err
+}
+
+// WithLock menjalankan fn hanya bila named lock MariaDB berhasil diambil;
+// dipakai agar tugas startup tidak berjalan ganda di beberapa replika.
+// Mengembalikan false bila lock sedang dipegang proses lain.
+func (r *CSVFileRepository) WithLock(ctx context.Context, name string, fn func() error) (bool, error) {
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	var got sql.NullInt64
+	if err := conn.QueryRowContext(ctx, `SELECT GET_LOCK(?, 0)`, name).Scan(&got); err != nil {
+		return false, err
+	}
+	if got.Int64 != 1 {
+		return false, nil
+	}
+	defer conn.ExecContext(context.Background(), `SELECT RELEASE_LOCK(?)`, name)
+	return true, fn()
+}
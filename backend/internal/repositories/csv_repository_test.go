@@ -0,0 +1,93 @@
+package repositories
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+
+	"cetasense-v2.0/internal/models"
+)
+
+func TestCreateManyRollsBackOnFailure(t *testing.T) {
+	fake, db := newFakeDB()
+	defer db.Close()
+	fake.failExec = func(query string, args []driver.Value) error {
+		if strings.Contains(query, "INSERT INTO data_csv") && args[0] == "f2" {
+			return errors.New("duplicate entry")
+		}
+		return nil
+	}
+	repo := NewCSVFileRepository(db)
+	files := []*models.CSI_File{
+		{ID: "f1", FileName: "a.csv", Labels: map[string]string{"aktivitas": "duduk"}},
+		{ID: "f2", FileName: "b.csv"},
+		{ID: "f3", FileName: "c.csv"},
+	}
+
+	err := repo.CreateMany(context.Background(), files)
+	if err == nil || !strings.Contains(err.Error(), "b.csv") {
+		t.Fatalf("got %v, want an error naming b.csv", err)
+	}
+	if fake.commits != 0 || fake.rollbacks != 1 {
+		t.Fatalf("commits=%d rollbacks=%d, want 0 and 1", fake.commits, fake.rollbacks)
+	}
+	inserts := fake.execsMatching("INSERT INTO data_csv")
+	if len(inserts) != 2 {
+		t.Fatalf("got %d inserts, want the loop to stop at the failing file", len(inserts))
+	}
+	for _, e := range inserts {
+		if !e.inTx {
+			t.Fatalf("insert %v ran outside the transaction", e.args[0])
+		}
+	}
+	if labels := inserts[0].args[len(inserts[0].args)-1]; labels != `{"aktivitas":"duduk"}` {
+		t.Errorf("labels column = %v", labels)
+	}
+}
+
+func TestCreateManyCommits(t *testing.T) {
+	fake, db := newFakeDB()
+	defer db.Close()
+	repo := NewCSVFileRepository(db)
+
+	if err := repo.CreateMany(context.Background(), []*models.CSI_File{{ID: "f1"}, {ID: "f2"}}); err != nil {
+		t.Fatalf("CreateMany: %v", err)
+	}
+	if fake.commits != 1 || len(fake.execsMatching("INSERT INTO data_csv")) != 2 {
+		t.Fatalf("commits=%d inserts=%d, want 1 and 2", fake.commits, len(fake.execsMatching("INSERT")))
+	}
+}
+
+func TestUpdateStorageDuplicateContent(t *testing.T) {
+	fake, db := newFakeDB()
+	defer db.Close()
+	fake.failExec = func(query string, args []driver.Value) error {
+		if args[2] != nil {
+			return &mysql.MySQLError{Number: 1062, Message: "Duplicate entry for key 'uq_data_csv_content'"}
+		}
+		return nil
+	}
+	repo := NewCSVFileRepository(db)
+	f := &models.CSI_File{ID: "f2", ObjectPath: "Data-Parameter/f2/a.csv", ContentHash: "abc", SizeBytes: 10}
+
+	if err := repo.UpdateStorage(context.Background(), f); !errors.Is(err, ErrDuplicateContent) {
+		t.Fatalf("got %v, want ErrDuplicateContent", err)
+	}
+	// tanpa hash, record tetap bisa dipindahkan
+	f.ContentHash = ""
+	if err := repo.UpdateStorage(context.Background(), f); err != nil {
+		t.Fatalf("UpdateStorage without hash: %v", err)
+	}
+	if updates := fake.execsMatching("UPDATE data_csv"); len(updates) != 2 || updates[1].args[2] != nil {
+		t.Errorf("updates %+v, want the second one to write a NULL hash", updates)
+	}
+
+	fake.failExec = func(string, []driver.Value) error { return errors.New("connection reset") }
+	if err := repo.UpdateStorage(context.Background(), f); err == nil || errors.Is(err, ErrDuplicateContent) {
+		t.Errorf("other errors must pass through, got %v", err)
+	}
+}
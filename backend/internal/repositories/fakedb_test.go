@@ -0,0 +1,142 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"strings"
+	"sync"
+)
+
+// fakeDB adalah driver database/sql minimal untuk test repository: setiap
+// statement dicatat, dan exec bisa digagalkan lewat failExec. Query
+// mengembalikan baris dari rows (per awalan query) bila ada.
+type fakeDB struct {
+	mu        sync.Mutex
+	execs     []fakeExec
+	commits   int
+	rollbacks int
+	failExec  func(query string, args []driver.Value) error
+	rows      map[string]fakeRows
+}
+
+type fakeExec struct {
+	query string
+	args  []driver.Value
+	inTx  bool
+}
+
+type fakeRows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func newFakeDB() (*fakeDB, *sql.DB) {
+	f := &fakeDB{rows: map[string]fakeRows{}}
+	return f, sql.OpenDB(f)
+}
+
+// execsMatching mengembalikan exec yang query-nya mengandung substr
+func (f *fakeDB) execsMatching(substr string) []fakeExec {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var out []fakeExec
+	for _, e := range f.execs {
+		if strings.Contains(e.query, substr) {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+func (f *fakeDB) Connect(context.Context) (driver.Conn, error) { return &fakeConn{db: f}, nil }
+func (f *fakeDB) Driver() driver.Driver                        { return fakeDriver{f} }
+
+type fakeDriver struct{ db *fakeDB }
+
+func (d fakeDriver) Open(string) (driver.Conn, error) { return &fakeConn{db: d.db}, nil }
+
+type fakeConn struct {
+	db   *fakeDB
+	inTx bool
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{conn: c, query: query}, nil
+}
+func (c *fakeConn) Close() error { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	c.inTx = true
+	return &fakeTx{conn: c}, nil
+}
+
+type fakeTx struct{ conn *fakeConn }
+
+func (t *fakeTx) Commit() error {
+	t.conn.inTx = false
+	t.conn.db.mu.Lock()
+	t.conn.db.commits++
+	t.conn.db.mu.Unlock()
+	return nil
+}
+
+func (t *fakeTx) Rollback() error {
+	t.conn.inTx = false
+	t.conn.db.mu.Lock()
+	t.conn.db.rollbacks++
+	t.conn.db.mu.Unlock()
+	return nil
+}
+
+type fakeStmt struct {
+	conn  *fakeConn
+	query string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	db := s.conn.db
+	db.mu.Lock()
+	db.execs = append(db.execs, fakeExec{query: s.query, args: args, inTx: s.conn.inTx})
+	fail := db.failExec
+	db.mu.Unlock()
+	if fail != nil {
+		if err := fail(s.query, args); err != nil {
+			return nil, err
+		}
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	db := s.conn.db
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	q := strings.TrimSpace(s.query)
+	for prefix, r := range db.rows {
+		if strings.HasPrefix(q, prefix) {
+			return &fakeRowsIter{rows: r}, nil
+		}
+	}
+	return &fakeRowsIter{}, nil
+}
+
+type fakeRowsIter struct {
+	rows fakeRows
+	pos  int
+}
+
+func (r *fakeRowsIter) Columns() []string { return r.rows.columns }
+func (r *fakeRowsIter) Close() error      { return nil }
+
+func (r *fakeRowsIter) Next(dest []driver.Value) error {
+	if r.pos >= len(r.rows.values) {
+		return io.EOF
+	}
+	copy(dest, r.rows.values[r.pos])
+	r.pos++
+	return nil
+}
@@ -3,6 +3,10 @@ package repositories
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
 
 	"cetasense-v2.0/internal/models"
 )
@@ -15,51 +19,94 @@ func NewFilterRepository(db *sql.DB) *FilterRepository {
 	return &FilterRepository{db: db}
 }
 
-// Create filter dengan prepared statement
+// kolom filter yang dibaca, urutannya harus sama dengan scanFilter
+const filterColumns = `id, nama_filter, pipeline, version`
+
+func scanFilter(row rowScanner) (*models.Filter, error) {
+	var filter models.Filter
+	var pipeline sql.NullString
+	if err := row.Scan(
+		&filter.ID,
+		&filter.NamaFilter,
+		&pipeline,
+		&filter.Version,
+	); err != nil {
+		return nil, err
+	}
+	stages, err := decodePipeline(pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("decode pipeline of filter %s: %w", filter.ID, err)
+	}
+	filter.Pipeline = stages
+	return &filter, nil
+}
+
+func decodePipeline(raw sql.NullString) ([]models.FilterStage, error) {
+	stages := []models.FilterStage{}
+	if raw.Valid && raw.String != "" {
+		if err := json.Unmarshal([]byte(raw.String), &stages); err != nil {
+			return nil, err
+		}
+	}
+	return stages, nil
+}
+
+func encodePipeline(stages []models.FilterStage) (any, error) {
+	if len(stages) == 0 {
+		return nil, nil
+	}
+	b, err := json.Marshal(stages)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Create filter beserta snapshot versi pertamanya
 func (r *FilterRepository) Create(ctx context.Context, filter *models.Filter) error {
-	stmt, err := r.db.PrepareContext(ctx, `
-		INSERT INTO filter 
-		(id, nama_filter)
-		VALUES (?, ?)`)
+	pipeline, err := encodePipeline(filter.Pipeline)
 	if err != nil {
 		return err
 	}
-	defer stmt.Close()
+	filter.Version = 1
 
-	_, err = stmt.ExecContext(ctx,
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO filter 
+		(id, nama_filter, pipeline, version)
+		VALUES (?, ?, ?, ?)`,
 		filter.ID,
 		filter.NamaFilter,
-	)
-
-	return err
+		pipeline,
+		filter.Version,
+	); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO filter_versions (filter_id, version, pipeline)
+		VALUES (?, ?, ?)`, filter.ID, filter.Version, pipeline); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 // GetByID untuk mendapatkan filter berdasarkan ID
 func (r *FilterRepository) GetByID(ctx context.Context, id string) (*models.Filter, error) {
 	row := r.db.QueryRowContext(ctx, `
-		SELECT 
-			id, nama_filter
+		SELECT `+filterColumns+`
 		FROM filter 
 		WHERE id = ?`, id)
-
-	var filter models.Filter
-	err := row.Scan(
-		&filter.ID,
-		&filter.NamaFilter,
-	)
-
-	if err != nil {
-		return nil, err
-	}
-
-	return &filter, nil
+	return scanFilter(row)
 }
 
 // GetAll untuk mendapatkan semua filter
 func (r *FilterRepository) GetAll(ctx context.Context) ([]*models.Filter, error) {
 	rows, err := r.db.QueryContext(ctx, `
-		SELECT 
-			id, nama_filter
+		SELECT `+filterColumns+`
 		FROM filter`)
 	if err != nil {
 		return nil, err
@@ -68,36 +115,95 @@ func (r *FilterRepository) GetAll(ctx context.Context) ([]*models.Filter, error)
 
 	var filters []*models.Filter
 	for rows.Next() {
-		var filter models.Filter
-		if err := rows.Scan(
-			&filter.ID,
-			&filter.NamaFilter,
-		); err != nil {
+		filter, err := scanFilter(rows)
+		if err != nil {
 			return nil, err
 		}
-		filters = append(filters, &filter)
+		filters = append(filters, filter)
 	}
 
-	return filters, nil
+	return filters, rows.Err()
 }
 
-// Update filter dengan prepared statement
+// Update mengganti nama filter. Pipeline nil berarti pipeline tidak
+// diubah; bila pipeline berubah, versi dinaikkan dan snapshot-nya disimpan.
+// filter.Pipeline dan filter.Version diisi dengan nilai yang tersimpan.
 func (r *FilterRepository) Update(ctx context.Context, filter *models.Filter) error {
-	stmt, err := r.db.PrepareContext(ctx, `
-		UPDATE filter 
-		SET nama_filter = ?
-		WHERE id = ?`)
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
-	defer stmt.Close()
+	defer tx.Rollback()
 
-	_, err = stmt.ExecContext(ctx,
+	current, err := scanFilter(tx.QueryRowContext(ctx, `
+		SELECT `+filterColumns+`
+		FROM filter 
+		WHERE id = ? FOR UPDATE`, filter.ID))
+	if err != nil {
+		return err
+	}
+	version := current.Version
+	if filter.Pipeline == nil {
+		filter.Pipeline = current.Pipeline
+	} else if !reflect.DeepEqual(filter.Pipeline, current.Pipeline) {
+		version++
+	}
+	pipeline, err := encodePipeline(filter.Pipeline)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE filter 
+		SET nama_filter = ?, pipeline = ?, version = ?
+		WHERE id = ?`,
 		filter.NamaFilter,
+		pipeline,
+		version,
 		filter.ID,
-	)
+	); err != nil {
+		return err
+	}
+	if version != current.Version {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO filter_versions (filter_id, version, pipeline)
+			VALUES (?, ?, ?)`, filter.ID, version, pipeline); err != nil {
+			return err
+		}
+	}
+	filter.Version = version
+	return tx.Commit()
+}
+
+// GetVersions mengembalikan semua snapshot pipeline sebuah filter, terbaru dulu
+func (r *FilterRepository) GetVersions(ctx context.Context, id string) ([]*models.FilterVersion, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT filter_id, version, pipeline, created_at
+		FROM filter_versions
+		WHERE filter_id = ?
+		ORDER BY version DESC`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 
-	return err
+	var versions []*models.FilterVersion
+	for rows.Next() {
+		var v models.FilterVersion
+		var pipeline sql.NullString
+		var createdAt sql.NullTime
+		if err := rows.Scan(&v.FilterID, &v.Version, &pipeline, &createdAt); err != nil {
+			return nil, err
+		}
+		if v.Pipeline, err = decodePipeline(pipeline); err != nil {
+			return nil, fmt.Errorf("decode pipeline of filter %s v%d: %w", id, v.Version, err)
+		}
+		if createdAt.Valid {
+			v.CreatedAt = createdAt.Time.Format(time.RFC3339)
+		}
+		versions = append(versions, &v)
+	}
+	return versions, rows.Err()
 }
 
 // Delete filter dengan prepared statement
@@ -148,20 +254,8 @@ func (r *DataRepository) GetAllBatchIDs(ctx context.Context) ([]int, error) {
 // GetFilterByNama retrieves a filter by its name
 func (r *FilterRepository) GetFilterByNama(ctx context.Context, namaFilter string) (*models.Filter, error) {
 	row := r.db.QueryRowContext(ctx, `
-		SELECT 
-			id, nama_filter
+		SELECT `+filterColumns+`
 		FROM filter 
 		WHERE nama_filter = ?`, namaFilter)
-
-	var filter models.Filter
-	err := row.Scan(
-		&filter.ID,
-		&filter.NamaFilter,
-	)
-
-	if err != nil {
-		return nil, err
-	}
-
-	return &filter, nil
+	return scanFilter(row)
 }
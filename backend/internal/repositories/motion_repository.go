@@ -0,0 +1,110 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"cetasense-v2.0/internal/models"
+)
+
+type MotionRepository struct {
+	db *sql.DB
+}
+
+func NewMotionRepository(db *sql.DB) *MotionRepository {
+	return &MotionRepository{db: db}
+}
+
+// deleteForFile menghapus semua run dan event sebuah file
+func deleteForFile(ctx context.Context, exec interface {
+	ExecContext(context.Context, string, ...any) (sql.Result, error)
+}, fileID string) error {
+	if _, err := exec.ExecContext(ctx, `DELETE FROM motion_events WHERE id_data_csv = ?`, fileID); err != nil {
+		return err
+	}
+	_, err := exec.ExecContext(ctx, `DELETE FROM motion_runs WHERE id_data_csv = ?`, fileID)
+	return err
+}
+
+// CreateRun menyimpan run beserta seluruh event-nya dalam satu transaksi.
+// Run sebelumnya untuk file yang sama diganti sehingga timeline ruangan
+// tidak mencampur event dari beberapa run.
+func (r *MotionRepository) CreateRun(ctx context.Context, run *models.MotionRun) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := deleteForFile(ctx, tx, run.FileID); err != nil {
+		return fmt.Errorf("replace previous run: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO motion_runs (id, id_data_csv, id_ruangan, method, params, threshold, threshold_source)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		run.ID, run.FileID, run.RuanganID, run.Method, run.Params, run.Threshold, run.Source); err != nil {
+		return err
+	}
+	for i, e := range run.Events {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO motion_events
+			  (id, run_id, id_ruangan, id_data_csv, state, start_packet, end_packet, start_s, end_s, confidence, peak_score)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			e.ID, run.ID, e.RuanganID, e.FileID, e.State, e.StartPacket, e.EndPacket,
+			e.StartTime, e.EndTime, e.Confidence, e.PeakScore); err != nil {
+			return fmt.Errorf("insert event %d: %w", i+1, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// ListEvents mengambil event gerakan sebuah ruangan (run terakhir tiap
+// file, lihat CreateRun), terbaru dulu; fileID dan state opsional
+func (r *MotionRepository) ListEvents(ctx context.Context, ruanganID, fileID, state string, limit int) ([]*models.MotionEvent, error) {
+	query := `
+		SELECT id, run_id, id_ruangan, id_data_csv, state, start_packet, end_packet,
+		       start_s, end_s, confidence, peak_score, created_at
+		FROM motion_events
+		WHERE id_ruangan = ?`
+	args := []any{ruanganID}
+	if fileID != "" {
+		query += ` AND id_data_csv = ?`
+		args = append(args, fileID)
+	}
+	if state != "" {
+		query += ` AND state = ?`
+		args = append(args, state)
+	}
+	query += ` ORDER BY created_at DESC, id_data_csv, start_packet LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []*models.MotionEvent{}
+	for rows.Next() {
+		var e models.MotionEvent
+		if err := rows.Scan(&e.ID, &e.RunID, &e.RuanganID, &e.FileID, &e.State, &e.StartPacket, &e.EndPacket,
+			&e.StartTime, &e.EndTime, &e.Confidence, &e.PeakScore, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		events = append(events, &e)
+	}
+	return events, rows.Err()
+}
+
+// DeleteForFile menghapus run dan event gerakan milik file yang dihapus
+func (r *MotionRepository) DeleteForFile(ctx context.Context, fileID string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := deleteForFile(ctx, tx, fileID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
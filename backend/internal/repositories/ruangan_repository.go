@@ -86,7 +86,7 @@ func (r *RuanganRepository) GetByID(ctx context.Context, id string) (*models.Rua
 	)
 
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("ruangan with ID %s not found", id)
+		return nil, fmt.Errorf("ruangan with ID %s not found: %w", id, err)
 	}
 
 	return &ruangan, err
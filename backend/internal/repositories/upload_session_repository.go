@@ -0,0 +1,168 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"cetasense-v2.0/internal/models"
+)
+
+// ErrSessionConflict berarti sesi sudah diubah request lain (chunk yang
+// sama dikirim paralel, atau sesi sedang difinalisasi)
+var ErrSessionConflict = errors.New("upload session was modified concurrently")
+
+type UploadSessionRepository struct {
+	db *sql.DB
+}
+
+func NewUploadSessionRepository(db *sql.DB) *UploadSessionRepository {
+	return &UploadSessionRepository{db: db}
+}
+
+const uploadSessionColumns = `id, filename, size_bytes, chunk_size, received_chunks, offset_bytes, format, object_path,
+	upload_id, hash_state, nama_ruangan, nama_filter, layout, nexmon_chip, status, created_at, expires_at`
+
+func scanUploadSession(row rowScanner) (*models.UploadSession, error) {
+	s := new(models.UploadSession)
+	var format, objectPath, uploadID, layout, chip sql.NullString
+	if err := row.Scan(
+		&s.ID,
+		&s.FileName,
+		&s.Size,
+		&s.ChunkSize,
+		&s.ReceivedChunks,
+		&s.Offset,
+		&format,
+		&objectPath,
+		&uploadID,
+		&s.HashState,
+		&s.NamaRuangan,
+		&s.NamaFilter,
+		&layout,
+		&chip,
+		&s.Status,
+		&s.CreatedAt,
+		&s.ExpiresAt,
+	); err != nil {
+		return nil, err
+	}
+	s.Format = format.String
+	s.ObjectPath = objectPath.String
+	s.UploadID = uploadID.String
+	s.Layout = layout.String
+	s.NexmonChip = chip.String
+	return s, nil
+}
+
+// Create menyimpan sesi baru
+func (r *UploadSessionRepository) Create(ctx context.Context, s *models.UploadSession) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO upload_sessions
+		  (id, filename, size_bytes, chunk_size, nama_ruangan, nama_filter, layout, nexmon_chip, status, created_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		s.ID,
+		s.FileName,
+		s.Size,
+		s.ChunkSize,
+		s.NamaRuangan,
+		s.NamaFilter,
+		nullIfEmpty(s.Layout),
+		nullIfEmpty(s.NexmonChip),
+		s.Status,
+		s.CreatedAt,
+		s.ExpiresAt,
+	)
+	return err
+}
+
+// GetByID mengambil sesi yang belum kedaluwarsa
+func (r *UploadSessionRepository) GetByID(ctx context.Context, id string) (*models.UploadSession, error) {
+	row := r.db.QueryRowContext(ctx, `
+		SELECT `+uploadSessionColumns+`
+		FROM upload_sessions WHERE id = ? AND expires_at > ?`, id, time.Now().UTC())
+	return scanUploadSession(row)
+}
+
+// AdvanceChunk mencatat satu chunk yang diterima. Update hanya berhasil bila
+// sesi masih terbuka dan belum ada chunk lain yang tercatat sejak s dibaca
+// (received_chunks masih prevChunks); selain itu ErrSessionConflict.
+func (r *UploadSessionRepository) AdvanceChunk(ctx context.Context, s *models.UploadSession, prevChunks int) error {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE upload_sessions
+		SET received_chunks = ?, offset_bytes = ?, format = ?, object_path = ?, upload_id = ?, hash_state = ?,
+		    expires_at = ?
+		WHERE id = ? AND received_chunks = ? AND status = ?`,
+		s.ReceivedChunks,
+		s.Offset,
+		nullIfEmpty(s.Format),
+		nullIfEmpty(s.ObjectPath),
+		nullIfEmpty(s.UploadID),
+		s.HashState,
+		s.ExpiresAt,
+		s.ID,
+		prevChunks,
+		models.UploadSessionOpen,
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrSessionConflict
+	}
+	return nil
+}
+
+// SetStatus memindahkan sesi dari status from ke to secara atomik
+func (r *UploadSessionRepository) SetStatus(ctx context.Context, id, from, to string, expiresAt time.Time) error {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE upload_sessions SET status = ?, expires_at = ?
+		WHERE id = ? AND status = ?`, to, expiresAt, id, from)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrSessionConflict
+	}
+	return nil
+}
+
+func (r *UploadSessionRepository) Delete(ctx context.Context, id string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM upload_sessions WHERE id = ?`, id)
+	return err
+}
+
+// ListExpired mengembalikan sesi yang sudah kedaluwarsa pada waktu now
+func (r *UploadSessionRepository) ListExpired(ctx context.Context, now time.Time, limit int) ([]*models.UploadSession, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+uploadSessionColumns+`
+		FROM upload_sessions WHERE expires_at <= ?
+		ORDER BY expires_at LIMIT ?`, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []*models.UploadSession
+	for rows.Next() {
+		s, err := scanUploadSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, s)
+	}
+	return sessions, rows.Err()
+}
+
+// DeleteExpired menghapus satu sesi kedaluwarsa; false bila sesi sudah
+// dihapus atau diperpanjang oleh replika lain
+func (r *UploadSessionRepository) DeleteExpired(ctx context.Context, id string, now time.Time) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `
+		DELETE FROM upload_sessions WHERE id = ? AND expires_at <= ?`, id, now)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
@@ -12,5 +12,27 @@ func RegisterDataRoutes(r *mux.Router, h *handlers.DataHandler) {
 
 func RegisterPlotRoutes(r *mux.Router, h *handlers.PlotHandler) {
 	r.HandleFunc("/api/plots", h.ListCSV).Methods("GET")
+	r.HandleFunc("/api/features/sets", h.GetFeatureSets).Methods("GET")
 	r.HandleFunc("/api/plots/{id}", h.GetPlots).Methods("GET")
+	r.HandleFunc("/api/plots/{id}/phase", h.GetPhase).Methods("GET")
+	r.HandleFunc("/api/plots/{id}/spectrogram", h.GetSpectrogram).Methods("GET")
+	r.HandleFunc("/api/plots/{id}/pca", h.GetPCA).Methods("GET")
+	r.HandleFunc("/api/plots/{id}/aoa-tof", h.GetAoAToF).Methods("GET")
+	r.HandleFunc("/api/plots/{id}/cir", h.GetCIR).Methods("GET")
+	r.HandleFunc("/api/plots/{id}/respiration", h.GetRespiration).Methods("GET")
+	r.HandleFunc("/api/plots/{id}/timing", h.GetTiming).Methods("GET")
+	r.HandleFunc("/api/plots/{id}/correlation", h.GetCorrelation).Methods("GET")
+	r.HandleFunc("/api/plots/{id}/diff", h.GetDiff).Methods("GET")
+	r.HandleFunc("/api/plots/{id}/features", h.GetFeatures).Methods("GET")
+	r.HandleFunc("/api/plots/{id}/artifacts", h.ListArtifacts).Methods("GET")
+	r.HandleFunc("/api/plots/{id}/artifacts", h.PrecomputeArtifacts).Methods("POST")
+	r.HandleFunc("/api/plots/{id}/artifacts", h.InvalidateArtifacts).Methods("DELETE")
+}
+
+func RegisterAnalysisPresetRoutes(r *mux.Router, h *handlers.AnalysisPresetHandler) {
+	r.HandleFunc("/api/analysis-presets", h.CreatePreset).Methods("POST")
+	r.HandleFunc("/api/analysis-presets", h.GetAllPresets).Methods("GET")
+	r.HandleFunc("/api/analysis-presets/{id}", h.GetPresetByID).Methods("GET")
+	r.HandleFunc("/api/analysis-presets/{id}", h.UpdatePreset).Methods("PUT")
+	r.HandleFunc("/api/analysis-presets/{id}", h.DeletePreset).Methods("DELETE")
 }
@@ -8,6 +8,7 @@ import (
 func RegisterFilterRoutes(r *mux.Router, h *handlers.FilterHandler) {
 	r.HandleFunc("/api/filter", h.CreateFilter).Methods("POST")
 	r.HandleFunc("/api/filter/{id}", h.GetFilterByID).Methods("GET")
+	r.HandleFunc("/api/filter/{id}/versions", h.GetFilterVersions).Methods("GET")
 	r.HandleFunc("/api/filter", h.GetAllFilter).Methods("GET")
 	r.HandleFunc("/api/filter/{id}", h.UpdateFilter).Methods("PUT")
 }
@@ -12,3 +12,16 @@ func RegisterRoomRoutes(r *mux.Router, h *handlers.RoomHandler) {
 	r.HandleFunc("/api/ruangan/{id}", h.UpdateRoom).Methods("PUT")
 	r.HandleFunc("/api/ruangan/{id}", h.DeleteRoom).Methods("DELETE")
 }
+
+func RegisterMotionRoutes(r *mux.Router, h *handlers.MotionHandler) {
+	r.HandleFunc("/api/plots/{id}/motion", h.DetectMotion).Methods("POST")
+	r.HandleFunc("/api/ruangan/{id}/motion-events", h.GetRoomMotionEvents).Methods("GET")
+}
+
+func RegisterBaselineRoutes(r *mux.Router, h *handlers.BaselineHandler) {
+	r.HandleFunc("/api/ruangan/{id}/baseline", h.MarkBaseline).Methods("POST")
+	r.HandleFunc("/api/ruangan/{id}/baseline", h.GetBaseline).Methods("GET")
+	r.HandleFunc("/api/ruangan/{id}/baseline/recompute", h.RecomputeBaseline).Methods("POST")
+	r.HandleFunc("/api/ruangan/{id}/baseline/versions", h.GetBaselineVersions).Methods("GET")
+	r.HandleFunc("/api/ruangan/{id}/baseline/compare", h.CompareBaselines).Methods("GET")
+}
@@ -9,7 +9,17 @@ import (
 func RegisterUploadRoutes(r *mux.Router, uploadHandler *handlers.UploadHandler) {
 	// Endpoint to upload a CSV file
 	r.HandleFunc("/api/upload", uploadHandler.HandleUpload).Methods("POST")
+	// Upload massal: arsip zip/tar.gz dengan manifest
+	r.HandleFunc("/api/upload/archive", uploadHandler.HandleArchiveUpload).Methods("POST")
 	r.HandleFunc("/api/uploads", uploadHandler.GetAllUploads).Methods("GET")
 	r.HandleFunc("/api/uploads/{id}", uploadHandler.DeleteUpload).Methods("DELETE")
 	r.HandleFunc("/api/uploads/{id}", uploadHandler.UpdateName).Methods("PUT")
+	r.HandleFunc("/api/uploads/{id}/layout", uploadHandler.UpdateLayout).Methods("PUT")
+
+	// Resumable upload: buat sesi, PUT chunk bernomor, cek offset, finalisasi
+	r.HandleFunc("/api/upload/sessions", uploadHandler.CreateUploadSession).Methods("POST")
+	r.HandleFunc("/api/upload/sessions/{id}", uploadHandler.GetUploadSession).Methods("GET")
+	r.HandleFunc("/api/upload/sessions/{id}", uploadHandler.AbortUploadSession).Methods("DELETE")
+	r.HandleFunc("/api/upload/sessions/{id}/chunks/{n:[0-9]+}", uploadHandler.PutUploadChunk).Methods("PUT")
+	r.HandleFunc("/api/upload/sessions/{id}/complete", uploadHandler.CompleteUploadSession).Methods("POST")
 }
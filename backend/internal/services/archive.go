@@ -0,0 +1,281 @@
+package services
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+// Jenis arsip untuk upload massal
+const (
+	ArchiveZip   = "zip"
+	ArchiveTarGz = "tar.gz"
+)
+
+// batas ukuran file manifest di dalam arsip
+const maxManifestBytes = 1 << 20
+
+// ManifestEntry memetakan satu file di arsip ke ruangan, filter, dan label.
+// File relatif terhadap folder tempat manifest berada.
+type ManifestEntry struct {
+	File        string            `json:"file"`
+	NamaRuangan string            `json:"nama_ruangan"`
+	NamaFilter  string            `json:"nama_filter"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Layout      json.RawMessage   `json:"layout,omitempty"`
+	NexmonChip  string            `json:"nexmon_chip,omitempty"`
+}
+
+// LayoutString mengembalikan layout entry sebagai string JSON (bentuk yang
+// sama dengan field form upload); layout boleh ditulis sebagai objek atau
+// string JSON
+func (e *ManifestEntry) LayoutString() string {
+	if len(e.Layout) == 0 || string(e.Layout) == "null" {
+		return ""
+	}
+	var s string
+	if json.Unmarshal(e.Layout, &s) == nil {
+		return s
+	}
+	return string(e.Layout)
+}
+
+// ArchiveFile adalah satu file reguler di dalam arsip
+type ArchiveFile struct {
+	Name string // path ternormalisasi di dalam arsip
+	Size int64
+}
+
+// ArchiveIndex adalah hasil pemindaian pertama arsip
+type ArchiveIndex struct {
+	Files        []ArchiveFile
+	ManifestPath string
+	Manifest     []ManifestEntry
+}
+
+// DetectArchive mengenali arsip dari magic bytes-nya
+func DetectArchive(head []byte) string {
+	switch {
+	case bytes.HasPrefix(head, []byte("PK\x03\x04")):
+		return ArchiveZip
+	case bytes.HasPrefix(head, []byte{0x1f, 0x8b}):
+		return ArchiveTarGz
+	}
+	return ""
+}
+
+// cleanArchivePath menormalisasi nama entry; "" untuk path yang keluar dari
+// root arsip
+func cleanArchivePath(name string) string {
+	name = strings.TrimPrefix(path.Clean("/"+strings.ReplaceAll(name, "\\", "/")), "/")
+	if name == "" || name == "." {
+		return ""
+	}
+	return name
+}
+
+// WalkArchive memanggil fn untuk setiap file reguler di arsip sesuai urutan
+// penyimpanannya. r hanya valid selama fn berjalan.
+func WalkArchive(f *os.File, kind string, fn func(name string, size int64, r io.Reader) error) error {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	switch kind {
+	case ArchiveZip:
+		st, err := f.Stat()
+		if err != nil {
+			return err
+		}
+		zr, err := zip.NewReader(f, st.Size())
+		if err != nil {
+			return fmt.Errorf("read zip: %w", err)
+		}
+		for _, zf := range zr.File {
+			if zf.FileInfo().IsDir() {
+				continue
+			}
+			name := cleanArchivePath(zf.Name)
+			if name == "" {
+				continue
+			}
+			rc, err := zf.Open()
+			if err != nil {
+				return fmt.Errorf("open %s: %w", zf.Name, err)
+			}
+			err = fn(name, int64(zf.UncompressedSize64), rc)
+			rc.Close()
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	case ArchiveTarGz:
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return fmt.Errorf("read gzip: %w", err)
+		}
+		defer gz.Close()
+		tr := tar.NewReader(gz)
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("read tar: %w", err)
+			}
+			if hdr.Typeflag != tar.TypeReg {
+				continue
+			}
+			name := cleanArchivePath(hdr.Name)
+			if name == "" {
+				continue
+			}
+			if err := fn(name, hdr.Size, tr); err != nil {
+				return err
+			}
+		}
+	}
+	return fmt.Errorf("unsupported archive type %q", kind)
+}
+
+// isManifest mengenali file manifest (manifest.json atau manifest.csv)
+func isManifest(name string) bool {
+	base := strings.ToLower(path.Base(name))
+	return base == "manifest.json" || base == "manifest.csv"
+}
+
+// ScanArchive mendaftar isi arsip dan mem-parse manifest-nya. Arsip harus
+// berisi tepat satu manifest.
+func ScanArchive(f *os.File, kind string) (*ArchiveIndex, error) {
+	idx := &ArchiveIndex{}
+	err := WalkArchive(f, kind, func(name string, size int64, r io.Reader) error {
+		if !isManifest(name) {
+			idx.Files = append(idx.Files, ArchiveFile{Name: name, Size: size})
+			return nil
+		}
+		if idx.ManifestPath != "" {
+			return fmt.Errorf("archive contains more than one manifest (%s, %s)", idx.ManifestPath, name)
+		}
+		raw, err := io.ReadAll(io.LimitReader(r, maxManifestBytes+1))
+		if err != nil {
+			return fmt.Errorf("read manifest: %w", err)
+		}
+		if len(raw) > maxManifestBytes {
+			return fmt.Errorf("manifest %s exceeds %d bytes", name, maxManifestBytes)
+		}
+		idx.ManifestPath = name
+		if strings.HasSuffix(strings.ToLower(name), ".csv") {
+			idx.Manifest, err = ParseManifestCSV(raw)
+		} else {
+			idx.Manifest, err = ParseManifestJSON(raw)
+		}
+		if err != nil {
+			return fmt.Errorf("manifest %s: %w", name, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if idx.ManifestPath == "" {
+		return nil, errors.New("archive has no manifest.json or manifest.csv")
+	}
+	if len(idx.Manifest) == 0 {
+		return nil, errors.New("manifest lists no files")
+	}
+	return idx, nil
+}
+
+// ResolveManifestPath memetakan file manifest ke path di dalam arsip
+func (idx *ArchiveIndex) ResolveManifestPath(file string) string {
+	return cleanArchivePath(path.Join(path.Dir(idx.ManifestPath), file))
+}
+
+// ParseManifestJSON menerima array entry atau objek {"files": [...]}
+func ParseManifestJSON(raw []byte) ([]ManifestEntry, error) {
+	var entries []ManifestEntry
+	if err := json.Unmarshal(raw, &entries); err == nil {
+		return entries, nil
+	}
+	var wrapped struct {
+		Files []ManifestEntry `json:"files"`
+	}
+	if err := json.Unmarshal(raw, &wrapped); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	return wrapped.Files, nil
+}
+
+// ParseManifestCSV membaca manifest CSV dengan header. Kolom wajib: file,
+// nama_ruangan, nama_filter; kolom layout dan nexmon_chip opsional; kolom
+// lain menjadi label.
+func ParseManifestCSV(raw []byte) ([]ManifestEntry, error) {
+	reader := csv.NewReader(bytes.NewReader(raw))
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read header: %w", err)
+	}
+	for i := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(header[i]))
+	}
+	header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	for _, req := range []string{"file", "nama_ruangan", "nama_filter"} {
+		found := false
+		for _, h := range header {
+			found = found || h == req
+		}
+		if !found {
+			return nil, fmt.Errorf("missing column %q", req)
+		}
+	}
+
+	var entries []ManifestEntry
+	for line := 2; ; line++ {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		var e ManifestEntry
+		for i, col := range header {
+			v := strings.TrimSpace(row[i])
+			switch col {
+			case "file":
+				e.File = v
+			case "nama_ruangan":
+				e.NamaRuangan = v
+			case "nama_filter":
+				e.NamaFilter = v
+			case "layout":
+				if v != "" {
+					e.Layout, _ = json.Marshal(v)
+				}
+			case "nexmon_chip":
+				e.NexmonChip = v
+			default:
+				if v == "" || col == "" {
+					continue
+				}
+				if e.Labels == nil {
+					e.Labels = map[string]string{}
+				}
+				e.Labels[col] = v
+			}
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
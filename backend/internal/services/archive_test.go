@@ -0,0 +1,130 @@
+package services
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"strings"
+	"testing"
+)
+
+// archiveEntry adalah satu file untuk arsip test
+type archiveEntry struct {
+	name string
+	body string
+}
+
+func writeTempArchive(t *testing.T, kind string, entries []archiveEntry) *os.File {
+	t.Helper()
+	var buf bytes.Buffer
+	switch kind {
+	case ArchiveZip:
+		zw := zip.NewWriter(&buf)
+		for _, e := range entries {
+			w, err := zw.Create(e.name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w.Write([]byte(e.body))
+		}
+		zw.Close()
+	case ArchiveTarGz:
+		gz := gzip.NewWriter(&buf)
+		tw := tar.NewWriter(gz)
+		for _, e := range entries {
+			tw.WriteHeader(&tar.Header{Name: e.name, Mode: 0o644, Size: int64(len(e.body)), Typeflag: tar.TypeReg})
+			tw.Write([]byte(e.body))
+		}
+		tw.Close()
+		gz.Close()
+	}
+	f, err := os.CreateTemp(t.TempDir(), "archive-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestScanArchiveJSONManifest(t *testing.T) {
+	for _, kind := range []string{ArchiveZip, ArchiveTarGz} {
+		f := writeTempArchive(t, kind, []archiveEntry{
+			{"batch/manifest.json", `{"files":[{"file":"lab/a.csv","nama_ruangan":"Lab","nama_filter":"none","layout":{"streams":1,"subcarriers":4}}]}`},
+			{"batch/lab/a.csv", "1,2,3,4\n"},
+			{"../escape.csv", "x"},
+		})
+		head := make([]byte, 4)
+		f.ReadAt(head, 0)
+		if got := DetectArchive(head); got != kind {
+			t.Fatalf("DetectArchive = %q, want %q", got, kind)
+		}
+
+		idx, err := ScanArchive(f, kind)
+		if err != nil {
+			t.Fatalf("%s: ScanArchive: %v", kind, err)
+		}
+		if idx.ManifestPath != "batch/manifest.json" || len(idx.Manifest) != 1 {
+			t.Fatalf("%s: manifest %q with %d entries", kind, idx.ManifestPath, len(idx.Manifest))
+		}
+		// path di luar root arsip dinormalisasi ke dalam root
+		names := []string{idx.Files[0].Name, idx.Files[1].Name}
+		if names[0] != "batch/lab/a.csv" || names[1] != "escape.csv" {
+			t.Fatalf("%s: files %v", kind, names)
+		}
+		e := idx.Manifest[0]
+		if got := idx.ResolveManifestPath(e.File); got != "batch/lab/a.csv" {
+			t.Errorf("%s: resolved path %q", kind, got)
+		}
+		if got := e.LayoutString(); got != `{"streams":1,"subcarriers":4}` {
+			t.Errorf("%s: layout %q", kind, got)
+		}
+	}
+}
+
+func TestScanArchiveRejects(t *testing.T) {
+	manifest := archiveEntry{"manifest.json", `[{"file":"a.csv","nama_ruangan":"Lab","nama_filter":"none"}]`}
+	cases := map[string][]archiveEntry{
+		"no manifest":     {{"a.csv", "1"}},
+		"two manifests":   {manifest, {"sub/manifest.csv", "file,nama_ruangan,nama_filter\n"}},
+		"empty manifest":  {{"manifest.json", `[]`}},
+		"broken manifest": {{"manifest.json", `{"files":`}},
+	}
+	for name, entries := range cases {
+		f := writeTempArchive(t, ArchiveZip, entries)
+		if _, err := ScanArchive(f, ArchiveZip); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestParseManifestCSV(t *testing.T) {
+	raw := "\ufeffFile, nama_ruangan, nama_filter, layout, aktivitas, orang\n" +
+		`a.csv, Lab, none, "{""streams"":3,""subcarriers"":30}", duduk, 2` + "\n" +
+		"b.csv, Lab, lowpass, , , \n"
+	entries, err := ParseManifestCSV([]byte(raw))
+	if err != nil {
+		t.Fatalf("ParseManifestCSV: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(entries))
+	}
+	a, b := entries[0], entries[1]
+	if a.File != "a.csv" || a.NamaRuangan != "Lab" || a.Labels["aktivitas"] != "duduk" || a.Labels["orang"] != "2" {
+		t.Errorf("entry a = %+v", a)
+	}
+	if got := a.LayoutString(); got != `{"streams":3,"subcarriers":30}` {
+		t.Errorf("layout %q", got)
+	}
+	if b.NamaFilter != "lowpass" || b.Labels != nil || b.LayoutString() != "" {
+		t.Errorf("entry b = %+v", b)
+	}
+
+	if _, err := ParseManifestCSV([]byte("file,nama_ruangan\na.csv,Lab\n")); err == nil || !strings.Contains(err.Error(), "nama_filter") {
+		t.Errorf("got %v, want missing nama_filter", err)
+	}
+}
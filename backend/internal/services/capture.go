@@ -0,0 +1,160 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Supported upload formats
+const (
+	FormatCSV       = "csv"
+	FormatIntel5300 = "intel5300"
+	FormatESP32     = "esp32"
+	FormatNexmon    = "nexmon"
+)
+
+// jarak subcarrier OFDM 802.11a/g/n/ac
+const ofdmSubcarrierHz = 312.5e3
+
+// DecodeOptions berisi parameter tambahan dari form upload
+type DecodeOptions struct {
+	NexmonDecoding string // kosong = otomatis dari chip version
+}
+
+// Capture adalah rekaman CSI yang sudah di-decode ke bentuk yang tidak
+// bergantung pada perangkat: satu baris per paket, kolom stream-major
+// (semua subcarrier stream 0, lalu stream 1, dst).
+type Capture struct {
+	Format      string
+	Streams     int // jumlah antena / spatial stream
+	Subcarriers int
+	Timestamps  []float64   // detik, relatif terhadap paket pertama
+	RSSI        []float64   // dBm
+	Amplitude   [][]float64 // [paket][stream*Subcarriers+subcarrier]
+	Phase       [][]float64 // [paket][stream*Subcarriers+subcarrier], radian
+	// Lebar kanal dan jarak antar subcarrier yang dilaporkan (Hz)
+	BandwidthHz         float64
+	SubcarrierSpacingHz float64
+}
+
+// Packets returns the number of decoded packets
+func (c *Capture) Packets() int {
+	return len(c.Amplitude)
+}
+
+// Row mengembalikan paket p dalam susunan kolom CSV ternormalisasi
+// (lihat CaptureLayout)
+func (c *Capture) Row(p int) []float64 {
+	n := c.Streams * c.Subcarriers
+	row := make([]float64, 2*n+2)
+	copy(row, c.Amplitude[p])
+	copy(row[n:], c.Phase[p])
+	row[2*n] = c.RSSI[p]
+	row[2*n+1] = c.Timestamps[p]
+	return row
+}
+
+// DetectFormat menebak format upload dari nama file dan beberapa byte awal.
+func DetectFormat(filename string, head []byte) string {
+	if looksLikePcap(head) {
+		return FormatNexmon
+	}
+	if looksLikeESP32(head) {
+		return FormatESP32
+	}
+	ext := strings.ToLower(filepath.Ext(filename))
+	if ext == ".dat" || looksLikeIntel5300(head) {
+		return FormatIntel5300
+	}
+	return FormatCSV
+}
+
+// DecodeCapture decodes a non-CSV upload into a Capture
+func DecodeCapture(format string, r io.Reader, opts DecodeOptions) (*Capture, error) {
+	switch format {
+	case FormatIntel5300:
+		return ParseIntel5300(r)
+	case FormatESP32:
+		return ParseESP32(r)
+	case FormatNexmon:
+		return ParseNexmon(r, opts.NexmonDecoding)
+	default:
+		return nil, fmt.Errorf("unsupported capture format %q", format)
+	}
+}
+
+// WriteNormalizedCSV menulis Capture sebagai CSV dengan header:
+// blok amplitudo (amp_<stream>_<sc>), blok fase (phase_<stream>_<sc>),
+// lalu rssi dan timestamp. Blok amplitudo sengaja diletakkan di depan
+// agar tetap terbaca oleh GetPlots.
+func WriteNormalizedCSV(w io.Writer, c *Capture) error {
+	cw := csv.NewWriter(w)
+	n := c.Streams * c.Subcarriers
+
+	header := make([]string, 0, 2*n+2)
+	for _, prefix := range []string{"amp", "phase"} {
+		for st := 0; st < c.Streams; st++ {
+			for sc := 0; sc < c.Subcarriers; sc++ {
+				header = append(header, fmt.Sprintf("%s_%d_%d", prefix, st, sc))
+			}
+		}
+	}
+	header = append(header, "rssi", "timestamp")
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	row := make([]string, 2*n+2)
+	for p := 0; p < c.Packets(); p++ {
+		for k, v := range c.Row(p) {
+			row[k] = formatFloat(v)
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// NormalizedCSV is a convenience wrapper returning the normalized CSV bytes
+func NormalizedCSV(c *Capture) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := WriteNormalizedCSV(&buf, c); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// NormalizedShape membaca header CSV ternormalisasi dan mengembalikan
+// jumlah stream dan subcarrier. ok=false bila header bukan hasil
+// WriteNormalizedCSV (misalnya CSV lama tanpa header).
+func NormalizedShape(header []string) (streams, subcarriers int, ok bool) {
+	count := 0
+	for _, col := range header {
+		var st, sc int
+		if _, err := fmt.Sscanf(col, "amp_%d_%d", &st, &sc); err != nil {
+			continue
+		}
+		count++
+		if st+1 > streams {
+			streams = st + 1
+		}
+		if sc+1 > subcarriers {
+			subcarriers = sc + 1
+		}
+	}
+	if count == 0 || count != streams*subcarriers {
+		return 0, 0, false
+	}
+	return streams, subcarriers, true
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', 6, 64)
+}
@@ -0,0 +1,271 @@
+package services
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"cetasense-v2.0/internal/models"
+)
+
+// CSIMatrix adalah isi file CSI yang sudah dipetakan lewat CSILayout
+type CSIMatrix struct {
+	Layout     models.CSILayout
+	Packets    int
+	Amplitude  [][][]float64 // [stream][subcarrier][packet]
+	Phase      [][][]float64 // nil bila layout tidak membawa fase
+	Timestamps []float64     // detik; nil bila layout tidak punya kolom timestamp
+	RSSI       []float64     // nil bila layout tidak punya kolom RSSI
+}
+
+// CSIRows adalah hasil parse mentah CSV CSI
+type CSIRows struct {
+	Header []string    // nil bila baris pertama adalah data
+	Rows   [][]float64 // baris=paket, kolom=data (NaN untuk cell tak terbaca)
+}
+
+// parsing helpers (lebih toleran terhadap koma desimal / header opsional)
+func parseFloatLoose(s string) (float64, bool) {
+	if v, err := strconv.ParseFloat(s, 64); err == nil {
+		return v, true
+	}
+	s2 := strings.ReplaceAll(s, ",", ".")
+	if v, err := strconv.ParseFloat(s2, 64); err == nil {
+		return v, true
+	}
+	return math.NaN(), false
+}
+
+// ReadFloatRow mem-parse satu baris dan mengembalikan rasio cell yang valid
+func ReadFloatRow(row []string) ([]float64, float64) {
+	vals := make([]float64, len(row))
+	ok := 0
+	for i, cell := range row {
+		v, okv := parseFloatLoose(cell)
+		if okv {
+			ok++
+		}
+		vals[i] = v
+	}
+	return vals, float64(ok) / float64(len(row))
+}
+
+// isHeaderRow menentukan apakah baris pertama CSV adalah header: layout
+// yang diketahui memutuskan lewat HasHeader, tanpa layout baris dianggap
+// header bila paling banyak 60% cell-nya numerik.
+func isHeaderRow(ratio float64, layout *models.CSILayout) bool {
+	if layout != nil {
+		return layout.HasHeader
+	}
+	return ratio <= 0.6
+}
+
+// ReadCSIRows mem-parse CSV CSI. layout boleh nil (upload lama tanpa
+// layout); lihat isHeaderRow untuk penentuan baris header.
+func ReadCSIRows(r io.Reader, layout *models.CSILayout) (*CSIRows, error) {
+	reader := csv.NewReader(r)
+
+	first, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read CSV: %w", err)
+	}
+	out := &CSIRows{Rows: make([][]float64, 0, 1024)}
+	vals, ratio := ReadFloatRow(first)
+	if isHeaderRow(ratio, layout) {
+		out.Header = first
+	} else {
+		out.Rows = append(out.Rows, vals)
+	}
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parse CSV: %w", err)
+		}
+		v, _ := ReadFloatRow(row)
+		out.Rows = append(out.Rows, v)
+	}
+	if len(out.Rows) == 0 {
+		return nil, errors.New("empty CSV")
+	}
+	return out, nil
+}
+
+// Columns mengembalikan jumlah kolom data
+func (c *CSIRows) Columns() int {
+	if len(c.Rows) == 0 {
+		return len(c.Header)
+	}
+	return len(c.Rows[0])
+}
+
+// ExtractCSI memetakan baris CSV ke matriks [stream][subcarrier][packet]
+// sesuai layout.
+func ExtractCSI(rows [][]float64, layout models.CSILayout) (*CSIMatrix, error) {
+	if len(rows) == 0 {
+		return nil, errors.New("no packets")
+	}
+	if err := CheckLayout(layout, len(rows[0])); err != nil {
+		return nil, err
+	}
+
+	P := len(rows)
+	C, S := layout.Streams, layout.Subcarriers
+	m := &CSIMatrix{Layout: layout, Packets: P}
+	cell := func(p, col int) float64 {
+		if col >= len(rows[p]) {
+			return math.NaN()
+		}
+		return rows[p][col]
+	}
+
+	m.Amplitude = make([][][]float64, C)
+	withPhase := layout.Encoding != models.LayoutEncodingAmplitude
+	if withPhase {
+		m.Phase = make([][][]float64, C)
+	}
+	for c := 0; c < C; c++ {
+		m.Amplitude[c] = make([][]float64, S)
+		if withPhase {
+			m.Phase[c] = make([][]float64, S)
+		}
+		for s := 0; s < S; s++ {
+			amp := make([]float64, P)
+			var phase []float64
+			if withPhase {
+				phase = make([]float64, P)
+			}
+			col := layout.Column(c, s)
+			for p := 0; p < P; p++ {
+				switch layout.Encoding {
+				case models.LayoutEncodingRealImag:
+					re, im := cell(p, col), cell(p, col+1)
+					amp[p] = math.Hypot(re, im)
+					phase[p] = math.Atan2(im, re)
+				case models.LayoutEncodingAmplitudePhase:
+					amp[p] = cell(p, col)
+					phase[p] = cell(p, layout.PhaseColumn(c, s))
+				default:
+					amp[p] = cell(p, col)
+				}
+			}
+			m.Amplitude[c][s] = amp
+			if withPhase {
+				m.Phase[c][s] = phase
+			}
+		}
+	}
+
+	column := func(col int) []float64 {
+		out := make([]float64, P)
+		for p := 0; p < P; p++ {
+			out[p] = cell(p, col)
+		}
+		return out
+	}
+	if layout.TimestampCol != nil {
+		m.Timestamps = column(*layout.TimestampCol)
+		if scale := layout.TimestampScale(); scale != 1 {
+			for p := range m.Timestamps {
+				m.Timestamps[p] *= scale
+			}
+		}
+	}
+	if layout.RSSICol != nil {
+		m.RSSI = column(*layout.RSSICol)
+	}
+	return m, nil
+}
+
+// Slice mengembalikan paket [lo, hi) sebagai matriks baru yang berbagi
+// data dengan m
+func (m *CSIMatrix) Slice(lo, hi int) *CSIMatrix {
+	cut := func(x [][][]float64) [][][]float64 {
+		if x == nil {
+			return nil
+		}
+		out := make([][][]float64, len(x))
+		for c := range x {
+			out[c] = make([][]float64, len(x[c]))
+			for s := range x[c] {
+				out[c][s] = x[c][s][lo:hi]
+			}
+		}
+		return out
+	}
+	out := &CSIMatrix{Layout: m.Layout, Packets: hi - lo, Amplitude: cut(m.Amplitude), Phase: cut(m.Phase)}
+	if m.Timestamps != nil {
+		out.Timestamps = m.Timestamps[lo:hi]
+	}
+	if m.RSSI != nil {
+		out.RSSI = m.RSSI[lo:hi]
+	}
+	return out
+}
+
+// WriteMatrixCSV menulis matriks [stream][subcarrier][packet] sebagai CSV
+// satu baris per paket dengan header <prefix>_<stream>_<sc> (stream-major,
+// sama dengan WriteNormalizedCSV). Sel NaN (dropout) ditulis sebagai nilai
+// dropout.
+func WriteMatrixCSV(w io.Writer, prefix string, m [][][]float64, dropout float64) error {
+	cw := csv.NewWriter(w)
+	var header []string
+	P := 0
+	for st := range m {
+		for sc := range m[st] {
+			header = append(header, fmt.Sprintf("%s_%d_%d", prefix, st, sc))
+			P = len(m[st][sc])
+		}
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	row := make([]string, len(header))
+	for p := 0; p < P; p++ {
+		k := 0
+		for st := range m {
+			for sc := range m[st] {
+				v := m[st][sc][p]
+				if math.IsNaN(v) {
+					v = dropout
+				}
+				row[k] = formatFloat(v)
+				k++
+			}
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteTableCSV menulis tabel baris × kolom dengan header apa adanya. NaN
+// ditulis sebagai "NaN".
+func WriteTableCSV(w io.Writer, header []string, rows [][]float64) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	row := make([]string, len(header))
+	for i, values := range rows {
+		if len(values) != len(header) {
+			return fmt.Errorf("row %d has %d values; header has %d", i+1, len(values), len(header))
+		}
+		for k, v := range values {
+			row[k] = formatFloat(v)
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
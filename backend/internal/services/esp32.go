@@ -0,0 +1,252 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const esp32Marker = "CSI_DATA"
+
+var macPattern = regexp.MustCompile(`^([0-9A-Fa-f]{2}:){5}[0-9A-Fa-f]{2}$`)
+
+// ESP32Packet adalah satu baris CSI_DATA dari ESP32-CSI-Tool / esp-csi
+type ESP32Packet struct {
+	MAC              string
+	RSSI             float64
+	Rate             int
+	SigMode          int // 0 = non-HT (11bg), 1 = HT (11n)
+	Bandwidth        int // cwb: 0 = 20 MHz, 1 = 40 MHz
+	STBC             int
+	Channel          int
+	SecondaryChannel int    // 0 = none, 1 = above, 2 = below
+	LocalTimestamp   uint32 // mikrodetik
+	Raw              []int8 // pasangan [imag, real] per subcarrier
+}
+
+// esp32Segment menjelaskan potongan buffer CSI yang dipakai (LLTF atau
+// HT-LTF) dan subcarrier mana yang berisi data.
+type esp32Segment struct {
+	Name   string
+	Offset int // dalam pasangan (subcarrier), bukan byte
+	Size   int // 64 (20 MHz) atau 128 (40 MHz)
+	MaxSC  int // indeks subcarrier terluar yang dipakai
+	MinSC  int // indeks terdalam (DC dan sekitarnya dibuang)
+}
+
+var (
+	esp32LLTF     = esp32Segment{Name: "lltf", Offset: 0, Size: 64, MinSC: 1, MaxSC: 26}
+	esp32HTLTF20  = esp32Segment{Name: "htltf20", Offset: 64, Size: 64, MinSC: 1, MaxSC: 28}
+	esp32HTLTF40  = esp32Segment{Name: "htltf40", Offset: 64, Size: 128, MinSC: 2, MaxSC: 58}
+	esp32Segments = map[string]esp32Segment{
+		esp32LLTF.Name:    esp32LLTF,
+		esp32HTLTF20.Name: esp32HTLTF20,
+		esp32HTLTF40.Name: esp32HTLTF40,
+	}
+)
+
+// looksLikeESP32 cek apakah potongan awal file berisi baris CSI_DATA
+func looksLikeESP32(head []byte) bool {
+	return bytes.Contains(head, []byte(esp32Marker))
+}
+
+// segment memilih layout subcarrier untuk paket ini. HT-LTF dipakai bila
+// ada (lebih akurat dari LLTF); pada 40 MHz HT-LTF berisi 128 subcarrier.
+func (p *ESP32Packet) segment() esp32Segment {
+	pairs := len(p.Raw) / 2
+	if p.SigMode == 1 {
+		if p.Bandwidth == 1 && pairs >= esp32HTLTF40.Offset+esp32HTLTF40.Size {
+			return esp32HTLTF40
+		}
+		if pairs >= esp32HTLTF20.Offset+esp32HTLTF20.Size {
+			return esp32HTLTF20
+		}
+	}
+	return esp32LLTF
+}
+
+// Subcarriers mengembalikan CSI kompleks per subcarrier terurut dari
+// indeks negatif ke positif, tanpa DC dan guard band.
+func (p *ESP32Packet) Subcarriers(seg esp32Segment) []complex128 {
+	out := make([]complex128, 0, 2*(seg.MaxSC-seg.MinSC+1))
+	half := seg.Size / 2
+	// buffer ESP32 berurutan 0..half-1 lalu -half..-1
+	at := func(k int) complex128 {
+		idx := k
+		if k < 0 {
+			idx = seg.Size + k
+		}
+		pos := 2 * (seg.Offset + idx)
+		if pos+1 >= len(p.Raw) {
+			return complex(math.NaN(), math.NaN())
+		}
+		return complex(float64(p.Raw[pos+1]), float64(p.Raw[pos]))
+	}
+	for k := -half; k < half; k++ {
+		ak := k
+		if ak < 0 {
+			ak = -ak
+		}
+		if ak < seg.MinSC || ak > seg.MaxSC {
+			continue
+		}
+		out = append(out, at(k))
+	}
+	return out
+}
+
+// ParseESP32Line mem-parse satu baris CSI_DATA. Kolom metadata dicari
+// relatif terhadap kolom MAC sehingga format ESP32-CSI-Tool (dengan
+// kolom role) maupun esp-csi (dengan kolom seq) sama-sama didukung.
+func ParseESP32Line(line string) (*ESP32Packet, error) {
+	start := strings.Index(line, esp32Marker)
+	if start < 0 {
+		return nil, errors.New("not a CSI_DATA line")
+	}
+	line = line[start:]
+	lb := strings.Index(line, "[")
+	rb := strings.LastIndex(line, "]")
+	if lb < 0 || rb < lb {
+		return nil, errors.New("missing CSI array")
+	}
+
+	fields := strings.Split(line[:lb], ",")
+	for i := range fields {
+		fields[i] = strings.Trim(strings.TrimSpace(fields[i]), `"`)
+	}
+	macIdx := -1
+	for i, f := range fields {
+		if macPattern.MatchString(f) {
+			macIdx = i
+			break
+		}
+	}
+	if macIdx < 0 || len(fields) <= macIdx+16 {
+		return nil, errors.New("incomplete CSI_DATA metadata")
+	}
+	num := func(off int) int {
+		v, _ := strconv.Atoi(fields[macIdx+off])
+		return v
+	}
+	rssi, err := strconv.ParseFloat(fields[macIdx+1], 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid rssi %q", fields[macIdx+1])
+	}
+	ts, err := strconv.ParseUint(fields[macIdx+16], 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid local_timestamp %q", fields[macIdx+16])
+	}
+
+	pkt := &ESP32Packet{
+		MAC:              fields[macIdx],
+		RSSI:             rssi,
+		Rate:             num(2),
+		SigMode:          num(3),
+		Bandwidth:        num(5),
+		STBC:             num(9),
+		Channel:          num(14),
+		SecondaryChannel: num(15),
+		LocalTimestamp:   uint32(ts),
+	}
+
+	values := strings.FieldsFunc(line[lb+1:rb], func(r rune) bool {
+		return r == ' ' || r == ',' || r == '\t'
+	})
+	pkt.Raw = make([]int8, 0, len(values))
+	for _, v := range values {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < math.MinInt8 || n > math.MaxInt8 {
+			return nil, fmt.Errorf("invalid CSI value %q", v)
+		}
+		pkt.Raw = append(pkt.Raw, int8(n))
+	}
+	if len(pkt.Raw) < 2*esp32LLTF.Size {
+		return nil, fmt.Errorf("CSI array too short (%d values)", len(pkt.Raw))
+	}
+	return pkt, nil
+}
+
+// ParseESP32 men-decode log serial ESP32 menjadi Capture satu stream.
+// Baris selain CSI_DATA (log boot, header) dilewati. Karena ESP32 bisa
+// menerima campuran paket non-HT/HT/40 MHz, layout subcarrier yang paling
+// sering muncul dipakai dan paket dengan layout lain dibuang.
+func ParseESP32(r io.Reader) (*Capture, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var packets []*ESP32Packet
+	counts := map[string]int{}
+	lineNo := 0
+	for sc.Scan() {
+		lineNo++
+		line := sc.Text()
+		if !strings.Contains(line, esp32Marker) || !strings.Contains(line, "[") {
+			continue
+		}
+		pkt, err := ParseESP32Line(line)
+		if err != nil {
+			// baris serial yang terpotong cukup umum; lewati saja
+			continue
+		}
+		packets = append(packets, pkt)
+		counts[pkt.segment().Name]++
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read ESP32 log at line %d: %w", lineNo, err)
+	}
+	if len(packets) == 0 {
+		return nil, errors.New("no valid CSI_DATA lines found")
+	}
+
+	best := ""
+	for name, n := range counts {
+		if best == "" || n > counts[best] || (n == counts[best] && name > best) {
+			best = name
+		}
+	}
+	seg := esp32Segments[best]
+
+	c := &Capture{
+		Format:              FormatESP32,
+		Streams:             1,
+		Subcarriers:         2 * (seg.MaxSC - seg.MinSC + 1),
+		BandwidthHz:         float64(seg.Size) * ofdmSubcarrierHz,
+		SubcarrierSpacingHz: ofdmSubcarrierHz,
+	}
+	var t0, wraps float64
+	var last uint32
+	first := true
+	for _, pkt := range packets {
+		if pkt.segment().Name != best {
+			continue
+		}
+		if !first && pkt.LocalTimestamp < last {
+			wraps += 1 << 32
+		}
+		ts := (float64(pkt.LocalTimestamp) + wraps) / 1e6
+		if first {
+			t0 = ts
+			first = false
+		}
+		last = pkt.LocalTimestamp
+
+		csi := pkt.Subcarriers(seg)
+		amp := make([]float64, len(csi))
+		phase := make([]float64, len(csi))
+		for k, v := range csi {
+			amp[k] = math.Hypot(real(v), imag(v))
+			phase[k] = math.Atan2(imag(v), real(v))
+		}
+		c.Amplitude = append(c.Amplitude, amp)
+		c.Phase = append(c.Phase, phase)
+		c.RSSI = append(c.RSSI, pkt.RSSI)
+		c.Timestamps = append(c.Timestamps, ts-t0)
+	}
+	return c, nil
+}
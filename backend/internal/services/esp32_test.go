@@ -0,0 +1,97 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+)
+
+// esp32Line menyusun baris CSI_DATA format ESP32-CSI-Tool dengan pairs
+// pasangan [imag, real] = [4, 3] (amplitudo 5)
+func esp32Line(sigMode, cwb, pairs int, ts uint32) string {
+	values := make([]string, 0, 2*pairs)
+	for i := 0; i < pairs; i++ {
+		values = append(values, "4", "3")
+	}
+	return fmt.Sprintf("CSI_DATA,AP,aa:bb:cc:dd:ee:ff,-42,11,%d,0,%d,0,1,0,0,0,0,-90,0,6,0,%d,0,100,0,%d,0,[%s]",
+		sigMode, cwb, ts, 2*pairs, strings.Join(values, " "))
+}
+
+func parseESP32Lines(t *testing.T, lines ...string) *Capture {
+	t.Helper()
+	c, err := ParseESP32(strings.NewReader(strings.Join(lines, "\n")))
+	if err != nil {
+		t.Fatalf("ParseESP32: %v", err)
+	}
+	return c
+}
+
+func TestParseESP32SkipsNoise(t *testing.T) {
+	c := parseESP32Lines(t,
+		"I (123) boot: ESP-IDF v4.4",
+		esp32Line(0, 0, 64, 1000),
+		"CSI_DATA,truncated",
+		esp32Line(0, 0, 64, 3000),
+	)
+	// L-LTF 20 MHz: 64 pasangan, 52 subcarrier berguna
+	if c.Streams != 1 || c.Subcarriers != 52 || c.Packets() != 2 {
+		t.Fatalf("got %d×%d with %d packets, want 1×52 with 2", c.Streams, c.Subcarriers, c.Packets())
+	}
+	if c.Timestamps[1] != 0.002 {
+		t.Errorf("timestamps = %v, want [0 0.002]", c.Timestamps)
+	}
+	for k, v := range c.Amplitude[0] {
+		if math.Abs(v-5) > 1e-12 {
+			t.Fatalf("amplitude[0][%d] = %g, want 5", k, v)
+		}
+	}
+	if c.RSSI[0] != -42 {
+		t.Errorf("rssi %g, want -42", c.RSSI[0])
+	}
+}
+
+func TestParseESP32TimestampWrap(t *testing.T) {
+	c := parseESP32Lines(t, esp32Line(1, 0, 128, 4294967000), esp32Line(1, 0, 128, 200))
+	if c.Subcarriers != 56 {
+		t.Fatalf("got %d subcarriers, want 56 for HT20", c.Subcarriers)
+	}
+	if got := c.Timestamps[1]; math.Abs(got-0.000496) > 1e-9 {
+		t.Errorf("wrapped timestamp %g, want 0.000496", got)
+	}
+}
+
+func TestParseESP32MajoritySegment(t *testing.T) {
+	// paket dengan bentuk minoritas dibuang, bukan dijadikan kolom campuran
+	c := parseESP32Lines(t,
+		esp32Line(1, 1, 192, 0),
+		esp32Line(0, 0, 64, 10),
+		esp32Line(1, 1, 192, 20),
+	)
+	if c.Subcarriers != 114 || c.Packets() != 2 {
+		t.Fatalf("got %d subcarriers and %d packets, want 114 and 2", c.Subcarriers, c.Packets())
+	}
+	if math.Abs(c.Timestamps[1]-0.00002) > 1e-12 {
+		t.Errorf("timestamps = %v, want [0 2e-05]", c.Timestamps)
+	}
+}
+
+func TestParseESP32LineErrors(t *testing.T) {
+	cases := []struct {
+		name string
+		line string
+	}{
+		{"no marker", "hello"},
+		{"no array", "CSI_DATA,AP,aa:bb:cc:dd:ee:ff,-42"},
+		{"short metadata", "CSI_DATA,AP,aa:bb:cc:dd:ee:ff,-42,11,[1 2]"},
+		{"short array", esp32Line(0, 0, 10, 0)},
+		{"value out of range", strings.Replace(esp32Line(0, 0, 64, 0), "[4", "[400", 1)},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := ParseESP32Line(tc.line); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
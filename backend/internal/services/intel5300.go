@@ -0,0 +1,296 @@
+package services
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/cmplx"
+)
+
+// Intel 5300 CSI Tool (log_to_file) record codes
+const (
+	intelCodeBfee    = 0xBB
+	intelSubcarriers = 30
+	// bit HT40 pada rate_n_flags iwlwifi
+	intelRateHT40 = 1 << 11
+)
+
+// BfeeRecord adalah satu record beamforming feedback (kode 0xBB) dari
+// Intel 5300 CSI Tool, mengikuti read_bfee.c.
+type BfeeRecord struct {
+	TimestampLow uint32 // mikrodetik, wrap 32-bit
+	BfeeCount    uint16
+	Nrx          int
+	Ntx          int
+	RSSIA        int
+	RSSIB        int
+	RSSIC        int
+	Noise        int
+	AGC          int
+	Perm         [3]int // 0-based
+	Rate         uint16
+	CSI          [][][]complex128 // [tx][rx][subcarrier]
+}
+
+// looksLikeIntel5300 cek apakah record pertama memiliki kode bfee
+func looksLikeIntel5300(head []byte) bool {
+	if len(head) < 3 {
+		return false
+	}
+	fieldLen := int(binary.BigEndian.Uint16(head[0:2]))
+	return head[2] == intelCodeBfee && fieldLen > 20
+}
+
+// ReadBfeeRecords membaca semua record bfee dari stream log_to_file.
+// Record dengan kode lain dilewati.
+func ReadBfeeRecords(r io.Reader) ([]*BfeeRecord, error) {
+	br := bufio.NewReader(r)
+	var records []*BfeeRecord
+	hdr := make([]byte, 3)
+	for {
+		if _, err := io.ReadFull(br, hdr); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				// trailing record yang terpotong diabaikan, seperti read_bf_file.m
+				break
+			}
+			return nil, fmt.Errorf("read record header: %w", err)
+		}
+		fieldLen := int(binary.BigEndian.Uint16(hdr[0:2]))
+		code := hdr[2]
+		if fieldLen < 1 {
+			return nil, fmt.Errorf("invalid record length %d", fieldLen)
+		}
+		payload := make([]byte, fieldLen-1)
+		if _, err := io.ReadFull(br, payload); err != nil {
+			break
+		}
+		if code != intelCodeBfee {
+			continue
+		}
+		rec, err := parseBfee(payload)
+		if err != nil {
+			return nil, fmt.Errorf("record %d: %w", len(records), err)
+		}
+		records = append(records, rec)
+	}
+	return records, nil
+}
+
+func parseBfee(b []byte) (*BfeeRecord, error) {
+	if len(b) < 20 {
+		return nil, errors.New("bfee record too short")
+	}
+	rec := &BfeeRecord{
+		TimestampLow: binary.LittleEndian.Uint32(b[0:4]),
+		BfeeCount:    binary.LittleEndian.Uint16(b[4:6]),
+		Nrx:          int(b[8]),
+		Ntx:          int(b[9]),
+		RSSIA:        int(b[10]),
+		RSSIB:        int(b[11]),
+		RSSIC:        int(b[12]),
+		Noise:        int(int8(b[13])),
+		AGC:          int(b[14]),
+		Rate:         binary.LittleEndian.Uint16(b[18:20]),
+	}
+	antennaSel := b[15]
+	rec.Perm = [3]int{int(antennaSel & 0x3), int((antennaSel >> 2) & 0x3), int((antennaSel >> 4) & 0x3)}
+
+	if rec.Nrx < 1 || rec.Nrx > 3 || rec.Ntx < 1 || rec.Ntx > 3 {
+		return nil, fmt.Errorf("invalid antenna configuration Nrx=%d Ntx=%d", rec.Nrx, rec.Ntx)
+	}
+	length := int(binary.LittleEndian.Uint16(b[16:18]))
+	calcLen := (intelSubcarriers*(rec.Nrx*rec.Ntx*8*2+3) + 7) / 8
+	if length != calcLen {
+		return nil, fmt.Errorf("wrong beamforming matrix size: got %d, expected %d", length, calcLen)
+	}
+	payload := b[20:]
+	if len(payload) < calcLen {
+		return nil, errors.New("truncated beamforming matrix")
+	}
+	// padding agar pembacaan index/8+2 tidak keluar batas pada elemen terakhir
+	payload = append(payload[:calcLen:calcLen], 0, 0)
+
+	csi := make([][][]complex128, rec.Ntx)
+	for tx := range csi {
+		csi[tx] = make([][]complex128, rec.Nrx)
+		for rx := range csi[tx] {
+			csi[tx][rx] = make([]complex128, intelSubcarriers)
+		}
+	}
+
+	// urutan elemen per subcarrier: tx tercepat lalu rx (column-major di MATLAB)
+	index := 0
+	for sc := 0; sc < intelSubcarriers; sc++ {
+		index += 3
+		rem := uint(index % 8)
+		for j := 0; j < rec.Nrx*rec.Ntx; j++ {
+			k := index / 8
+			re := int8((payload[k] >> rem) | (payload[k+1] << (8 - rem)))
+			im := int8((payload[k+1] >> rem) | (payload[k+2] << (8 - rem)))
+			tx, rx := j%rec.Ntx, j/rec.Ntx
+			csi[tx][rx][sc] = complex(float64(re), float64(im))
+			index += 16
+		}
+	}
+
+	// terapkan permutasi antena penerima bila perm[:Nrx] merupakan permutasi
+	// dari 0..Nrx-1 (seperti read_bf_file.m, juga untuk Nrx = 2)
+	if rec.Nrx > 1 {
+		seen := make([]bool, rec.Nrx)
+		valid := true
+		for rx := 0; rx < rec.Nrx; rx++ {
+			p := rec.Perm[rx]
+			if p < 0 || p >= rec.Nrx || seen[p] {
+				valid = false
+				break
+			}
+			seen[p] = true
+		}
+		if valid {
+			for tx := range csi {
+				permuted := make([][]complex128, rec.Nrx)
+				for rx := 0; rx < rec.Nrx; rx++ {
+					permuted[rec.Perm[rx]] = csi[tx][rx]
+				}
+				csi[tx] = permuted
+			}
+		}
+	}
+	rec.CSI = csi
+	return rec, nil
+}
+
+func dbinv(x float64) float64 {
+	return math.Pow(10, x/10)
+}
+
+// TotalRSS menghitung RSSI total (dBm) seperti get_total_rss.m
+func (rec *BfeeRecord) TotalRSS() float64 {
+	mag := 0.0
+	for _, v := range []int{rec.RSSIA, rec.RSSIB, rec.RSSIC} {
+		if v != 0 {
+			mag += dbinv(float64(v))
+		}
+	}
+	if mag == 0 {
+		return math.NaN()
+	}
+	return 10*math.Log10(mag) - 44 - float64(rec.AGC)
+}
+
+// ScaledCSI mengembalikan CSI dalam satuan SNR absolut seperti get_scaled_csi.m
+func (rec *BfeeRecord) ScaledCSI() [][][]complex128 {
+	csiPwr := 0.0
+	for tx := range rec.CSI {
+		for rx := range rec.CSI[tx] {
+			for _, v := range rec.CSI[tx][rx] {
+				csiPwr += real(v)*real(v) + imag(v)*imag(v)
+			}
+		}
+	}
+
+	out := make([][][]complex128, rec.Ntx)
+	for tx := range out {
+		out[tx] = make([][]complex128, rec.Nrx)
+		for rx := range out[tx] {
+			out[tx][rx] = make([]complex128, intelSubcarriers)
+		}
+	}
+	rss := rec.TotalRSS()
+	if csiPwr == 0 || math.IsNaN(rss) {
+		return out
+	}
+
+	rssiPwr := dbinv(rss)
+	scale := rssiPwr / (csiPwr / intelSubcarriers)
+	noiseDb := float64(rec.Noise)
+	if rec.Noise == -127 {
+		noiseDb = -92
+	}
+	thermalNoisePwr := dbinv(noiseDb)
+	quantErrorPwr := scale * float64(rec.Nrx*rec.Ntx)
+	totalNoisePwr := thermalNoisePwr + quantErrorPwr
+
+	factor := math.Sqrt(scale / totalNoisePwr)
+	switch rec.Ntx {
+	case 2:
+		factor *= math.Sqrt2
+	case 3:
+		factor *= math.Sqrt(dbinv(4.5))
+	}
+	for tx := range rec.CSI {
+		for rx := range rec.CSI[tx] {
+			for sc, v := range rec.CSI[tx][rx] {
+				out[tx][rx][sc] = v * complex(factor, 0)
+			}
+		}
+	}
+	return out
+}
+
+// ParseIntel5300 men-decode file .dat log_to_file menjadi Capture dengan
+// CSI ter-skala. Stream diurutkan tx-major (stream = tx*Nrx + rx); paket
+// dengan konfigurasi antena berbeda dari paket pertama dilewati.
+func ParseIntel5300(r io.Reader) (*Capture, error) {
+	records, err := ReadBfeeRecords(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, errors.New("no bfee records found")
+	}
+
+	nrx, ntx := records[0].Nrx, records[0].Ntx
+	c := &Capture{
+		Format:      FormatIntel5300,
+		Streams:     nrx * ntx,
+		Subcarriers: intelSubcarriers,
+		// 30 grup subcarrier: tiap 2 subcarrier pada 20 MHz, tiap 4 pada 40 MHz
+		BandwidthHz:         20e6,
+		SubcarrierSpacingHz: 2 * ofdmSubcarrierHz,
+	}
+	if records[0].Rate&intelRateHT40 != 0 {
+		c.BandwidthHz, c.SubcarrierSpacingHz = 40e6, 4*ofdmSubcarrierHz
+	}
+
+	var t0 float64
+	var last uint32
+	var wraps float64
+	first := true
+	for _, rec := range records {
+		if rec.Nrx != nrx || rec.Ntx != ntx {
+			continue
+		}
+		// unwrap timestamp 32-bit mikrodetik
+		if !first && rec.TimestampLow < last {
+			wraps += 1 << 32
+		}
+		ts := (float64(rec.TimestampLow) + wraps) / 1e6
+		if first {
+			t0 = ts
+			first = false
+		}
+		last = rec.TimestampLow
+
+		scaled := rec.ScaledCSI()
+		amp := make([]float64, c.Streams*c.Subcarriers)
+		phase := make([]float64, c.Streams*c.Subcarriers)
+		for tx := 0; tx < ntx; tx++ {
+			for rx := 0; rx < nrx; rx++ {
+				base := (tx*nrx + rx) * intelSubcarriers
+				for sc, v := range scaled[tx][rx] {
+					amp[base+sc] = cmplx.Abs(v)
+					phase[base+sc] = cmplx.Phase(v)
+				}
+			}
+		}
+		c.Amplitude = append(c.Amplitude, amp)
+		c.Phase = append(c.Phase, phase)
+		c.RSSI = append(c.RSSI, rec.TotalRSS())
+		c.Timestamps = append(c.Timestamps, ts-t0)
+	}
+	return c, nil
+}
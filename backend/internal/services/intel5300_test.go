@@ -0,0 +1,199 @@
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+// bfeeSpec menjelaskan satu record bfee sintetis
+type bfeeSpec struct {
+	ts         uint32
+	nrx, ntx   int
+	antennaSel byte
+	rate       uint16
+}
+
+// rawIntelCSI adalah nilai CSI mentah (sebelum permutasi) untuk tx, rx, sc
+func rawIntelCSI(tx, rx, sc int) (int8, int8) {
+	return int8(10*(rx+1) + tx), int8(sc - 15)
+}
+
+// putBits menulis 8 bit v mulai dari bit ke-pos (LSB dulu), kebalikan dari
+// pembacaan parseBfee
+func putBits(buf []byte, pos int, v int8) {
+	for i := 0; i < 8; i++ {
+		if uint8(v)>>i&1 == 1 {
+			buf[(pos+i)/8] |= 1 << ((pos + i) % 8)
+		}
+	}
+}
+
+// encodeBfee menyusun record log_to_file (header 3 byte + payload)
+func encodeBfee(s bfeeSpec) []byte {
+	calcLen := (intelSubcarriers*(s.nrx*s.ntx*8*2+3) + 7) / 8
+	p := make([]byte, 20+calcLen)
+	binary.LittleEndian.PutUint32(p[0:4], s.ts)
+	binary.LittleEndian.PutUint16(p[4:6], 1)
+	p[8], p[9] = byte(s.nrx), byte(s.ntx)
+	p[10], p[11], p[12] = 30, 28, 0
+	p[13] = byte(0x81) // noise -127
+	p[14] = 20
+	p[15] = s.antennaSel
+	binary.LittleEndian.PutUint16(p[16:18], uint16(calcLen))
+	binary.LittleEndian.PutUint16(p[18:20], s.rate)
+
+	bits := p[20:]
+	index := 0
+	for sc := 0; sc < intelSubcarriers; sc++ {
+		index += 3
+		for j := 0; j < s.nrx*s.ntx; j++ {
+			tx, rx := j%s.ntx, j/s.ntx
+			re, im := rawIntelCSI(tx, rx, sc)
+			putBits(bits, index, re)
+			putBits(bits, index+8, im)
+			index += 16
+		}
+	}
+
+	out := make([]byte, 3, 3+len(p))
+	binary.BigEndian.PutUint16(out[0:2], uint16(len(p)+1))
+	out[2] = intelCodeBfee
+	return append(out, p...)
+}
+
+func antennaSel(perm ...int) byte {
+	var b byte
+	for i, p := range perm {
+		b |= byte(p) << (2 * i)
+	}
+	return b
+}
+
+func TestReadBfeeRecordsPermutation(t *testing.T) {
+	cases := []struct {
+		name string
+		nrx  int
+		ntx  int
+		sel  byte
+		want []int // rx mentah -> rx keluaran
+	}{
+		{"single antenna", 1, 1, antennaSel(2, 1, 0), []int{0}},
+		{"nrx3 rotated", 3, 1, antennaSel(2, 0, 1), []int{2, 0, 1}},
+		{"nrx3 duplicate perm kept", 3, 2, antennaSel(1, 1, 0), []int{0, 1, 2}},
+		{"nrx2 swapped", 2, 1, antennaSel(1, 0, 2), []int{1, 0}},
+		{"nrx2 identity", 2, 2, antennaSel(0, 1, 2), []int{0, 1}},
+		{"nrx2 invalid perm kept", 2, 1, antennaSel(2, 0, 1), []int{0, 1}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			data := encodeBfee(bfeeSpec{ts: 1000, nrx: tc.nrx, ntx: tc.ntx, antennaSel: tc.sel})
+			records, err := ReadBfeeRecords(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("ReadBfeeRecords: %v", err)
+			}
+			if len(records) != 1 {
+				t.Fatalf("got %d records, want 1", len(records))
+			}
+			rec := records[0]
+			if rec.Nrx != tc.nrx || rec.Ntx != tc.ntx {
+				t.Fatalf("got Nrx=%d Ntx=%d, want %d/%d", rec.Nrx, rec.Ntx, tc.nrx, tc.ntx)
+			}
+			for tx := 0; tx < tc.ntx; tx++ {
+				for rx := 0; rx < tc.nrx; rx++ {
+					for _, sc := range []int{0, 14, 29} {
+						re, im := rawIntelCSI(tx, rx, sc)
+						want := complex(float64(re), float64(im))
+						if got := rec.CSI[tx][tc.want[rx]][sc]; got != want {
+							t.Errorf("CSI[%d][%d][%d] = %v, want %v", tx, tc.want[rx], sc, got, want)
+						}
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestParseIntel5300(t *testing.T) {
+	var data []byte
+	for _, ts := range []uint32{1000, 2000, 4000} {
+		data = append(data, encodeBfee(bfeeSpec{ts: ts, nrx: 3, ntx: 1, antennaSel: antennaSel(0, 1, 2)})...)
+	}
+	// paket dengan konfigurasi antena lain dilewati
+	data = append(data, encodeBfee(bfeeSpec{ts: 5000, nrx: 2, ntx: 1, antennaSel: antennaSel(0, 1, 2)})...)
+	// record terpotong di akhir file diabaikan
+	data = append(data, 0x01)
+
+	c, err := ParseIntel5300(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("ParseIntel5300: %v", err)
+	}
+	if c.Format != FormatIntel5300 || c.Streams != 3 || c.Subcarriers != intelSubcarriers {
+		t.Fatalf("got %s %d×%d, want intel5300 3×%d", c.Format, c.Streams, c.Subcarriers, intelSubcarriers)
+	}
+	if c.Packets() != 3 {
+		t.Fatalf("got %d packets, want 3", c.Packets())
+	}
+	for i, want := range []float64{0, 0.001, 0.003} {
+		if math.Abs(c.Timestamps[i]-want) > 1e-9 {
+			t.Errorf("timestamp[%d] = %g, want %g", i, c.Timestamps[i], want)
+		}
+	}
+	for p := range c.Amplitude {
+		if len(c.Amplitude[p]) != 3*intelSubcarriers || len(c.Phase[p]) != 3*intelSubcarriers {
+			t.Fatalf("packet %d has %d amplitude and %d phase values", p, len(c.Amplitude[p]), len(c.Phase[p]))
+		}
+		for k, v := range c.Amplitude[p] {
+			if !(v > 0) {
+				t.Fatalf("amplitude[%d][%d] = %g, want positive", p, k, v)
+			}
+		}
+		if math.IsNaN(c.RSSI[p]) {
+			t.Fatalf("rssi[%d] is NaN", p)
+		}
+	}
+}
+
+func TestParseIntel5300TimestampWrap(t *testing.T) {
+	// timestamp_low 32-bit mikrodetik wrap dua kali dalam satu capture
+	ts := []uint32{0xFFFFFF00, 0x100, 0xFFFFFFF0, 0x10}
+	var data []byte
+	for _, v := range ts {
+		data = append(data, encodeBfee(bfeeSpec{ts: v, nrx: 1, ntx: 1})...)
+	}
+	c, err := ParseIntel5300(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("ParseIntel5300: %v", err)
+	}
+	want := []float64{0, 0.000512, 4294.967536, 4294.967568}
+	for i := range want {
+		if math.Abs(c.Timestamps[i]-want[i]) > 1e-6 {
+			t.Fatalf("timestamps = %v, want %v", c.Timestamps, want)
+		}
+	}
+}
+
+func TestParseIntel5300Empty(t *testing.T) {
+	if _, err := ParseIntel5300(bytes.NewReader(nil)); err == nil {
+		t.Fatal("expected error for a file without bfee records")
+	}
+}
+
+func TestParseIntel5300Bandwidth(t *testing.T) {
+	for _, tc := range []struct {
+		rate      uint16
+		bandwidth float64
+		spacing   float64
+	}{{0x4101, 20e6, 625e3}, {0x4101 | intelRateHT40, 40e6, 1.25e6}} {
+		data := encodeBfee(bfeeSpec{ts: 0, nrx: 2, ntx: 2, antennaSel: antennaSel(0, 1, 2), rate: tc.rate})
+		c, err := ParseIntel5300(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("ParseIntel5300: %v", err)
+		}
+		// 30 grup subcarrier Intel: tiap 2 subcarrier di HT20, tiap 4 di HT40
+		if c.BandwidthHz != tc.bandwidth || c.SubcarrierSpacingHz != tc.spacing {
+			t.Errorf("rate %#x: bandwidth %g spacing %g, want %g and %g", tc.rate, c.BandwidthHz, c.SubcarrierSpacingHz, tc.bandwidth, tc.spacing)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+
+	"cetasense-v2.0/internal/models"
+	"github.com/go-playground/validator/v10"
+)
+
+// Layout default CSV lama: 3 antena × 30 subcarrier, blok 30-30-30
+const (
+	legacyStreams     = 3
+	legacySubcarriers = 30
+)
+
+// CheckLayout memvalidasi konsistensi layout terhadap jumlah kolom file.
+// nCols <= 0 berarti jumlah kolom belum diketahui.
+func CheckLayout(l models.CSILayout, nCols int) error {
+	if l.Streams < 1 || l.Subcarriers < 1 {
+		return errors.New("layout: streams and subcarriers must be positive")
+	}
+	switch l.Order {
+	case models.LayoutOrderStreamMajor, models.LayoutOrderSubcarrierMajor:
+	default:
+		return fmt.Errorf("layout: unknown order %q", l.Order)
+	}
+	switch l.Encoding {
+	case models.LayoutEncodingAmplitude, models.LayoutEncodingAmplitudePhase, models.LayoutEncodingRealImag:
+	default:
+		return fmt.Errorf("layout: unknown encoding %q", l.Encoding)
+	}
+	if l.Encoding != models.LayoutEncodingAmplitudePhase && l.PhaseOffset != nil {
+		return errors.New("layout: phase_offset only applies to amplitude_phase encoding")
+	}
+	if nCols > 0 && l.MaxColumn() >= nCols {
+		return fmt.Errorf("layout references column %d but file has only %d columns", l.MaxColumn(), nCols)
+	}
+	return nil
+}
+
+// ParseLayout decodes a JSON layout descriptor from an upload form
+func ParseLayout(raw string) (*models.CSILayout, error) {
+	var l models.CSILayout
+	if err := json.Unmarshal([]byte(raw), &l); err != nil {
+		return nil, fmt.Errorf("invalid layout JSON: %w", err)
+	}
+	if l.Order == "" {
+		l.Order = models.LayoutOrderStreamMajor
+	}
+	if l.Encoding == "" {
+		l.Encoding = models.LayoutEncodingAmplitude
+	}
+	l.Source = models.LayoutSourceDeclared
+	if err := validator.New().Struct(l); err != nil {
+		return nil, fmt.Errorf("invalid layout: %w", err)
+	}
+	return &l, nil
+}
+
+// CaptureLayout adalah layout CSV hasil WriteNormalizedCSV
+func CaptureLayout(streams, subcarriers int) models.CSILayout {
+	n := streams * subcarriers
+	phase, rssi, ts := n, 2*n, 2*n+1
+	return models.CSILayout{
+		Streams:      streams,
+		Subcarriers:  subcarriers,
+		Order:        models.LayoutOrderStreamMajor,
+		Encoding:     models.LayoutEncodingAmplitudePhase,
+		DataOffset:   0,
+		PhaseOffset:  &phase,
+		RSSICol:      &rssi,
+		TimestampCol: &ts,
+		HasHeader:    true,
+		Source:       models.LayoutSourceFormat,
+	}
+}
+
+// InferLayout menebak layout file tanpa deskriptor. CSV ternormalisasi
+// dikenali dari header-nya; selain itu dipakai heuristik lama: jendela 90
+// kolom berurutan paling "sehat" sebagai amplitudo 3×30 blok 30-30-30.
+func InferLayout(rows *CSIRows) (models.CSILayout, error) {
+	if rows.Header != nil {
+		if streams, subcarriers, ok := NormalizedShape(rows.Header); ok {
+			return CaptureLayout(streams, subcarriers), nil
+		}
+	}
+	width := legacyStreams * legacySubcarriers
+	if rows.Columns() < width {
+		return models.CSILayout{}, fmt.Errorf("cannot infer layout: CSV must contain at least %d numeric columns (%d×%d) or declare a layout",
+			width, legacyStreams, legacySubcarriers)
+	}
+	start := pickHealthiestWindow(rows.Rows, width)
+	return models.CSILayout{
+		Streams:     legacyStreams,
+		Subcarriers: legacySubcarriers,
+		Order:       models.LayoutOrderStreamMajor,
+		Encoding:    models.LayoutEncodingAmplitude,
+		DataOffset:  start,
+		HasHeader:   rows.Header != nil,
+		Source:      models.LayoutSourceInferred,
+	}, nil
+}
+
+// ResolveLayout memakai layout tersimpan bila ada, selain itu menebak.
+func ResolveLayout(stored *models.CSILayout, rows *CSIRows) (models.CSILayout, error) {
+	if stored != nil {
+		if err := CheckLayout(*stored, rows.Columns()); err != nil {
+			return models.CSILayout{}, err
+		}
+		return *stored, nil
+	}
+	return InferLayout(rows)
+}
+
+// pickHealthiestWindow memilih jendela width kolom berurutan dengan rasio
+// cell valid tertinggi dan mengembalikan kolom awalnya
+func pickHealthiestWindow(data [][]float64, width int) int {
+	if len(data) == 0 {
+		return 0
+	}
+	nCols := len(data[0])
+	// valid ratio per kolom
+	valid := make([]float64, nCols)
+	for j := 0; j < nCols; j++ {
+		c := 0
+		for i := 0; i < len(data); i++ {
+			if j < len(data[i]) && !math.IsNaN(data[i][j]) {
+				c++
+			}
+		}
+		valid[j] = float64(c) / float64(len(data))
+	}
+	// cari window dengan rata-rata valid terbesar
+	bestStart, bestScore := 0, -1.0
+	for start := 0; start <= nCols-width; start++ {
+		sum := 0.0
+		for j := start; j < start+width; j++ {
+			sum += valid[j]
+		}
+		score := sum / float64(width)
+		if score > bestScore {
+			bestScore = score
+			bestStart = start
+		}
+	}
+	return bestStart
+}
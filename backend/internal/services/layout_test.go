@@ -0,0 +1,190 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"cetasense-v2.0/internal/models"
+)
+
+func TestInferLayoutNormalizedCapture(t *testing.T) {
+	c := &Capture{
+		Format:      FormatESP32,
+		Streams:     2,
+		Subcarriers: 3,
+		Timestamps:  []float64{0, 0.01},
+		RSSI:        []float64{-40, -41},
+		Amplitude:   [][]float64{{1, 2, 3, 4, 5, 6}, {6, 5, 4, 3, 2, 1}},
+		Phase:       [][]float64{{0.1, 0.2, 0.3, 0.4, 0.5, 0.6}, {0, 0, 0, 0, 0, 0}},
+	}
+	data, err := NormalizedCSV(c)
+	if err != nil {
+		t.Fatalf("NormalizedCSV: %v", err)
+	}
+	rows, err := ReadCSIRows(bytes.NewReader(data), nil)
+	if err != nil {
+		t.Fatalf("ReadCSIRows: %v", err)
+	}
+	layout, err := InferLayout(rows)
+	if err != nil {
+		t.Fatalf("InferLayout: %v", err)
+	}
+	if layout.Source != models.LayoutSourceFormat || layout.Streams != 2 || layout.Subcarriers != 3 {
+		t.Fatalf("got %+v, want a 2×3 format layout", layout)
+	}
+
+	m, err := ExtractCSI(rows.Rows, layout)
+	if err != nil {
+		t.Fatalf("ExtractCSI: %v", err)
+	}
+	if got := m.Amplitude[1][0][0]; got != 4 {
+		t.Errorf("amplitude stream 1 sc 0 = %g, want 4", got)
+	}
+	if got := m.Phase[0][2][0]; math.Abs(got-0.3) > 1e-9 {
+		t.Errorf("phase stream 0 sc 2 = %g, want 0.3", got)
+	}
+	if m.RSSI[1] != -41 || m.Timestamps[1] != 0.01 {
+		t.Errorf("rssi %v timestamps %v", m.RSSI, m.Timestamps)
+	}
+}
+
+func TestInferLayoutLegacyWindow(t *testing.T) {
+	// dua kolom indeks/label kosong di depan, lalu 90 kolom amplitudo
+	var sb strings.Builder
+	for p := 0; p < 4; p++ {
+		sb.WriteString(",x")
+		for k := 0; k < 90; k++ {
+			fmt.Fprintf(&sb, ",%d", k)
+		}
+		sb.WriteString("\n")
+	}
+	rows, err := ReadCSIRows(strings.NewReader(sb.String()), nil)
+	if err != nil {
+		t.Fatalf("ReadCSIRows: %v", err)
+	}
+	layout, err := InferLayout(rows)
+	if err != nil {
+		t.Fatalf("InferLayout: %v", err)
+	}
+	if layout.DataOffset != 2 || layout.Source != models.LayoutSourceInferred {
+		t.Fatalf("got offset %d source %s, want 2 inferred", layout.DataOffset, layout.Source)
+	}
+	if layout.Encoding != models.LayoutEncodingAmplitude || layout.Cells() != 90 {
+		t.Fatalf("got %s with %d cells, want amplitude 3×30", layout.Encoding, layout.Cells())
+	}
+
+	narrow := &CSIRows{Rows: [][]float64{make([]float64, 89)}}
+	if _, err := InferLayout(narrow); err == nil {
+		t.Fatal("expected error for fewer than 90 columns")
+	}
+}
+
+func TestExtractCSIRealImagSubcarrierMajor(t *testing.T) {
+	ts := 0
+	layout := models.CSILayout{
+		Streams:      2,
+		Subcarriers:  2,
+		Order:        models.LayoutOrderSubcarrierMajor,
+		Encoding:     models.LayoutEncodingRealImag,
+		DataOffset:   1,
+		TimestampCol: &ts,
+	}
+	// kolom: ts, (sc0,st0), (sc0,st1), (sc1,st0), (sc1,st1) sebagai pasangan re,im
+	rows := [][]float64{{5, 3, 4, 0, 1, 1, 0, -2, 0}}
+	m, err := ExtractCSI(rows, layout)
+	if err != nil {
+		t.Fatalf("ExtractCSI: %v", err)
+	}
+	want := [2][2]float64{{5, 1}, {1, 2}} // [stream][subcarrier]
+	for st := range want {
+		for sc := range want[st] {
+			if got := m.Amplitude[st][sc][0]; math.Abs(got-want[st][sc]) > 1e-12 {
+				t.Errorf("amplitude[%d][%d] = %g, want %g", st, sc, got, want[st][sc])
+			}
+		}
+	}
+	if got := m.Phase[1][1][0]; math.Abs(got-math.Pi) > 1e-12 {
+		t.Errorf("phase[1][1] = %g, want π", got)
+	}
+	if m.Timestamps[0] != 5 {
+		t.Errorf("timestamp %g, want 5", m.Timestamps[0])
+	}
+}
+
+func TestCheckLayout(t *testing.T) {
+	one := 1
+	base := models.CSILayout{Streams: 3, Subcarriers: 30, Order: models.LayoutOrderStreamMajor, Encoding: models.LayoutEncodingAmplitude}
+	if err := CheckLayout(base, 90); err != nil {
+		t.Fatalf("valid layout rejected: %v", err)
+	}
+
+	bad := map[string]func(l *models.CSILayout) int{
+		"too few columns":      func(l *models.CSILayout) int { return 89 },
+		"unknown order":        func(l *models.CSILayout) int { l.Order = "row_major"; return 90 },
+		"unknown encoding":     func(l *models.CSILayout) int { l.Encoding = "polar"; return 90 },
+		"phase offset misused": func(l *models.CSILayout) int { l.PhaseOffset = &one; return 90 },
+		"zero streams":         func(l *models.CSILayout) int { l.Streams = 0; return 90 },
+		"real imag overflow":   func(l *models.CSILayout) int { l.Encoding = models.LayoutEncodingRealImag; return 179 },
+	}
+	for name, mutate := range bad {
+		l := base
+		if err := CheckLayout(l, mutate(&l)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestParseLayoutDefaults(t *testing.T) {
+	l, err := ParseLayout(`{"streams":1,"subcarriers":56}`)
+	if err != nil {
+		t.Fatalf("ParseLayout: %v", err)
+	}
+	if l.Order != models.LayoutOrderStreamMajor || l.Encoding != models.LayoutEncodingAmplitude || l.Source != models.LayoutSourceDeclared {
+		t.Fatalf("got %+v", *l)
+	}
+	for _, raw := range []string{`{"streams":0,"subcarriers":30}`, `{"streams":1,"subcarriers":30,"order":"x"}`, `not json`} {
+		if _, err := ParseLayout(raw); err == nil {
+			t.Errorf("ParseLayout(%s): expected error", raw)
+		}
+	}
+}
+
+func TestReadCSIRowsHasHeader(t *testing.T) {
+	// header berupa nomor subcarrier lolos heuristik numerik
+	numericHeader := "1,2,3\n4,5,6\n7,8,9\n"
+	// baris pertama penuh dropout tidak lolos heuristik
+	sparseFirst := "x,,5\n4,5,6\n"
+
+	layout := &models.CSILayout{Streams: 1, Subcarriers: 3, HasHeader: true}
+	rows, err := ReadCSIRows(strings.NewReader(numericHeader), layout)
+	if err != nil {
+		t.Fatalf("ReadCSIRows: %v", err)
+	}
+	if len(rows.Header) != 3 || len(rows.Rows) != 2 || rows.Rows[0][0] != 4 {
+		t.Errorf("has_header=true: header %q rows %v", rows.Header, rows.Rows)
+	}
+	if rows, _ := ReadCSIRows(strings.NewReader(numericHeader), nil); rows.Header != nil || len(rows.Rows) != 3 {
+		t.Errorf("without layout the numeric first row is data, got header %q", rows.Header)
+	}
+
+	layout.HasHeader = false
+	rows, err = ReadCSIRows(strings.NewReader(sparseFirst), layout)
+	if err != nil {
+		t.Fatalf("ReadCSIRows: %v", err)
+	}
+	if rows.Header != nil || len(rows.Rows) != 2 || rows.Rows[0][2] != 5 || !math.IsNaN(rows.Rows[0][0]) {
+		t.Errorf("has_header=false: header %q rows %v", rows.Header, rows.Rows)
+	}
+
+	// profil upload memakai aturan yang sama dengan layout deklarasi
+	res, err := ProfileCSVStream(strings.NewReader(sparseFirst), layout)
+	if err != nil {
+		t.Fatalf("ProfileCSVStream: %v", err)
+	}
+	if res.Profile.Packets != 2 {
+		t.Errorf("profiled %d packets, want 2", res.Profile.Packets)
+	}
+}
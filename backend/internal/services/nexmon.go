@@ -0,0 +1,426 @@
+package services
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Nexmon CSI dikirim sebagai frame UDP ke port 5500
+const (
+	nexmonUDPPort    = 5500
+	nexmonMagic      = 0x1111
+	nexmonHeaderSize = 18
+
+	pcapMagicMicro        = 0xa1b2c3d4
+	pcapMagicNano         = 0xa1b23c4d
+	linkTypeEthernet      = 1
+	linkTypeRaw           = 101
+	linkTypeLinuxCooked   = 113
+	etherTypeIPv4         = 0x0800
+	etherTypeVLAN         = 0x8100
+	ipProtoUDP            = 17
+	nexmonFloatNBits      = 10
+	nexmonFloatNMan       = 12
+	nexmonFloatNExp       = 6
+	nexmonFloatSignMask   = 1 << 31
+	nexmonDefaultMaxFrame = 1 << 18
+)
+
+// Cara decoding CSI Nexmon, tergantung chip
+const (
+	NexmonInt16 = "int16" // bcm4339, bcm43455c0 (Raspberry Pi)
+	NexmonFloat = "float" // bcm4358, bcm4366c0 (format floating-point terkompresi)
+)
+
+// nexmonChipDecoding memetakan field chip version ke format CSI
+var nexmonChipDecoding = map[uint16]string{
+	0x4339: NexmonInt16,
+	0x4345: NexmonInt16,
+	0x4358: NexmonFloat,
+	0x4366: NexmonFloat,
+	0x0065: NexmonFloat, // 4366c0 melaporkan revisi chip
+}
+
+// NexmonFrame adalah satu payload CSI Nexmon (satu core/spatial stream)
+type NexmonFrame struct {
+	Time          float64 // detik (timestamp pcap)
+	RSSI          int
+	FrameControl  byte
+	SourceMAC     [6]byte
+	Sequence      uint16
+	Core          int
+	SpatialStream int
+	Chanspec      uint16
+	ChipVersion   uint16
+	CSI           []complex128 // urutan FFT asli (0..N/2-1, -N/2..-1)
+}
+
+// BandwidthMHz diturunkan dari chanspec (format d11ac)
+func (f *NexmonFrame) BandwidthMHz() int {
+	switch f.Chanspec & 0x3800 {
+	case 0x1000:
+		return 20
+	case 0x1800:
+		return 40
+	case 0x2000:
+		return 80
+	}
+	return 0
+}
+
+// looksLikePcap cek magic number file pcap (kedua endianness)
+func looksLikePcap(head []byte) bool {
+	if len(head) < 4 {
+		return false
+	}
+	for _, m := range []uint32{binary.LittleEndian.Uint32(head), binary.BigEndian.Uint32(head)} {
+		if m == pcapMagicMicro || m == pcapMagicNano {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadNexmonPcap membaca file pcap dan mengembalikan semua frame CSI Nexmon.
+// decoding boleh kosong; bila kosong, format ditentukan dari chip version.
+func ReadNexmonPcap(r io.Reader, decoding string) ([]*NexmonFrame, error) {
+	br := bufio.NewReader(r)
+	gh := make([]byte, 24)
+	if _, err := io.ReadFull(br, gh); err != nil {
+		return nil, fmt.Errorf("read pcap header: %w", err)
+	}
+
+	var order binary.ByteOrder
+	var nano bool
+	switch {
+	case binary.LittleEndian.Uint32(gh) == pcapMagicMicro:
+		order = binary.LittleEndian
+	case binary.LittleEndian.Uint32(gh) == pcapMagicNano:
+		order, nano = binary.LittleEndian, true
+	case binary.BigEndian.Uint32(gh) == pcapMagicMicro:
+		order = binary.BigEndian
+	case binary.BigEndian.Uint32(gh) == pcapMagicNano:
+		order, nano = binary.BigEndian, true
+	default:
+		return nil, errors.New("not a pcap file (pcapng is not supported)")
+	}
+	linkType := order.Uint32(gh[20:24])
+
+	var frames []*NexmonFrame
+	rh := make([]byte, 16)
+	for {
+		if _, err := io.ReadFull(br, rh); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				break
+			}
+			return nil, fmt.Errorf("read pcap record: %w", err)
+		}
+		sec := order.Uint32(rh[0:4])
+		frac := order.Uint32(rh[4:8])
+		inclLen := order.Uint32(rh[8:12])
+		if inclLen > nexmonDefaultMaxFrame {
+			return nil, fmt.Errorf("pcap record too large (%d bytes)", inclLen)
+		}
+		data := make([]byte, inclLen)
+		if _, err := io.ReadFull(br, data); err != nil {
+			break
+		}
+
+		payload := udpPayload(linkType, data, nexmonUDPPort)
+		if payload == nil {
+			continue
+		}
+		ts := float64(sec) + float64(frac)/1e6
+		if nano {
+			ts = float64(sec) + float64(frac)/1e9
+		}
+		frame, err := parseNexmonPayload(payload, decoding)
+		if err != nil {
+			continue
+		}
+		frame.Time = ts
+		frames = append(frames, frame)
+	}
+	return frames, nil
+}
+
+// udpPayload mengupas header link/IPv4/UDP dan mengembalikan payload
+// bila port sumber atau tujuan sama dengan port.
+func udpPayload(linkType uint32, data []byte, port uint16) []byte {
+	var ip []byte
+	switch linkType {
+	case linkTypeEthernet:
+		if len(data) < 14 {
+			return nil
+		}
+		etherType := binary.BigEndian.Uint16(data[12:14])
+		off := 14
+		for etherType == etherTypeVLAN && len(data) >= off+4 {
+			etherType = binary.BigEndian.Uint16(data[off+2 : off+4])
+			off += 4
+		}
+		if etherType != etherTypeIPv4 {
+			return nil
+		}
+		ip = data[off:]
+	case linkTypeLinuxCooked:
+		if len(data) < 16 || binary.BigEndian.Uint16(data[14:16]) != etherTypeIPv4 {
+			return nil
+		}
+		ip = data[16:]
+	case linkTypeRaw:
+		ip = data
+	default:
+		return nil
+	}
+
+	if len(ip) < 20 || ip[0]>>4 != 4 || ip[9] != ipProtoUDP {
+		return nil
+	}
+	ihl := int(ip[0]&0x0f) * 4
+	if len(ip) < ihl+8 {
+		return nil
+	}
+	udp := ip[ihl:]
+	src := binary.BigEndian.Uint16(udp[0:2])
+	dst := binary.BigEndian.Uint16(udp[2:4])
+	if src != port && dst != port {
+		return nil
+	}
+	udpLen := int(binary.BigEndian.Uint16(udp[4:6]))
+	if udpLen < 8 || udpLen > len(udp) {
+		udpLen = len(udp)
+	}
+	return udp[8:udpLen]
+}
+
+func parseNexmonPayload(p []byte, decoding string) (*NexmonFrame, error) {
+	if len(p) < nexmonHeaderSize+4 {
+		return nil, errors.New("nexmon payload too short")
+	}
+	if binary.LittleEndian.Uint16(p[0:2]) != nexmonMagic {
+		return nil, errors.New("bad nexmon magic")
+	}
+	f := &NexmonFrame{
+		RSSI:         int(int8(p[2])),
+		FrameControl: p[3],
+		Sequence:     binary.LittleEndian.Uint16(p[10:12]),
+		Chanspec:     binary.LittleEndian.Uint16(p[14:16]),
+		ChipVersion:  binary.LittleEndian.Uint16(p[16:18]),
+	}
+	copy(f.SourceMAC[:], p[4:10])
+	coreSS := binary.LittleEndian.Uint16(p[12:14])
+	f.Core = int(coreSS & 0x7)
+	f.SpatialStream = int((coreSS >> 3) & 0x7)
+
+	nfft := (len(p) - nexmonHeaderSize) / 4
+	body := p[nexmonHeaderSize : nexmonHeaderSize+nfft*4]
+
+	if decoding == "" {
+		decoding = nexmonChipDecoding[f.ChipVersion]
+		if decoding == "" {
+			decoding = NexmonInt16
+		}
+	}
+	switch decoding {
+	case NexmonInt16:
+		f.CSI = make([]complex128, nfft)
+		for k := 0; k < nfft; k++ {
+			re := int16(binary.LittleEndian.Uint16(body[4*k:]))
+			im := int16(binary.LittleEndian.Uint16(body[4*k+2:]))
+			f.CSI[k] = complex(float64(re), float64(im))
+		}
+	case NexmonFloat:
+		raw := make([]uint32, nfft)
+		for k := range raw {
+			raw[k] = binary.LittleEndian.Uint32(body[4*k:])
+		}
+		f.CSI = unpackNexmonFloat(raw)
+	default:
+		return nil, fmt.Errorf("unknown nexmon decoding %q", decoding)
+	}
+	return f, nil
+}
+
+// unpackNexmonFloat adalah port dari unpack_float_acphy (nexmon_csi,
+// nbits=10, autoscale=1, nman=12, nexp=6).
+func unpackNexmonFloat(h []uint32) []complex128 {
+	nman, nexp := uint(nexmonFloatNMan), uint(nexmonFloatNExp)
+	iqMask := uint32(1<<(nman-1)) - 1
+	eMask := uint32(1<<nexp) - 1
+	eP := 1 << (nexp - 1)
+	sgnrMask := uint32(1) << (nexp + 2*nman - 1)
+	sgniMask := sgnrMask >> nman
+	eZero := -int(nman)
+
+	n := len(h)
+	he := make([]int, n)
+	out := make([]uint32, 2*n)
+	maxbit := -eP
+	for i, v := range h {
+		vi := (v >> (nexp + nman)) & iqMask
+		vq := (v >> nexp) & iqMask
+		e := int(v & eMask)
+		if e >= eP {
+			e -= eP << 1
+		}
+		he[i] = e
+		if x := vi | vq; x != 0 {
+			m, b := uint32(0xffff0000), uint32(0xffff)
+			s := uint(16)
+			for s > 0 {
+				if x&m != 0 {
+					e += int(s)
+					x >>= s
+				}
+				s >>= 1
+				m = (m >> s) & b
+				b >>= s
+			}
+			if e > maxbit {
+				maxbit = e
+			}
+		}
+		if v&sgnrMask != 0 {
+			vi |= nexmonFloatSignMask
+		}
+		if v&sgniMask != 0 {
+			vq |= nexmonFloatSignMask
+		}
+		out[2*i] = vi
+		out[2*i+1] = vq
+	}
+
+	shft := nexmonFloatNBits - maxbit
+	vals := make([]float64, 2*n)
+	for i, raw := range out {
+		e := he[i>>1] + shft
+		sgn := 1.0
+		if raw&nexmonFloatSignMask != 0 {
+			sgn = -1
+			raw &^= nexmonFloatSignMask
+		}
+		var v float64
+		switch {
+		case e < eZero:
+			v = 0
+		case e < 0:
+			v = float64(raw >> uint(-e))
+		default:
+			v = float64(raw) * math.Pow(2, float64(e))
+		}
+		vals[i] = sgn * v
+	}
+
+	csi := make([]complex128, n)
+	for k := range csi {
+		csi[k] = complex(vals[2*k], vals[2*k+1])
+	}
+	return csi
+}
+
+// ParseNexmon men-decode pcap Nexmon menjadi Capture. Frame dengan nomor
+// sequence yang sama (satu per core/spatial stream) digabung menjadi satu
+// paket; stream yang tidak terekam diisi NaN. Semua nfft subcarrier
+// (64/128/256) dipertahankan dan diurutkan dari -N/2 ke N/2-1.
+func ParseNexmon(r io.Reader, decoding string) (*Capture, error) {
+	frames, err := ReadNexmonPcap(r, decoding)
+	if err != nil {
+		return nil, err
+	}
+	if len(frames) == 0 {
+		return nil, fmt.Errorf("no nexmon CSI frames on UDP port %d", nexmonUDPPort)
+	}
+
+	// pakai ukuran FFT yang paling sering muncul
+	counts := map[int]int{}
+	for _, f := range frames {
+		counts[len(f.CSI)]++
+	}
+	nfft := 0
+	for n, c := range counts {
+		if c > counts[nfft] || (c == counts[nfft] && n > nfft) {
+			nfft = n
+		}
+	}
+
+	streamIdx := map[int]int{}
+	var keys []int
+	for _, f := range frames {
+		if len(f.CSI) != nfft {
+			continue
+		}
+		key := f.Core<<3 | f.SpatialStream
+		if _, ok := streamIdx[key]; !ok {
+			streamIdx[key] = 0
+			keys = append(keys, key)
+		}
+	}
+	sort.Ints(keys)
+	for i, k := range keys {
+		streamIdx[k] = i
+	}
+
+	c := &Capture{
+		Format:              FormatNexmon,
+		Streams:             len(keys),
+		Subcarriers:         nfft,
+		BandwidthHz:         float64(nfft) * ofdmSubcarrierHz,
+		SubcarrierSpacingHz: ofdmSubcarrierHz,
+	}
+	width := c.Streams * nfft
+	var t0 float64
+	lastSeq := -1
+	for _, f := range frames {
+		if len(f.CSI) != nfft {
+			continue
+		}
+		if int(f.Sequence) != lastSeq || len(c.Amplitude) == 0 {
+			amp := make([]float64, width)
+			phase := make([]float64, width)
+			for k := range amp {
+				amp[k] = math.NaN()
+				phase[k] = math.NaN()
+			}
+			if len(c.Amplitude) == 0 {
+				t0 = f.Time
+			}
+			c.Amplitude = append(c.Amplitude, amp)
+			c.Phase = append(c.Phase, phase)
+			c.RSSI = append(c.RSSI, float64(f.RSSI))
+			c.Timestamps = append(c.Timestamps, f.Time-t0)
+			lastSeq = int(f.Sequence)
+		}
+		p := len(c.Amplitude) - 1
+		base := streamIdx[f.Core<<3|f.SpatialStream] * nfft
+		half := nfft / 2
+		for k := 0; k < nfft; k++ {
+			// fftshift: indeks FFT k -> posisi (k+half) mod nfft
+			v := f.CSI[k]
+			pos := (k + half) % nfft
+			c.Amplitude[p][base+pos] = math.Hypot(real(v), imag(v))
+			c.Phase[p][base+pos] = math.Atan2(imag(v), real(v))
+		}
+	}
+	return c, nil
+}
+
+// NexmonDecodingFromForm menormalkan override decoding dari form upload
+// (nama chip atau nama format)
+func NexmonDecodingFromForm(v string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(v)) {
+	case "":
+		return "", nil
+	case NexmonInt16, "4339", "bcm4339", "43455c0", "bcm43455c0":
+		return NexmonInt16, nil
+	case NexmonFloat, "4358", "bcm4358", "4366c0", "bcm4366c0":
+		return NexmonFloat, nil
+	}
+	return "", fmt.Errorf("unknown nexmon chip/decoding %q", v)
+}
@@ -0,0 +1,165 @@
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+// nexmonFrameSpec menjelaskan satu frame CSI Nexmon sintetis (int16)
+type nexmonFrameSpec struct {
+	usec uint32
+	seq  uint16
+	core int
+	nfft int
+}
+
+// nexmonPayload: CSI pada indeks FFT k bernilai (k+1) + j·0
+func nexmonPayload(f nexmonFrameSpec) []byte {
+	p := make([]byte, nexmonHeaderSize+4*f.nfft)
+	binary.LittleEndian.PutUint16(p[0:2], nexmonMagic)
+	p[2] = byte(0xC4) // rssi -60
+	copy(p[4:10], []byte{1, 2, 3, 4, 5, 6})
+	binary.LittleEndian.PutUint16(p[10:12], f.seq)
+	binary.LittleEndian.PutUint16(p[12:14], uint16(f.core))
+	binary.LittleEndian.PutUint16(p[14:16], 0x1000|36)
+	binary.LittleEndian.PutUint16(p[16:18], 0x4345)
+	for k := 0; k < f.nfft; k++ {
+		binary.LittleEndian.PutUint16(p[nexmonHeaderSize+4*k:], uint16(k+1))
+	}
+	return p
+}
+
+// nexmonPcap membungkus frame sebagai Ethernet/IPv4/UDP:5500 di pcap
+func nexmonPcap(frames []nexmonFrameSpec) []byte {
+	var buf bytes.Buffer
+	gh := make([]byte, 24)
+	binary.LittleEndian.PutUint32(gh[0:4], pcapMagicMicro)
+	binary.LittleEndian.PutUint16(gh[4:6], 2)
+	binary.LittleEndian.PutUint16(gh[6:8], 4)
+	binary.LittleEndian.PutUint32(gh[16:20], 65535)
+	binary.LittleEndian.PutUint32(gh[20:24], linkTypeEthernet)
+	buf.Write(gh)
+	for _, f := range frames {
+		payload := nexmonPayload(f)
+		pkt := make([]byte, 14+20+8, 14+20+8+len(payload))
+		binary.BigEndian.PutUint16(pkt[12:14], etherTypeIPv4)
+		ip := pkt[14:34]
+		ip[0] = 0x45
+		ip[9] = ipProtoUDP
+		binary.BigEndian.PutUint16(ip[2:4], uint16(20+8+len(payload)))
+		udp := pkt[34:42]
+		binary.BigEndian.PutUint16(udp[0:2], nexmonUDPPort)
+		binary.BigEndian.PutUint16(udp[2:4], nexmonUDPPort)
+		binary.BigEndian.PutUint16(udp[4:6], uint16(8+len(payload)))
+		pkt = append(pkt, payload...)
+
+		rh := make([]byte, 16)
+		binary.LittleEndian.PutUint32(rh[0:4], 100)
+		binary.LittleEndian.PutUint32(rh[4:8], f.usec)
+		binary.LittleEndian.PutUint32(rh[8:12], uint32(len(pkt)))
+		binary.LittleEndian.PutUint32(rh[12:16], uint32(len(pkt)))
+		buf.Write(rh)
+		buf.Write(pkt)
+	}
+	return buf.Bytes()
+}
+
+func TestParseNexmon(t *testing.T) {
+	cases := []struct {
+		name        string
+		frames      []nexmonFrameSpec
+		decoding    string
+		wantStreams int
+		wantSC      int
+		wantTimes   []float64
+		absent      [][2]int // [paket, stream] yang harus NaN
+	}{
+		{
+			name: "single core",
+			frames: []nexmonFrameSpec{
+				{usec: 0, seq: 1, nfft: 64},
+				{usec: 10000, seq: 2, nfft: 64},
+			},
+			wantStreams: 1,
+			wantSC:      64,
+			wantTimes:   []float64{0, 0.01},
+		},
+		{
+			name: "two cores with a missing frame",
+			frames: []nexmonFrameSpec{
+				{usec: 0, seq: 1, core: 0, nfft: 64},
+				{usec: 10, seq: 1, core: 1, nfft: 64},
+				{usec: 5000, seq: 2, core: 0, nfft: 64},
+			},
+			decoding:    NexmonInt16,
+			wantStreams: 2,
+			wantSC:      64,
+			wantTimes:   []float64{0, 0.005},
+			absent:      [][2]int{{1, 1}},
+		},
+		{
+			name: "minority fft size dropped",
+			frames: []nexmonFrameSpec{
+				{usec: 0, seq: 1, nfft: 128},
+				{usec: 100, seq: 2, nfft: 64},
+				{usec: 200, seq: 3, nfft: 128},
+			},
+			wantStreams: 1,
+			wantSC:      128,
+			wantTimes:   []float64{0, 0.0002},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := ParseNexmon(bytes.NewReader(nexmonPcap(tc.frames)), tc.decoding)
+			if err != nil {
+				t.Fatalf("ParseNexmon: %v", err)
+			}
+			if c.Streams != tc.wantStreams || c.Subcarriers != tc.wantSC {
+				t.Fatalf("shape %d×%d, want %d×%d", c.Streams, c.Subcarriers, tc.wantStreams, tc.wantSC)
+			}
+			if c.Packets() != len(tc.wantTimes) {
+				t.Fatalf("got %d packets, want %d", c.Packets(), len(tc.wantTimes))
+			}
+			for i, want := range tc.wantTimes {
+				if math.Abs(c.Timestamps[i]-want) > 1e-9 {
+					t.Errorf("timestamp[%d] = %g, want %g", i, c.Timestamps[i], want)
+				}
+			}
+			isAbsent := func(p, st int) bool {
+				for _, a := range tc.absent {
+					if a == [2]int{p, st} {
+						return true
+					}
+				}
+				return false
+			}
+			half := tc.wantSC / 2
+			for p := range c.Amplitude {
+				for st := 0; st < c.Streams; st++ {
+					for pos := 0; pos < tc.wantSC; pos++ {
+						got := c.Amplitude[p][st*tc.wantSC+pos]
+						if isAbsent(p, st) {
+							if !math.IsNaN(got) {
+								t.Fatalf("amplitude[%d][%d] = %g, want NaN for absent core", p, st, got)
+							}
+							continue
+						}
+						// fftshift: posisi pos berasal dari indeks FFT (pos+half) mod N
+						if want := float64((pos+half)%tc.wantSC + 1); got != want {
+							t.Fatalf("amplitude[%d][%d][%d] = %g, want %g", p, st, pos, got, want)
+						}
+					}
+				}
+			}
+
+			// core yang tidak terekam tidak dihitung sebagai gagal parse
+			_, profile := ProfileCapture(c)
+			if profile.ParseRatio != 1 {
+				t.Errorf("parse ratio %g, want 1", profile.ParseRatio)
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package services
+
+import (
+	"fmt"
+	"math"
+
+	"cetasense-v2.0/internal/models"
+)
+
+// QualityThresholds adalah batas minimum kualitas upload; nilai nol
+// menonaktifkan pengecekan terkait.
+type QualityThresholds struct {
+	MinPackets      int
+	MinParseRatio   float64
+	MaxMeanDropout  float64
+	MinPacketRateHz float64
+}
+
+// CSIProfiler menghitung profil capture baris demi baris sehingga bisa
+// dipakai juga saat file di-stream.
+type CSIProfiler struct {
+	layout *models.CSILayout
+
+	packets  int
+	columns  int
+	cells    int
+	parsed   int
+	dropouts []int // per sel layout (stream-major)
+	ampMin   float64
+	ampMax   float64
+	tsFirst  float64
+	tsLast   float64
+	tsValid  int
+}
+
+// NewCSIProfiler membuat profiler; layout boleh nil
+func NewCSIProfiler(layout *models.CSILayout) *CSIProfiler {
+	p := &CSIProfiler{
+		layout: layout,
+		ampMin: math.Inf(1),
+		ampMax: math.Inf(-1),
+	}
+	if layout != nil {
+		p.dropouts = make([]int, layout.Cells())
+	}
+	return p
+}
+
+// Add memasukkan satu baris (paket) ke profil. unparsed adalah jumlah cell
+// yang gagal di-parse menurut parser; cell NaN tidak dihitung gagal karena
+// bisa berarti data memang tidak ada (mis. core Nexmon yang tidak terekam).
+func (p *CSIProfiler) Add(row []float64, unparsed int) {
+	p.packets++
+	if len(row) > p.columns {
+		p.columns = len(row)
+	}
+	p.cells += len(row)
+	p.parsed += len(row) - unparsed
+	if p.layout == nil {
+		return
+	}
+
+	at := func(col int) float64 {
+		if col < len(row) {
+			return row[col]
+		}
+		return math.NaN()
+	}
+	l := p.layout
+	for st := 0; st < l.Streams; st++ {
+		for sc := 0; sc < l.Subcarriers; sc++ {
+			col := l.Column(st, sc)
+			v := at(col)
+			if l.Encoding == models.LayoutEncodingRealImag {
+				v = math.Hypot(v, at(col+1))
+			}
+			if math.IsNaN(v) || v <= 0 {
+				p.dropouts[st*l.Subcarriers+sc]++
+				continue
+			}
+			p.ampMin = math.Min(p.ampMin, v)
+			p.ampMax = math.Max(p.ampMax, v)
+		}
+	}
+	if l.TimestampCol != nil {
+		if ts := at(*l.TimestampCol); !math.IsNaN(ts) {
+			ts *= l.TimestampScale()
+			if p.tsValid == 0 {
+				p.tsFirst = ts
+			}
+			p.tsLast = ts
+			p.tsValid++
+		}
+	}
+}
+
+// Report menghasilkan profil akhir (tanpa evaluasi kualitas)
+func (p *CSIProfiler) Report() *models.CSIProfile {
+	r := &models.CSIProfile{
+		Packets: p.packets,
+		Columns: p.columns,
+	}
+	if p.cells > 0 {
+		r.ParseRatio = float64(p.parsed) / float64(p.cells)
+	}
+	if p.layout == nil || p.packets == 0 {
+		return r
+	}
+
+	l := p.layout
+	r.Streams, r.Subcarriers = l.Streams, l.Subcarriers
+	r.DropoutRatio = make([][]float64, l.Streams)
+	sum := 0.0
+	for st := 0; st < l.Streams; st++ {
+		r.DropoutRatio[st] = make([]float64, l.Subcarriers)
+		for sc := 0; sc < l.Subcarriers; sc++ {
+			ratio := float64(p.dropouts[st*l.Subcarriers+sc]) / float64(p.packets)
+			r.DropoutRatio[st][sc] = ratio
+			sum += ratio
+			r.MaxDropout = math.Max(r.MaxDropout, ratio)
+		}
+	}
+	r.MeanDropout = sum / float64(l.Cells())
+
+	if !math.IsInf(p.ampMin, 1) {
+		lo, hi := p.ampMin, p.ampMax
+		r.AmplitudeMin, r.AmplitudeMax = &lo, &hi
+	}
+	if p.tsValid >= 2 {
+		duration := p.tsLast - p.tsFirst
+		r.DurationSec = &duration
+		if duration > 0 {
+			rate := float64(p.tsValid-1) / duration
+			r.PacketRateHz = &rate
+		}
+	}
+	return r
+}
+
+// EvaluateProfile mengisi Quality dan Issues berdasarkan ambang batas.
+// Mengembalikan true bila profil lolos semua pengecekan.
+func EvaluateProfile(r *models.CSIProfile, t QualityThresholds, hasLayout bool) bool {
+	r.Issues = nil
+	if t.MinPackets > 0 && r.Packets < t.MinPackets {
+		r.Issues = append(r.Issues, fmt.Sprintf("only %d packets (minimum %d)", r.Packets, t.MinPackets))
+	}
+	if t.MinParseRatio > 0 && r.ParseRatio < t.MinParseRatio {
+		r.Issues = append(r.Issues, fmt.Sprintf("parse ratio %.3f below %.3f", r.ParseRatio, t.MinParseRatio))
+	}
+	if !hasLayout {
+		r.Issues = append(r.Issues, "column layout unknown; subcarrier checks skipped")
+	} else if t.MaxMeanDropout > 0 && r.MeanDropout > t.MaxMeanDropout {
+		r.Issues = append(r.Issues, fmt.Sprintf("mean dropout %.3f above %.3f", r.MeanDropout, t.MaxMeanDropout))
+	}
+	if t.MinPacketRateHz > 0 && r.PacketRateHz != nil && *r.PacketRateHz < t.MinPacketRateHz {
+		r.Issues = append(r.Issues, fmt.Sprintf("packet rate %.1f Hz below %.1f Hz", *r.PacketRateHz, t.MinPacketRateHz))
+	}
+	if len(r.Issues) == 0 {
+		r.Quality = models.QualityOK
+		return true
+	}
+	r.Quality = models.QualityFlagged
+	return false
+}
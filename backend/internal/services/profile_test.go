@@ -0,0 +1,97 @@
+package services
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"cetasense-v2.0/internal/models"
+)
+
+func TestCSIProfiler(t *testing.T) {
+	ts := 0
+	layout := &models.CSILayout{
+		Streams:       1,
+		Subcarriers:   3,
+		Order:         models.LayoutOrderStreamMajor,
+		Encoding:      models.LayoutEncodingAmplitude,
+		DataOffset:    1,
+		TimestampCol:  &ts,
+		TimestampUnit: "ms",
+	}
+	nan := math.NaN()
+	rows := [][]float64{
+		{0, 1, 2, 3},
+		{10, 4, nan, 6}, // cell gagal di-parse
+		{20, 0, 8, 9},   // amplitudo nol dihitung dropout
+		{30, 2, 2, 2},
+		{40, 7, nan, 1}, // data memang tidak ada, bukan gagal parse
+	}
+	unparsed := []int{0, 1, 0, 0, 0}
+	p := NewCSIProfiler(layout)
+	for i, row := range rows {
+		p.Add(row, unparsed[i])
+	}
+	r := p.Report()
+
+	if r.Packets != 5 || r.Columns != 4 || r.Streams != 1 || r.Subcarriers != 3 {
+		t.Fatalf("got %+v", r)
+	}
+	if math.Abs(r.ParseRatio-19.0/20) > 1e-12 {
+		t.Errorf("parse ratio %g, want 0.95", r.ParseRatio)
+	}
+	want := []float64{0.2, 0.4, 0}
+	for sc, w := range want {
+		if math.Abs(r.DropoutRatio[0][sc]-w) > 1e-12 {
+			t.Errorf("dropout[0][%d] = %g, want %g", sc, r.DropoutRatio[0][sc], w)
+		}
+	}
+	if r.MaxDropout != 0.4 || math.Abs(r.MeanDropout-0.2) > 1e-12 {
+		t.Errorf("max %g mean %g, want 0.4 and 0.2", r.MaxDropout, r.MeanDropout)
+	}
+	if *r.AmplitudeMin != 1 || *r.AmplitudeMax != 9 {
+		t.Errorf("amplitude range [%g, %g], want [1, 9]", *r.AmplitudeMin, *r.AmplitudeMax)
+	}
+	// 5 paket dalam 40 ms
+	if math.Abs(*r.DurationSec-0.04) > 1e-12 || math.Abs(*r.PacketRateHz-100) > 1e-9 {
+		t.Errorf("duration %g rate %g, want 0.04 s and 100 Hz", *r.DurationSec, *r.PacketRateHz)
+	}
+}
+
+func TestCSIProfilerWithoutLayout(t *testing.T) {
+	res, err := ProfileCSVStream(strings.NewReader("1,2,x\n3,4,5\n"), nil)
+	if err != nil {
+		t.Fatalf("ProfileCSVStream: %v", err)
+	}
+	r := res.Profile
+	if r.Packets != 2 || r.DropoutRatio != nil || r.PacketRateHz != nil {
+		t.Fatalf("got %+v", r)
+	}
+	if math.Abs(r.ParseRatio-5.0/6) > 1e-12 {
+		t.Errorf("parse ratio %g, want 5/6", r.ParseRatio)
+	}
+}
+
+func TestEvaluateProfile(t *testing.T) {
+	slow := 5.0
+	r := &models.CSIProfile{Packets: 40, ParseRatio: 0.99, MeanDropout: 0.3, PacketRateHz: &slow}
+	th := QualityThresholds{MinPackets: 100, MinParseRatio: 0.95, MaxMeanDropout: 0.2, MinPacketRateHz: 10}
+
+	if EvaluateProfile(r, th, true) {
+		t.Fatal("profile should be flagged")
+	}
+	if r.Quality != models.QualityFlagged || len(r.Issues) != 3 {
+		t.Fatalf("quality %s issues %q, want flagged with packets, dropout and rate issues", r.Quality, r.Issues)
+	}
+
+	// tanpa layout, dropout tidak dievaluasi tetapi dicatat sebagai issue
+	r.Packets = 500
+	r.PacketRateHz = nil
+	if EvaluateProfile(r, th, false) || len(r.Issues) != 1 {
+		t.Fatalf("issues %q, want only the unknown layout note", r.Issues)
+	}
+
+	if !EvaluateProfile(r, QualityThresholds{}, true) || r.Quality != models.QualityOK || r.Issues != nil {
+		t.Fatalf("zero thresholds should pass, got %s %q", r.Quality, r.Issues)
+	}
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"errors"
+	"math"
+
+	"cetasense-v2.0/internal/dsp"
+)
+
+// ResampleMatrix menginterpolasi CSI ke grid waktu seragam rate Hz yang
+// dimulai di timestamp valid pertama. Paket dengan timestamp NaN atau tidak
+// naik dilewati; amplitudo <= 0 dianggap dropout, fase di-unwrap sepanjang
+// waktu sebelum diinterpolasi lalu dibungkus lagi ke (-π, π]. Titik grid di
+// dalam celah lebih dari maxGap detik bernilai NaN. Grid lebih dari
+// maxPackets titik (0 = tanpa batas) ditolak dengan dsp.ErrGridTooLong.
+func ResampleMatrix(m *CSIMatrix, rate float64, method string, maxGap float64, maxPackets int) (*CSIMatrix, error) {
+	if m.Timestamps == nil {
+		return nil, errors.New("capture has no timestamps")
+	}
+	if rate <= 0 {
+		return nil, errors.New("resample rate must be positive")
+	}
+	var idx []int
+	last := math.Inf(-1)
+	for i, t := range m.Timestamps {
+		if !math.IsNaN(t) && !math.IsInf(t, 0) && t > last {
+			idx = append(idx, i)
+			last = t
+		}
+	}
+	if len(idx) < 2 {
+		return nil, errors.New("capture needs at least two valid timestamps")
+	}
+	t := make([]float64, len(idx))
+	for i, p := range idx {
+		t[i] = m.Timestamps[p]
+	}
+	grid, err := dsp.UniformGrid(t[0], t[len(t)-1], rate, maxPackets)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := make([]float64, len(idx))
+	resample := func(x []float64, prep func(float64) float64) ([]float64, error) {
+		for i, p := range idx {
+			buf[i] = prep(x[p])
+		}
+		return dsp.Resample(t, buf, grid, method, maxGap)
+	}
+	amplitude := func(v float64) float64 {
+		if v <= 0 {
+			return math.NaN()
+		}
+		return v
+	}
+	identity := func(v float64) float64 { return v }
+
+	out := &CSIMatrix{Layout: m.Layout, Packets: len(grid), Timestamps: grid}
+	out.Amplitude = make([][][]float64, len(m.Amplitude))
+	for c := range m.Amplitude {
+		out.Amplitude[c] = make([][]float64, len(m.Amplitude[c]))
+		for s, x := range m.Amplitude[c] {
+			y, err := resample(x, amplitude)
+			if err != nil {
+				return nil, err
+			}
+			out.Amplitude[c][s] = y
+		}
+	}
+	if m.Phase != nil {
+		out.Phase = make([][][]float64, len(m.Phase))
+		for c := range m.Phase {
+			out.Phase[c] = make([][]float64, len(m.Phase[c]))
+			for s, x := range m.Phase[c] {
+				sel := make([]float64, len(idx))
+				for i, p := range idx {
+					sel[i] = x[p]
+				}
+				y, err := dsp.Resample(t, dsp.Unwrap(sel), grid, method, maxGap)
+				if err != nil {
+					return nil, err
+				}
+				for i, v := range y {
+					y[i] = math.Remainder(v, 2*math.Pi)
+				}
+				out.Phase[c][s] = y
+			}
+		}
+	}
+	if m.RSSI != nil {
+		y, err := resample(m.RSSI, identity)
+		if err != nil {
+			return nil, err
+		}
+		out.RSSI = y
+	}
+	return out, nil
+}
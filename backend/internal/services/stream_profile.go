@@ -0,0 +1,107 @@
+package services
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"cetasense-v2.0/internal/models"
+)
+
+// jumlah baris awal yang ditahan di memori untuk auto-detect layout
+const inferSampleRows = 2000
+
+// StreamProfileResult adalah hasil ProfileCSVStream
+type StreamProfileResult struct {
+	Layout    *models.CSILayout // nil bila layout tidak bisa ditentukan
+	LayoutErr error             // alasan layout nil
+	Profile   *models.CSIProfile
+}
+
+// ProfileCSVStream membaca CSV CSI secara streaming: beberapa ribu baris
+// awal dipakai untuk menentukan layout (declared atau auto-detect), lalu
+// seluruh baris dimasukkan ke profiler tanpa menahan file di memori.
+func ProfileCSVStream(r io.Reader, declared *models.CSILayout) (*StreamProfileResult, error) {
+	reader := csv.NewReader(r)
+	reader.ReuseRecord = true
+
+	first, err := reader.Read()
+	if err != nil {
+		if err == io.EOF {
+			return nil, errors.New("empty CSV")
+		}
+		return nil, fmt.Errorf("read CSV: %w", err)
+	}
+	sample := &CSIRows{}
+	unparsed := []int{} // jumlah cell gagal per baris sample
+	vals, ratio := ReadFloatRow(first)
+	if isHeaderRow(ratio, declared) {
+		sample.Header = append([]string(nil), first...)
+	} else {
+		sample.Rows = append(sample.Rows, vals)
+		unparsed = append(unparsed, unparsedCells(vals, ratio))
+	}
+
+	eof := false
+	for len(sample.Rows) < inferSampleRows {
+		row, err := reader.Read()
+		if err == io.EOF {
+			eof = true
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parse CSV: %w", err)
+		}
+		v, ratio := ReadFloatRow(row)
+		sample.Rows = append(sample.Rows, v)
+		unparsed = append(unparsed, unparsedCells(v, ratio))
+	}
+	if len(sample.Rows) == 0 {
+		return nil, errors.New("empty CSV")
+	}
+
+	res := &StreamProfileResult{}
+	if layout, err := ResolveLayout(declared, sample); err != nil {
+		res.LayoutErr = err
+	} else {
+		res.Layout = &layout
+	}
+
+	profiler := NewCSIProfiler(res.Layout)
+	for i, row := range sample.Rows {
+		profiler.Add(row, unparsed[i])
+	}
+	for !eof {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parse CSV at packet %d: %w", profiler.packets+1, err)
+		}
+		v, ratio := ReadFloatRow(row)
+		profiler.Add(v, unparsedCells(v, ratio))
+	}
+	res.Profile = profiler.Report()
+	return res, nil
+}
+
+// unparsedCells mengubah rasio ReadFloatRow kembali menjadi jumlah cell
+// yang gagal di-parse
+func unparsedCells(row []float64, ratio float64) int {
+	return len(row) - int(math.Round(ratio*float64(len(row))))
+}
+
+// ProfileCapture memprofilkan capture biner yang sudah di-decode
+func ProfileCapture(c *Capture) (*models.CSILayout, *models.CSIProfile) {
+	layout := CaptureLayout(c.Streams, c.Subcarriers)
+	layout.BandwidthHz, layout.SubcarrierSpacingHz = c.BandwidthHz, c.SubcarrierSpacingHz
+	profiler := NewCSIProfiler(&layout)
+	for p := 0; p < c.Packets(); p++ {
+		// seluruh cell capture biner ter-decode; NaN berarti stream tidak ada
+		profiler.Add(c.Row(p), 0)
+	}
+	return &layout, profiler.Report()
+}